@@ -1,121 +1,857 @@
 package action_sequencer
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 	"tsw_controller_app/chan_utils"
 	"tsw_controller_app/logger"
+	"tsw_controller_app/map_utils"
 	"tsw_controller_app/tswconnector"
-
-	"github.com/go-vgo/robotgo"
 )
 
 const ACTIONS_QUEUE_BUFFER_SIZE = 32
 
+/* scroll steps which are waiting to be sent by the mouse output */
+const SCROLL_QUEUE_BUFFER_SIZE = 32
+
+/* the delay between pressing (or releasing) the modifier keys and the other keys */
+const MODIFIER_GROUP_DELAY = 30 * time.Millisecond
+
+/* the interval in which continuous scrolling emits scroll steps */
+const SCROLL_TICK_INTERVAL = 20 * time.Millisecond
+
+type ActionSequencerScrollVelocity struct {
+	/* scroll steps per second; positive values scroll right and up */
+	X float64
+	Y float64
+}
+
+type ActionSequencerMouseAction struct {
+	/* movement in pixels; relative to the current position unless absolute */
+	MoveX    int
+	MoveY    int
+	Absolute bool
+	/* the button to click (with a press time) or hold; left, right or middle */
+	Button string
+	/* scroll steps; positive values scroll right and up */
+	ScrollX int
+	ScrollY int
+	/* sets the continuous scroll velocity of the action source; a zero velocity stops scrolling */
+	ScrollVelocity *ActionSequencerScrollVelocity
+}
+
 type ActionSequencerAction struct {
-	Keys      string
+	Keys string
+	/* mouse actions are executed instead of the keys when set */
+	Mouse     *ActionSequencerMouseAction
 	PressTime float64
 	WaitTime  float64
 	Release   bool
+	/* identifies where the action came from (eg: the control name); actions from the same source are executed in order */
+	Source string
+}
+
+type ActionSequencerSequence_Step struct {
+	/* key action to queue for this step; the step waits until its press and wait time have passed. Optional */
+	Action *ActionSequencerAction
+	/* non-key actions (eg: direct control) are executed through a callback; optional */
+	Callback func()
+	/* time in seconds to wait after the step (and its action) before continuing with the next step */
+	Delay float64
+}
+
+type ActionSequencerSequence struct {
+	/* identifies the sequence; starting a sequence with the same ID will cancel the previous one */
+	Id    string
+	Steps []ActionSequencerSequence_Step
+	/* when set the sequence with this ID is cancelled instead of started */
+	Cancel bool
+}
+
+type ActionSequencerMetrics struct {
+	ActionsExecuted int
+	/* the queue delay is the time between enqueueing an action and it starting to execute */
+	LastQueueDelay    time.Duration
+	MaxQueueDelay     time.Duration
+	AverageQueueDelay time.Duration
+	TotalQueueDelay   time.Duration
+}
+
+type ActionSequencer_QueuedAction struct {
+	Action     ActionSequencerAction
+	EnqueuedAt time.Time
+	/* closed once the action has finished executing (or was dropped); optional */
+	Done chan struct{}
+}
+
+/* a timed operation executed by the scheduler */
+type ActionSequencer_ScheduledOp struct {
+	At    time.Time
+	Order uint64
+	/* the source of the action which scheduled the operation */
+	Source string
+	Run    func(now time.Time)
+}
+
+type ActionSequencer_ScheduledOpHeap []*ActionSequencer_ScheduledOp
+
+/* releases the keys of the matching sources; all sources when there is no match function */
+type ActionSequencer_ReleaseRequest struct {
+	Match func(source string) bool
+	Done  chan struct{}
+}
+
+/* a key held down by one or more sources */
+type ActionSequencer_PressedKey struct {
+	Key     Key
+	Holders map[string]int
+}
+
+/* the continuous scrolling state of a source */
+type ActionSequencer_Scroller struct {
+	Velocity ActionSequencerScrollVelocity
+	/* fractional steps which have not been emitted yet */
+	RemainderX float64
+	RemainderY float64
+	LastTick   time.Time
+}
+
+/* scroll steps to send through the mouse output */
+type ActionSequencer_ScrollSteps struct {
+	X int
+	Y int
+}
+
+/* a lane executes the actions of a single source in order */
+type ActionSequencer_Lane struct {
+	Busy bool
+	/* the action currently executing; nil when the lane is not busy */
+	Current *ActionSequencer_QueuedAction
+	Pending []ActionSequencer_QueuedAction
 }
 
 type ActionSequencer struct {
+	/* the context of the running sequencer; set by Run and read by the callers of the sequencer */
+	contextMutex sync.RWMutex
+	context      context.Context
 	Connector    tswconnector.TSWConnector
-	ActionsQueue chan ActionSequencerAction
+	Output       KeyOutput
+	Mouse        MouseOutput
+	ActionsQueue chan ActionSequencer_QueuedAction
+	Sequences    *map_utils.LockMap[string, context.CancelFunc]
+
+	/* requests to release keys are handled by the scheduler go-routine */
+	releaseQueue chan ActionSequencer_ReleaseRequest
+	/* scroll steps are sent from their own go-routine so slow mouse outputs don't delay the scheduler */
+	scrollQueue chan ActionSequencer_ScrollSteps
+
+	/* scheduler state; only accessed from the scheduler go-routine */
+	ops      ActionSequencer_ScheduledOpHeap
+	opsOrder uint64
+	lanes    map[string]*ActionSequencer_Lane
+	scrolls  map[string]*ActionSequencer_Scroller
+
+	/* pressed keys by key name */
+	pressedMutex sync.RWMutex
+	pressed      map[string]*ActionSequencer_PressedKey
+
+	metricsMutex sync.RWMutex
+	metrics      ActionSequencerMetrics
+}
+
+func (h ActionSequencer_ScheduledOpHeap) Len() int { return len(h) }
+func (h ActionSequencer_ScheduledOpHeap) Less(i, j int) bool {
+	if h[i].At.Equal(h[j].At) {
+		return h[i].Order < h[j].Order
+	}
+	return h[i].At.Before(h[j].At)
+}
+func (h ActionSequencer_ScheduledOpHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *ActionSequencer_ScheduledOpHeap) Push(x any) {
+	*h = append(*h, x.(*ActionSequencer_ScheduledOp))
+}
+func (h *ActionSequencer_ScheduledOpHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
 }
 
-func New(connector tswconnector.TSWConnector) *ActionSequencer {
+func New(connector tswconnector.TSWConnector, output KeyOutput, mouse MouseOutput) *ActionSequencer {
 	return &ActionSequencer{
+		context:      context.Background(),
 		Connector:    connector,
-		ActionsQueue: make(chan ActionSequencerAction, ACTIONS_QUEUE_BUFFER_SIZE),
+		Output:       output,
+		Mouse:        mouse,
+		ActionsQueue: make(chan ActionSequencer_QueuedAction, ACTIONS_QUEUE_BUFFER_SIZE),
+		Sequences:    map_utils.NewLockMap[string, context.CancelFunc](),
+		releaseQueue: make(chan ActionSequencer_ReleaseRequest),
+		scrollQueue:  make(chan ActionSequencer_ScrollSteps, SCROLL_QUEUE_BUFFER_SIZE),
+		ops:          ActionSequencer_ScheduledOpHeap{},
+		lanes:        map[string]*ActionSequencer_Lane{},
+		scrolls:      map[string]*ActionSequencer_Scroller{},
+		pressed:      map[string]*ActionSequencer_PressedKey{},
 	}
 }
 
 func (seq *ActionSequencer) Enqueue(action ActionSequencerAction) {
-	chan_utils.SendTimeout(seq.ActionsQueue, time.Second, action)
+	chan_utils.SendTimeout(seq.ActionsQueue, time.Second, ActionSequencer_QueuedAction{
+		Action:     action,
+		EnqueuedAt: time.Now(),
+	})
 }
 
-func (seq *ActionSequencer) ToggleKeys(keys []string, modifiers []string, state string) {
-	execution_groups := [][]string{}
-	switch state {
-	case "down":
-		execution_groups = [][]string{modifiers, keys}
-	case "up":
-		execution_groups = [][]string{keys, modifiers}
+/*
+Enqueues an action and blocks until it has finished executing (including its press and wait time),
+it was dropped or the context is cancelled
+*/
+func (seq *ActionSequencer) enqueueAndWait(ctx context.Context, action ActionSequencerAction) {
+	done := make(chan struct{})
+	err := chan_utils.SendTimeout(seq.ActionsQueue, time.Second, ActionSequencer_QueuedAction{
+		Action:     action,
+		EnqueuedAt: time.Now(),
+		Done:       done,
+	})
+	if err != nil {
+		return
 	}
-	for _, key_group := range execution_groups {
-		for _, key := range key_group {
-			robotgo.KeyToggle(key, state)
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+/* signals that the queued action has finished executing */
+func (queued *ActionSequencer_QueuedAction) finish() {
+	if queued.Done != nil {
+		close(queued.Done)
+	}
+}
+
+/*
+Releases every key currently held by the sequencer and drops all pending actions and sequences.
+Used when shutting down. Blocks until the keys are released (or the sequencer is not running)
+*/
+func (seq *ActionSequencer) ReleaseAll() {
+	seq.release(nil)
+}
+
+/*
+Releases the keys held by the matching sources and drops their pending actions, scrolling and sequences;
+keys which are also held by other sources stay pressed. Blocks until the keys are released (or the sequencer is not running)
+*/
+func (seq *ActionSequencer) ReleaseSources(match func(source string) bool) {
+	seq.release(match)
+}
+
+func (seq *ActionSequencer) release(match func(source string) bool) {
+	seq.Sequences.ForEach(func(cancel context.CancelFunc, id string) bool {
+		if match == nil || match(id) {
+			cancel()
 		}
-		robotgo.MilliSleep(30)
+		return true
+	})
+
+	done := make(chan struct{})
+	select {
+	case seq.releaseQueue <- ActionSequencer_ReleaseRequest{Match: match, Done: done}:
+		<-done
+	case <-seq.runContext().Done():
+	case <-time.After(time.Second):
+		logger.Logger.Error("[ActionSequencer::release] timed out waiting for scheduler")
 	}
 }
 
-func (seq *ActionSequencer) Run(ctx context.Context) context.CancelFunc {
-	modifier_keys_map := map[string]bool{
-		"cmd":     true,
-		"lcmd":    true,
-		"rcmd":    true,
-		"alt":     true,
-		"lalt":    true,
-		"ralt":    true,
-		"ctrl":    true,
-		"lctrl":   true,
-		"rctrl":   true,
-		"control": true,
-		"shift":   true,
-		"lshift":  true,
-		"rshift":  true,
+/* Returns a snapshot of the keys currently held down and the number of holders */
+func (seq *ActionSequencer) PressedKeys() map[string]int {
+	seq.pressedMutex.RLock()
+	defer seq.pressedMutex.RUnlock()
+	pressed_keys := map[string]int{}
+	for key, pressed_key := range seq.pressed {
+		total := 0
+		for _, count := range pressed_key.Holders {
+			total += count
+		}
+		pressed_keys[key] = total
+	}
+	return pressed_keys
+}
+
+func (seq *ActionSequencer) runContext() context.Context {
+	seq.contextMutex.RLock()
+	defer seq.contextMutex.RUnlock()
+	return seq.context
+}
+
+func (seq *ActionSequencer) Metrics() ActionSequencerMetrics {
+	seq.metricsMutex.RLock()
+	defer seq.metricsMutex.RUnlock()
+	return seq.metrics
+}
+
+func (seq *ActionSequencer) recordQueueDelay(delay time.Duration) {
+	seq.metricsMutex.Lock()
+	defer seq.metricsMutex.Unlock()
+	seq.metrics.ActionsExecuted++
+	seq.metrics.LastQueueDelay = delay
+	seq.metrics.TotalQueueDelay += delay
+	if delay > seq.metrics.MaxQueueDelay {
+		seq.metrics.MaxQueueDelay = delay
 	}
+	seq.metrics.AverageQueueDelay = seq.metrics.TotalQueueDelay / time.Duration(seq.metrics.ActionsExecuted)
+}
 
+/*
+Runs a multi-step sequence in its own go-routine so the delays between steps don't block any other actions.
+Key steps are still queued through the regular actions queue; the sequence waits for them to finish before continuing.
+*/
+func (seq *ActionSequencer) RunSequence(sequence ActionSequencerSequence) {
+	seq.CancelSequence(sequence.Id)
+	if sequence.Cancel {
+		return
+	}
+
+	ctx_with_cancel, cancel := context.WithCancel(seq.runContext())
+	seq.Sequences.Set(sequence.Id, cancel)
+
+	go func() {
+		/* keys which were pressed without a press time are held and need to be released when cancelled */
+		held_actions := map[string]ActionSequencerAction{}
+		defer func() {
+			for _, held_action := range held_actions {
+				held_action.Release = true
+				seq.Enqueue(held_action)
+			}
+		}()
+		defer seq.finishSequence(sequence.Id, ctx_with_cancel)
+
+		logger.Logger.Debug("[ActionSequencer::RunSequence] starting sequence", "id", sequence.Id, "steps", len(sequence.Steps))
+		for _, step := range sequence.Steps {
+			if ctx_with_cancel.Err() != nil {
+				logger.Logger.Debug("[ActionSequencer::RunSequence] sequence cancelled", "id", sequence.Id)
+				return
+			}
+
+			if step.Action != nil {
+				action := *step.Action
+				if action.Source == "" {
+					action.Source = sequence.Id
+				}
+				seq.enqueueAndWait(ctx_with_cancel, action)
+				if held_key := action.heldKey(); held_key != "" {
+					if action.Release {
+						delete(held_actions, held_key)
+					} else if action.PressTime == 0 {
+						held_actions[held_key] = action
+					}
+				}
+			}
+			if ctx_with_cancel.Err() != nil {
+				logger.Logger.Debug("[ActionSequencer::RunSequence] sequence cancelled", "id", sequence.Id)
+				return
+			}
+			if step.Callback != nil {
+				step.Callback()
+			}
+
+			if step.Delay > 0 {
+				select {
+				case <-ctx_with_cancel.Done():
+					logger.Logger.Debug("[ActionSequencer::RunSequence] sequence cancelled", "id", sequence.Id)
+					return
+				case <-time.After(time.Duration(step.Delay * float64(time.Second))):
+				}
+			}
+		}
+
+		/* a completed sequence keeps its held keys pressed until it is cancelled or restarted */
+		if len(held_actions) > 0 {
+			<-ctx_with_cancel.Done()
+		}
+	}()
+}
+
+func (mouse_action *ActionSequencerMouseAction) ToString() string {
+	str := fmt.Sprintf("mouse:%d,%d,%t,%s,%d,%d", mouse_action.MoveX, mouse_action.MoveY, mouse_action.Absolute, mouse_action.Button, mouse_action.ScrollX, mouse_action.ScrollY)
+	if mouse_action.ScrollVelocity != nil {
+		str = fmt.Sprintf("%s,%f,%f", str, mouse_action.ScrollVelocity.X, mouse_action.ScrollVelocity.Y)
+	}
+	return str
+}
+
+/* returns the key of what the action holds down when pressed without a press time; empty if it doesn't hold anything */
+func (action *ActionSequencerAction) heldKey() string {
+	if action.Mouse != nil {
+		if action.Mouse.Button == "" {
+			return ""
+		}
+		return "mouse:" + action.Mouse.Button
+	}
+	return action.Keys
+}
+
+func (seq *ActionSequencer) CancelSequence(id string) {
+	if cancel, has_sequence := seq.Sequences.Get(id); has_sequence {
+		cancel()
+		seq.Sequences.Delete(id)
+	}
+}
+
+/* removes the sequence entry, unless it has already been replaced by a newer sequence with the same ID */
+func (seq *ActionSequencer) finishSequence(id string, ctx context.Context) {
+	seq.Sequences.Mutate(func(cancel context.CancelFunc, key string) map_utils.LockMapMutateAction[string, context.CancelFunc] {
+		if key == id && ctx.Err() == nil {
+			cancel()
+			return map_utils.LockMapMutateAction[string, context.CancelFunc]{
+				Action: map_utils.LockMapMutateActionType_Delete,
+				Key:    key,
+			}
+		}
+		return map_utils.LockMapMutateAction[string, context.CancelFunc]{
+			Action: map_utils.LockMapMutateActionType_Noop,
+		}
+	})
+}
+
+/* schedules an operation to be executed by the scheduler go-routine at the given time */
+func (seq *ActionSequencer) schedule(source string, at time.Time, run func(now time.Time)) {
+	seq.opsOrder++
+	heap.Push(&seq.ops, &ActionSequencer_ScheduledOp{
+		At:     at,
+		Order:  seq.opsOrder,
+		Source: source,
+		Run:    run,
+	})
+}
+
+func (seq *ActionSequencer) keyDown(key Key) error {
+	if key.Mouse {
+		return seq.Mouse.ButtonDown(key)
+	}
+	return seq.Output.KeyDown(key)
+}
+
+func (seq *ActionSequencer) keyUp(key Key) error {
+	if key.Mouse {
+		return seq.Mouse.ButtonUp(key)
+	}
+	return seq.Output.KeyUp(key)
+}
+
+func (seq *ActionSequencer) pressKey(key Key, source string) {
+	seq.pressedMutex.Lock()
+	defer seq.pressedMutex.Unlock()
+	pressed_key, is_pressed := seq.pressed[key.Name]
+	if !is_pressed {
+		pressed_key = &ActionSequencer_PressedKey{Key: key, Holders: map[string]int{}}
+		seq.pressed[key.Name] = pressed_key
+		if err := seq.keyDown(key); err != nil {
+			logger.Logger.Error("[ActionSequencer::pressKey] failed to press key", "key", key.Name, "error", err)
+		}
+	}
+	pressed_key.Holders[source]++
+}
+
+func (seq *ActionSequencer) releaseKey(key Key, source string) {
+	seq.pressedMutex.Lock()
+	defer seq.pressedMutex.Unlock()
+	pressed_key, is_pressed := seq.pressed[key.Name]
+	if !is_pressed || pressed_key.Holders[source] == 0 {
+		/* this source is not holding the key; releasing would break other holders */
+		return
+	}
+	pressed_key.Holders[source]--
+	if pressed_key.Holders[source] == 0 {
+		delete(pressed_key.Holders, source)
+	}
+	if len(pressed_key.Holders) == 0 {
+		delete(seq.pressed, key.Name)
+		if err := seq.keyUp(key); err != nil {
+			logger.Logger.Error("[ActionSequencer::releaseKey] failed to release key", "key", key.Name, "error", err)
+		}
+	}
+}
+
+/*
+Releases the keys held by the matching sources and drops their scheduled operations, lanes and scrolling; releases everything without a match function
+*/
+func (seq *ActionSequencer) releaseSources(match func(source string) bool) {
+	for source, lane := range seq.lanes {
+		if match == nil || match(source) {
+			lane.drop()
+		}
+	}
+	if match == nil {
+		seq.ops = ActionSequencer_ScheduledOpHeap{}
+		seq.lanes = map[string]*ActionSequencer_Lane{}
+		seq.scrolls = map[string]*ActionSequencer_Scroller{}
+		seq.releaseAllKeys()
+		return
+	}
+
+	remaining_ops := ActionSequencer_ScheduledOpHeap{}
+	for _, op := range seq.ops {
+		if !match(op.Source) {
+			remaining_ops = append(remaining_ops, op)
+		}
+	}
+	heap.Init(&remaining_ops)
+	seq.ops = remaining_ops
+	for source := range seq.lanes {
+		if match(source) {
+			delete(seq.lanes, source)
+		}
+	}
+	for source := range seq.scrolls {
+		if match(source) {
+			delete(seq.scrolls, source)
+		}
+	}
+
+	seq.pressedMutex.Lock()
+	defer seq.pressedMutex.Unlock()
+	for name, pressed_key := range seq.pressed {
+		for source := range pressed_key.Holders {
+			if match(source) {
+				delete(pressed_key.Holders, source)
+			}
+		}
+		if len(pressed_key.Holders) == 0 {
+			if err := seq.keyUp(pressed_key.Key); err != nil {
+				logger.Logger.Error("[ActionSequencer::releaseSources] failed to release key", "key", name, "error", err)
+			}
+			delete(seq.pressed, name)
+		}
+	}
+}
+
+func (seq *ActionSequencer) releaseAllKeys() {
+	seq.pressedMutex.Lock()
+	defer seq.pressedMutex.Unlock()
+	for name, pressed_key := range seq.pressed {
+		if err := seq.keyUp(pressed_key.Key); err != nil {
+			logger.Logger.Error("[ActionSequencer::releaseAllKeys] failed to release key", "key", name, "error", err)
+		}
+		delete(seq.pressed, name)
+	}
+}
+
+/*
+Schedules the key toggles of an action and returns the time at which the lane is free for the next action.
+Modifiers are pressed before and released after the other keys.
+*/
+func (seq *ActionSequencer) scheduleAction(now time.Time, action ActionSequencerAction) time.Time {
+	if action.Mouse != nil {
+		return seq.scheduleMouseAction(now, action)
+	}
+
+	parsed_keys, err := ParseKeys(action.Keys)
+	if err != nil {
+		logger.Logger.Error("[ActionSequencer::scheduleAction] skipping action", "error", err)
+		return now
+	}
+	modifier_keys, other_keys := parsed_keys.Modifiers, parsed_keys.Keys
+	group_delay := time.Duration(0)
+	if len(modifier_keys) > 0 && len(other_keys) > 0 {
+		group_delay = MODIFIER_GROUP_DELAY
+	}
+
+	release_groups := func(at time.Time) time.Time {
+		seq.schedule(action.Source, at, func(time.Time) {
+			for _, key := range other_keys {
+				seq.releaseKey(key, action.Source)
+			}
+		})
+		seq.schedule(action.Source, at.Add(group_delay), func(time.Time) {
+			for _, key := range modifier_keys {
+				seq.releaseKey(key, action.Source)
+			}
+		})
+		return at.Add(group_delay)
+	}
+
+	if action.Release {
+		return release_groups(now)
+	}
+
+	seq.schedule(action.Source, now, func(time.Time) {
+		for _, key := range modifier_keys {
+			seq.pressKey(key, action.Source)
+		}
+	})
+	keys_pressed_at := now.Add(group_delay)
+	seq.schedule(action.Source, keys_pressed_at, func(time.Time) {
+		for _, key := range other_keys {
+			seq.pressKey(key, action.Source)
+		}
+	})
+
+	lane_free_at := keys_pressed_at
+	if action.PressTime != 0 {
+		lane_free_at = release_groups(keys_pressed_at.Add(time.Duration(action.PressTime * float64(time.Second))))
+	}
+	if action.WaitTime != 0 {
+		lane_free_at = lane_free_at.Add(time.Duration(action.WaitTime * float64(time.Second)))
+	}
+	return lane_free_at
+}
+
+/*
+Schedules a mouse action and returns the time at which the lane is free for the next action.
+The mouse is moved first, then the button is pressed and finally the scroll steps are sent
+*/
+func (seq *ActionSequencer) scheduleMouseAction(now time.Time, action ActionSequencerAction) time.Time {
+	mouse_action := *action.Mouse
+	var button *Key = nil
+	if mouse_action.Button != "" {
+		parsed_button, err := ParseMouseButton(mouse_action.Button)
+		if err != nil {
+			logger.Logger.Error("[ActionSequencer::scheduleMouseAction] skipping action", "error", err)
+			return now
+		}
+		button = &parsed_button
+	}
+
+	if action.Release {
+		if button != nil {
+			seq.schedule(action.Source, now, func(time.Time) {
+				seq.releaseKey(*button, action.Source)
+			})
+		}
+		return now
+	}
+
+	if mouse_action.ScrollVelocity != nil {
+		seq.setScrollVelocity(now, action.Source, *mouse_action.ScrollVelocity)
+	}
+
+	seq.schedule(action.Source, now, func(time.Time) {
+		var err error
+		if mouse_action.Absolute {
+			err = seq.Mouse.MoveTo(mouse_action.MoveX, mouse_action.MoveY)
+		} else if mouse_action.MoveX != 0 || mouse_action.MoveY != 0 {
+			err = seq.Mouse.Move(mouse_action.MoveX, mouse_action.MoveY)
+		}
+		if err != nil {
+			logger.Logger.Error("[ActionSequencer::scheduleMouseAction] failed to move mouse", "error", err)
+		}
+		if button != nil {
+			seq.pressKey(*button, action.Source)
+		}
+		if mouse_action.ScrollX != 0 || mouse_action.ScrollY != 0 {
+			seq.queueScroll(mouse_action.ScrollX, mouse_action.ScrollY)
+		}
+	})
+
+	lane_free_at := now
+	if button != nil && action.PressTime != 0 {
+		lane_free_at = now.Add(time.Duration(action.PressTime * float64(time.Second)))
+		seq.schedule(action.Source, lane_free_at, func(time.Time) {
+			seq.releaseKey(*button, action.Source)
+		})
+	}
+	if action.WaitTime != 0 {
+		lane_free_at = lane_free_at.Add(time.Duration(action.WaitTime * float64(time.Second)))
+	}
+	return lane_free_at
+}
+
+/*
+Updates the continuous scroll velocity of a source; starts the scroll ticks if the source was not scrolling yet
+*/
+func (seq *ActionSequencer) setScrollVelocity(now time.Time, source string, velocity ActionSequencerScrollVelocity) {
+	scroller, is_scrolling := seq.scrolls[source]
+	if velocity.X == 0 && velocity.Y == 0 {
+		delete(seq.scrolls, source)
+		return
+	}
+	if is_scrolling {
+		scroller.Velocity = velocity
+		return
+	}
+
+	scroller = &ActionSequencer_Scroller{Velocity: velocity, LastTick: now}
+	seq.scrolls[source] = scroller
+	var tick func(now time.Time)
+	tick = func(now time.Time) {
+		if current_scroller, has_scroller := seq.scrolls[source]; !has_scroller || current_scroller != scroller {
+			/* stopped (or replaced) in the meantime */
+			return
+		}
+		elapsed := now.Sub(scroller.LastTick).Seconds()
+		scroller.LastTick = now
+		scroller.RemainderX += scroller.Velocity.X * elapsed
+		scroller.RemainderY += scroller.Velocity.Y * elapsed
+		steps_x, steps_y := int(scroller.RemainderX), int(scroller.RemainderY)
+		scroller.RemainderX -= float64(steps_x)
+		scroller.RemainderY -= float64(steps_y)
+		if steps_x != 0 || steps_y != 0 {
+			seq.queueScroll(steps_x, steps_y)
+		}
+		seq.schedule(source, now.Add(SCROLL_TICK_INTERVAL), tick)
+	}
+	seq.schedule(source, now.Add(SCROLL_TICK_INTERVAL), tick)
+}
+
+/* finishes the current and pending actions of a lane which is dropped */
+func (lane *ActionSequencer_Lane) drop() {
+	if lane.Current != nil {
+		lane.Current.finish()
+		lane.Current = nil
+	}
+	for _, pending := range lane.Pending {
+		pending.finish()
+	}
+	lane.Pending = nil
+}
+
+/* queues scroll steps for the scroll go-routine; drops them when the mouse output can't keep up */
+func (seq *ActionSequencer) queueScroll(x int, y int) {
+	select {
+	case seq.scrollQueue <- ActionSequencer_ScrollSteps{X: x, Y: y}:
+	default:
+		logger.Logger.Error("[ActionSequencer::queueScroll] dropping scroll steps, the mouse output is too slow", "x", x, "y", y)
+	}
+}
+
+/* starts the next pending action of the lane if the lane is not busy */
+func (seq *ActionSequencer) advanceLane(source string, now time.Time) {
+	lane, has_lane := seq.lanes[source]
+	if !has_lane {
+		return
+	}
+	if lane.Busy {
+		return
+	}
+	if len(lane.Pending) == 0 {
+		delete(seq.lanes, source)
+		return
+	}
+
+	queued := lane.Pending[0]
+	lane.Pending = lane.Pending[1:]
+	lane.Busy = true
+	lane.Current = &queued
+	seq.recordQueueDelay(now.Sub(queued.EnqueuedAt))
+	logger.Logger.Debug("[ActionSequencer::advanceLane] executing action", "action", queued.Action)
+
+	lane_free_at := seq.scheduleAction(now, queued.Action)
+	seq.schedule(source, lane_free_at, func(now time.Time) {
+		lane.Busy = false
+		lane.Current = nil
+		queued.finish()
+		seq.advanceLane(source, now)
+	})
+}
+
+func (seq *ActionSequencer) handleQueuedAction(queued ActionSequencer_QueuedAction, now time.Time) {
+	switch conn := seq.Connector.(type) {
+	case *tswconnector.SocketProxyConnection:
+		/* the sequencing is done on the receiving end */
+		seq.recordQueueDelay(now.Sub(queued.EnqueuedAt))
+		message := tswconnector.TSWConnector_Message{
+			EventName: "action_sequence",
+			Properties: map[string]string{
+				"keys":       queued.Action.Keys,
+				"press_time": fmt.Sprintf("%f", queued.Action.PressTime),
+				"wait_time":  fmt.Sprintf("%f", queued.Action.WaitTime),
+				"release":    strconv.FormatBool(queued.Action.Release),
+				"source":     queued.Action.Source,
+			},
+		}
+		if queued.Action.Mouse != nil {
+			mouse_action := queued.Action.Mouse
+			message.Properties["mouse_move_x"] = strconv.Itoa(mouse_action.MoveX)
+			message.Properties["mouse_move_y"] = strconv.Itoa(mouse_action.MoveY)
+			message.Properties["mouse_absolute"] = strconv.FormatBool(mouse_action.Absolute)
+			message.Properties["mouse_button"] = mouse_action.Button
+			message.Properties["mouse_scroll_x"] = strconv.Itoa(mouse_action.ScrollX)
+			message.Properties["mouse_scroll_y"] = strconv.Itoa(mouse_action.ScrollY)
+			if mouse_action.ScrollVelocity != nil {
+				message.Properties["mouse_scroll_velocity_x"] = fmt.Sprintf("%f", mouse_action.ScrollVelocity.X)
+				message.Properties["mouse_scroll_velocity_y"] = fmt.Sprintf("%f", mouse_action.ScrollVelocity.Y)
+			}
+		}
+		conn.Send(message)
+		/* the receiving end executes the action; assume it is done after its press and wait time */
+		done_at := now.Add(time.Duration((queued.Action.PressTime + queued.Action.WaitTime) * float64(time.Second)))
+		seq.schedule(queued.Action.Source, done_at, func(time.Time) {
+			queued.finish()
+		})
+	default:
+		logger.Logger.Debug("[ActionSequencer::Run] received action from queue", "action", queued.Action)
+		lane, has_lane := seq.lanes[queued.Action.Source]
+		if !has_lane {
+			lane = &ActionSequencer_Lane{Busy: false, Pending: []ActionSequencer_QueuedAction{}}
+			seq.lanes[queued.Action.Source] = lane
+		}
+		lane.Pending = append(lane.Pending, queued)
+		seq.advanceLane(queued.Action.Source, now)
+	}
+}
+
+/* executes all operations which are due at the given time */
+func (seq *ActionSequencer) runDueOps(now time.Time) {
+	for seq.ops.Len() > 0 && !seq.ops[0].At.After(now) {
+		op := heap.Pop(&seq.ops).(*ActionSequencer_ScheduledOp)
+		op.Run(now)
+	}
+}
+
+func (seq *ActionSequencer) Run(ctx context.Context) context.CancelFunc {
 	ctx_with_cancel, cancel := context.WithCancel(ctx)
+	seq.contextMutex.Lock()
+	seq.context = ctx_with_cancel
+	seq.contextMutex.Unlock()
 
 	go func() {
+		timer := time.NewTimer(time.Hour)
+		defer timer.Stop()
+		defer seq.releaseAllKeys()
+
 		for {
+			seq.runDueOps(time.Now())
+
+			/* wait until the next operation is due or a new action comes in */
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			if seq.ops.Len() > 0 {
+				timer.Reset(time.Until(seq.ops[0].At))
+			} else {
+				timer.Reset(time.Hour)
+			}
+
 			select {
 			case <-ctx_with_cancel.Done():
 				return
-			case action := <-seq.ActionsQueue:
-				switch conn := seq.Connector.(type) {
-				case *tswconnector.SocketProxyConnection:
-					conn.Send(tswconnector.TSWConnector_Message{
-						EventName: "action_sequence",
-						Properties: map[string]string{
-							"keys":       action.Keys,
-							"press_time": fmt.Sprintf("%f", action.PressTime),
-							"wait_time":  fmt.Sprintf("%f", action.WaitTime),
-							"release":    strconv.FormatBool(action.Release),
-						},
-					})
-				default:
-					logger.Logger.Debug("[ActionSequencer::Run] received action from queue", "action", action)
-					keys_list := strings.Split(action.Keys, "+")
-					modifier_keys := []string{}
-					other_keys := []string{}
-					for _, input := range keys_list {
-						key := strings.ToLower(input)
-						if is_modifier_key, has_is_modifier_key := modifier_keys_map[key]; has_is_modifier_key && is_modifier_key {
-							modifier_keys = append(modifier_keys, key)
-						} else {
-							other_keys = append(other_keys, key)
-						}
-					}
+			case <-timer.C:
+			case queued := <-seq.ActionsQueue:
+				seq.handleQueuedAction(queued, time.Now())
+			case request := <-seq.releaseQueue:
+				logger.Logger.Info("[ActionSequencer::Run] releasing keys", "all", request.Match == nil)
+				seq.releaseSources(request.Match)
+				close(request.Done)
+			}
+		}
+	}()
 
-					if action.Release {
-						seq.ToggleKeys(other_keys, modifier_keys, "up")
-					} else {
-						seq.ToggleKeys(other_keys, modifier_keys, "down")
-						if action.PressTime != 0 {
-							robotgo.MilliSleep(int(action.PressTime * 1000))
-							seq.ToggleKeys(other_keys, modifier_keys, "up")
-						}
-						if action.WaitTime != 0 {
-							robotgo.MilliSleep(int(action.WaitTime * 1000))
-						}
-					}
+	go func() {
+		for {
+			select {
+			case <-ctx_with_cancel.Done():
+				return
+			case steps := <-seq.scrollQueue:
+				if err := seq.Mouse.Scroll(steps.X, steps.Y); err != nil {
+					logger.Logger.Error("[ActionSequencer::Run] failed to scroll", "error", err)
 				}
 			}
 		}
@@ -136,9 +872,11 @@ func (seq *ActionSequencer) Run(ctx context.Context) context.CancelFunc {
 					release, _ := strconv.ParseBool(msg.Properties["release"])
 					seq.Enqueue(ActionSequencerAction{
 						Keys:      msg.Properties["keys"],
+						Mouse:     mouseActionFromMessage(msg),
 						PressTime: press_time,
 						WaitTime:  wait_time,
 						Release:   release,
+						Source:    msg.Properties["source"],
 					})
 				}
 			}
@@ -147,3 +885,25 @@ func (seq *ActionSequencer) Run(ctx context.Context) context.CancelFunc {
 
 	return cancel
 }
+
+/* parses the mouse action of a proxied "action_sequence" message; returns nil if the message is not a mouse action */
+func mouseActionFromMessage(msg tswconnector.TSWConnector_Message) *ActionSequencerMouseAction {
+	if _, has_mouse := msg.Properties["mouse_button"]; !has_mouse {
+		return nil
+	}
+	mouse_action := ActionSequencerMouseAction{
+		Button: msg.Properties["mouse_button"],
+	}
+	mouse_action.MoveX, _ = strconv.Atoi(msg.Properties["mouse_move_x"])
+	mouse_action.MoveY, _ = strconv.Atoi(msg.Properties["mouse_move_y"])
+	mouse_action.Absolute, _ = strconv.ParseBool(msg.Properties["mouse_absolute"])
+	mouse_action.ScrollX, _ = strconv.Atoi(msg.Properties["mouse_scroll_x"])
+	mouse_action.ScrollY, _ = strconv.Atoi(msg.Properties["mouse_scroll_y"])
+	if _, has_velocity := msg.Properties["mouse_scroll_velocity_x"]; has_velocity {
+		velocity := ActionSequencerScrollVelocity{}
+		velocity.X, _ = strconv.ParseFloat(msg.Properties["mouse_scroll_velocity_x"], 64)
+		velocity.Y, _ = strconv.ParseFloat(msg.Properties["mouse_scroll_velocity_y"], 64)
+		mouse_action.ScrollVelocity = &velocity
+	}
+	return &mouse_action
+}
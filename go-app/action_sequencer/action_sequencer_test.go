@@ -0,0 +1,257 @@
+package action_sequencer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"tsw_controller_app/tswconnector"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConnector struct{}
+
+func (c *testConnector) Start() error { return nil }
+func (c *testConnector) Stop() error  { return nil }
+func (c *testConnector) Subscribe() (chan tswconnector.TSWConnector_Message, func()) {
+	return make(chan tswconnector.TSWConnector_Message), func() {}
+}
+func (c *testConnector) Send(m tswconnector.TSWConnector_Message) error { return nil }
+func (c *testConnector) IsConnected() bool                              { return true }
+
+func newTestSequencer(t *testing.T) (*ActionSequencer, *RecordingKeyOutput) {
+	seq, output, _ := newTestSequencerWithMouse(t)
+	return seq, output
+}
+
+func newTestSequencerWithMouse(t *testing.T) (*ActionSequencer, *RecordingKeyOutput, *RecordingMouseOutput) {
+	output := NewRecordingKeyOutput()
+	mouse := NewRecordingMouseOutput()
+	seq := New(&testConnector{}, output, mouse)
+	cancel := seq.Run(context.Background())
+	t.Cleanup(cancel)
+	return seq, output, mouse
+}
+
+func eventNames(events []RecordingKeyOutput_Event) []string {
+	names := []string{}
+	for _, event := range events {
+		if event.Down {
+			names = append(names, "+"+event.Key)
+		} else {
+			names = append(names, "-"+event.Key)
+		}
+	}
+	return names
+}
+
+func TestActionSequencer_PressAndRelease(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "ctrl+a", PressTime: 0.05, Source: "test"})
+
+	assert.Eventually(t, func() bool { return len(output.Events()) == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+ctrl", "+a", "-a", "-ctrl"}, eventNames(output.Events()))
+	assert.Empty(t, seq.PressedKeys())
+}
+
+func TestActionSequencer_SharedKeyIsReleasedByLastHolder(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "first"})
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "second"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["w"] == 2 }, time.Second, 5*time.Millisecond)
+
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Release: true, Source: "first"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["w"] == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+w"}, eventNames(output.Events()))
+
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Release: true, Source: "second"})
+	assert.Eventually(t, func() bool { return len(output.Events()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+w", "-w"}, eventNames(output.Events()))
+}
+
+func TestActionSequencer_SourceHoldsAreCounted(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "first"})
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "first"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["w"] == 2 }, time.Second, 5*time.Millisecond)
+
+	/* a source which is not holding the key can't release it */
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Release: true, Source: "second"})
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Release: true, Source: "first"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["w"] == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+w"}, eventNames(output.Events()))
+
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Release: true, Source: "first"})
+	assert.Eventually(t, func() bool { return len(output.Events()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+w", "-w"}, eventNames(output.Events()))
+	assert.Empty(t, seq.PressedKeys())
+}
+
+func TestActionSequencer_SharedModifierIsHeldUntilLastHolderReleases(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "shift+a", Source: "first"})
+	seq.Enqueue(ActionSequencerAction{Keys: "shift+b", Source: "second"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["shift"] == 2 }, time.Second, 5*time.Millisecond)
+
+	seq.Enqueue(ActionSequencerAction{Keys: "shift+a", Release: true, Source: "first"})
+	assert.Eventually(t, func() bool { return seq.PressedKeys()["shift"] == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, map[string]int{"shift": 1, "b": 1}, seq.PressedKeys())
+	assert.NotContains(t, eventNames(output.Events()), "-shift")
+}
+
+func TestActionSequencer_UnknownKeysAreSkipped(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "ctrl+nope", PressTime: 0.01, Source: "test"})
+	seq.Enqueue(ActionSequencerAction{Keys: "b", PressTime: 0.01, Source: "test"})
+
+	assert.Eventually(t, func() bool { return len(output.Events()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"+b", "-b"}, eventNames(output.Events()))
+}
+
+func TestActionSequencer_ReleaseAll(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "shift+x", Source: "test"})
+	assert.Eventually(t, func() bool { return len(seq.PressedKeys()) == 2 }, time.Second, 5*time.Millisecond)
+
+	seq.ReleaseAll()
+	assert.Empty(t, seq.PressedKeys())
+	assert.Len(t, output.Events(), 4)
+}
+
+func TestActionSequencer_ReleaseSources(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "first/throttle"})
+	seq.Enqueue(ActionSequencerAction{Keys: "w", Source: "second/throttle"})
+	seq.Enqueue(ActionSequencerAction{Keys: "a", Source: "first/brake"})
+	/* the release of the pending press should be dropped */
+	seq.Enqueue(ActionSequencerAction{Keys: "d", PressTime: 10, Source: "first/horn"})
+	assert.Eventually(t, func() bool { return len(seq.PressedKeys()) == 3 }, time.Second, 5*time.Millisecond)
+
+	seq.ReleaseSources(func(source string) bool { return strings.HasPrefix(source, "first/") })
+	/* w is still held by the second source */
+	assert.Equal(t, map[string]int{"w": 1}, seq.PressedKeys())
+	assert.ElementsMatch(t, []string{"+w", "+a", "+d", "-a", "-d"}, eventNames(output.Events()))
+}
+
+func TestActionSequencer_MouseClick(t *testing.T) {
+	seq, _, mouse := newTestSequencerWithMouse(t)
+	seq.Enqueue(ActionSequencerAction{
+		Mouse:     &ActionSequencerMouseAction{MoveX: 10, MoveY: -5, Button: "left"},
+		PressTime: 0.02,
+		Source:    "test",
+	})
+
+	assert.Eventually(t, func() bool { return len(mouse.Events()) == 3 }, time.Second, 5*time.Millisecond)
+	events := mouse.Events()
+	assert.Equal(t, "move", events[0].Kind)
+	assert.Equal(t, 10, events[0].X)
+	assert.Equal(t, -5, events[0].Y)
+	assert.Equal(t, "mouse_left", events[1].Button)
+	assert.True(t, events[1].Down)
+	assert.Equal(t, "mouse_left", events[2].Button)
+	assert.False(t, events[2].Down)
+	assert.Empty(t, seq.PressedKeys())
+}
+
+func TestActionSequencer_ScrollVelocity(t *testing.T) {
+	/* the scheduler is driven by hand with a fake clock instead of running it */
+	seq := New(&testConnector{}, NewRecordingKeyOutput(), NewRecordingMouseOutput())
+	now := time.Unix(0, 0)
+	steps := 0
+	scrolled_steps := func() int {
+		for {
+			select {
+			case scroll_steps := <-seq.scrollQueue:
+				steps += scroll_steps.Y
+			default:
+				return steps
+			}
+		}
+	}
+	advance := func(ticks int) {
+		for range ticks {
+			now = now.Add(SCROLL_TICK_INTERVAL)
+			seq.runDueOps(now)
+		}
+	}
+	set_velocity := func(velocity ActionSequencerScrollVelocity) {
+		seq.handleQueuedAction(ActionSequencer_QueuedAction{
+			Action:     ActionSequencerAction{Mouse: &ActionSequencerMouseAction{ScrollVelocity: &velocity}, Source: "test"},
+			EnqueuedAt: now,
+		}, now)
+	}
+
+	/* 100 steps per second for 10 ticks of 20ms */
+	set_velocity(ActionSequencerScrollVelocity{Y: -100})
+	advance(10)
+	assert.Equal(t, -20, scrolled_steps())
+
+	/* fractional steps are carried over to the next ticks */
+	set_velocity(ActionSequencerScrollVelocity{Y: 30})
+	advance(1)
+	assert.Equal(t, -20, scrolled_steps())
+	advance(3)
+	assert.Equal(t, -18, scrolled_steps())
+
+	set_velocity(ActionSequencerScrollVelocity{})
+	advance(10)
+	assert.Equal(t, -18, scrolled_steps())
+}
+
+func TestActionSequencer_SequenceWaitsForHeldKeyStep(t *testing.T) {
+	seq, output := newTestSequencer(t)
+	callback_at := make(chan time.Time, 1)
+	seq.RunSequence(ActionSequencerSequence{
+		Id: "test",
+		Steps: []ActionSequencerSequence_Step{
+			{Action: &ActionSequencerAction{Keys: "a", PressTime: 0.1, WaitTime: 0.05}},
+			{Callback: func() { callback_at <- time.Now() }},
+		},
+	})
+
+	var called_at time.Time
+	select {
+	case called_at = <-callback_at:
+	case <-time.After(time.Second):
+		assert.FailNow(t, "the direct control step was not executed")
+	}
+	events := output.Events()
+	/* the direct control step runs after the key was released and the wait time has passed */
+	assert.Equal(t, []string{"+a", "-a"}, eventNames(events))
+	assert.True(t, called_at.After(events[1].At))
+	/* the key toggles are recorded slightly after they were scheduled */
+	assert.GreaterOrEqual(t, called_at.Sub(events[0].At), 145*time.Millisecond)
+	assert.GreaterOrEqual(t, called_at.Sub(events[1].At), 45*time.Millisecond)
+}
+
+/* a mouse output which blocks while scrolling */
+type slowScrollMouseOutput struct {
+	*RecordingMouseOutput
+}
+
+func (o *slowScrollMouseOutput) Scroll(dx int, dy int) error {
+	time.Sleep(50 * time.Millisecond)
+	return o.RecordingMouseOutput.Scroll(dx, dy)
+}
+
+func TestActionSequencer_ScrollingDoesNotDelayKeys(t *testing.T) {
+	output := NewRecordingKeyOutput()
+	mouse := &slowScrollMouseOutput{NewRecordingMouseOutput()}
+	seq := New(&testConnector{}, output, mouse)
+	cancel := seq.Run(context.Background())
+	t.Cleanup(cancel)
+
+	seq.Enqueue(ActionSequencerAction{
+		Mouse:  &ActionSequencerMouseAction{ScrollVelocity: &ActionSequencerScrollVelocity{Y: 100}},
+		Source: "scroll",
+	})
+	assert.Eventually(t, func() bool { return len(mouse.Events()) > 0 }, time.Second, 5*time.Millisecond)
+
+	enqueued_at := time.Now()
+	seq.Enqueue(ActionSequencerAction{Keys: "a", PressTime: 0.02, Source: "key"})
+	assert.Eventually(t, func() bool { return len(output.Events()) == 2 }, time.Second, 5*time.Millisecond)
+	events := output.Events()
+	assert.Less(t, events[0].At.Sub(enqueued_at), 40*time.Millisecond)
+	assert.Less(t, events[1].At.Sub(events[0].At), 40*time.Millisecond)
+}
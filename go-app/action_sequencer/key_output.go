@@ -0,0 +1,11 @@
+package action_sequencer
+
+/*
+A key output is responsible for actually pressing and releasing keys.
+The sequencer keeps track of which keys are held, so outputs only receive a single down for every up.
+*/
+type KeyOutput interface {
+	KeyDown(key Key) error
+	KeyUp(key Key) error
+	Close() error
+}
@@ -0,0 +1,62 @@
+package action_sequencer
+
+import (
+	"sync"
+	"time"
+)
+
+type RecordingKeyOutput_Event struct {
+	Key  string
+	Down bool
+	At   time.Time
+}
+
+/* records the key events instead of sending them; used for testing */
+type RecordingKeyOutput struct {
+	mutex  sync.Mutex
+	events []RecordingKeyOutput_Event
+}
+
+func NewRecordingKeyOutput() *RecordingKeyOutput {
+	return &RecordingKeyOutput{
+		events: []RecordingKeyOutput_Event{},
+	}
+}
+
+func (o *RecordingKeyOutput) record(key Key, down bool) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, RecordingKeyOutput_Event{
+		Key:  key.Name,
+		Down: down,
+		At:   time.Now(),
+	})
+	return nil
+}
+
+func (o *RecordingKeyOutput) KeyDown(key Key) error {
+	return o.record(key, true)
+}
+
+func (o *RecordingKeyOutput) KeyUp(key Key) error {
+	return o.record(key, false)
+}
+
+func (o *RecordingKeyOutput) Close() error {
+	return nil
+}
+
+/* Returns a copy of the recorded events */
+func (o *RecordingKeyOutput) Events() []RecordingKeyOutput_Event {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	events := make([]RecordingKeyOutput_Event, len(o.events))
+	copy(events, o.events)
+	return events
+}
+
+func (o *RecordingKeyOutput) Reset() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = []RecordingKeyOutput_Event{}
+}
@@ -0,0 +1,22 @@
+package action_sequencer
+
+import "github.com/go-vgo/robotgo"
+
+/* sends keys through the desktop session (X11, Windows or macOS) */
+type RobotgoKeyOutput struct{}
+
+func NewRobotgoKeyOutput() *RobotgoKeyOutput {
+	return &RobotgoKeyOutput{}
+}
+
+func (o *RobotgoKeyOutput) KeyDown(key Key) error {
+	return robotgo.KeyToggle(key.Name, "down")
+}
+
+func (o *RobotgoKeyOutput) KeyUp(key Key) error {
+	return robotgo.KeyToggle(key.Name, "up")
+}
+
+func (o *RobotgoKeyOutput) Close() error {
+	return nil
+}
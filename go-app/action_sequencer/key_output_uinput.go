@@ -0,0 +1,59 @@
+package action_sequencer
+
+import (
+	"sort"
+	"tsw_controller_app/uinput"
+)
+
+const UINPUT_KEYBOARD_NAME = "TSW Controller Virtual Keyboard"
+
+/*
+Sends keys through a Linux uinput virtual keyboard.
+Works independently of the desktop session, so it can be used on Wayland and headless setups.
+*/
+type UinputKeyOutput struct {
+	device *uinput.Device
+}
+
+func NewUinputKeyOutput() (*UinputKeyOutput, error) {
+	codes_map := map[uint16]bool{}
+	for _, key := range known_keys {
+		codes_map[key.Code] = true
+	}
+	codes := make([]uint16, 0, len(codes_map))
+	for code := range codes_map {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	device, err := uinput.Open(uinput.DeviceConfig{
+		Name:    UINPUT_KEYBOARD_NAME,
+		Vendor:  0x1209,
+		Product: 0x5457,
+		Version: 1,
+		Keys:    codes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UinputKeyOutput{device: device}, nil
+}
+
+func (o *UinputKeyOutput) toggle(key Key, value int32) error {
+	if err := o.device.Emit(uinput.EV_KEY, key.Code, value); err != nil {
+		return err
+	}
+	return o.device.Sync()
+}
+
+func (o *UinputKeyOutput) KeyDown(key Key) error {
+	return o.toggle(key, 1)
+}
+
+func (o *UinputKeyOutput) KeyUp(key Key) error {
+	return o.toggle(key, 0)
+}
+
+func (o *UinputKeyOutput) Close() error {
+	return o.device.Close()
+}
@@ -0,0 +1,173 @@
+package action_sequencer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrEmptyKey = errors.New("empty key name")
+var ErrUnknownKey = errors.New("unknown key name")
+
+type Key struct {
+	/* the canonical key name; aliases (eg: "escape") are resolved to their canonical name (eg: "esc") */
+	Name string
+	/* the Linux input event code (see linux/input-event-codes.h) */
+	Code uint16
+	/* modifiers are pressed before and released after the other keys of an action */
+	Modifier bool
+	/* mouse buttons are sent through the mouse output */
+	Mouse bool
+}
+
+type ParsedKeys struct {
+	Modifiers []Key
+	Keys      []Key
+}
+
+/*
+The known key names; the names follow the robotgo naming so existing profiles keep working.
+The codes are the Linux input event codes used by the uinput output.
+*/
+var known_keys = map[string]Key{
+	"cmd":    {Code: 125, Modifier: true},
+	"lcmd":   {Code: 125, Modifier: true},
+	"rcmd":   {Code: 126, Modifier: true},
+	"alt":    {Code: 56, Modifier: true},
+	"lalt":   {Code: 56, Modifier: true},
+	"ralt":   {Code: 100, Modifier: true},
+	"ctrl":   {Code: 29, Modifier: true},
+	"lctrl":  {Code: 29, Modifier: true},
+	"rctrl":  {Code: 97, Modifier: true},
+	"shift":  {Code: 42, Modifier: true},
+	"lshift": {Code: 42, Modifier: true},
+	"rshift": {Code: 54, Modifier: true},
+
+	"esc":         {Code: 1},
+	"backspace":   {Code: 14},
+	"tab":         {Code: 15},
+	"enter":       {Code: 28},
+	"space":       {Code: 57},
+	"capslock":    {Code: 58},
+	"printscreen": {Code: 99},
+	"insert":      {Code: 110},
+	"delete":      {Code: 111},
+	"home":        {Code: 102},
+	"end":         {Code: 107},
+	"pageup":      {Code: 104},
+	"pagedown":    {Code: 109},
+	"up":          {Code: 103},
+	"down":        {Code: 108},
+	"left":        {Code: 105},
+	"right":       {Code: 106},
+	"menu":        {Code: 127},
+
+	"1": {Code: 2}, "2": {Code: 3}, "3": {Code: 4}, "4": {Code: 5}, "5": {Code: 6},
+	"6": {Code: 7}, "7": {Code: 8}, "8": {Code: 9}, "9": {Code: 10}, "0": {Code: 11},
+
+	"q": {Code: 16}, "w": {Code: 17}, "e": {Code: 18}, "r": {Code: 19}, "t": {Code: 20},
+	"y": {Code: 21}, "u": {Code: 22}, "i": {Code: 23}, "o": {Code: 24}, "p": {Code: 25},
+	"a": {Code: 30}, "s": {Code: 31}, "d": {Code: 32}, "f": {Code: 33}, "g": {Code: 34},
+	"h": {Code: 35}, "j": {Code: 36}, "k": {Code: 37}, "l": {Code: 38},
+	"z": {Code: 44}, "x": {Code: 45}, "c": {Code: 46}, "v": {Code: 47}, "b": {Code: 48},
+	"n": {Code: 49}, "m": {Code: 50},
+
+	"-": {Code: 12}, "=": {Code: 13}, "[": {Code: 26}, "]": {Code: 27}, ";": {Code: 39},
+	"'": {Code: 40}, "`": {Code: 41}, "\\": {Code: 43}, ",": {Code: 51}, ".": {Code: 52},
+	"/": {Code: 53},
+
+	"f1": {Code: 59}, "f2": {Code: 60}, "f3": {Code: 61}, "f4": {Code: 62}, "f5": {Code: 63},
+	"f6": {Code: 64}, "f7": {Code: 65}, "f8": {Code: 66}, "f9": {Code: 67}, "f10": {Code: 68},
+	"f11": {Code: 87}, "f12": {Code: 88}, "f13": {Code: 183}, "f14": {Code: 184}, "f15": {Code: 185},
+	"f16": {Code: 186}, "f17": {Code: 187}, "f18": {Code: 188}, "f19": {Code: 189}, "f20": {Code: 190},
+	"f21": {Code: 191}, "f22": {Code: 192}, "f23": {Code: 193}, "f24": {Code: 194},
+
+	"num0": {Code: 82}, "num1": {Code: 79}, "num2": {Code: 80}, "num3": {Code: 81}, "num4": {Code: 75},
+	"num5": {Code: 76}, "num6": {Code: 77}, "num7": {Code: 71}, "num8": {Code: 72}, "num9": {Code: 73},
+	"num_lock": {Code: 69}, "num.": {Code: 83}, "num+": {Code: 78}, "num-": {Code: 74},
+	"num*": {Code: 55}, "num/": {Code: 98}, "num_enter": {Code: 96}, "num_equal": {Code: 117},
+
+	"audio_mute":     {Code: 113},
+	"audio_vol_down": {Code: 114},
+	"audio_vol_up":   {Code: 115},
+	"audio_next":     {Code: 163},
+	"audio_play":     {Code: 164},
+	"audio_prev":     {Code: 165},
+	"audio_stop":     {Code: 166},
+}
+
+/* alternative names which resolve to a canonical key name */
+var key_aliases = map[string]string{
+	"command":     "cmd",
+	"control":     "ctrl",
+	"right_shift": "rshift",
+	"escape":      "esc",
+	"print":       "printscreen",
+	"numpad_0":    "num0",
+	"numpad_1":    "num1",
+	"numpad_2":    "num2",
+	"numpad_3":    "num3",
+	"numpad_4":    "num4",
+	"numpad_5":    "num5",
+	"numpad_6":    "num6",
+	"numpad_7":    "num7",
+	"numpad_8":    "num8",
+	"numpad_9":    "num9",
+	"numpad_lock": "num_lock",
+}
+
+/*
+Parses a single key name (case insensitive)
+*/
+func ParseKey(name string) (Key, error) {
+	key_name := strings.ToLower(strings.TrimSpace(name))
+	if key_name == "" {
+		return Key{}, ErrEmptyKey
+	}
+	if alias, is_alias := key_aliases[key_name]; is_alias {
+		key_name = alias
+	}
+	key, is_known := known_keys[key_name]
+	if !is_known {
+		return Key{}, fmt.Errorf("%w (%s)", ErrUnknownKey, name)
+	}
+	key.Name = key_name
+	return key, nil
+}
+
+/* splits the keys string on "+" while keeping the numpad plus key ("num+") intact */
+func splitKeys(keys string) []string {
+	parts := strings.Split(keys, "+")
+	result := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		if strings.EqualFold(strings.TrimSpace(parts[i]), "num") && i+1 < len(parts) && strings.TrimSpace(parts[i+1]) == "" {
+			result = append(result, "num+")
+			i++
+			continue
+		}
+		result = append(result, parts[i])
+	}
+	return result
+}
+
+/*
+Parses a keys string (eg: "ctrl+shift+a") and splits it into modifier keys and other keys
+*/
+func ParseKeys(keys string) (ParsedKeys, error) {
+	parsed := ParsedKeys{
+		Modifiers: []Key{},
+		Keys:      []Key{},
+	}
+	for _, input := range splitKeys(keys) {
+		key, err := ParseKey(input)
+		if err != nil {
+			return ParsedKeys{}, fmt.Errorf("invalid keys \"%s\": %w", keys, err)
+		}
+		if key.Modifier {
+			parsed.Modifiers = append(parsed.Modifiers, key)
+		} else {
+			parsed.Keys = append(parsed.Keys, key)
+		}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,42 @@
+package action_sequencer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys_SplitsModifiers(t *testing.T) {
+	parsed, err := ParseKeys("Ctrl+shift+A")
+	assert.NoError(t, err)
+	assert.Len(t, parsed.Modifiers, 2)
+	assert.Equal(t, "ctrl", parsed.Modifiers[0].Name)
+	assert.Equal(t, "shift", parsed.Modifiers[1].Name)
+	assert.Len(t, parsed.Keys, 1)
+	assert.Equal(t, "a", parsed.Keys[0].Name)
+	assert.Equal(t, uint16(30), parsed.Keys[0].Code)
+}
+
+func TestParseKeys_Aliases(t *testing.T) {
+	parsed, err := ParseKeys("control+escape")
+	assert.NoError(t, err)
+	assert.Equal(t, "ctrl", parsed.Modifiers[0].Name)
+	assert.Equal(t, "esc", parsed.Keys[0].Name)
+}
+
+func TestParseKeys_NumpadPlus(t *testing.T) {
+	parsed, err := ParseKeys("shift+num+")
+	assert.NoError(t, err)
+	assert.Equal(t, "shift", parsed.Modifiers[0].Name)
+	assert.Len(t, parsed.Keys, 1)
+	assert.Equal(t, "num+", parsed.Keys[0].Name)
+}
+
+func TestParseKeys_UnknownKey(t *testing.T) {
+	_, err := ParseKeys("ctrl+foo")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Contains(t, err.Error(), "foo")
+
+	_, err = ParseKeys("ctrl++a")
+	assert.ErrorIs(t, err, ErrEmptyKey)
+}
@@ -0,0 +1,40 @@
+package action_sequencer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownMouseButton = errors.New("unknown mouse button")
+
+/*
+A mouse output is responsible for moving the mouse, pressing its buttons and scrolling.
+Scroll steps are positive for scrolling up (or right) and negative for scrolling down (or left)
+*/
+type MouseOutput interface {
+	Move(dx int, dy int) error
+	MoveTo(x int, y int) error
+	ButtonDown(button Key) error
+	ButtonUp(button Key) error
+	Scroll(dx int, dy int) error
+	Close() error
+}
+
+/* mouse buttons are tracked like keys; they are prefixed so they don't collide with key names (eg: "left") */
+var known_mouse_buttons = map[string]Key{
+	"left":   {Name: "mouse_left", Code: 0x110, Mouse: true},
+	"right":  {Name: "mouse_right", Code: 0x111, Mouse: true},
+	"middle": {Name: "mouse_middle", Code: 0x112, Mouse: true},
+}
+
+/*
+Parses a mouse button name (left, right or middle)
+*/
+func ParseMouseButton(name string) (Key, error) {
+	button, is_known := known_mouse_buttons[strings.ToLower(strings.TrimSpace(name))]
+	if !is_known {
+		return Key{}, fmt.Errorf("%w (%s)", ErrUnknownMouseButton, name)
+	}
+	return button, nil
+}
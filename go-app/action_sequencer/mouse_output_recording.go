@@ -0,0 +1,69 @@
+package action_sequencer
+
+import (
+	"sync"
+	"time"
+)
+
+type RecordingMouseOutput_Event struct {
+	/* move, move_to, button or scroll */
+	Kind   string
+	X      int
+	Y      int
+	Button string
+	Down   bool
+	At     time.Time
+}
+
+/* records the mouse events instead of sending them; used for testing */
+type RecordingMouseOutput struct {
+	mutex  sync.Mutex
+	events []RecordingMouseOutput_Event
+}
+
+func NewRecordingMouseOutput() *RecordingMouseOutput {
+	return &RecordingMouseOutput{
+		events: []RecordingMouseOutput_Event{},
+	}
+}
+
+func (o *RecordingMouseOutput) record(event RecordingMouseOutput_Event) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	event.At = time.Now()
+	o.events = append(o.events, event)
+	return nil
+}
+
+func (o *RecordingMouseOutput) Move(dx int, dy int) error {
+	return o.record(RecordingMouseOutput_Event{Kind: "move", X: dx, Y: dy})
+}
+
+func (o *RecordingMouseOutput) MoveTo(x int, y int) error {
+	return o.record(RecordingMouseOutput_Event{Kind: "move_to", X: x, Y: y})
+}
+
+func (o *RecordingMouseOutput) ButtonDown(button Key) error {
+	return o.record(RecordingMouseOutput_Event{Kind: "button", Button: button.Name, Down: true})
+}
+
+func (o *RecordingMouseOutput) ButtonUp(button Key) error {
+	return o.record(RecordingMouseOutput_Event{Kind: "button", Button: button.Name, Down: false})
+}
+
+func (o *RecordingMouseOutput) Scroll(dx int, dy int) error {
+	return o.record(RecordingMouseOutput_Event{Kind: "scroll", X: dx, Y: dy})
+}
+
+func (o *RecordingMouseOutput) Close() error {
+	return nil
+}
+
+/* Returns a copy of the recorded events */
+func (o *RecordingMouseOutput) Events() []RecordingMouseOutput_Event {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	events := make([]RecordingMouseOutput_Event, len(o.events))
+	copy(events, o.events)
+	return events
+}
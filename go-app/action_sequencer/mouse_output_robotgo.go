@@ -0,0 +1,50 @@
+package action_sequencer
+
+import (
+	"strings"
+
+	"github.com/go-vgo/robotgo"
+)
+
+/* controls the mouse through the desktop session (X11, Windows or macOS) */
+type RobotgoMouseOutput struct{}
+
+func NewRobotgoMouseOutput() *RobotgoMouseOutput {
+	return &RobotgoMouseOutput{}
+}
+
+func (o *RobotgoMouseOutput) robotgoButton(button Key) string {
+	name := strings.TrimPrefix(button.Name, "mouse_")
+	if name == "middle" {
+		return "center"
+	}
+	return name
+}
+
+func (o *RobotgoMouseOutput) Move(dx int, dy int) error {
+	robotgo.MoveRelative(dx, dy)
+	return nil
+}
+
+func (o *RobotgoMouseOutput) MoveTo(x int, y int) error {
+	robotgo.Move(x, y)
+	return nil
+}
+
+func (o *RobotgoMouseOutput) ButtonDown(button Key) error {
+	return robotgo.Toggle(o.robotgoButton(button), "down")
+}
+
+func (o *RobotgoMouseOutput) ButtonUp(button Key) error {
+	return robotgo.Toggle(o.robotgoButton(button), "up")
+}
+
+func (o *RobotgoMouseOutput) Scroll(dx int, dy int) error {
+	/* without a delay robotgo sleeps after every scroll */
+	robotgo.Scroll(dx, dy, 0)
+	return nil
+}
+
+func (o *RobotgoMouseOutput) Close() error {
+	return nil
+}
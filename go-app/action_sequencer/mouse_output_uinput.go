@@ -0,0 +1,89 @@
+package action_sequencer
+
+import (
+	"errors"
+	"tsw_controller_app/uinput"
+)
+
+const UINPUT_MOUSE_NAME = "TSW Controller Virtual Mouse"
+
+const (
+	uinput_REL_X      uint16 = 0x00
+	uinput_REL_Y      uint16 = 0x01
+	uinput_REL_HWHEEL uint16 = 0x06
+	uinput_REL_WHEEL  uint16 = 0x08
+)
+
+var ErrAbsoluteMoveUnsupported = errors.New("absolute mouse movement is not supported by the uinput mouse")
+
+/*
+Controls the mouse through a Linux uinput virtual mouse.
+The virtual mouse is a relative pointer device, so absolute movement is not supported
+*/
+type UinputMouseOutput struct {
+	device *uinput.Device
+}
+
+func NewUinputMouseOutput() (*UinputMouseOutput, error) {
+	buttons := []uint16{}
+	for _, button := range known_mouse_buttons {
+		buttons = append(buttons, button.Code)
+	}
+	device, err := uinput.Open(uinput.DeviceConfig{
+		Name:    UINPUT_MOUSE_NAME,
+		Vendor:  0x1209,
+		Product: 0x5459,
+		Version: 1,
+		Keys:    buttons,
+		RelAxes: []uint16{uinput_REL_X, uinput_REL_Y, uinput_REL_HWHEEL, uinput_REL_WHEEL},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UinputMouseOutput{device: device}, nil
+}
+
+func (o *UinputMouseOutput) emitRelative(x_code uint16, dx int, y_code uint16, dy int) error {
+	if dx != 0 {
+		if err := o.device.Emit(uinput.EV_REL, x_code, int32(dx)); err != nil {
+			return err
+		}
+	}
+	if dy != 0 {
+		if err := o.device.Emit(uinput.EV_REL, y_code, int32(dy)); err != nil {
+			return err
+		}
+	}
+	return o.device.Sync()
+}
+
+func (o *UinputMouseOutput) Move(dx int, dy int) error {
+	return o.emitRelative(uinput_REL_X, dx, uinput_REL_Y, dy)
+}
+
+func (o *UinputMouseOutput) MoveTo(x int, y int) error {
+	return ErrAbsoluteMoveUnsupported
+}
+
+func (o *UinputMouseOutput) toggle(button Key, value int32) error {
+	if err := o.device.Emit(uinput.EV_KEY, button.Code, value); err != nil {
+		return err
+	}
+	return o.device.Sync()
+}
+
+func (o *UinputMouseOutput) ButtonDown(button Key) error {
+	return o.toggle(button, 1)
+}
+
+func (o *UinputMouseOutput) ButtonUp(button Key) error {
+	return o.toggle(button, 0)
+}
+
+func (o *UinputMouseOutput) Scroll(dx int, dy int) error {
+	return o.emitRelative(uinput_REL_HWHEEL, dx, uinput_REL_WHEEL, dy)
+}
+
+func (o *UinputMouseOutput) Close() error {
+	return o.device.Close()
+}
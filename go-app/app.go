@@ -14,6 +14,7 @@ import (
 	go_runtime "runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 	"tsw_controller_app/action_sequencer"
 	"tsw_controller_app/cabdebugger"
@@ -26,6 +27,7 @@ import (
 	"tsw_controller_app/string_utils"
 	"tsw_controller_app/tswapi"
 	"tsw_controller_app/tswconnector"
+	"tsw_controller_app/virtual_joystick"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -34,13 +36,26 @@ import (
 //go:embed mod_assets/*
 var mod_assets embed.FS
 
+/* how long to wait for a control to be moved when identifying a controller */
+const IDENTIFY_CONTROLLER_TIMEOUT = 15 * time.Second
+
+/* how long and how strong the controller rumbles when testing the rumble */
+const (
+	TEST_RUMBLE_DURATION = 500 * time.Millisecond
+	TEST_RUMBLE_STRENGTH = 0.5
+)
+
 type AppEventType = string
 
 const (
 	AppEventType_JoyDevicesUpdated AppEventType = "joydevices_updated"
 	AppEventType_ProfilesUpdated   AppEventType = "profiles_updated"
-	AppEventType_RawEvent          AppEventType = "rawevent"
-	AppEventType_Log               AppEventType = "log"
+	/* emitted when selected profiles are restored for connected controllers */
+	AppEventType_SelectedProfilesUpdated AppEventType = "selected_profiles_updated"
+	/* emitted when the profile used by a controller changed (eg: when a profile was auto-selected) */
+	AppEventType_ProfileSelectionChanged AppEventType = "profile_selection_changed"
+	AppEventType_RawEvent                AppEventType = "rawevent"
+	AppEventType_Log                     AppEventType = "log"
 )
 
 type AppConfig_Mode = string
@@ -108,15 +123,25 @@ type App struct {
 	sdl_manager        *sdl_mgr.SDLMgr
 	controller_manager *controller_mgr.ControllerManager
 	action_sequencer   *action_sequencer.ActionSequencer
+	key_output         action_sequencer.KeyOutput
+	mouse_output       action_sequencer.MouseOutput
 	connector          tswconnector.TSWConnector
 	tswapi             *tswapi.TSWAPI
 	cab_debugger       *cabdebugger.CabDebugger
 	direct_controller  *profile_runner.DirectController
 	sync_controller    *profile_runner.SyncController
+	mod_sync_feedback  *profile_runner.ModSyncFeedbackSource
+	api_sync_feedback  *profile_runner.ApiSyncFeedbackSource
 	api_controller     *profile_runner.ApiController
+	virtual_joystick   *profile_runner.VirtualJoystickController
 	profile_runner     *profile_runner.ProfileRunner
 
 	raw_subscriber *AppRawSubscriber
+	/* guards the calibration session; the calibration session methods can be called concurrently */
+	calibration_session_mutex sync.Mutex
+	calibration_session       *controller_mgr.ControllerManager_CalibrationSession
+	/* guards the program config; every read, write and save of the program config after startup holds it */
+	program_config_mutex sync.Mutex
 }
 
 func NewApp(
@@ -155,32 +180,64 @@ func (a *App) startupInitialize() {
 	}
 
 	controller_manager := controller_mgr.New(a.sdl_manager)
-	action_sequencer := action_sequencer.New(connector)
+	for hardware_key, alias := range a.program_config.DeviceAliases {
+		controller_manager.Config.DeviceAliases.Set(hardware_key, alias)
+	}
+	key_output, mouse_output := a.createInputOutputs()
+	action_sequencer := action_sequencer.New(connector, key_output, mouse_output)
 
 	cab_debugger := cabdebugger.NewCabDebugger(tsw_api, connector, cabdebugger.CabDebugger_Config{})
 	api_controller := profile_runner.NewAPIController(tsw_api)
 	direct_controller := profile_runner.NewDirectController(connector)
-	sync_controller := profile_runner.NewSyncController(connector)
+	mod_sync_feedback := profile_runner.NewModSyncFeedbackSource(connector)
+	api_sync_feedback := profile_runner.NewApiSyncFeedbackSource(tsw_api, profile_runner.ApiSyncFeedbackSource_Config{})
+	sync_controller := profile_runner.NewSyncController(mod_sync_feedback, api_sync_feedback)
+	virtual_joystick_controller := profile_runner.NewVirtualJoystickController(virtual_joystick.NewUinputOutput())
 	profile_runner := profile_runner.New(
 		action_sequencer,
 		controller_manager,
 		direct_controller,
 		sync_controller,
 		api_controller,
+		virtual_joystick_controller,
 		cab_debugger,
 	)
+	for guid, policy := range a.program_config.DeviceProfilePolicies {
+		profile_runner.SetDevicePolicy(guid, deviceProfilePolicyFromConfig(policy))
+	}
 
 	a.controller_manager = controller_manager
 	a.action_sequencer = action_sequencer
+	a.key_output = key_output
+	a.mouse_output = mouse_output
 	a.connector = connector
 	a.tswapi = tsw_api
 	a.cab_debugger = cab_debugger
 	a.direct_controller = direct_controller
 	a.sync_controller = sync_controller
+	a.mod_sync_feedback = mod_sync_feedback
+	a.api_sync_feedback = api_sync_feedback
 	a.api_controller = api_controller
+	a.virtual_joystick = virtual_joystick_controller
 	a.profile_runner = profile_runner
 }
 
+func (a *App) createInputOutputs() (action_sequencer.KeyOutput, action_sequencer.MouseOutput) {
+	if a.program_config.KeyOutput == config.KeyOutput_Uinput {
+		uinput_output, err := action_sequencer.NewUinputKeyOutput()
+		if err == nil {
+			var uinput_mouse_output *action_sequencer.UinputMouseOutput
+			uinput_mouse_output, err = action_sequencer.NewUinputMouseOutput()
+			if err == nil {
+				return uinput_output, uinput_mouse_output
+			}
+			uinput_output.Close()
+		}
+		logger.Logger.Error("[App::createInputOutputs] could not create uinput keyboard and mouse; falling back to robotgo", "error", err)
+	}
+	return action_sequencer.NewRobotgoKeyOutput(), action_sequencer.NewRobotgoMouseOutput()
+}
+
 func (a *App) startupLoad() {
 	a.LoadConfiguration()
 
@@ -190,6 +247,11 @@ func (a *App) startupLoad() {
 			TSWAPISubscriptionIDStart: a.program_config.TSWAPISubscriptionIDStart,
 		})
 	}
+	a.api_sync_feedback.UpdateConfig(profile_runner.ApiSyncFeedbackSource_Config{
+		/* the subscription id start itself is used by the cab debugger */
+		SubscriptionID: a.program_config.TSWAPISubscriptionIDStart + 1,
+	})
+	a.applySyncControlFeedback()
 
 	if a.program_config.PreferredControlMode == config.PreferredControlMode_DirectControl ||
 		a.program_config.PreferredControlMode == config.PreferredControlMode_SyncControl ||
@@ -254,6 +316,12 @@ func (a *App) startupRun() {
 		<-a.ctx.Done()
 	}()
 
+	go func() {
+		cancel := a.virtual_joystick.Run(a.ctx)
+		defer cancel()
+		<-a.ctx.Done()
+	}()
+
 	go func() {
 		cancel := a.sync_controller.Run(a.ctx)
 		defer cancel()
@@ -270,6 +338,20 @@ func (a *App) startupRun() {
 				return
 			case <-channel:
 				runtime.EventsEmit(a.ctx, AppEventType_JoyDevicesUpdated)
+				a.restoreSelectedProfiles()
+			}
+		}
+	}()
+
+	go func() {
+		channel, cancel := a.profile_runner.SubscribeProfileSelection()
+		defer cancel()
+		for {
+			select {
+			case <-a.ctx.Done():
+				return
+			case selection := <-channel:
+				runtime.EventsEmit(a.ctx, AppEventType_ProfileSelectionChanged, toInteropProfileSelection(selection))
 			}
 		}
 	}()
@@ -283,58 +365,115 @@ func (a *App) startup(ctx context.Context) {
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	/* make sure no keys or controls are left pressed after closing the app */
+	a.profile_runner.Shutdown()
+	a.action_sequencer.ReleaseAll()
+	a.key_output.Close()
+	a.mouse_output.Close()
+	a.virtual_joystick.Output.Close()
 }
 
 func (a *App) GetVersion() string {
 	return VERSION
 }
 
+/* applies an update to the program config and saves it */
+func (a *App) updateProgramConfig(update func(program_config *config.Config_ProgramConfig)) error {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
+	update(a.program_config)
+	return a.saveProgramConfig()
+}
+
+/* saves the program config; the program config mutex must be held */
+func (a *App) saveProgramConfig() error {
+	return a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+}
+
 func (a *App) GetLastInstalledModVersion() string {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
 	return a.program_config.LastInstalledModVersion
 }
 
 func (a *App) SetLastInstalledModVersion(version string) {
-	a.program_config.LastInstalledModVersion = version
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.LastInstalledModVersion = version
+	})
 }
 
 func (a *App) GetTSWAPIKeyLocation() string {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
 	return a.program_config.TSWAPIKeyLocation
 }
 
 func (a *App) SetTSWAPIKeyLocation(location string) {
-	a.program_config.TSWAPIKeyLocation = location
 	a.tswapi.LoadAPIKey(location)
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.TSWAPIKeyLocation = location
+	})
 }
 
 func (a *App) GetPreferredControlMode() string {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
 	return a.program_config.PreferredControlMode
 }
 
 func (a *App) SetPreferredControlMode(mode config.PreferredControlMode) {
-	a.program_config.PreferredControlMode = mode
 	a.profile_runner.Settings.SetPreferredControlMode(mode)
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.PreferredControlMode = mode
+	})
+}
+
+func (a *App) applySyncControlFeedback() {
+	feedback := a.GetSyncControlFeedback()
+	a.mod_sync_feedback.SetEnabled(feedback == config.SyncControlFeedback_Mod || feedback == config.SyncControlFeedback_Both)
+	a.api_sync_feedback.SetEnabled(feedback == config.SyncControlFeedback_Api || feedback == config.SyncControlFeedback_Both)
+}
+
+func (a *App) GetSyncControlFeedback() string {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
+	return a.program_config.SyncControlFeedback
+}
+
+func (a *App) SetSyncControlFeedback(feedback config.SyncControlFeedback) error {
+	if err := config.ValidateSyncControlFeedback(feedback); err != nil {
+		return err
+	}
+	err := a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.SyncControlFeedback = feedback
+	})
+	a.applySyncControlFeedback()
+	return err
 }
 
 func (a *App) GetAlwaysOnTop() bool {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
 	return a.program_config.AlwaysOnTop
 }
 
 func (a *App) SetAlwaysOnTop(enabled bool) {
-	a.program_config.AlwaysOnTop = enabled
 	runtime.WindowSetAlwaysOnTop(a.ctx, enabled)
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.AlwaysOnTop = enabled
+	})
 }
 
 func (a *App) GetTheme() string {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
 	return a.program_config.Theme
 }
 
 func (a *App) SetTheme(theme string) {
-	a.program_config.Theme = theme
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.Theme = theme
+	})
 }
 
 func (a *App) LoadConfiguration() {
@@ -352,18 +491,25 @@ func (a *App) LoadConfiguration() {
 			logger.Logger.Error("[App] encountered error while reading configuration files", "error", err)
 		}
 
+		/* the game controller mappings only apply to devices connected afterwards */
+		if db, has_db, err := a.config_loader.GameControllerDBFromDirectory(dir); err != nil {
+			logger.Logger.Error("[App] encountered error while reading the game controller database", "error", err)
+		} else if has_db {
+			added, db_errors := sdl_mgr.AddGameControllerMappings(db)
+			for _, err := range db_errors {
+				logger.Logger.Error("[App] encountered error while adding game controller mappings", "error", err)
+			}
+			logger.Logger.Info("[App] added game controller mappings", "dir", dir, "count", added)
+		}
+
 		for _, sdl_mapping := range sdl_mappings {
-			var calibration *config.Config_Controller_Calibration
-			for _, c := range calibrations {
-				if c.UsbID == sdl_mapping.UsbID {
-					calibration = &c
-					break
+			/* there may be a shared calibration and calibrations for single devices */
+			for _, calibration := range calibrations {
+				if calibration.UsbID == sdl_mapping.UsbID {
+					logger.Logger.Info("[App] registering SDL map and calibration for controller", "name", sdl_mapping.Name, "usb_id", sdl_mapping.UsbID, "guid", calibration.GUID)
+					a.controller_manager.RegisterConfig(sdl_mapping, calibration)
 				}
 			}
-			if calibration != nil {
-				logger.Logger.Info("[App] registering SDL map and calibration for controller", "name", sdl_mapping.Name, "usb_id", sdl_mapping.UsbID)
-				a.controller_manager.RegisterConfig(sdl_mapping, *calibration)
-			}
 		}
 
 		for _, profile := range profiles {
@@ -373,27 +519,88 @@ func (a *App) LoadConfiguration() {
 	}
 
 	a.profile_runner.Resolve()
+	/* the profile IDs change when a profile is renamed or moved */
+	if err := a.persistSelectedProfiles(a.profile_runner.RefreshSelectedProfiles()...); err != nil {
+		logger.Logger.Error("[App] failed to persist selected profiles", "error", err)
+	}
 	runtime.EventsEmit(a.ctx, AppEventType_ProfilesUpdated)
+	a.restoreSelectedProfiles()
+	runtime.EventsEmit(a.ctx, AppEventType_SelectedProfilesUpdated)
+}
+
+/*
+selects the persisted profiles for connected controllers without a selected profile
+profiles which were renamed or moved are matched by their name or path; deleted profiles are skipped
+*/
+func (a *App) restoreSelectedProfiles() {
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
+
+	did_restore := false
+	selected_profiles := a.profile_runner.Settings.GetSelectedProfiles()
+	for guid, persisted := range a.program_config.SelectedProfiles {
+		if _, has_selected_profile := selected_profiles.Get(guid); has_selected_profile {
+			continue
+		}
+		if _, is_connected := a.controller_manager.GetDevice(guid); !is_connected {
+			continue
+		}
+
+		profile, has_profile := a.profile_runner.FindProfileByNameAndPath(persisted.Name, persisted.Path)
+		if !has_profile {
+			logger.Logger.Info("[App::restoreSelectedProfiles] previously selected profile not found", "guid", guid, "name", persisted.Name, "path", persisted.Path)
+			continue
+		}
+		if err := a.profile_runner.SetProfile(guid, profile.Id()); err != nil {
+			logger.Logger.Error("[App::restoreSelectedProfiles] failed to restore selected profile", "guid", guid, "error", err)
+			continue
+		}
+		a.program_config.SelectedProfiles[guid] = config.Config_ProgramConfig_SelectedProfile{
+			Name: profile.Name,
+			Path: profile.Metadata.Path,
+		}
+		did_restore = true
+	}
+
+	if did_restore {
+		a.saveProgramConfig()
+		runtime.EventsEmit(a.ctx, AppEventType_SelectedProfilesUpdated)
+	}
+}
+
+/* persists the current profile selection of the controllers */
+func (a *App) persistSelectedProfiles(guids ...controller_mgr.JoystickGUIDString) error {
+	if len(guids) == 0 {
+		return nil
+	}
+
+	a.program_config_mutex.Lock()
+	defer a.program_config_mutex.Unlock()
+
+	if a.program_config.SelectedProfiles == nil {
+		a.program_config.SelectedProfiles = map[string]config.Config_ProgramConfig_SelectedProfile{}
+	}
+	for _, guid := range guids {
+		if selected_profile, has_selected_profile := a.profile_runner.Settings.GetSelectedProfiles().Get(guid); has_selected_profile {
+			a.program_config.SelectedProfiles[guid] = config.Config_ProgramConfig_SelectedProfile{
+				Name: selected_profile.Profile.Name,
+				Path: selected_profile.Profile.Metadata.Path,
+			}
+		} else {
+			delete(a.program_config.SelectedProfiles, guid)
+		}
+	}
+	return a.saveProgramConfig()
 }
 
 func (a *App) GetControllers() []Interop_GenericController {
 	var controllers []Interop_GenericController
 	a.controller_manager.ConfiguredControllers.ForEach(func(c controller_mgr.ControllerManager_ConfiguredController, _ controller_mgr.JoystickGUIDString) bool {
-		controllers = append(controllers, Interop_GenericController{
-			GUID:         c.Joystick.GUID,
-			UsbID:        c.Joystick.ToString(),
-			Name:         c.Joystick.Name,
-			IsConfigured: true,
-		})
+		controllers = append(controllers, a.toInteropGenericController(c.Joystick, true))
 		return true
 	})
 	a.controller_manager.UnconfiguredControllers.ForEach(func(c controller_mgr.ControllerManager_UnconfiguredController, key controller_mgr.JoystickGUIDString) bool {
-		controllers = append(controllers, Interop_GenericController{
-			GUID:         c.Joystick.GUID,
-			UsbID:        c.Joystick.ToString(),
-			Name:         c.Joystick.Name,
-			IsConfigured: false,
-		})
+		controllers = append(controllers, a.toInteropGenericController(c.Joystick, false))
 		return true
 	})
 	sort.Slice(controllers, func(i, j int) bool {
@@ -402,6 +609,71 @@ func (a *App) GetControllers() []Interop_GenericController {
 	return controllers
 }
 
+func (a *App) toInteropGenericController(joystick *sdl_mgr.SDLMgr_Joystick, is_configured bool) Interop_GenericController {
+	controller := Interop_GenericController{
+		GUID:         joystick.GUID,
+		UsbID:        joystick.ToString(),
+		Name:         joystick.Name,
+		IsConfigured: is_configured,
+
+		IsGameController: joystick.InternalGameController != nil,
+		HasRumble:        joystick.HasRumble(),
+	}
+	if device, has_device := a.controller_manager.GetDevice(joystick.GUID); has_device {
+		controller.Alias = device.Alias
+		controller.IdentitySource = device.IdentitySource
+	}
+	return controller
+}
+
+/*
+Assigns an alias to a controller; for controllers without a serial number or device path the profile selection
+and calibration follow the alias. Assigning an alias used by an identical controller swaps the aliases
+*/
+func (a *App) SetControllerAlias(guid controller_mgr.JoystickGUIDString, alias string) error {
+	renames, err := a.controller_manager.SetDeviceAlias(guid, strings.TrimSpace(alias))
+	if err != nil {
+		return err
+	}
+	/* a selection persisted for the new GUID takes precedence over the moved selection */
+	a.restoreSelectedProfiles()
+	a.profile_runner.RenameJoysticks(renames)
+	renamed_guids := []controller_mgr.JoystickGUIDString{}
+	for _, rename := range renames {
+		renamed_guids = append(renamed_guids, rename.From, rename.To)
+	}
+	if err := a.persistSelectedProfiles(renamed_guids...); err != nil {
+		return err
+	}
+	device_profile_policies := a.deviceProfilePoliciesToConfig()
+	device_aliases := map[string]string{}
+	a.controller_manager.Config.DeviceAliases.ForEach(func(alias string, hardware_key string) bool {
+		device_aliases[hardware_key] = alias
+		return true
+	})
+	return a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.DeviceProfilePolicies = device_profile_policies
+		program_config.DeviceAliases = device_aliases
+	})
+}
+
+/*
+Waits for a control to be moved on one of the controllers with the USB ID and returns its GUID
+Used to tell identical controllers apart
+*/
+func (a *App) IdentifyController(usb_id string) (controller_mgr.JoystickGUIDString, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, IDENTIFY_CONTROLLER_TIMEOUT)
+	defer cancel()
+	return a.controller_manager.IdentifyJoystick(ctx, usb_id)
+}
+
+/*
+Briefly rumbles the controller; also tells identical game controllers apart
+*/
+func (a *App) RumbleController(guid controller_mgr.JoystickGUIDString) error {
+	return a.controller_manager.Rumble(guid, TEST_RUMBLE_STRENGTH, TEST_RUMBLE_STRENGTH, TEST_RUMBLE_DURATION)
+}
+
 func (a *App) GetProfiles() []Interop_Profile {
 	var profiles []Interop_Profile
 
@@ -424,6 +696,9 @@ func (a *App) GetProfiles() []Interop_Profile {
 				warnings = append(warnings, "This profile extends from itself, which is not a valid use-case")
 			}
 		}
+		if profile.Controller != nil && profile.Controller.Mapping != nil && len(profile.Controller.Mapping.Derived) > 0 {
+			warnings = append(warnings, "The derived controls of the mapping in this profile are not used; declare them in the SDL mapping file of the controller")
+		}
 
 		profiles = append(profiles, Interop_Profile{
 			Id:         profile.Id(),
@@ -461,19 +736,99 @@ func (a *App) GetSelectedProfiles() map[controller_mgr.JoystickGUIDString]Intero
 	return selected_profiles
 }
 
+func toInteropProfileSelection(selection profile_runner.ProfileRunner_ProfileSelection) Interop_ProfileSelection {
+	return Interop_ProfileSelection{
+		GUID:        selection.GUID,
+		ProfileId:   selection.ProfileId,
+		ProfileName: selection.ProfileName,
+		Reason:      selection.Reason,
+		Description: selection.Description,
+	}
+}
+
+/* the profiles last used by the controllers including auto-selected and fallback profiles */
+func (a *App) GetProfileSelections() map[controller_mgr.JoystickGUIDString]Interop_ProfileSelection {
+	selections := map[controller_mgr.JoystickGUIDString]Interop_ProfileSelection{}
+	for guid, selection := range a.profile_runner.GetProfileSelections() {
+		selections[guid] = toInteropProfileSelection(selection)
+	}
+	return selections
+}
+
+func deviceProfilePolicyFromConfig(policy config.Config_ProgramConfig_DeviceProfilePolicy) profile_runner.ProfileRunner_DevicePolicy {
+	device_policy := profile_runner.DefaultDevicePolicy()
+	if policy.StickyManualSelection != nil {
+		device_policy.StickyManualSelection = *policy.StickyManualSelection
+	}
+	device_policy.FallbackProfile = policy.FallbackProfile
+	return device_policy
+}
+
+func (a *App) deviceProfilePoliciesToConfig() map[string]config.Config_ProgramConfig_DeviceProfilePolicy {
+	policies := map[string]config.Config_ProgramConfig_DeviceProfilePolicy{}
+	a.profile_runner.Settings.DevicePoliciesByGUID.ForEach(func(policy profile_runner.ProfileRunner_DevicePolicy, guid controller_mgr.JoystickGUIDString) bool {
+		sticky_manual_selection := policy.StickyManualSelection
+		policies[guid] = config.Config_ProgramConfig_DeviceProfilePolicy{
+			StickyManualSelection: &sticky_manual_selection,
+			FallbackProfile:       policy.FallbackProfile,
+		}
+		return true
+	})
+	return policies
+}
+
+func (a *App) GetControllerProfilePolicy(guid controller_mgr.JoystickGUIDString) Interop_ControllerProfilePolicy {
+	policy := a.profile_runner.Settings.GetDevicePolicy(guid)
+	interop_policy := Interop_ControllerProfilePolicy{StickyManualSelection: policy.StickyManualSelection}
+	if policy.FallbackProfile != nil {
+		if profile, has_profile := a.profile_runner.FindProfileByNameAndPath(policy.FallbackProfile.Name, policy.FallbackProfile.Path); has_profile {
+			interop_policy.FallbackProfileId = profile.Id()
+		}
+	}
+	return interop_policy
+}
+
+/* sets how the profile of the controller is selected; the fallback profile is stored by name and path like the selected profiles */
+func (a *App) SetControllerProfilePolicy(guid controller_mgr.JoystickGUIDString, policy Interop_ControllerProfilePolicy) error {
+	device_policy := profile_runner.ProfileRunner_DevicePolicy{StickyManualSelection: policy.StickyManualSelection}
+	if policy.FallbackProfileId != "" {
+		profile, has_profile := a.profile_runner.Profiles.Get(policy.FallbackProfileId)
+		if !has_profile {
+			return fmt.Errorf("could not find profile by ID %s", policy.FallbackProfileId)
+		}
+		device_policy.FallbackProfile = &config.Config_ProgramConfig_SelectedProfile{
+			Name: profile.Name,
+			Path: profile.Metadata.Path,
+		}
+	}
+	a.profile_runner.SetDevicePolicy(guid, device_policy)
+	device_profile_policies := a.deviceProfilePoliciesToConfig()
+	return a.updateProgramConfig(func(program_config *config.Config_ProgramConfig) {
+		program_config.DeviceProfilePolicies = device_profile_policies
+	})
+}
+
 func (a *App) GetControllerConfiguration(guid controller_mgr.JoystickGUIDString) *Interop_ControllerConfiguration {
 	if controller, has_controller := a.controller_manager.ConfiguredControllers.Get(guid); has_controller {
 		/* when configured the SDL map and calibration always exist */
-		sdl_mapping, _ := controller.Manager.Config.SDLMappingsByUsbID.Get(controller.Joystick.ToString())
+		sdl_mapping, _ := controller.Manager.FindSDLMapping(controller.Joystick)
 		interop_calibration := Interop_ControllerCalibration{
 			Name:     sdl_mapping.Name,
 			UsbId:    sdl_mapping.UsbID,
 			Controls: []Interop_ControllerCalibration_Control{},
 		}
+		if _, has_device_calibration := controller.Manager.Config.CalibrationsByGUID.Get(guid); has_device_calibration {
+			interop_calibration.GUID = guid
+		}
 		controller.Controls.ForEach(func(control controller_mgr.ControllerManager_Controller_Control, key string) bool {
+			/* derived controls are declared in the SDL mapping file and can't be calibrated */
+			if control.Derived != nil {
+				return true
+			}
 			calibration := Interop_ControllerCalibration_Control{
 				Kind:        control.SDLMapping.Kind,
 				Index:       control.SDLMapping.Index,
+				Direction:   control.SDLMapping.HatDirection(),
 				Name:        control.Name,
 				Min:         control.Calibration.Min,
 				Max:         control.Calibration.Max,
@@ -481,6 +836,7 @@ func (a *App) GetControllerConfiguration(guid controller_mgr.JoystickGUIDString)
 				Deadzone:    0,
 				Invert:      false,
 				EasingCurve: []float64{0.0, 0.0, 1.0, 1.0},
+				Detents:     []Interop_ControllerCalibration_Detent{},
 			}
 			if control.Calibration.Idle != nil {
 				calibration.Idle = *control.Calibration.Idle
@@ -494,6 +850,18 @@ func (a *App) GetControllerConfiguration(guid controller_mgr.JoystickGUIDString)
 			if control.Calibration.EasingCurve != nil {
 				calibration.EasingCurve = *control.Calibration.EasingCurve
 			}
+			calibration.ResponseCurve = control.Calibration.ResponseCurve
+			calibration.ResponseCurveBelowIdle = control.Calibration.ResponseCurveBelowIdle
+			calibration.Filter = control.Calibration.Filter
+			if control.Calibration.Detents != nil {
+				for _, detent := range *control.Calibration.Detents {
+					interop_detent := Interop_ControllerCalibration_Detent{Name: detent.Name, Value: detent.Value}
+					if detent.CaptureWidth != nil {
+						interop_detent.CaptureWidth = *detent.CaptureWidth
+					}
+					calibration.Detents = append(calibration.Detents, interop_detent)
+				}
+			}
 			interop_calibration.Controls = append(interop_calibration.Controls, calibration)
 			return true
 		})
@@ -524,6 +892,27 @@ func (a *App) GetCabControlState() (Interop_Cab_ControlState, error) {
 	return control_state, nil
 }
 
+/*
+Returns which control modes are currently available and which control mode was used for the controls of the joystick
+*/
+func (a *App) GetControlModeStatus(guid controller_mgr.JoystickGUIDString) Interop_ControlModeStatus {
+	availability := a.profile_runner.GetControlModeAvailability()
+	status := Interop_ControlModeStatus{
+		DirectControlAvailable: availability.DirectControl,
+		ApiControlAvailable:    availability.ApiControl,
+		SyncControlAvailable:   availability.SyncControl,
+		Controls:               []Interop_ControlModeStatus_Control{},
+	}
+	for _, selection := range a.profile_runner.GetControlModeSelections(guid) {
+		status.Controls = append(status.Controls, Interop_ControlModeStatus_Control{
+			ControlName: selection.ControlName,
+			ControlMode: selection.ControlMode,
+			Fallback:    selection.Fallback,
+		})
+	}
+	return status
+}
+
 func (a *App) ResetCabControlState() {
 	a.cab_debugger.Clear()
 }
@@ -587,11 +976,14 @@ func (a *App) SelectProfile(guid controller_mgr.JoystickGUIDString, id string) e
 		logger.Logger.Error("failed to select profile by ID", "id", id, "error", err)
 		return err
 	}
-	return nil
+	return a.persistSelectedProfiles(guid)
 }
 
 func (a *App) ClearProfile(guid controller_mgr.JoystickGUIDString) {
 	a.profile_runner.ClearProfile(guid)
+	if err := a.persistSelectedProfiles(guid); err != nil {
+		logger.Logger.Error("[App::ClearProfile] failed to persist selected profiles", "error", err)
+	}
 }
 
 func (a *App) LastRawEvent() *Interop_RawEvent {
@@ -622,6 +1014,7 @@ func (a *App) LastRawEvent() *Interop_RawEvent {
 				Kind:      sdl_mgr.SDLMgr_Control_Kind_Hat,
 				Index:     int(e.Hat),
 				Value:     float64(e.Value),
+				Direction: sdl_mgr.HatDirectionFromValue(e.Value),
 				Timestamp: int(e.Timestamp),
 			}
 		}
@@ -674,6 +1067,94 @@ func (a *App) SubscribeRaw(guid string) error {
 	return nil
 }
 
+/* starts detecting the calibration of the controller; a running calibration session is stopped first */
+func (a *App) StartCalibrationSession(guid controller_mgr.JoystickGUIDString) error {
+	a.calibration_session_mutex.Lock()
+	defer a.calibration_session_mutex.Unlock()
+	if a.calibration_session != nil {
+		a.calibration_session.Stop()
+		a.calibration_session = nil
+	}
+	session, err := a.controller_manager.StartCalibrationSession(a.ctx, guid)
+	if err != nil {
+		logger.Logger.Error("[App::StartCalibrationSession] failed to start calibration session", "guid", guid, "error", err)
+		return err
+	}
+	a.calibration_session = session
+	return nil
+}
+
+/* the calibration detected so far by the running calibration session */
+func (a *App) GetCalibrationSessionResult() (Interop_CalibrationSessionResult, error) {
+	a.calibration_session_mutex.Lock()
+	defer a.calibration_session_mutex.Unlock()
+	if a.calibration_session == nil {
+		return Interop_CalibrationSessionResult{}, fmt.Errorf("no calibration session running")
+	}
+	return toInteropCalibrationSessionResult(a.calibration_session.Result(time.Now())), nil
+}
+
+/* stops the calibration session and returns the detected calibration for review; nothing is saved */
+func (a *App) StopCalibrationSession() (Interop_CalibrationSessionResult, error) {
+	a.calibration_session_mutex.Lock()
+	defer a.calibration_session_mutex.Unlock()
+	if a.calibration_session == nil {
+		return Interop_CalibrationSessionResult{}, fmt.Errorf("no calibration session running")
+	}
+	session := a.calibration_session
+	a.calibration_session = nil
+	session.Stop()
+	return toInteropCalibrationSessionResult(session.Result(time.Now())), nil
+}
+
+func toInteropCalibrationSessionResult(result controller_mgr.ControllerManager_CalibrationResult, err error) Interop_CalibrationSessionResult {
+	interop_result := Interop_CalibrationSessionResult{
+		Calibration: Interop_ControllerCalibration{
+			Name:     result.SDLMapping.Name,
+			UsbId:    result.SDLMapping.UsbID,
+			Controls: []Interop_ControllerCalibration_Control{},
+		},
+		Axes:     []Interop_CalibrationSession_Axis{},
+		Warnings: result.Warnings,
+	}
+	if err != nil {
+		interop_result.Error = err.Error()
+	}
+
+	for _, control := range result.SDLMapping.Data {
+		interop_control := Interop_ControllerCalibration_Control{
+			Kind:        control.Kind,
+			Index:       control.Index,
+			Direction:   control.HatDirection(),
+			Name:        control.Name,
+			EasingCurve: []float64{0.0, 0.0, 1.0, 1.0},
+			Detents:     []Interop_ControllerCalibration_Detent{},
+		}
+		for _, axis := range result.Axes {
+			if control.Kind == sdl_mgr.SDLMgr_Control_Kind_Axis && axis.Index == control.Index {
+				interop_control.Min = axis.Min
+				interop_control.Max = axis.Max
+				interop_control.Idle = axis.Idle
+				interop_control.Deadzone = axis.Deadzone
+				interop_control.Invert = axis.Inverted
+			}
+		}
+		interop_result.Calibration.Controls = append(interop_result.Calibration.Controls, interop_control)
+	}
+	for _, axis := range result.Axes {
+		interop_result.Axes = append(interop_result.Axes, Interop_CalibrationSession_Axis{
+			Index:        axis.Index,
+			Name:         axis.Name,
+			IdleDetected: axis.IdleDetected,
+			Noise:        axis.Noise,
+			Inverted:     axis.Inverted,
+			Centered:     axis.Centered,
+			Warnings:     axis.Warnings,
+		})
+	}
+	return interop_result
+}
+
 func (a *App) SaveProfileForSharing(guid controller_mgr.JoystickGUIDString, id string) error {
 	if profile, has_profile := a.profile_runner.Profiles.Get(id); has_profile {
 		controller, has_controller := a.controller_manager.ConfiguredControllers.Get(guid)
@@ -704,9 +1185,14 @@ func (a *App) SaveProfileForSharing(guid controller_mgr.JoystickGUIDString, id s
 				Data:  []config.Config_Controller_SDLMap_Control{},
 			}
 			controller.Controls.ForEach(func(value controller_mgr.ControllerManager_Controller_Control, key string) bool {
-				mapping.Data = append(mapping.Data, value.SDLMapping)
+				if value.Derived == nil {
+					mapping.Data = append(mapping.Data, value.SDLMapping)
+				}
 				return true
 			})
+			if sdl_mapping, has_sdl_mapping := controller.Manager.FindSDLMapping(controller.Joystick); has_sdl_mapping {
+				mapping.Derived = sdl_mapping.Derived
+			}
 			profile_for_sharing.Controller.Mapping = &mapping
 		}
 
@@ -776,27 +1262,64 @@ func (a *App) SaveCalibration(data Interop_ControllerCalibration) error {
 		UsbID: data.UsbId,
 		Data:  []config.Config_Controller_CalibrationData{},
 	}
+	calibration_filename := string_utils.Sluggify(data.Name)
+	if data.GUID != "" {
+		calibration.GUID = &data.GUID
+		calibration_filename = fmt.Sprintf("%s.%s", calibration_filename, string_utils.Sluggify(data.GUID))
+		if device, has_device := a.controller_manager.GetDevice(data.GUID); has_device && device.Alias != "" {
+			calibration_filename = fmt.Sprintf("%s.%s", string_utils.Sluggify(data.Name), string_utils.Sluggify(device.Alias))
+		}
+	}
 	for _, control := range data.Controls {
 		if control.Name != "" {
-			sdl_mapping.Data = append(sdl_mapping.Data, config.Config_Controller_SDLMap_Control{
+			sdl_mapping_control := config.Config_Controller_SDLMap_Control{
 				Kind:  control.Kind,
 				Index: control.Index,
 				Name:  control.Name,
-			})
-			if control.Kind == sdl_mgr.SDLMgr_Control_Kind_Axis {
-				calibration.Data = append(calibration.Data, config.Config_Controller_CalibrationData{
-					Id:          control.Name,
-					Min:         control.Min,
-					Max:         control.Max,
-					Idle:        &control.Idle,
-					Deadzone:    &control.Deadzone,
-					Invert:      &control.Invert,
-					EasingCurve: &control.EasingCurve,
-				})
+			}
+			if control.Direction != "" {
+				direction := control.Direction
+				sdl_mapping_control.Direction = &direction
+			}
+			sdl_mapping.Data = append(sdl_mapping.Data, sdl_mapping_control)
+			if control.Kind == sdl_mgr.SDLMgr_Control_Kind_Axis || control.Kind == sdl_mgr.SDLMgr_Control_Kind_ControllerAxis {
+				calibration_data := config.Config_Controller_CalibrationData{
+					Id:                     control.Name,
+					Min:                    control.Min,
+					Max:                    control.Max,
+					Idle:                   &control.Idle,
+					Deadzone:               &control.Deadzone,
+					Invert:                 &control.Invert,
+					EasingCurve:            &control.EasingCurve,
+					ResponseCurve:          control.ResponseCurve,
+					ResponseCurveBelowIdle: control.ResponseCurveBelowIdle,
+					Filter:                 control.Filter,
+				}
+				if len(control.Detents) > 0 {
+					detents := []config.Config_Controller_CalibrationData_Detent{}
+					for _, detent := range control.Detents {
+						detents = append(detents, config.Config_Controller_CalibrationData_Detent{
+							Name:         detent.Name,
+							Value:        detent.Value,
+							CaptureWidth: &detent.CaptureWidth,
+						})
+					}
+					calibration_data.Detents = &detents
+				}
+				calibration.Data = append(calibration.Data, calibration_data)
 			}
 		}
 	}
 
+	/* the derived controls are only declared in the SDL mapping file; keep them when recalibrating */
+	if existing_sdl_mapping, has_existing_sdl_mapping := a.controller_manager.Config.SDLMappingsByUsbID.Get(data.UsbId); has_existing_sdl_mapping {
+		sdl_mapping.Derived = existing_sdl_mapping.Derived
+	}
+
+	if err := config.ValidateControllerConfiguration(sdl_mapping, calibration); err != nil {
+		return err
+	}
+
 	sdl_mapping_filepath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
 		Title:            "Select SDL mapping file save location",
 		DefaultFilename:  fmt.Sprintf("%s.sdl.json", string_utils.Sluggify(data.Name)),
@@ -808,7 +1331,7 @@ func (a *App) SaveCalibration(data Interop_ControllerCalibration) error {
 
 	calibration_filepath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
 		Title:            "Select calibration file save location",
-		DefaultFilename:  fmt.Sprintf("%s.calibration.json", string_utils.Sluggify(data.Name)),
+		DefaultFilename:  fmt.Sprintf("%s.calibration.json", calibration_filename),
 		DefaultDirectory: filepath.Join(a.config.GlobalConfigDir, "calibration"),
 	})
 	if err != nil {
@@ -952,8 +1475,7 @@ func (a *App) InstallTrainSimWorldMod() error {
 
 	/* write version file */
 	os.WriteFile(filepath.Join(install_path, "ue4ss_tsw_controller_mod/Mods/TSWControllerMod/version.txt"), []byte(VERSION), 0755)
-	a.program_config.LastInstalledModVersion = VERSION
-	a.program_config.Save(filepath.Join(a.config.GlobalConfigDir, "program.json"))
+	a.SetLastInstalledModVersion(VERSION)
 
 	return nil
 }
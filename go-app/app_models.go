@@ -10,6 +10,13 @@ type Interop_GenericController struct {
 	UsbID        string
 	Name         string
 	IsConfigured bool
+	/* the user assigned alias to tell identical controllers apart */
+	Alias string
+	/* what the GUID is based on; serial, path, alias or order */
+	IdentitySource string
+	/* SDL reports the standard named buttons and axes of the device */
+	IsGameController bool
+	HasRumble        bool
 }
 
 type Interop_Profile_Metadata struct {
@@ -33,6 +40,14 @@ type Interop_RawEvent struct {
 	Index     int
 	Value     float64
 	Timestamp int
+	/* the direction a hat points in; empty for other controls */
+	Direction sdl_mgr.SDLMgr_HatDirection
+}
+
+type Interop_ControllerCalibration_Detent struct {
+	Name         string
+	Value        float64
+	CaptureWidth float64
 }
 
 type Interop_ControllerCalibration_Control struct {
@@ -45,14 +60,43 @@ type Interop_ControllerCalibration_Control struct {
 	Deadzone    float64
 	EasingCurve []float64
 	Invert      bool
+	Detents     []Interop_ControllerCalibration_Detent
+	/* kept as is; response curves and filters are edited in the calibration file */
+	ResponseCurve          *config.Config_Controller_CalibrationData_Curve
+	ResponseCurveBelowIdle *config.Config_Controller_CalibrationData_Curve
+	Filter                 *config.Config_Controller_CalibrationData_Filter
+	/* the hat direction exposed as a button; empty for the raw value of a hat and for other controls */
+	Direction sdl_mgr.SDLMgr_HatDirection
 }
 
 type Interop_ControllerCalibration struct {
-	Name     string
-	UsbId    string
+	Name  string
+	UsbId string
+	/* when set the calibration only applies to the controller with this GUID */
+	GUID     string
 	Controls []Interop_ControllerCalibration_Control
 }
 
+/* what the calibration session detected for an axis */
+type Interop_CalibrationSession_Axis struct {
+	Index        int
+	Name         string
+	IdleDetected bool
+	Noise        float64
+	Inverted     bool
+	Centered     bool
+	Warnings     []string
+}
+
+type Interop_CalibrationSessionResult struct {
+	/* the detected calibration; reviewed and saved with SaveCalibration */
+	Calibration Interop_ControllerCalibration
+	Axes        []Interop_CalibrationSession_Axis
+	Warnings    []string
+	/* why the detected calibration is not valid yet */
+	Error string
+}
+
 type Interop_ControllerConfiguration struct {
 	Calibration Interop_ControllerCalibration
 	SDLMapping  config.Config_Controller_SDLMap
@@ -87,3 +131,32 @@ type Interop_SelectedProfileInfo struct {
 	Id   string
 	Name string
 }
+
+/* the profile used by a controller and why */
+type Interop_ProfileSelection struct {
+	GUID        string
+	ProfileId   string
+	ProfileName string
+	/* manual, auto_select, fallback or none */
+	Reason      string
+	Description string
+}
+
+type Interop_ControllerProfilePolicy struct {
+	StickyManualSelection bool
+	/* empty when there is no fallback profile */
+	FallbackProfileId string
+}
+
+type Interop_ControlModeStatus_Control struct {
+	ControlName string
+	ControlMode string
+	Fallback    []string
+}
+
+type Interop_ControlModeStatus struct {
+	DirectControlAvailable bool
+	ApiControlAvailable    bool
+	SyncControlAvailable   bool
+	Controls               []Interop_ControlModeStatus_Control
+}
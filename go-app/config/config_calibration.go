@@ -2,28 +2,88 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+	"tsw_controller_app/input_transform"
+	"tsw_controller_app/logger"
 	"tsw_controller_app/math_utils"
+	"tsw_controller_app/sdl_mgr"
 
 	"github.com/creasty/go-easing"
 	"github.com/go-playground/validator/v10"
 )
 
+/* a physical notch of a lever (eg: idle, a brake notch or emergency) */
+type Config_Controller_CalibrationData_Detent struct {
+	/* the name profiles use to reference the detent (eg: "idle", "notch1", "emergency") */
+	Name string `json:"name" validate:"required"`
+	/* the raw value of the detent; compared after inverting like min, max and idle */
+	Value float64 `json:"value"`
+	/* the raw values within this distance of the detent snap to it */
+	CaptureWidth *float64 `json:"capture_width,omitempty" validate:"omitempty,gte=0"`
+}
+
+/*
+A response curve mapping the distance from idle (0) to the distance at min or max (1); replaces the easing curve
+*/
+type Config_Controller_CalibrationData_Curve struct {
+	/* linear (default) connects the points with straight lines; spline uses a smooth curve through the points which does not overshoot */
+	Type *string `json:"type,omitempty" validate:"omitempty,oneof=linear spline"`
+	/* [input, output] pairs within 0 and 1 */
+	Points [][2]float64 `json:"points" validate:"required"`
+	/* the curve built from the points on first use */
+	transform atomic.Pointer[input_transform.Transform]
+}
+
+/*
+Filters the raw values of a noisy axis before they are normalized and published; the filters are applied in the order
+median, smoothing, min_change and min_interval
+*/
+type Config_Controller_CalibrationData_Filter struct {
+	/* the number of raw values the median is taken of to reject single spikes; an odd number of 3 or more */
+	MedianWindow *int `json:"median_window,omitempty" validate:"omitempty,gte=3"`
+	/* moving_average or one_euro */
+	Smoothing *string `json:"smoothing,omitempty" validate:"omitempty,oneof=moving_average one_euro"`
+	/* moving_average; the number of values to average */
+	AverageWindow *int `json:"average_window,omitempty" validate:"omitempty,gte=2"`
+	/* one_euro; the cutoff frequency in Hz at rest (defaults to 1) and how fast it increases with the speed of the axis in raw units per second (defaults to 0.001) */
+	MinCutoff *float64 `json:"min_cutoff,omitempty" validate:"omitempty,gt=0"`
+	Beta      *float64 `json:"beta,omitempty" validate:"omitempty,gte=0"`
+	/* raw changes smaller than this are not published; changes reaching min or max are always published */
+	MinChange *float64 `json:"min_change,omitempty" validate:"omitempty,gte=0"`
+	/* the minimum time in seconds between published values; the latest value is published once the time passed */
+	MinInterval *float64 `json:"min_interval,omitempty" validate:"omitempty,gt=0"`
+}
+
 type Config_Controller_CalibrationData struct {
 	/** the ID of the controller button or trigger as named in the controller mapping config (see other file - eg: "throttle1", "throttle2", "button1") */
-	Id           string     `json:"id" validate:"required"`
-	IsCalibrated bool       `json:"-"`
-	Deadzone     *float64   `json:"deadzone,omitempty"`
-	Invert       *bool      `json:"invert,omitempty"`
-	Min          float64    `json:"min" validate:"required"`
-	Max          float64    `json:"max" validate:"required"`
-	Idle         *float64   `json:"idle,omitempty"`
-	EasingCurve  *[]float64 `json:"easing_curve,omitempty"`
+	Id           string   `json:"id" validate:"required"`
+	IsCalibrated bool     `json:"-"`
+	Deadzone     *float64 `json:"deadzone,omitempty"`
+	Invert       *bool    `json:"invert,omitempty"`
+	Min          float64  `json:"min" validate:"required"`
+	Max          float64  `json:"max" validate:"required"`
+	Idle         *float64 `json:"idle,omitempty"`
+	/* a cubic bezier easing given as 4 numbers (x1, y1, x2, y2); ignored when a response curve is set, linear when not 4 numbers */
+	EasingCurve *[]float64 `json:"easing_curve,omitempty"`
+	/* the response curve used for both sides of idle, or only above idle when a curve below idle is set */
+	ResponseCurve *Config_Controller_CalibrationData_Curve `json:"response_curve,omitempty"`
+	/* the response curve used below idle */
+	ResponseCurveBelowIdle *Config_Controller_CalibrationData_Curve `json:"response_curve_below_idle,omitempty"`
+	/* filters the raw values of the axis */
+	Filter *Config_Controller_CalibrationData_Filter `json:"filter,omitempty"`
+	/* the physical detents of the axis; profiles can reference them by name */
+	Detents *[]Config_Controller_CalibrationData_Detent `json:"detents,omitempty" validate:"omitempty,dive"`
 }
 
 type Config_Controller_Calibration struct {
-	UsbID string                              `json:"usb_id" validate:"required" example:"{0xVENDOR_ID}:{0xPRODUCT_ID}"`
-	Data  []Config_Controller_CalibrationData `json:"data" validate:"required"`
+	UsbID string `json:"usb_id" validate:"required" example:"{0xVENDOR_ID}:{0xPRODUCT_ID}"`
+	/** the app GUID of a single device; when set the calibration only applies to that device instead of all devices with the USB ID */
+	GUID *string                             `json:"guid,omitempty"`
+	Data []Config_Controller_CalibrationData `json:"data" validate:"required"`
 }
 
 type NormalizedValue struct {
@@ -41,10 +101,167 @@ func ControllerCalibrationFromJSON(json_str string) (*Config_Controller_Calibrat
 	if err := v.Struct(c); err != nil {
 		return nil, err
 	}
+	for _, data := range c.Data {
+		if err := data.Validate(); err != nil {
+			return nil, err
+		}
+		if data.EasingCurve != nil && len(*data.EasingCurve) != 4 {
+			logger.Logger.Error("[ControllerCalibrationFromJSON] the easing curve needs exactly 4 numbers; using a linear curve instead", "id", data.Id, "easing_curve", *data.EasingCurve)
+		}
+	}
 
 	return &c, nil
 }
 
+/*
+Validates the response curves and the filter of the control; an invalid easing curve falls back to a linear curve
+*/
+func (calibration *Config_Controller_CalibrationData) Validate() error {
+	if calibration.ResponseCurve != nil {
+		if err := calibration.ResponseCurve.Validate(); err != nil {
+			return fmt.Errorf("the response curve of %s is invalid: %w", calibration.Id, err)
+		}
+	}
+	if calibration.ResponseCurveBelowIdle != nil {
+		if err := calibration.ResponseCurveBelowIdle.Validate(); err != nil {
+			return fmt.Errorf("the response curve below idle of %s is invalid: %w", calibration.Id, err)
+		}
+	}
+	if calibration.Filter != nil {
+		if err := calibration.Filter.Validate(); err != nil {
+			return fmt.Errorf("the filter of %s is invalid: %w", calibration.Id, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config_Controller_CalibrationData_Filter) Validate() error {
+	v := validator.New()
+	if err := v.Struct(c); err != nil {
+		return err
+	}
+	if c.MedianWindow != nil && *c.MedianWindow%2 == 0 {
+		return fmt.Errorf("the median window needs an odd number of values")
+	}
+	if c.Smoothing != nil && *c.Smoothing == "moving_average" && c.AverageWindow == nil {
+		return fmt.Errorf("moving_average smoothing requires an average_window")
+	}
+	return nil
+}
+
+func (c *Config_Controller_CalibrationData_Curve) Validate() error {
+	if c.Type != nil && *c.Type != "linear" && *c.Type != "spline" {
+		return fmt.Errorf("unknown curve type %s", *c.Type)
+	}
+	if len(c.Points) < 2 {
+		return fmt.Errorf("a curve needs at least 2 points")
+	}
+	for index, point := range c.Points {
+		if point[0] < 0 || point[0] > 1 || point[1] < 0 || point[1] > 1 {
+			return fmt.Errorf("point %d has to be within 0 and 1", index)
+		}
+		if index > 0 && point[0] <= c.Points[index-1][0] {
+			return fmt.Errorf("the inputs of the points have to be increasing")
+		}
+	}
+	return nil
+}
+
+func (c *Config_Controller_CalibrationData_Curve) Apply(value float64) float64 {
+	transform := c.transform.Load()
+	if transform == nil {
+		points := []input_transform.Point{}
+		for _, point := range c.Points {
+			points = append(points, input_transform.Point{X: point[0], Y: point[1]})
+		}
+		var built input_transform.Transform = input_transform.NewCurve(points)
+		if c.Type != nil && *c.Type == "spline" {
+			built = input_transform.NewSpline(points)
+		}
+		transform = &built
+		c.transform.Store(transform)
+	}
+	return (*transform).Apply(value, time.Time{})
+}
+
+/*
+checks the SDL mapping and calibration of a controller belong together: every control has a unique name and index,
+every calibrated control exists in the mapping and its idle position is within its range
+*/
+func ValidateControllerConfiguration(sdl_map Config_Controller_SDLMap, calibration Config_Controller_Calibration) error {
+	v := validator.New()
+	if err := v.Struct(sdl_map); err != nil {
+		return err
+	}
+	if err := v.Struct(calibration); err != nil {
+		return err
+	}
+	if sdl_map.UsbID != calibration.UsbID {
+		return fmt.Errorf("the SDL mapping is for %s but the calibration is for %s", sdl_map.UsbID, calibration.UsbID)
+	}
+
+	names := map[string]bool{}
+	controls := map[string]bool{}
+	for _, control := range sdl_map.Data {
+		if control.Name == "" {
+			return fmt.Errorf("the %s %d has no name", control.Kind, control.Index)
+		}
+		if names[control.Name] {
+			return fmt.Errorf("the name %s is used by multiple controls", control.Name)
+		}
+		if control.Direction != nil && control.Kind != sdl_mgr.SDLMgr_Control_Kind_Hat {
+			return fmt.Errorf("the %s %d can not have a direction; only hats do", control.Kind, control.Index)
+		}
+		if control.Direction != nil && !slices.Contains(sdl_mgr.SDLMgr_HatDirections, *control.Direction) {
+			return fmt.Errorf("the direction %s of the %s %d is not a hat direction", *control.Direction, control.Kind, control.Index)
+		}
+		control_key := fmt.Sprintf("%s:%d:%s", control.Kind, control.Index, control.HatDirection())
+		if controls[control_key] {
+			if control.Direction != nil {
+				return fmt.Errorf("the direction %s of the %s %d is mapped multiple times", *control.Direction, control.Kind, control.Index)
+			}
+			return fmt.Errorf("the %s %d is mapped multiple times", control.Kind, control.Index)
+		}
+		names[control.Name] = true
+		controls[control_key] = true
+	}
+
+	if err := sdl_map.ValidateDerived(); err != nil {
+		return err
+	}
+
+	for _, data := range calibration.Data {
+		if !names[data.Id] {
+			return fmt.Errorf("the calibrated control %s does not exist in the SDL mapping", data.Id)
+		}
+		if data.Min >= data.Max {
+			return fmt.Errorf("the minimum of %s has to be below its maximum", data.Id)
+		}
+		if data.Idle != nil && (*data.Idle < data.Min || *data.Idle > data.Max) {
+			return fmt.Errorf("the idle position of %s has to be within its range", data.Id)
+		}
+		if data.Deadzone != nil && *data.Deadzone < 0 {
+			return fmt.Errorf("the deadzone of %s can not be negative", data.Id)
+		}
+		if err := data.Validate(); err != nil {
+			return err
+		}
+		if data.Detents != nil {
+			detent_names := map[string]bool{}
+			for _, detent := range *data.Detents {
+				if detent_names[detent.Name] {
+					return fmt.Errorf("the detent %s of %s is defined multiple times", detent.Name, data.Id)
+				}
+				if detent.Value < data.Min || detent.Value > data.Max {
+					return fmt.Errorf("the detent %s of %s has to be within its range", detent.Name, data.Id)
+				}
+				detent_names[detent.Name] = true
+			}
+		}
+	}
+	return nil
+}
+
 /*
 Normalizes the raw input value to a [-1,1] range.
 Will return IsWithinDeadzone true when within deadzone
@@ -80,28 +297,83 @@ func (calibration *Config_Controller_CalibrationData) NormalizeRawValue(raw_valu
 		idle_value + deadzone_value,
 	}
 
-	easing_curve_value := []float64{0.0, 0.0, 1.0, 1.0}
-	if calibration.EasingCurve != nil && len(*calibration.EasingCurve) == 4 {
-		easing_curve_value = *calibration.EasingCurve
-	}
-
 	value := float64(raw_value)
 	if invert_value {
 		value = -value
 	}
+	value = calibration.snapToDetent(value)
 
 	if value >= deadzone_range[0] && value <= deadzone_range[1] {
 		return NormalizedValue{Value: 0, IsWithinDeadzone: true}
 	}
 
-	ease_func := easing.NewCustomEasing(easing_curve_value[0], easing_curve_value[1], easing_curve_value[2], easing_curve_value[3])
-
 	/* below idle value -- negative value */
 	if value < idle_value && calibration.Min != idle_value {
 		abs_value := math.Abs(math_utils.Clamp((value-idle_value)/(calibration.Min-idle_value), 0.0, 1.0))
-		return NormalizedValue{Value: ease_func(abs_value) * -1.0, IsWithinDeadzone: false}
+		return NormalizedValue{Value: calibration.applyResponseCurve(abs_value, true) * -1.0, IsWithinDeadzone: false}
 	}
 
 	abs_value := math.Abs(math_utils.Clamp((value-idle_value)/(calibration.Max-idle_value), 0.0, 1.0))
-	return NormalizedValue{Value: ease_func(abs_value), IsWithinDeadzone: false}
+	return NormalizedValue{Value: calibration.applyResponseCurve(abs_value, false), IsWithinDeadzone: false}
+}
+
+/*
+Applies the response curve of the side of idle to the distance from idle; falls back to the easing curve when there is no response curve
+*/
+func (calibration *Config_Controller_CalibrationData) applyResponseCurve(value float64, is_below_idle bool) float64 {
+	response_curve := calibration.ResponseCurve
+	if is_below_idle && calibration.ResponseCurveBelowIdle != nil {
+		response_curve = calibration.ResponseCurveBelowIdle
+	}
+	if response_curve != nil {
+		return response_curve.Apply(value)
+	}
+
+	easing_curve_value := []float64{0.0, 0.0, 1.0, 1.0}
+	if calibration.EasingCurve != nil && len(*calibration.EasingCurve) == 4 {
+		easing_curve_value = *calibration.EasingCurve
+	}
+	ease_func := easing.NewCustomEasing(easing_curve_value[0], easing_curve_value[1], easing_curve_value[2], easing_curve_value[3])
+	return ease_func(value)
+}
+
+/* snaps the (inverted) raw value to the closest detent it is captured by */
+func (calibration *Config_Controller_CalibrationData) snapToDetent(value float64) float64 {
+	if calibration.Detents == nil {
+		return value
+	}
+
+	snapped_value := value
+	closest_distance := math.Inf(1)
+	for _, detent := range *calibration.Detents {
+		if detent.CaptureWidth == nil {
+			continue
+		}
+		distance := math.Abs(value - detent.Value)
+		if distance <= *detent.CaptureWidth && distance < closest_distance {
+			snapped_value = detent.Value
+			closest_distance = distance
+		}
+	}
+	return snapped_value
+}
+
+/*
+Returns the normalized value of each detent by name; used to resolve the detents referenced by profiles
+*/
+func (calibration *Config_Controller_CalibrationData) NormalizedDetents() map[string]float64 {
+	detents := map[string]float64{}
+	if !calibration.IsCalibrated || calibration.Detents == nil {
+		return detents
+	}
+
+	for _, detent := range *calibration.Detents {
+		/* detent values are already inverted */
+		raw_value := detent.Value
+		if calibration.Invert != nil && *calibration.Invert {
+			raw_value = -raw_value
+		}
+		detents[detent.Name] = math_utils.RoundToMarginOfError(calibration.NormalizeRawValue(raw_value).Value)
+	}
+	return detents
 }
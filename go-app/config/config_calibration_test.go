@@ -0,0 +1,270 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateControllerConfiguration(t *testing.T) {
+	idle := -30000.0
+	sdl_map := Config_Controller_SDLMap{
+		Name:  "Throttle Quadrant",
+		UsbID: "1234:5678",
+		Data: []Config_Controller_SDLMap_Control{
+			{Kind: "axis", Index: 0, Name: "Throttle"},
+			{Kind: "button", Index: 0, Name: "Horn"},
+		},
+	}
+	calibration := Config_Controller_Calibration{
+		UsbID: "1234:5678",
+		Data:  []Config_Controller_CalibrationData{{Id: "Throttle", Min: -32768, Max: 32767, Idle: &idle}},
+	}
+	assert.NoError(t, ValidateControllerConfiguration(sdl_map, calibration))
+
+	duplicate_name := sdl_map
+	duplicate_name.Data = append([]Config_Controller_SDLMap_Control{}, sdl_map.Data...)
+	duplicate_name.Data[1].Name = "Throttle"
+	assert.Error(t, ValidateControllerConfiguration(duplicate_name, calibration))
+
+	duplicate_index := sdl_map
+	duplicate_index.Data = append([]Config_Controller_SDLMap_Control{}, sdl_map.Data...)
+	duplicate_index.Data[1].Kind = "axis"
+	assert.Error(t, ValidateControllerConfiguration(duplicate_index, calibration))
+
+	outside_idle := -40000.0
+	invalid_idle := calibration
+	invalid_idle.Data = []Config_Controller_CalibrationData{{Id: "Throttle", Min: -32768, Max: 32767, Idle: &outside_idle}}
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, invalid_idle))
+
+	invalid_range := calibration
+	invalid_range.Data = []Config_Controller_CalibrationData{{Id: "Throttle", Min: 100, Max: 100}}
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, invalid_range))
+
+	unknown_control := calibration
+	unknown_control.Data = []Config_Controller_CalibrationData{{Id: "Brake", Min: -32768, Max: 32767}}
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, unknown_control))
+
+	other_usb_id := calibration
+	other_usb_id.UsbID = "ffff:ffff"
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, other_usb_id))
+}
+
+func TestValidateControllerConfiguration_HatDirections(t *testing.T) {
+	up, down, diagonal := "up", "down", "up_down"
+	sdl_map := Config_Controller_SDLMap{
+		Name:  "Throttle Quadrant",
+		UsbID: "1234:5678",
+		Data: []Config_Controller_SDLMap_Control{
+			{Kind: "hat", Index: 0, Name: "HatUp", Direction: &up},
+			{Kind: "hat", Index: 0, Name: "HatDown", Direction: &down},
+			{Kind: "hat", Index: 1, Name: "Hat2"},
+		},
+	}
+	calibration := Config_Controller_Calibration{UsbID: "1234:5678", Data: []Config_Controller_CalibrationData{}}
+	assert.NoError(t, ValidateControllerConfiguration(sdl_map, calibration))
+
+	duplicate_direction := sdl_map
+	duplicate_direction.Data = append([]Config_Controller_SDLMap_Control{}, sdl_map.Data...)
+	duplicate_direction.Data[1].Direction = &up
+	assert.Error(t, ValidateControllerConfiguration(duplicate_direction, calibration))
+
+	invalid_direction := sdl_map
+	invalid_direction.Data = append([]Config_Controller_SDLMap_Control{}, sdl_map.Data...)
+	invalid_direction.Data[1].Direction = &diagonal
+	assert.Error(t, ValidateControllerConfiguration(invalid_direction, calibration))
+
+	button_direction := sdl_map
+	button_direction.Data = append([]Config_Controller_SDLMap_Control{}, sdl_map.Data...)
+	button_direction.Data[2].Kind = "button"
+	button_direction.Data[2].Direction = &up
+	assert.Error(t, ValidateControllerConfiguration(button_direction, calibration))
+
+	control, err := sdl_map.FindByKindIndexAndDirection("hat", 0, "down")
+	assert.NoError(t, err)
+	assert.Equal(t, "HatDown", control.Name)
+	control, err = sdl_map.FindByKindIndexAndDirection("hat", 1, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hat2", control.Name)
+	_, err = sdl_map.FindByKindIndexAndDirection("hat", 0, "left")
+	assert.Error(t, err)
+}
+
+func TestSDLMap_ValidateDerived(t *testing.T) {
+	ramp_rate := 0.5
+	sdl_map := Config_Controller_SDLMap{
+		Data: []Config_Controller_SDLMap_Control{
+			{Kind: "axis", Index: 0, Name: "Left"},
+			{Kind: "axis", Index: 1, Name: "Right"},
+		},
+	}
+	valid := []Config_Controller_SDLMap_DerivedControl{
+		{Kind: "sum", Name: "Throttle", Inputs: []string{"Left", "Right"}},
+		{Kind: "button_axis", Name: "Trim", Inputs: []string{"Left", "Right"}, RampRate: &ramp_rate},
+	}
+	invalid := []Config_Controller_SDLMap_DerivedControl{
+		{Kind: "sum", Name: "Throttle", Inputs: []string{"Left"}},
+		{Kind: "difference", Name: "Split", Inputs: []string{"Left", "Brake"}},
+		{Kind: "latch", Name: "Left", Inputs: []string{"Left"}},
+		{Kind: "latch", Inputs: []string{"Left"}},
+		{Kind: "zones", Inputs: []string{"Left"}},
+		{Kind: "button_axis", Name: "Trim", Inputs: []string{"Left", "Right"}},
+	}
+	for _, derived := range valid {
+		sdl_map.Derived = []Config_Controller_SDLMap_DerivedControl{derived}
+		assert.NoError(t, sdl_map.ValidateDerived(), derived.Kind)
+	}
+	for _, derived := range invalid {
+		sdl_map.Derived = []Config_Controller_SDLMap_DerivedControl{derived}
+		assert.Error(t, sdl_map.ValidateDerived(), derived.Kind)
+	}
+
+	sdl_map.Derived = valid
+	sdl_map.Derived[1].Name = "Throttle"
+	assert.Error(t, sdl_map.ValidateDerived())
+}
+
+func TestSDLMapControl_RawHatValue(t *testing.T) {
+	up_right := "up_right"
+	direction_control := Config_Controller_SDLMap_Control{Kind: "hat", Index: 0, Name: "HatUpRight", Direction: &up_right}
+	/* SDL_HAT_RIGHTUP is SDL_HAT_RIGHT | SDL_HAT_UP */
+	assert.Equal(t, 1.0, direction_control.RawHatValue(0x02|0x01))
+	assert.Equal(t, 0.0, direction_control.RawHatValue(0x01))
+	assert.Equal(t, 0.0, direction_control.RawHatValue(0x00))
+
+	raw_control := Config_Controller_SDLMap_Control{Kind: "hat", Index: 0, Name: "Hat1"}
+	assert.Equal(t, 3.0, raw_control.RawHatValue(0x02|0x01))
+}
+
+func TestValidateControllerConfiguration_Detents(t *testing.T) {
+	sdl_map := Config_Controller_SDLMap{
+		Name:  "Throttle Quadrant",
+		UsbID: "1234:5678",
+		Data:  []Config_Controller_SDLMap_Control{{Kind: "axis", Index: 0, Name: "Throttle"}},
+	}
+	calibration := Config_Controller_Calibration{
+		UsbID: "1234:5678",
+		Data: []Config_Controller_CalibrationData{{Id: "Throttle", Min: -32768, Max: 32767, Detents: &[]Config_Controller_CalibrationData_Detent{
+			{Name: "idle", Value: -32768},
+			{Name: "notch1", Value: 0},
+		}}},
+	}
+	assert.NoError(t, ValidateControllerConfiguration(sdl_map, calibration))
+
+	duplicate_detent := calibration
+	duplicate_detent.Data = []Config_Controller_CalibrationData{{Id: "Throttle", Min: -32768, Max: 32767, Detents: &[]Config_Controller_CalibrationData_Detent{
+		{Name: "notch1", Value: -100},
+		{Name: "notch1", Value: 100},
+	}}}
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, duplicate_detent))
+
+	outside_detent := calibration
+	outside_detent.Data = []Config_Controller_CalibrationData{{Id: "Throttle", Min: 0, Max: 32767, Detents: &[]Config_Controller_CalibrationData_Detent{
+		{Name: "notch1", Value: -100},
+	}}}
+	assert.Error(t, ValidateControllerConfiguration(sdl_map, outside_detent))
+}
+
+func TestCalibrationData_Detents(t *testing.T) {
+	idle := 0.0
+	capture_width := 1000.0
+	calibration := Config_Controller_CalibrationData{
+		Id:           "Throttle",
+		IsCalibrated: true,
+		Min:          -10000,
+		Max:          10000,
+		Idle:         &idle,
+		Detents: &[]Config_Controller_CalibrationData_Detent{
+			{Name: "notch1", Value: 5000, CaptureWidth: &capture_width},
+			{Name: "emergency", Value: -10000},
+		},
+	}
+
+	/* values within the capture width snap to the detent */
+	assert.Equal(t, 0.5, calibration.NormalizeRawValue(5800).Value)
+	assert.Equal(t, 0.5, calibration.NormalizeRawValue(4200).Value)
+	assert.Equal(t, 0.7, calibration.NormalizeRawValue(7000).Value)
+	assert.Equal(t, map[string]float64{"notch1": 0.5, "emergency": -1}, calibration.NormalizedDetents())
+
+	/* detents are compared after inverting */
+	invert := true
+	calibration.Invert = &invert
+	assert.Equal(t, 0.5, calibration.NormalizeRawValue(-5800).Value)
+	assert.Equal(t, map[string]float64{"notch1": 0.5, "emergency": -1}, calibration.NormalizedDetents())
+}
+
+func TestCalibrationData_Curve_Validate(t *testing.T) {
+	spline := "spline"
+	assert.NoError(t, (&Config_Controller_CalibrationData_Curve{Type: &spline, Points: [][2]float64{{0, 0}, {0.5, 0.2}, {1, 1}}}).Validate())
+
+	unknown_type := "bezier"
+	assert.Error(t, (&Config_Controller_CalibrationData_Curve{Type: &unknown_type, Points: [][2]float64{{0, 0}, {1, 1}}}).Validate())
+	assert.Error(t, (&Config_Controller_CalibrationData_Curve{Points: [][2]float64{{0, 0}}}).Validate())
+	assert.Error(t, (&Config_Controller_CalibrationData_Curve{Points: [][2]float64{{0, 0}, {1.5, 1}}}).Validate())
+	assert.Error(t, (&Config_Controller_CalibrationData_Curve{Points: [][2]float64{{0, 0}, {0.5, 0.5}, {0.5, 0.7}, {1, 1}}}).Validate())
+
+	/* an easing curve without 4 numbers falls back to a linear curve */
+	calibration, err := ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"idle":0,"easing_curve":[0.9,0,1]}]}`)
+	require.NoError(t, err)
+	calibration.Data[0].IsCalibrated = true
+	assert.InDelta(t, 0.25, calibration.Data[0].NormalizeRawValue(25).Value, 1e-9)
+	_, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"response_curve":{"points":[[0,0]]}}]}`)
+	assert.Error(t, err)
+	calibration, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"easing_curve":[0,0,1,1],"response_curve":{"type":"spline","points":[[0,0],[0.5,0.2],[1,1]]}}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "spline", *calibration.Data[0].ResponseCurve.Type)
+}
+
+func TestCalibrationData_ResponseCurve(t *testing.T) {
+	idle := 0.0
+	calibration := Config_Controller_CalibrationData{
+		Id:           "Throttle",
+		IsCalibrated: true,
+		Min:          -100,
+		Max:          100,
+		Idle:         &idle,
+		ResponseCurve: &Config_Controller_CalibrationData_Curve{
+			Points: [][2]float64{{0, 0}, {0.5, 0.2}, {1, 1}},
+		},
+	}
+	/* the curve is used for both sides of idle */
+	assert.InDelta(t, 0.2, calibration.NormalizeRawValue(50).Value, 1e-9)
+	assert.InDelta(t, 0.6, calibration.NormalizeRawValue(75).Value, 1e-9)
+	assert.InDelta(t, -0.2, calibration.NormalizeRawValue(-50).Value, 1e-9)
+
+	/* a separate curve below idle */
+	spline := "spline"
+	calibration.ResponseCurveBelowIdle = &Config_Controller_CalibrationData_Curve{
+		Type:   &spline,
+		Points: [][2]float64{{0, 0}, {0.5, 0.8}, {1, 1}},
+	}
+	assert.InDelta(t, 0.2, calibration.NormalizeRawValue(50).Value, 1e-9)
+	assert.InDelta(t, -0.8, calibration.NormalizeRawValue(-50).Value, 1e-9)
+	/* the curve is built once and reused */
+	built_curve := calibration.ResponseCurveBelowIdle.transform.Load()
+	assert.NotNil(t, built_curve)
+	assert.InDelta(t, -0.8, calibration.NormalizeRawValue(-50).Value, 1e-9)
+	assert.Same(t, built_curve, calibration.ResponseCurveBelowIdle.transform.Load())
+	assert.Equal(t, -1.0, calibration.NormalizeRawValue(-100).Value)
+
+	/* the easing curve is still used without a response curve */
+	easing_curve := []float64{0.0, 0.0, 1.0, 1.0}
+	easing_calibration := Config_Controller_CalibrationData{Id: "Brake", IsCalibrated: true, Min: -100, Max: 100, Idle: &idle, EasingCurve: &easing_curve}
+	assert.InDelta(t, 0.5, easing_calibration.NormalizeRawValue(50).Value, 1e-6)
+}
+
+func TestCalibrationData_Filter_Validate(t *testing.T) {
+	calibration, err := ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"filter":{"median_window":3,"smoothing":"one_euro","beta":0.002,"min_change":40,"min_interval":0.02}}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *calibration.Data[0].Filter.MedianWindow)
+
+	_, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"filter":{"median_window":4}}]}`)
+	assert.Error(t, err)
+	_, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"filter":{"smoothing":"moving_average"}}]}`)
+	assert.Error(t, err)
+	_, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"filter":{"smoothing":"kalman"}}]}`)
+	assert.Error(t, err)
+	_, err = ControllerCalibrationFromJSON(`{"usb_id":"1234:5678","data":[{"id":"Throttle","min":-100,"max":100,"filter":{"min_change":-1}}]}`)
+	assert.Error(t, err)
+}
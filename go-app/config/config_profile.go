@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"tsw_controller_app/input_transform"
 	"tsw_controller_app/math_utils"
 
 	"github.com/go-playground/validator/v10"
@@ -18,6 +22,8 @@ const (
 	PreferredControlMode_DirectControl PreferredControlMode = "direct_control"
 	PreferredControlMode_SyncControl   PreferredControlMode = "sync_control"
 	PreferredControlMode_ApiControl    PreferredControlMode = "api_control"
+	/* only used in a control mode fallback chain; uses the key based assignments only */
+	PreferredControlMode_Keys PreferredControlMode = "keys"
 )
 
 type FreeRangeZone struct {
@@ -47,10 +53,53 @@ type Config_Controller_Profile_Control_Assignment_Action_ApiControl struct {
 	ApiValue float64 `json:"api_value"`
 }
 
+type Config_Controller_Profile_Control_Assignment_Action_VirtualJoystick struct {
+	/* the virtual joystick button number to press (1-32) */
+	Button *string `json:"virtual_button,omitempty" validate:"required_without=Axis"`
+	/* the virtual joystick axis to move (x, y, z, rx, ry, rz, throttle, rudder, wheel, gas, brake) */
+	Axis *string `json:"virtual_axis,omitempty" validate:"required_without=Button,excluded_with=Button"`
+	/* the axis value in the range of 0 to 1 */
+	Value *float64 `json:"value,omitempty" validate:"required_with=Axis,omitempty,gte=0,lte=1"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Action_Mouse_Params struct {
+	/* movement in pixels; relative to the current position unless absolute is set */
+	MoveX    *int  `json:"move_x,omitempty"`
+	MoveY    *int  `json:"move_y,omitempty"`
+	Absolute *bool `json:"absolute,omitempty"`
+	/* the button to click (with a press time) or hold */
+	Button *string `json:"button,omitempty" validate:"omitempty,oneof=left right middle"`
+	/* scroll steps; positive values scroll up (or right) */
+	ScrollX *int `json:"scroll_x,omitempty"`
+	ScrollY *int `json:"scroll_y,omitempty"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Action_Mouse struct {
+	Mouse     Config_Controller_Profile_Control_Assignment_Action_Mouse_Params `json:"mouse"`
+	PressTime *float64                                                         `json:"press_time,omitempty"`
+	WaitTime  *float64                                                         `json:"wait_time,omitempty"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Action_Sequence_Step struct {
+	/* the action to execute for this step; can be omitted for a pure delay step */
+	Action *Config_Controller_Profile_Control_Assignment_Action `json:"-"`
+	/* time in seconds to wait after executing the action before moving on to the next step */
+	Delay *float64 `json:"delay,omitempty"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Action_Sequence struct {
+	Sequence []Config_Controller_Profile_Control_Assignment_Action_Sequence_Step `json:"sequence" validate:"required,min=1,dive"`
+	/* whether to cancel the remaining steps once the triggering control is released; defaults to true */
+	CancelOnRelease *bool `json:"cancel_on_release,omitempty"`
+}
+
 type Config_Controller_Profile_Control_Assignment_Action struct {
-	Keys          *Config_Controller_Profile_Control_Assignment_Action_Keys          `json:"-"`
-	DirectControl *Config_Controller_Profile_Control_Assignment_Action_DirectControl `json:"-"`
-	ApiControl    *Config_Controller_Profile_Control_Assignment_Action_ApiControl    `json:"-"`
+	Keys            *Config_Controller_Profile_Control_Assignment_Action_Keys            `json:"-"`
+	DirectControl   *Config_Controller_Profile_Control_Assignment_Action_DirectControl   `json:"-"`
+	ApiControl      *Config_Controller_Profile_Control_Assignment_Action_ApiControl      `json:"-"`
+	Sequence        *Config_Controller_Profile_Control_Assignment_Action_Sequence        `json:"-"`
+	VirtualJoystick *Config_Controller_Profile_Control_Assignment_Action_VirtualJoystick `json:"-"`
+	Mouse           *Config_Controller_Profile_Control_Assignment_Action_Mouse           `json:"-"`
 }
 
 type Config_Controller_Profile_Control_Assignment_Condition struct {
@@ -62,6 +111,21 @@ type Config_Controller_Profile_Control_Assignment_Condition struct {
 	Value       float64 `json:"value"`
 }
 
+/* whether the value satisfies the condition operator */
+func (c *Config_Controller_Profile_Control_Assignment_Condition) Matches(value float64) bool {
+	switch c.Operator {
+	case "gte":
+		return value >= c.Value
+	case "lte":
+		return value <= c.Value
+	case "gt":
+		return value > c.Value
+	case "lt":
+		return value < c.Value
+	}
+	return true
+}
+
 type Config_Controller_Profile_Control_Assignment_Shared struct {
 	Conditions *[]Config_Controller_Profile_Control_Assignment_Condition `json:"conditions,omitempty"`
 }
@@ -78,6 +142,8 @@ type Config_Controller_Profile_Control_Assignment_Momentary struct {
 
 type Config_Controller_Profile_Control_Assignment_Linear_Threshold struct {
 	Value float64 `json:"value"`
+	/* the name of a calibrated detent of the control; the threshold is at the detent instead of value */
+	Detent *string `json:"detent,omitempty"`
 	/* ValueEnd and ValueStep can be used to automatically generate a set of thresholds while keeping the same action (ie: throttle) */
 	ValueEnd  *float64 `json:"value_end,omitempty"`
 	ValueStep *float64 `json:"value_step,omitempty"`
@@ -107,8 +173,32 @@ type Config_Controller_Profile_Control_Assignment_DirectLike_InputValue struct {
 	Max  float64  `json:"max"`
 	Step *float64 `json:"step,omitempty"`
 	/** steps can be combined with null values to create automatic interpolation */
-	Steps  *[]*float64 `json:"steps,omitempty"`
-	Invert *bool       `json:"invert,omitempty"`
+	Steps *[]*float64 `json:"steps,omitempty"`
+	/* the names of the calibrated detents used as steps keyed by their index in steps; see ResolveDetents */
+	StepDetents map[int]string `json:"-"`
+	Invert      *bool          `json:"invert,omitempty"`
+	/* the output value applied when the profile is deactivated, the controller is removed or the app is closed */
+	SafeValue *float64 `json:"safe_value,omitempty"`
+	/* applied in order; curve and split transform the controller value, the others transform the output value */
+	Transforms *[]Config_Controller_Profile_Control_Assignment_DirectLike_Transform `json:"transforms,omitempty" validate:"omitempty,dive"`
+}
+
+type Config_Controller_Profile_Control_Assignment_DirectLike_Transform struct {
+	Type string `json:"type" validate:"required,oneof=curve split detents hysteresis rate_limit smoothing"`
+	/* curve; [input, output] pairs */
+	Points *[][2]float64 `json:"points,omitempty"`
+	/* split; the part of the controller range to use, from maps to 0 and to maps to 1 */
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+	/* detents; the output values to snap to and how close the value needs to be to snap */
+	Detents      *[]float64 `json:"detents,omitempty"`
+	CaptureWidth *float64   `json:"capture_width,omitempty" validate:"omitempty,gte=0"`
+	/* hysteresis; how far past the midpoint between two steps the value needs to move to change steps */
+	Width *float64 `json:"width,omitempty" validate:"omitempty,gte=0"`
+	/* rate_limit; the maximum change of the output value per second */
+	MaxRate *float64 `json:"max_rate,omitempty" validate:"omitempty,gt=0"`
+	/* smoothing; the time in seconds to reach ~63% of a change */
+	TimeConstant *float64 `json:"time_constant,omitempty" validate:"omitempty,gt=0"`
 }
 
 type Config_Controller_Profile_Control_Assignment_DirectControl struct {
@@ -139,6 +229,231 @@ type Config_Controller_Profile_Control_Assignment_SyncControl struct {
 	InputValue     Config_Controller_Profile_Control_Assignment_DirectLike_InputValue `json:"input_value" validate:"required"`
 	ActionIncrease Config_Controller_Profile_Control_Assignment_Action_Keys           `json:"action_increase" validate:"required"`
 	ActionDecrease Config_Controller_Profile_Control_Assignment_Action_Keys           `json:"action_decrease" validate:"required"`
+	/* hold (default) keeps the key pressed until the target is reached; pulse taps the key for notched controls */
+	Mode *string `json:"mode,omitempty" validate:"omitempty,oneof=hold pulse"`
+	/* how close the value needs to be to the target; defaults to 0.005 */
+	Tolerance *float64 `json:"tolerance,omitempty" validate:"omitempty,gt=0"`
+	/* how long a pulse is held and the minimum time between pulses in seconds */
+	PulseTime     *float64 `json:"pulse_time,omitempty" validate:"omitempty,gt=0"`
+	PulseInterval *float64 `json:"pulse_interval,omitempty" validate:"omitempty,gt=0"`
+	/* stop pressing when the value did not change for this many seconds while pressing */
+	StuckTimeout *float64 `json:"stuck_timeout,omitempty" validate:"omitempty,gt=0"`
+}
+
+type Config_Controller_Profile_Control_Assignment_VirtualAxis struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type string `json:"type" validate:"required,eq=virtual_axis"`
+	/* the virtual joystick axis to move (x, y, z, rx, ry, rz, throttle, rudder, wheel, gas, brake) */
+	Axis string `json:"axis" validate:"required"`
+	/* the output value should be in the range of 0 to 1; 0.5 being the center of the axis */
+	InputValue Config_Controller_Profile_Control_Assignment_DirectLike_InputValue `json:"input_value" validate:"required"`
+}
+
+type Config_Controller_Profile_Control_Assignment_MouseScroll struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type string `json:"type" validate:"required,eq=mouse_scroll"`
+	/* vertical (default) or horizontal */
+	Direction *string `json:"direction,omitempty" validate:"omitempty,oneof=vertical horizontal"`
+	/* the output value is the scroll velocity in steps per second; positive values scroll up (or right) */
+	InputValue Config_Controller_Profile_Control_Assignment_DirectLike_InputValue `json:"input_value" validate:"required"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Flick struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type string `json:"type" validate:"required,eq=flick"`
+	/* up (the normalized value increases) or down */
+	Direction string `json:"direction" validate:"required,oneof=up down"`
+	/* the velocity the control needs to reach in the direction; in normalized units per second */
+	MinVelocity float64 `json:"min_velocity" validate:"gt=0"`
+	/* which action to perform once per flick; keys and buttons are pressed and released right away */
+	ActionActivate Config_Controller_Profile_Control_Assignment_Action `json:"action_activate" validate:"required"`
+	/* which action to perform once the flick ended */
+	ActionDeactivate *Config_Controller_Profile_Control_Assignment_Action `json:"action_deactivate,omitempty"`
+}
+
+/*
+Returns the velocity in the direction of the flick; negative when moving the other way
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Flick) DirectionalVelocity(velocity float64) float64 {
+	if c.Direction == "down" {
+		return -velocity
+	}
+	return velocity
+}
+
+/* the flick is active while the velocity is at least the min velocity; it ends once the velocity dropped below half of it */
+func (c *Config_Controller_Profile_Control_Assignment_Flick) IsActive(velocity float64) bool {
+	return c.DirectionalVelocity(velocity) >= c.MinVelocity
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Flick) HasEnded(velocity float64) bool {
+	return c.DirectionalVelocity(velocity) < c.MinVelocity/2
+}
+
+type Config_Controller_Profile_Control_Assignment_LongPress struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type      string  `json:"type" validate:"required,eq=long_press"`
+	Threshold float64 `json:"threshold"`
+	/* how long the control needs to be held in seconds */
+	Duration float64 `json:"duration" validate:"gt=0"`
+	/* which action to perform once the control was held for the duration */
+	ActionActivate Config_Controller_Profile_Control_Assignment_Action `json:"action_activate" validate:"required"`
+	/* which action to perform once the control is released after a long press; defaults to releasing the activate action if keys */
+	ActionDeactivate *Config_Controller_Profile_Control_Assignment_Action `json:"action_deactivate,omitempty"`
+	/* which action to perform (pressed and released) when the control is released before the duration */
+	ActionPress *Config_Controller_Profile_Control_Assignment_Action `json:"action_press,omitempty"`
+}
+
+type Config_Controller_Profile_Control_Assignment_MultiTap struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type      string  `json:"type" validate:"required,eq=multi_tap"`
+	Threshold float64 `json:"threshold"`
+	/* the time in seconds after releasing the control in which the next tap needs to start */
+	Window float64 `json:"window" validate:"gt=0"`
+	/* which action to perform for a single tap; only performed once no other tap followed within the window */
+	ActionPress     *Config_Controller_Profile_Control_Assignment_Action `json:"action_press,omitempty"`
+	ActionDoubleTap *Config_Controller_Profile_Control_Assignment_Action `json:"action_double_tap,omitempty"`
+	ActionTripleTap *Config_Controller_Profile_Control_Assignment_Action `json:"action_triple_tap,omitempty"`
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_MultiTap) Validate() error {
+	if c.ActionDoubleTap == nil && c.ActionTripleTap == nil {
+		return fmt.Errorf("multi_tap assignment requires an action_double_tap or action_triple_tap")
+	}
+	return nil
+}
+
+/*
+Returns the action for the number of taps or nil if there is none
+*/
+func (c *Config_Controller_Profile_Control_Assignment_MultiTap) ActionForTaps(taps int) *Config_Controller_Profile_Control_Assignment_Action {
+	switch taps {
+	case 1:
+		return c.ActionPress
+	case 2:
+		return c.ActionDoubleTap
+	case 3:
+		return c.ActionTripleTap
+	}
+	return nil
+}
+
+/* the highest number of taps with an action; reaching it performs the action right away */
+func (c *Config_Controller_Profile_Control_Assignment_MultiTap) MaxTaps() int {
+	if c.ActionTripleTap != nil {
+		return 3
+	}
+	return 2
+}
+
+type Config_Controller_Profile_Control_Assignment_Chord struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type      string  `json:"type" validate:"required,eq=chord"`
+	Threshold float64 `json:"threshold"`
+	/* the other controls which need to be pressed together with this control */
+	Controls []string `json:"controls" validate:"required,min=1,dive,required"`
+	/* which action to perform once all controls are pressed */
+	ActionActivate Config_Controller_Profile_Control_Assignment_Action `json:"action_activate" validate:"required"`
+	/* which action to perform once one of the controls is released; defaults to releasing the activate action if keys */
+	ActionDeactivate *Config_Controller_Profile_Control_Assignment_Action `json:"action_deactivate,omitempty"`
+	/* which action to perform (pressed and released) when this control is released without completing the chord */
+	ActionPress *Config_Controller_Profile_Control_Assignment_Action `json:"action_press,omitempty"`
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Chord) HasControl(control_name string) bool {
+	for _, name := range c.Controls {
+		if name == control_name {
+			return true
+		}
+	}
+	return false
+}
+
+type Config_Controller_Profile_Control_Assignment_Rate struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type string `json:"type" validate:"required,eq=rate"`
+	/* the normalized value at which nothing is repeated; defaults to 0 (the idle value of centred axes) */
+	Center *float64 `json:"center,omitempty" validate:"omitempty,gt=-1,lt=1"`
+	/* the deflection from the center (0-1) in which nothing is repeated */
+	Deadband *float64 `json:"deadband,omitempty" validate:"omitempty,gte=0,lt=1"`
+	/* repeats per second just outside the deadband and at full deflection */
+	MinRate float64 `json:"min_rate" validate:"gte=0"`
+	MaxRate float64 `json:"max_rate" validate:"gt=0,gtefield=MinRate"`
+	/* maps the deflection outside of the deadband (0-1) to the rate between min and max (0-1); linear by default */
+	Curve *Config_Controller_CalibrationData_Curve `json:"curve,omitempty"`
+	/* which action to repeat (pressed and released) above and below the center */
+	ActionIncrease Config_Controller_Profile_Control_Assignment_Action `json:"action_increase" validate:"required"`
+	ActionDecrease Config_Controller_Profile_Control_Assignment_Action `json:"action_decrease" validate:"required"`
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Rate) Validate() error {
+	if c.Curve != nil {
+		if err := c.Curve.Validate(); err != nil {
+			return fmt.Errorf("rate curve is invalid: %w", err)
+		}
+	}
+	return nil
+}
+
+/*
+Returns the direction (1 to increase, -1 to decrease, 0 within the deadband) and the repeats per second for the normalized value;
+the deflection is relative to the distance from the center to the closest end of the range
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Rate) CalculateRate(value float64) (int, float64) {
+	center := 0.0
+	if c.Center != nil {
+		center = *c.Center
+	}
+	deadband := 0.0
+	if c.Deadband != nil {
+		deadband = *c.Deadband
+	}
+
+	deflection := math_utils.Clamp(math.Abs(value-center)/(1-math.Abs(center)), 0, 1)
+	if deflection <= deadband || value == center {
+		return 0, 0
+	}
+	direction := 1
+	if value < center {
+		direction = -1
+	}
+
+	rate_position := (deflection - deadband) / (1 - deadband)
+	if c.Curve != nil {
+		rate_position = c.Curve.Apply(rate_position)
+	}
+	return direction, c.MinRate + (c.MaxRate-c.MinRate)*rate_position
+}
+
+/* returns the action to repeat in the direction */
+func (c *Config_Controller_Profile_Control_Assignment_Rate) ActionForDirection(direction int) Config_Controller_Profile_Control_Assignment_Action {
+	if direction < 0 {
+		return c.ActionDecrease
+	}
+	return c.ActionIncrease
+}
+
+type Config_Controller_Profile_Control_Assignment_Combined_Zone struct {
+	/* the part of the normalized axis used by the zone; from maps to 0 (neutral) and to maps to 1 (full) */
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+	/* a direct_control, api_control, sync_control or virtual_axis assignment receiving the zone value */
+	Output Config_Controller_Profile_Control_Assignment `json:"output" validate:"required"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Combined_Idle struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+	/* snaps to idle when the lever is within this distance of the idle zone */
+	CaptureWidth *float64 `json:"capture_width,omitempty" validate:"omitempty,gte=0"`
+}
+
+type Config_Controller_Profile_Control_Assignment_Combined struct {
+	Config_Controller_Profile_Control_Assignment_Shared
+	Type  string                                                      `json:"type" validate:"required,eq=combined"`
+	Brake *Config_Controller_Profile_Control_Assignment_Combined_Zone `json:"brake,omitempty"`
+	Idle  *Config_Controller_Profile_Control_Assignment_Combined_Idle `json:"idle,omitempty"`
+	Power *Config_Controller_Profile_Control_Assignment_Combined_Zone `json:"power,omitempty"`
 }
 
 type Config_Controller_Profile_Control_Assignment struct {
@@ -148,16 +463,29 @@ type Config_Controller_Profile_Control_Assignment struct {
 	DirectControl *Config_Controller_Profile_Control_Assignment_DirectControl `json:"-"`
 	SyncControl   *Config_Controller_Profile_Control_Assignment_SyncControl   `json:"-"`
 	ApiControl    *Config_Controller_Profile_Control_Assignment_ApiControl    `json:"-"`
+	VirtualAxis   *Config_Controller_Profile_Control_Assignment_VirtualAxis   `json:"-"`
+	MouseScroll   *Config_Controller_Profile_Control_Assignment_MouseScroll   `json:"-"`
+	Combined      *Config_Controller_Profile_Control_Assignment_Combined      `json:"-"`
+	Flick         *Config_Controller_Profile_Control_Assignment_Flick         `json:"-"`
+	LongPress     *Config_Controller_Profile_Control_Assignment_LongPress     `json:"-"`
+	MultiTap      *Config_Controller_Profile_Control_Assignment_MultiTap      `json:"-"`
+	Chord         *Config_Controller_Profile_Control_Assignment_Chord         `json:"-"`
+	Rate          *Config_Controller_Profile_Control_Assignment_Rate          `json:"-"`
 }
 
 type Config_Controller_Profile_Control struct {
 	Name        string                                          `json:"name"`
 	Assignment  *Config_Controller_Profile_Control_Assignment   `json:"assignment,omitempty"`
 	Assignments *[]Config_Controller_Profile_Control_Assignment `json:"assignments,omitempty"`
+	/* overrides the control mode fallback chain of the profile for this control */
+	ControlModeFallback *[]PreferredControlMode `json:"control_mode_fallback,omitempty" validate:"omitempty,dive,oneof=direct_control sync_control api_control keys"`
 }
 
+/* the usb_id of profiles which can be used (and auto-selected) for any controller */
+const Config_Controller_Profile_Controller_AnyUsbID = "*"
+
 type Config_Controller_Profile_Controller struct {
-	/* if defined ; specifies this profile can only be used with the below controller */
+	/* if defined ; specifies this profile can only be used with the below controller; "*" allows any controller */
 	UsbID *string `json:"usb_id,omitempty"`
 	/* Can be defined to specify a specific SDL mapping for this controller and profile; useful for sharing */
 	Mapping *Config_Controller_SDLMap `json:"mapping,omitempty"`
@@ -170,7 +498,16 @@ type Config_Controller_Profile_Metadata struct {
 }
 
 type Config_Controller_Profile_RailClassInformationEntry struct {
-	ClassName *string `json:"class_name"`
+	/* the exact rail class name */
+	ClassName *string `json:"class_name,omitempty"`
+	/* a glob pattern matched against the rail class name; "*" matches any characters and "?" a single character */
+	ClassNamePattern *string `json:"class_name_pattern,omitempty"`
+	/* a regular expression matched against the rail class name */
+	ClassNameRegex *string `json:"class_name_regex,omitempty"`
+	/* cab variable conditions which all have to match as well (eg: to tell apart variants sharing a rail class) */
+	Conditions *[]Config_Controller_Profile_Control_Assignment_Condition `json:"conditions,omitempty"`
+	/* overrides the auto select priority of the profile for this entry */
+	Priority *int `json:"priority,omitempty"`
 }
 
 type Config_Controller_Profile struct {
@@ -178,16 +515,27 @@ type Config_Controller_Profile struct {
 	Extends  *string                            `json:"extends,omitempty"`
 	Name     string                             `json:"name" validate:"required"`
 	/* specifies if this profile can be autoselected */
-	AutoSelect           *bool                                                  `json:"auto_select,omitempty"`
+	AutoSelect *bool `json:"auto_select,omitempty"`
+	/* when multiple profiles can be auto-selected the profile with the highest priority wins; defaults to 0 */
+	AutoSelectPriority   *int                                                   `json:"auto_select_priority,omitempty"`
 	Controller           *Config_Controller_Profile_Controller                  `json:"controller,omitempty"`
 	RailClassInformation *[]Config_Controller_Profile_RailClassInformationEntry `json:"rail_class_information,omitempty"`
-	Controls             []Config_Controller_Profile_Control                    `json:"controls" validate:"required"`
+	/*
+		the control modes to try in order (eg: ["direct_control", "api_control", "keys"]);
+		the first mode which is available and has assignments is used. Defaults to the preferred control mode setting
+	*/
+	ControlModeFallback *[]PreferredControlMode             `json:"control_mode_fallback,omitempty" validate:"omitempty,dive,oneof=direct_control sync_control api_control keys"`
+	Controls            []Config_Controller_Profile_Control `json:"controls" validate:"required,dive"`
 }
 
 func (c *Config_Controller_Profile_Control_Assignment_Action) UnmarshalJSON(data []byte) error {
 	var peek struct {
-		Controls *string  `json:"controls,omitempty"`
-		ApiValue *float64 `json:"api_value,omitempty"`
+		Controls *string            `json:"controls,omitempty"`
+		ApiValue *float64           `json:"api_value,omitempty"`
+		Sequence *[]json.RawMessage `json:"sequence,omitempty"`
+		Button   *string            `json:"virtual_button,omitempty"`
+		Axis     *string            `json:"virtual_axis,omitempty"`
+		Mouse    *json.RawMessage   `json:"mouse,omitempty"`
 	}
 	if err := json.Unmarshal(data, &peek); err != nil {
 		return err
@@ -195,6 +543,48 @@ func (c *Config_Controller_Profile_Control_Assignment_Action) UnmarshalJSON(data
 
 	v := validator.New()
 
+	/* if a sequence is defined; try to unmarshal it as a sequence action */
+	if peek.Sequence != nil {
+		var sequence_action Config_Controller_Profile_Control_Assignment_Action_Sequence
+		if err := json.Unmarshal(data, &sequence_action); err != nil {
+			return err
+		}
+		if err := v.Struct(sequence_action); err != nil {
+			return err
+		}
+		c.Sequence = &sequence_action
+		return nil
+	}
+
+	/* if mouse is defined; try to unmarshal it as a mouse action */
+	if peek.Mouse != nil {
+		var mouse_action Config_Controller_Profile_Control_Assignment_Action_Mouse
+		if err := json.Unmarshal(data, &mouse_action); err != nil {
+			return err
+		}
+		if err := v.Struct(mouse_action); err != nil {
+			return err
+		}
+		if err := mouse_action.Validate(); err != nil {
+			return err
+		}
+		c.Mouse = &mouse_action
+		return nil
+	}
+
+	/* if a virtual button or axis is defined; try to unmarshal it as a virtual joystick action */
+	if peek.Button != nil || peek.Axis != nil {
+		var vj_action Config_Controller_Profile_Control_Assignment_Action_VirtualJoystick
+		if err := json.Unmarshal(data, &vj_action); err != nil {
+			return err
+		}
+		if err := v.Struct(vj_action); err != nil {
+			return err
+		}
+		c.VirtualJoystick = &vj_action
+		return nil
+	}
+
 	/* if api value is defined; try to unmarshall as API control action */
 	if peek.ApiValue != nil {
 		var ac_action Config_Controller_Profile_Control_Assignment_Action_ApiControl
@@ -237,10 +627,79 @@ func (c Config_Controller_Profile_Control_Assignment_Action) MarshalJSON() ([]by
 	if c.DirectControl != nil {
 		return json.Marshal(c.DirectControl)
 	}
+	if c.ApiControl != nil {
+		return json.Marshal(c.ApiControl)
+	}
+	if c.Sequence != nil {
+		return json.Marshal(c.Sequence)
+	}
+	if c.VirtualJoystick != nil {
+		return json.Marshal(c.VirtualJoystick)
+	}
+	if c.Mouse != nil {
+		return json.Marshal(c.Mouse)
+	}
 	if c.Keys != nil {
 		return json.Marshal(c.Keys)
 	}
-	return nil, fmt.Errorf("unable to marshal control assignment action; has to be one of direct_control or keys but neither was found")
+	return nil, fmt.Errorf("unable to marshal control assignment action; has to be one of direct_control, api_control, sequence, virtual joystick, mouse or keys but none was found")
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Action_Sequence_Step) UnmarshalJSON(data []byte) error {
+	var peek map[string]json.RawMessage
+	if err := json.Unmarshal(data, &peek); err != nil {
+		return err
+	}
+
+	if delay, has_delay := peek["delay"]; has_delay {
+		if err := json.Unmarshal(delay, &c.Delay); err != nil {
+			return err
+		}
+		delete(peek, "delay")
+	}
+
+	/* a step with only a delay is a valid wait step */
+	if len(peek) == 0 {
+		if c.Delay == nil {
+			return fmt.Errorf("sequence step requires an action, a delay or both")
+		}
+		return nil
+	}
+
+	if _, has_sequence := peek["sequence"]; has_sequence {
+		return fmt.Errorf("sequence steps can not contain nested sequences")
+	}
+
+	var action Config_Controller_Profile_Control_Assignment_Action
+	if err := json.Unmarshal(data, &action); err != nil {
+		return err
+	}
+	c.Action = &action
+	return nil
+}
+
+func (c Config_Controller_Profile_Control_Assignment_Action_Sequence_Step) MarshalJSON() ([]byte, error) {
+	step := map[string]any{}
+	if c.Action != nil {
+		action_bytes, err := json.Marshal(c.Action)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(action_bytes, &step); err != nil {
+			return nil, err
+		}
+	}
+	if c.Delay != nil {
+		step["delay"] = *c.Delay
+	}
+	return json.Marshal(step)
+}
+
+/*
+Returns whether the sequence should be cancelled when the triggering control is released
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Action_Sequence) ShouldCancelOnRelease() bool {
+	return c.CancelOnRelease == nil || *c.CancelOnRelease
 }
 
 func (c *Config_Controller_Profile_Control_Assignment) Conditions() *[]Config_Controller_Profile_Control_Assignment_Condition {
@@ -262,6 +721,30 @@ func (c *Config_Controller_Profile_Control_Assignment) Conditions() *[]Config_Co
 	if c.SyncControl != nil {
 		return c.SyncControl.Conditions
 	}
+	if c.VirtualAxis != nil {
+		return c.VirtualAxis.Conditions
+	}
+	if c.MouseScroll != nil {
+		return c.MouseScroll.Conditions
+	}
+	if c.Combined != nil {
+		return c.Combined.Conditions
+	}
+	if c.Flick != nil {
+		return c.Flick.Conditions
+	}
+	if c.LongPress != nil {
+		return c.LongPress.Conditions
+	}
+	if c.MultiTap != nil {
+		return c.MultiTap.Conditions
+	}
+	if c.Chord != nil {
+		return c.Chord.Conditions
+	}
+	if c.Rate != nil {
+		return c.Rate.Conditions
+	}
 	return nil
 }
 
@@ -339,6 +822,95 @@ func (c *Config_Controller_Profile_Control_Assignment) UnmarshalJSON(data []byte
 		}
 		c.SyncControl = &sc
 		return nil
+	case "virtual_axis":
+		var va Config_Controller_Profile_Control_Assignment_VirtualAxis
+		if err := json.Unmarshal(data, &va); err != nil {
+			return err
+		}
+		if err := v.Struct(va); err != nil {
+			return err
+		}
+		c.VirtualAxis = &va
+		return nil
+	case "mouse_scroll":
+		var ms Config_Controller_Profile_Control_Assignment_MouseScroll
+		if err := json.Unmarshal(data, &ms); err != nil {
+			return err
+		}
+		if err := v.Struct(ms); err != nil {
+			return err
+		}
+		c.MouseScroll = &ms
+		return nil
+	case "combined":
+		var combined Config_Controller_Profile_Control_Assignment_Combined
+		if err := json.Unmarshal(data, &combined); err != nil {
+			return err
+		}
+		if err := v.Struct(combined); err != nil {
+			return err
+		}
+		if err := combined.Validate(); err != nil {
+			return err
+		}
+		c.Combined = &combined
+		return nil
+	case "flick":
+		var flick Config_Controller_Profile_Control_Assignment_Flick
+		if err := json.Unmarshal(data, &flick); err != nil {
+			return err
+		}
+		if err := v.Struct(flick); err != nil {
+			return err
+		}
+		c.Flick = &flick
+		return nil
+	case "long_press":
+		var long_press Config_Controller_Profile_Control_Assignment_LongPress
+		if err := json.Unmarshal(data, &long_press); err != nil {
+			return err
+		}
+		if err := v.Struct(long_press); err != nil {
+			return err
+		}
+		c.LongPress = &long_press
+		return nil
+	case "multi_tap":
+		var multi_tap Config_Controller_Profile_Control_Assignment_MultiTap
+		if err := json.Unmarshal(data, &multi_tap); err != nil {
+			return err
+		}
+		if err := v.Struct(multi_tap); err != nil {
+			return err
+		}
+		if err := multi_tap.Validate(); err != nil {
+			return err
+		}
+		c.MultiTap = &multi_tap
+		return nil
+	case "chord":
+		var chord Config_Controller_Profile_Control_Assignment_Chord
+		if err := json.Unmarshal(data, &chord); err != nil {
+			return err
+		}
+		if err := v.Struct(chord); err != nil {
+			return err
+		}
+		c.Chord = &chord
+		return nil
+	case "rate":
+		var rate Config_Controller_Profile_Control_Assignment_Rate
+		if err := json.Unmarshal(data, &rate); err != nil {
+			return err
+		}
+		if err := v.Struct(rate); err != nil {
+			return err
+		}
+		if err := rate.Validate(); err != nil {
+			return err
+		}
+		c.Rate = &rate
+		return nil
 	}
 	return fmt.Errorf("invalid assignment type (%s)", peek.Type)
 }
@@ -362,6 +934,30 @@ func (c Config_Controller_Profile_Control_Assignment) MarshalJSON() ([]byte, err
 	if c.ApiControl != nil {
 		return json.Marshal(c.ApiControl)
 	}
+	if c.VirtualAxis != nil {
+		return json.Marshal(c.VirtualAxis)
+	}
+	if c.MouseScroll != nil {
+		return json.Marshal(c.MouseScroll)
+	}
+	if c.Combined != nil {
+		return json.Marshal(c.Combined)
+	}
+	if c.Flick != nil {
+		return json.Marshal(c.Flick)
+	}
+	if c.LongPress != nil {
+		return json.Marshal(c.LongPress)
+	}
+	if c.MultiTap != nil {
+		return json.Marshal(c.MultiTap)
+	}
+	if c.Chord != nil {
+		return json.Marshal(c.Chord)
+	}
+	if c.Rate != nil {
+		return json.Marshal(c.Rate)
+	}
 	return nil, fmt.Errorf("unable to marshal control assignment; no valid assignment found")
 }
 
@@ -380,6 +976,243 @@ func (c *Config_Controller_Profile_Control_Assignment_Action_DirectControl) ToSt
 	return fmt.Sprintf("%s,%f,%s", c.Controls, c.Value, strings.Join(flags, "|"))
 }
 
+/*
+Returns the input value of direct-like assignments (direct, api, sync control, virtual axis and mouse scroll); nil otherwise
+*/
+func (c *Config_Controller_Profile_Control_Assignment) GetInputValue() *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue {
+	switch {
+	case c.DirectControl != nil:
+		return &c.DirectControl.InputValue
+	case c.ApiControl != nil:
+		return &c.ApiControl.InputValue
+	case c.SyncControl != nil:
+		return &c.SyncControl.InputValue
+	case c.VirtualAxis != nil:
+		return &c.VirtualAxis.InputValue
+	case c.MouseScroll != nil:
+		return &c.MouseScroll.InputValue
+	}
+	return nil
+}
+
+/*
+Validates the zones of a combined assignment; outputs need to be direct-like assignments of the same type and zones can't overlap the idle zone
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Combined) Validate() error {
+	zones := c.Zones()
+	if len(zones) == 0 {
+		return fmt.Errorf("combined assignment requires a brake or power zone")
+	}
+	output_type := ""
+	for _, zone := range zones {
+		if zone.Zone.From == zone.Zone.To {
+			return fmt.Errorf("combined %s zone requires different from and to values", zone.Name)
+		}
+		output := zone.Zone.Output
+		if output.GetInputValue() == nil || output.MouseScroll != nil {
+			return fmt.Errorf("combined %s zone output must be a direct_control, api_control, sync_control or virtual_axis assignment", zone.Name)
+		}
+		if output.Conditions() != nil {
+			return fmt.Errorf("combined %s zone output can't have conditions", zone.Name)
+		}
+		if output_type != "" && output_type != output.Type() {
+			return fmt.Errorf("combined zone outputs must be of the same type")
+		}
+		output_type = output.Type()
+	}
+	if c.Brake != nil && c.Power != nil {
+		brake_start, brake_end := math.Min(c.Brake.From, c.Brake.To), math.Max(c.Brake.From, c.Brake.To)
+		power_start, power_end := math.Min(c.Power.From, c.Power.To), math.Max(c.Power.From, c.Power.To)
+		if brake_start < power_end && power_start < brake_end {
+			return fmt.Errorf("combined brake and power zones can't overlap")
+		}
+	}
+	return nil
+}
+
+type Config_Controller_Profile_Control_Assignment_Combined_NamedZone struct {
+	Name string
+	Zone *Config_Controller_Profile_Control_Assignment_Combined_Zone
+}
+
+/*
+Returns the defined brake and power zones
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Combined) Zones() []Config_Controller_Profile_Control_Assignment_Combined_NamedZone {
+	var zones []Config_Controller_Profile_Control_Assignment_Combined_NamedZone
+	if c.Brake != nil {
+		zones = append(zones, Config_Controller_Profile_Control_Assignment_Combined_NamedZone{Name: "brake", Zone: c.Brake})
+	}
+	if c.Power != nil {
+		zones = append(zones, Config_Controller_Profile_Control_Assignment_Combined_NamedZone{Name: "power", Zone: c.Power})
+	}
+	return zones
+}
+
+/*
+Returns the name of the zone the lever is in ("brake", "power" or "idle") and the value within the zone [0, 1].
+The idle zone (extended by its capture width) takes precedence; outside of every zone the lever is considered idle
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Combined) FindZone(value float64) (string, float64) {
+	if c.Idle != nil {
+		capture_width := 0.0
+		if c.Idle.CaptureWidth != nil {
+			capture_width = *c.Idle.CaptureWidth
+		}
+		idle_start, idle_end := math.Min(c.Idle.From, c.Idle.To), math.Max(c.Idle.From, c.Idle.To)
+		if value >= idle_start-capture_width && value <= idle_end+capture_width {
+			return "idle", 0
+		}
+	}
+	for _, zone := range c.Zones() {
+		zone_start, zone_end := math.Min(zone.Zone.From, zone.Zone.To), math.Max(zone.Zone.From, zone.Zone.To)
+		if value >= zone_start && value <= zone_end {
+			return zone.Name, math_utils.Clamp((value-zone.Zone.From)/(zone.Zone.To-zone.Zone.From), 0, 1)
+		}
+	}
+	return "idle", 0
+}
+
+/*
+Returns the assignment type as used in the JSON config
+*/
+func (c *Config_Controller_Profile_Control_Assignment) Type() string {
+	switch {
+	case c.Momentary != nil:
+		return c.Momentary.Type
+	case c.Linear != nil:
+		return c.Linear.Type
+	case c.Toggle != nil:
+		return c.Toggle.Type
+	case c.DirectControl != nil:
+		return c.DirectControl.Type
+	case c.ApiControl != nil:
+		return c.ApiControl.Type
+	case c.SyncControl != nil:
+		return c.SyncControl.Type
+	case c.VirtualAxis != nil:
+		return c.VirtualAxis.Type
+	case c.MouseScroll != nil:
+		return c.MouseScroll.Type
+	case c.Combined != nil:
+		return c.Combined.Type
+	case c.Flick != nil:
+		return c.Flick.Type
+	case c.LongPress != nil:
+		return c.LongPress.Type
+	case c.MultiTap != nil:
+		return c.MultiTap.Type
+	case c.Chord != nil:
+		return c.Chord.Type
+	case c.Rate != nil:
+		return c.Rate.Type
+	}
+	return ""
+}
+
+/*
+Returns whether the action can be released once the triggering control is released (keys, sequences and virtual buttons)
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Action) IsReleasable() bool {
+	return c.Keys != nil || c.Sequence != nil || (c.VirtualJoystick != nil && c.VirtualJoystick.Button != nil) || (c.Mouse != nil && c.Mouse.Mouse.Button != nil)
+}
+
+/*
+Validates the mouse action defines something to do; absolute movement requires both coordinates
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Action_Mouse) Validate() error {
+	params := c.Mouse
+	if params.MoveX == nil && params.MoveY == nil && params.Button == nil && params.ScrollX == nil && params.ScrollY == nil {
+		return fmt.Errorf("mouse action requires a move, a button or a scroll")
+	}
+	if params.Absolute != nil && *params.Absolute && (params.MoveX == nil || params.MoveY == nil) {
+		return fmt.Errorf("absolute mouse movement requires both move_x and move_y")
+	}
+	return nil
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_Transform) UnmarshalJSON(data []byte) error {
+	type transform Config_Controller_Profile_Control_Assignment_DirectLike_Transform
+	var t transform
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	*c = Config_Controller_Profile_Control_Assignment_DirectLike_Transform(t)
+	return c.Validate()
+}
+
+/*
+Validates the transform defines the fields required by its type
+*/
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_Transform) Validate() error {
+	switch c.Type {
+	case "curve":
+		if c.Points == nil || len(*c.Points) < 2 {
+			return fmt.Errorf("curve transform requires at least 2 points")
+		}
+	case "split":
+		if c.From == nil || c.To == nil || *c.From == *c.To {
+			return fmt.Errorf("split transform requires different from and to values")
+		}
+	case "detents":
+		if c.Detents == nil || len(*c.Detents) == 0 || c.CaptureWidth == nil {
+			return fmt.Errorf("detents transform requires detents and a capture_width")
+		}
+	case "hysteresis":
+		if c.Width == nil {
+			return fmt.Errorf("hysteresis transform requires a width")
+		}
+	case "rate_limit":
+		if c.MaxRate == nil {
+			return fmt.Errorf("rate_limit transform requires a max_rate")
+		}
+	case "smoothing":
+		if c.TimeConstant == nil {
+			return fmt.Errorf("smoothing transform requires a time_constant")
+		}
+	}
+	return nil
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Action_Mouse) ToString() string {
+	params := c.Mouse
+	parts := []string{}
+	int_value := func(value *int) int {
+		if value == nil {
+			return 0
+		}
+		return *value
+	}
+	if params.MoveX != nil || params.MoveY != nil {
+		if params.Absolute != nil && *params.Absolute {
+			parts = append(parts, fmt.Sprintf("move_to:%d,%d", int_value(params.MoveX), int_value(params.MoveY)))
+		} else {
+			parts = append(parts, fmt.Sprintf("move:%d,%d", int_value(params.MoveX), int_value(params.MoveY)))
+		}
+	}
+	if params.Button != nil {
+		parts = append(parts, fmt.Sprintf("button:%s", *params.Button))
+	}
+	if params.ScrollX != nil || params.ScrollY != nil {
+		parts = append(parts, fmt.Sprintf("scroll:%d,%d", int_value(params.ScrollX), int_value(params.ScrollY)))
+	}
+	return fmt.Sprintf("mouse[%s]", strings.Join(parts, ";"))
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_Action_VirtualJoystick) ToString() string {
+	if c.Axis != nil {
+		value := 0.0
+		if c.Value != nil {
+			value = *c.Value
+		}
+		return fmt.Sprintf("virtual_axis:%s,%f", *c.Axis, value)
+	}
+	if c.Button != nil {
+		return fmt.Sprintf("virtual_button:%s", *c.Button)
+	}
+	return ""
+}
+
 func (c *Config_Controller_Profile_Control_Assignment_Action) ToString() string {
 	if c.Keys != nil {
 		return c.Keys.Keys
@@ -387,16 +1220,65 @@ func (c *Config_Controller_Profile_Control_Assignment_Action) ToString() string
 	if c.DirectControl != nil {
 		return c.DirectControl.ToString()
 	}
+	if c.ApiControl != nil {
+		return fmt.Sprintf("%s,%f", c.ApiControl.Controls, c.ApiControl.ApiValue)
+	}
+	if c.Sequence != nil {
+		steps := []string{}
+		for _, step := range c.Sequence.Sequence {
+			step_str := ""
+			if step.Action != nil {
+				step_str = step.Action.ToString()
+			}
+			if step.Delay != nil {
+				step_str = fmt.Sprintf("%s@%f", step_str, *step.Delay)
+			}
+			steps = append(steps, step_str)
+		}
+		return fmt.Sprintf("sequence[%s]", strings.Join(steps, ";"))
+	}
+	if c.VirtualJoystick != nil {
+		return c.VirtualJoystick.ToString()
+	}
+	if c.Mouse != nil {
+		return c.Mouse.ToString()
+	}
 	return ""
 }
 
 func (c *Config_Controller_Profile_Control_Assignment_Linear_Threshold) IsExceedingThreshold(value float64) bool {
 	if c.Value < 0.0 {
+		/* detent thresholds are reached once the lever is in the detent */
+		if c.Detent != nil {
+			return value <= c.Value
+		}
 		return value < c.Value
 	}
 	return value >= c.Value
 }
 
+/*
+Returns a copy with the thresholds referencing detents moved to the (neutralized) normalized value of the detent.
+Thresholds referencing detents which are not calibrated are left out and returned as missing
+*/
+func (c *Config_Controller_Profile_Control_Assignment_Linear) ResolveDetents(detents map[string]float64) (*Config_Controller_Profile_Control_Assignment_Linear, []string) {
+	var missing_detents []string
+	resolved := *c
+	resolved.Thresholds = []Config_Controller_Profile_Control_Assignment_Linear_Threshold{}
+	for _, threshold := range c.Thresholds {
+		if threshold.Detent != nil {
+			detent_value, has_detent := detents[*threshold.Detent]
+			if !has_detent {
+				missing_detents = append(missing_detents, *threshold.Detent)
+				continue
+			}
+			threshold.Value = math_utils.RoundToMarginOfError(c.CalculateNeutralizedValue(detent_value))
+		}
+		resolved.Thresholds = append(resolved.Thresholds, threshold)
+	}
+	return &resolved, missing_detents
+}
+
 func (c *Config_Controller_Profile_Control_Assignment_Linear) GenerateThresholds() []Config_Controller_Profile_Control_Assignment_Linear_Threshold {
 	var thresholds []Config_Controller_Profile_Control_Assignment_Linear_Threshold
 	for _, threshold := range c.Thresholds {
@@ -485,25 +1367,10 @@ func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) Get
 }
 
 /*
-*
-The incoming value here can only be [-1, 1]
-This calculates the actual value which would be sent to the game
+Returns the steps the output value is snapped to; either the defined steps or the steps generated from the step size
 */
-func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) CalculateOutputValue(value float64) float64 {
-	input_value := value
-	if c.Invert != nil && *c.Invert {
-		if value < 0.0 {
-			input_value = -1.0 - value
-		} else {
-			input_value = 1.0 - value
-		}
-	}
-
-	total_distance := math.Abs(c.Max - c.Min)
-	normal := (input_value * total_distance) + c.Min
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) GetQuantizationSteps() *[]float64 {
 	normal_steps := c.GetNormalSteps()
-	free_zones := c.GetFreeRangeZones()
-
 	if normal_steps == nil && c.Step != nil {
 		var auto_steps []float64
 		current_value := c.Min
@@ -517,8 +1384,175 @@ func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) Cal
 		}
 		normal_steps = &auto_steps
 	}
+	return normal_steps
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) UnmarshalJSON(data []byte) error {
+	type input_value Config_Controller_Profile_Control_Assignment_DirectLike_InputValue
+	var v struct {
+		input_value
+		/* steps can be numbers, null or the name of a detent */
+		Steps *[]json.RawMessage `json:"steps,omitempty"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Config_Controller_Profile_Control_Assignment_DirectLike_InputValue(v.input_value)
+	if v.Steps == nil {
+		return nil
+	}
+
+	steps := []*float64{}
+	for index, raw_step := range *v.Steps {
+		var step *float64
+		if err := json.Unmarshal(raw_step, &step); err == nil {
+			steps = append(steps, step)
+			continue
+		}
+		var detent string
+		if err := json.Unmarshal(raw_step, &detent); err != nil || detent == "" {
+			return fmt.Errorf("step %d has to be a number, null or the name of a detent", index)
+		}
+		if c.StepDetents == nil {
+			c.StepDetents = map[int]string{}
+		}
+		/* placeholder until the detent is resolved */
+		c.StepDetents[index] = detent
+		steps = append(steps, nil)
+	}
+	c.Steps = &steps
+	return nil
+}
+
+func (c Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) MarshalJSON() ([]byte, error) {
+	type input_value Config_Controller_Profile_Control_Assignment_DirectLike_InputValue
+	if c.Steps == nil || len(c.StepDetents) == 0 {
+		return json.Marshal(input_value(c))
+	}
+
+	steps := []any{}
+	for index, step := range *c.Steps {
+		if detent, is_detent := c.StepDetents[index]; is_detent {
+			steps = append(steps, detent)
+		} else {
+			steps = append(steps, step)
+		}
+	}
+	return json.Marshal(struct {
+		input_value
+		Steps []any `json:"steps"`
+	}{input_value(c), steps})
+}
+
+/*
+Returns a copy with the steps referencing detents replaced by the output value at the (normalized) value of the detent.
+Steps referencing detents which are not calibrated are left out and returned as missing
+*/
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) ResolveDetents(detents map[string]float64) (*Config_Controller_Profile_Control_Assignment_DirectLike_InputValue, []string) {
+	if c.Steps == nil || len(c.StepDetents) == 0 {
+		return c, nil
+	}
+
+	var missing_detents []string
+	resolved := *c
+	resolved.StepDetents = nil
+	steps := []*float64{}
+	for index, step := range *c.Steps {
+		detent, is_detent := c.StepDetents[index]
+		if !is_detent {
+			steps = append(steps, step)
+			continue
+		}
+		detent_value, has_detent := detents[detent]
+		if !has_detent {
+			missing_detents = append(missing_detents, detent)
+			continue
+		}
+		output_value := math_utils.RoundToMarginOfError(c.scaleInputValue(c.invertInputValue(detent_value)))
+		steps = append(steps, &output_value)
+	}
+	resolved.Steps = &steps
+	return &resolved, missing_detents
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) invertInputValue(value float64) float64 {
+	if c.Invert == nil || !*c.Invert {
+		return value
+	}
+	if value < 0.0 {
+		return -1.0 - value
+	}
+	return 1.0 - value
+}
+
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) scaleInputValue(value float64) float64 {
+	return (value * math.Abs(c.Max-c.Min)) + c.Min
+}
+
+/*
+Creates a new transform pipeline for the input value; the pipeline holds the state of the time based transforms
+and should be kept per assignment
+*/
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) NewTransformPipeline() *input_transform.Pipeline {
+	pipeline := input_transform.NewPipeline()
+	if c.Transforms == nil {
+		return pipeline
+	}
+
+	for _, transform := range *c.Transforms {
+		switch transform.Type {
+		case "curve":
+			var points []input_transform.Point
+			for _, point := range *transform.Points {
+				points = append(points, input_transform.Point{X: point[0], Y: point[1]})
+			}
+			pipeline.Transforms = append(pipeline.Transforms, input_transform.NewCurve(points))
+		case "split":
+			pipeline.Transforms = append(pipeline.Transforms, &input_transform.Split{From: *transform.From, To: *transform.To})
+		case "detents":
+			pipeline.Transforms = append(pipeline.Transforms, &input_transform.Detents{Detents: *transform.Detents, CaptureWidth: *transform.CaptureWidth})
+		case "hysteresis":
+			var steps []float64
+			if quantization_steps := c.GetQuantizationSteps(); quantization_steps != nil {
+				steps = *quantization_steps
+			}
+			var free_zones []input_transform.Zone
+			for _, zone := range c.GetFreeRangeZones() {
+				free_zones = append(free_zones, input_transform.Zone{Start: zone.Start, End: zone.End})
+			}
+			pipeline.Transforms = append(pipeline.Transforms, input_transform.NewHysteresis(*transform.Width, steps, free_zones))
+		case "rate_limit":
+			pipeline.Transforms = append(pipeline.Transforms, &input_transform.RateLimit{MaxRate: *transform.MaxRate})
+		case "smoothing":
+			pipeline.Transforms = append(pipeline.Transforms, &input_transform.Smoothing{TimeConstant: *transform.TimeConstant})
+		}
+	}
+	return pipeline
+}
+
+/*
+*
+The incoming value here can only be [-1, 1]
+This calculates the actual value which would be sent to the game
+*/
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) CalculateOutputValue(value float64) float64 {
+	return c.CalculateTransformedOutputValue(value, c.NewTransformPipeline(), time.Now())
+}
+
+/*
+Same as CalculateOutputValue but uses the given transform pipeline; this keeps the state of the time based transforms between calls
+*/
+func (c *Config_Controller_Profile_Control_Assignment_DirectLike_InputValue) CalculateTransformedOutputValue(
+	value float64,
+	pipeline *input_transform.Pipeline,
+	now time.Time,
+) float64 {
+	input_value := pipeline.ApplyInput(c.invertInputValue(value), now)
+	normal := pipeline.ApplyOutput(c.scaleInputValue(input_value), now)
+	normal_steps := c.GetQuantizationSteps()
+	free_zones := c.GetFreeRangeZones()
 
-	if normal_steps != nil {
+	if normal_steps != nil && len(*normal_steps) > 0 {
 		/* check free range first */
 		for _, zone := range free_zones {
 			if normal >= zone.Start && normal <= zone.End {
@@ -569,5 +1603,105 @@ func ControllerProfileFromJSON(json_str string, metadata Config_Controller_Profi
 		return nil, err
 	}
 
+	if c.RailClassInformation != nil {
+		for _, entry := range *c.RailClassInformation {
+			if err := entry.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &c, nil
 }
+
+/* the compiled rail class regular expressions; profiles are matched on every input event */
+var rail_class_regex_cache = sync.Map{}
+
+func compileRailClassRegex(expression string) (*regexp.Regexp, error) {
+	if cached, has_cached := rail_class_regex_cache.Load(expression); has_cached {
+		return cached.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	rail_class_regex_cache.Store(expression, compiled)
+	return compiled, nil
+}
+
+func (e *Config_Controller_Profile_RailClassInformationEntry) Validate() error {
+	if e.ClassName == nil && e.ClassNamePattern == nil && e.ClassNameRegex == nil {
+		return fmt.Errorf("rail class information requires a class_name, class_name_pattern or class_name_regex")
+	}
+	if e.ClassNamePattern != nil {
+		if _, err := path.Match(*e.ClassNamePattern, ""); err != nil {
+			return fmt.Errorf("invalid class_name_pattern %s: %w", *e.ClassNamePattern, err)
+		}
+	}
+	if e.ClassNameRegex != nil {
+		if _, err := compileRailClassRegex(*e.ClassNameRegex); err != nil {
+			return fmt.Errorf("invalid class_name_regex %s: %w", *e.ClassNameRegex, err)
+		}
+	}
+	if e.Conditions != nil {
+		for _, condition := range *e.Conditions {
+			if condition.CabVariable == nil {
+				return fmt.Errorf("rail class information conditions require a cab_variable")
+			}
+		}
+	}
+	return nil
+}
+
+/* whether the rail class name matches all of the defined class name, pattern and regular expression */
+func (e *Config_Controller_Profile_RailClassInformationEntry) MatchesClassName(class_name string) bool {
+	if e.ClassName == nil && e.ClassNamePattern == nil && e.ClassNameRegex == nil {
+		return false
+	}
+	if e.ClassName != nil && *e.ClassName != class_name {
+		return false
+	}
+	if e.ClassNamePattern != nil {
+		if matches, err := path.Match(*e.ClassNamePattern, class_name); err != nil || !matches {
+			return false
+		}
+	}
+	if e.ClassNameRegex != nil {
+		compiled, err := compileRailClassRegex(*e.ClassNameRegex)
+		if err != nil || !compiled.MatchString(class_name) {
+			return false
+		}
+	}
+	return true
+}
+
+/* the priority of the entry falling back to the profile priority */
+func (e *Config_Controller_Profile_RailClassInformationEntry) GetPriority(profile *Config_Controller_Profile) int {
+	if e.Priority != nil {
+		return *e.Priority
+	}
+	if profile.AutoSelectPriority != nil {
+		return *profile.AutoSelectPriority
+	}
+	return 0
+}
+
+/* a short description of what the entry matches on; used to explain why a profile was auto-selected */
+func (e *Config_Controller_Profile_RailClassInformationEntry) Describe() string {
+	parts := []string{}
+	if e.ClassName != nil {
+		parts = append(parts, fmt.Sprintf("class name %s", *e.ClassName))
+	}
+	if e.ClassNamePattern != nil {
+		parts = append(parts, fmt.Sprintf("class name pattern %s", *e.ClassNamePattern))
+	}
+	if e.ClassNameRegex != nil {
+		parts = append(parts, fmt.Sprintf("class name regex %s", *e.ClassNameRegex))
+	}
+	if e.Conditions != nil {
+		for _, condition := range *e.Conditions {
+			parts = append(parts, fmt.Sprintf("%s %s %g", *condition.CabVariable, condition.Operator, condition.Value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
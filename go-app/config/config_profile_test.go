@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
@@ -137,3 +138,344 @@ func TestConfigProfile_DirectOrSyncControl_InputValue_CalculateOutputValue(t *te
 	assert.Equal(t, 0.9, input_value.CalculateOutputValue(0.9))
 	assert.Equal(t, 1.0, input_value.CalculateOutputValue(1.0))
 }
+
+func TestConfigProfile_Action_Sequence_UnmarshalJSON(t *testing.T) {
+	var action Config_Controller_Profile_Control_Assignment_Action
+	err := json.Unmarshal([]byte(`{
+		"sequence": [
+			{ "keys": "ctrl+b", "press_time": 0.1 },
+			{ "delay": 2.5 },
+			{ "controls": "Throttle1", "value": 0.5, "delay": 0.2 },
+			{ "controls": "Reverser1", "api_value": 1.0 }
+		],
+		"cancel_on_release": false
+	}`), &action)
+	assert.NoError(t, err)
+	assert.NotNil(t, action.Sequence)
+	assert.False(t, action.Sequence.ShouldCancelOnRelease())
+
+	steps := action.Sequence.Sequence
+	assert.Len(t, steps, 4)
+	assert.Equal(t, "ctrl+b", steps[0].Action.Keys.Keys)
+	assert.Nil(t, steps[0].Delay)
+	assert.Nil(t, steps[1].Action)
+	assert.Equal(t, 2.5, *steps[1].Delay)
+	assert.Equal(t, "Throttle1", steps[2].Action.DirectControl.Controls)
+	assert.Equal(t, 0.2, *steps[2].Delay)
+	assert.Equal(t, 1.0, steps[3].Action.ApiControl.ApiValue)
+
+	/* round trip should result in the same sequence */
+	marshalled, err := json.Marshal(action)
+	assert.NoError(t, err)
+	var round_trip Config_Controller_Profile_Control_Assignment_Action
+	assert.NoError(t, json.Unmarshal(marshalled, &round_trip))
+	assert.Equal(t, action.ToString(), round_trip.ToString())
+}
+
+func TestConfigProfile_Action_Sequence_Invalid(t *testing.T) {
+	var action Config_Controller_Profile_Control_Assignment_Action
+	assert.Error(t, json.Unmarshal([]byte(`{ "sequence": [] }`), &action))
+	assert.Error(t, json.Unmarshal([]byte(`{ "sequence": [{}] }`), &action))
+	assert.Error(t, json.Unmarshal([]byte(`{ "sequence": [{ "sequence": [{ "keys": "a" }] }] }`), &action))
+}
+
+func TestConfigProfile_Action_VirtualJoystick_UnmarshalJSON(t *testing.T) {
+	var button_action Config_Controller_Profile_Control_Assignment_Action
+	assert.NoError(t, json.Unmarshal([]byte(`{"virtual_button":"3"}`), &button_action))
+	assert.NotNil(t, button_action.VirtualJoystick)
+	assert.Equal(t, "3", *button_action.VirtualJoystick.Button)
+	assert.Nil(t, button_action.Keys)
+
+	var axis_action Config_Controller_Profile_Control_Assignment_Action
+	assert.NoError(t, json.Unmarshal([]byte(`{"virtual_axis":"x","value":0.25}`), &axis_action))
+	assert.NotNil(t, axis_action.VirtualJoystick)
+	assert.Equal(t, "x", *axis_action.VirtualJoystick.Axis)
+	assert.Equal(t, 0.25, *axis_action.VirtualJoystick.Value)
+
+	/* an axis action requires a value within range */
+	var invalid_action Config_Controller_Profile_Control_Assignment_Action
+	assert.Error(t, json.Unmarshal([]byte(`{"virtual_axis":"x"}`), &invalid_action))
+	assert.Error(t, json.Unmarshal([]byte(`{"virtual_axis":"x","value":1.5}`), &invalid_action))
+	assert.Error(t, json.Unmarshal([]byte(`{"virtual_axis":"x","virtual_button":"1","value":0.5}`), &invalid_action))
+}
+
+func TestConfigProfile_Assignment_VirtualAxis_UnmarshalJSON(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"virtual_axis","axis":"throttle","input_value":{"min":0,"max":1}}`), &assignment))
+	assert.NotNil(t, assignment.VirtualAxis)
+	assert.Equal(t, "throttle", assignment.VirtualAxis.Axis)
+
+	marshalled, err := json.Marshal(assignment)
+	assert.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"type":"virtual_axis"`)
+}
+
+func TestConfigProfile_Action_Mouse_UnmarshalJSON(t *testing.T) {
+	var click_action Config_Controller_Profile_Control_Assignment_Action
+	assert.NoError(t, json.Unmarshal([]byte(`{"mouse":{"button":"left","move_x":5},"press_time":0.1}`), &click_action))
+	assert.NotNil(t, click_action.Mouse)
+	assert.Equal(t, "left", *click_action.Mouse.Mouse.Button)
+	assert.Equal(t, 5, *click_action.Mouse.Mouse.MoveX)
+	assert.Equal(t, 0.1, *click_action.Mouse.PressTime)
+	assert.True(t, click_action.IsReleasable())
+
+	var scroll_action Config_Controller_Profile_Control_Assignment_Action
+	assert.NoError(t, json.Unmarshal([]byte(`{"mouse":{"scroll_y":-2}}`), &scroll_action))
+	assert.False(t, scroll_action.IsReleasable())
+
+	var invalid_action Config_Controller_Profile_Control_Assignment_Action
+	assert.Error(t, json.Unmarshal([]byte(`{"mouse":{}}`), &invalid_action))
+	assert.Error(t, json.Unmarshal([]byte(`{"mouse":{"button":"side"}}`), &invalid_action))
+	assert.Error(t, json.Unmarshal([]byte(`{"mouse":{"move_x":100,"absolute":true}}`), &invalid_action))
+}
+
+func TestConfigProfile_InputValue_Transforms_UnmarshalJSON(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":1,"transforms":[{"type":"split","from":0.5,"to":1},{"type":"rate_limit","max_rate":0.5}]}}`), &assignment))
+	assert.Len(t, *assignment.DirectControl.InputValue.Transforms, 2)
+	assert.Same(t, &assignment.DirectControl.InputValue, assignment.GetInputValue())
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":1,"transforms":[{"type":"curve","points":[[0,0]]}]}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":1,"transforms":[{"type":"smoothing"}]}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":1,"transforms":[{"type":"wobble"}]}}`), &invalid_assignment))
+}
+
+func TestConfigProfile_InputValue_CalculateOutputValue_Transforms(t *testing.T) {
+	from, to := 0.5, 0.0
+	capture_width := 0.5
+	input_value := Config_Controller_Profile_Control_Assignment_DirectLike_InputValue{
+		Min: 0.0,
+		Max: 10.0,
+		Transforms: &[]Config_Controller_Profile_Control_Assignment_DirectLike_Transform{
+			{Type: "split", From: &from, To: &to},
+			{Type: "curve", Points: &[][2]float64{{0, 0}, {0.5, 0.2}, {1, 1}}},
+			{Type: "detents", Detents: &[]float64{5.0}, CaptureWidth: &capture_width},
+		},
+	}
+	/* the lower half of the lever is used in reverse */
+	assert.Equal(t, 0.0, input_value.CalculateOutputValue(0.6))
+	assert.Equal(t, 10.0, input_value.CalculateOutputValue(0.0))
+	assert.InDelta(t, 2.0, input_value.CalculateOutputValue(0.25), 1e-9)
+	/* 0.17 maps to 0.66 on the split, 4.56 after the curve and snaps to the detent */
+	assert.Equal(t, 5.0, input_value.CalculateOutputValue(0.17))
+}
+
+func TestConfigProfile_Assignment_Combined_UnmarshalJSON(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"type": "combined",
+		"brake": { "from": 0.45, "to": 0.0, "output": { "type": "direct_control", "controls": "TrainBrake1", "input_value": { "min": 0, "max": 1 } } },
+		"idle": { "from": 0.45, "to": 0.55, "capture_width": 0.02 },
+		"power": { "from": 0.55, "to": 1.0, "output": { "type": "direct_control", "controls": "Throttle1", "input_value": { "min": 0, "max": 1 } } }
+	}`), &assignment))
+	assert.NotNil(t, assignment.Combined)
+	assert.Equal(t, "combined", assignment.Type())
+	assert.Len(t, assignment.Combined.Zones(), 2)
+
+	marshalled, err := json.Marshal(assignment)
+	assert.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"type":"combined"`)
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	/* no zones */
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"combined"}`), &invalid_assignment))
+	/* key based output */
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"combined","power":{"from":0.5,"to":1,"output":{"type":"momentary","threshold":0.5,"action_activate":{"keys":"a"}}}}`), &invalid_assignment))
+	/* mixed output types */
+	assert.Error(t, json.Unmarshal([]byte(`{
+		"type": "combined",
+		"brake": { "from": 0.5, "to": 0.0, "output": { "type": "api_control", "controls": "TrainBrake1", "input_value": { "min": 0, "max": 1 } } },
+		"power": { "from": 0.5, "to": 1.0, "output": { "type": "direct_control", "controls": "Throttle1", "input_value": { "min": 0, "max": 1 } } }
+	}`), &invalid_assignment))
+	/* overlapping zones */
+	assert.Error(t, json.Unmarshal([]byte(`{
+		"type": "combined",
+		"brake": { "from": 0.6, "to": 0.0, "output": { "type": "direct_control", "controls": "TrainBrake1", "input_value": { "min": 0, "max": 1 } } },
+		"power": { "from": 0.5, "to": 1.0, "output": { "type": "direct_control", "controls": "Throttle1", "input_value": { "min": 0, "max": 1 } } }
+	}`), &invalid_assignment))
+}
+
+func TestConfigProfile_Assignment_Combined_FindZone(t *testing.T) {
+	capture_width := 0.02
+	combined := Config_Controller_Profile_Control_Assignment_Combined{
+		Type:  "combined",
+		Brake: &Config_Controller_Profile_Control_Assignment_Combined_Zone{From: 0.4, To: 0.0},
+		Idle:  &Config_Controller_Profile_Control_Assignment_Combined_Idle{From: 0.4, To: 0.6, CaptureWidth: &capture_width},
+		Power: &Config_Controller_Profile_Control_Assignment_Combined_Zone{From: 0.6, To: 1.0},
+	}
+
+	zone, value := combined.FindZone(0.0)
+	assert.Equal(t, "brake", zone)
+	assert.Equal(t, 1.0, value)
+
+	zone, value = combined.FindZone(0.2)
+	assert.Equal(t, "brake", zone)
+	assert.InDelta(t, 0.5, value, 1e-9)
+
+	/* within the capture width of the idle zone */
+	zone, _ = combined.FindZone(0.39)
+	assert.Equal(t, "idle", zone)
+	zone, _ = combined.FindZone(0.61)
+	assert.Equal(t, "idle", zone)
+
+	zone, value = combined.FindZone(0.8)
+	assert.Equal(t, "power", zone)
+	assert.InDelta(t, 0.5, value, 1e-9)
+
+	/* outside of every zone is idle */
+	combined.Idle = nil
+	combined.Power = &Config_Controller_Profile_Control_Assignment_Combined_Zone{From: 0.7, To: 1.0}
+	zone, value = combined.FindZone(0.5)
+	assert.Equal(t, "idle", zone)
+	assert.Equal(t, 0.0, value)
+}
+
+func TestConfigProfile_RailClassInformationEntry_MatchesClassName(t *testing.T) {
+	exact := "RVM_DTG_Class101_DMBS_C"
+	pattern := "RVM_DTG_Class10?_*"
+	regex := `^RVM_DTG_Class1(01|08)_`
+	empty := Config_Controller_Profile_RailClassInformationEntry{}
+	assert.False(t, empty.MatchesClassName("RVM_DTG_Class101_DMBS_C"))
+
+	exact_entry := Config_Controller_Profile_RailClassInformationEntry{ClassName: &exact}
+	assert.True(t, exact_entry.MatchesClassName("RVM_DTG_Class101_DMBS_C"))
+	assert.False(t, exact_entry.MatchesClassName("RVM_DTG_Class101_DMSL_C"))
+
+	pattern_entry := Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: &pattern}
+	assert.True(t, pattern_entry.MatchesClassName("RVM_DTG_Class101_DMSL_C"))
+	assert.False(t, pattern_entry.MatchesClassName("RVM_DTG_Class111_DMSL_C"))
+
+	regex_entry := Config_Controller_Profile_RailClassInformationEntry{ClassNameRegex: &regex}
+	assert.True(t, regex_entry.MatchesClassName("RVM_DTG_Class108_DMBS_C"))
+	assert.False(t, regex_entry.MatchesClassName("RVM_DTG_Class105_DMBS_C"))
+
+	/* all defined matchers have to match */
+	combined_entry := Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: &pattern, ClassNameRegex: &regex}
+	assert.True(t, combined_entry.MatchesClassName("RVM_DTG_Class101_DMBS_C"))
+	assert.False(t, combined_entry.MatchesClassName("RVM_DTG_Class105_DMBS_C"))
+}
+
+func TestConfigProfile_RailClassInformation_Invalid(t *testing.T) {
+	_, err := ControllerProfileFromJSON(`{ "name": "Invalid", "controls": [], "rail_class_information": [{ "class_name_regex": "(" }] }`, Config_Controller_Profile_Metadata{})
+	assert.Error(t, err)
+	_, err = ControllerProfileFromJSON(`{ "name": "Invalid", "controls": [], "rail_class_information": [{ "class_name_pattern": "[" }] }`, Config_Controller_Profile_Metadata{})
+	assert.Error(t, err)
+	_, err = ControllerProfileFromJSON(`{ "name": "Invalid", "controls": [], "rail_class_information": [{ "priority": 1 }] }`, Config_Controller_Profile_Metadata{})
+	assert.Error(t, err)
+	_, err = ControllerProfileFromJSON(`{ "name": "Valid", "controls": [], "rail_class_information": [{ "class_name_pattern": "RVM_*", "conditions": [{ "cab_variable": "Reverser", "operator": "gte", "value": 0.5 }] }] }`, Config_Controller_Profile_Metadata{})
+	assert.NoError(t, err)
+}
+
+func TestConfigProfile_InputValue_StepDetents(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":10,"steps":[0,"notch1",null,"emergency"]}}`), &assignment))
+	input_value := assignment.DirectControl.InputValue
+	assert.Equal(t, map[int]string{1: "notch1", 3: "emergency"}, input_value.StepDetents)
+
+	marshalled, err := json.Marshal(input_value)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"min":0,"max":10,"steps":[0,"notch1",null,"emergency"]}`, string(marshalled))
+
+	resolved, missing_detents := input_value.ResolveDetents(map[string]float64{"notch1": 0.25})
+	assert.Equal(t, []string{"emergency"}, missing_detents)
+	assert.Equal(t, &[]float64{0, 2.5}, resolved.GetNormalSteps())
+	assert.Equal(t, 2.5, resolved.CalculateOutputValue(0.2))
+	/* the free range after the detent is kept */
+	assert.Equal(t, 3.0, resolved.CalculateOutputValue(0.3))
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"direct_control","controls":"Throttle1","input_value":{"min":0,"max":10,"steps":[0,true]}}`), &invalid_assignment))
+}
+
+func TestConfigProfile_Linear_ResolveDetents(t *testing.T) {
+	notch, emergency, unknown := "notch1", "emergency", "unknown"
+	linear := Config_Controller_Profile_Control_Assignment_Linear{
+		Thresholds: []Config_Controller_Profile_Control_Assignment_Linear_Threshold{
+			{Value: 0.1},
+			{Detent: &notch},
+			{Detent: &emergency},
+			{Detent: &unknown},
+		},
+	}
+	resolved, missing_detents := linear.ResolveDetents(map[string]float64{"notch1": 0.5, "emergency": -1})
+	assert.Equal(t, []string{"unknown"}, missing_detents)
+	assert.Len(t, resolved.Thresholds, 3)
+	assert.Equal(t, 0.5, resolved.Thresholds[1].Value)
+	/* detent thresholds are reached once the lever is in the detent */
+	assert.True(t, resolved.Thresholds[2].IsExceedingThreshold(-1))
+	assert.Len(t, linear.Thresholds, 4)
+}
+
+func TestConfigProfile_Assignment_Flick_UnmarshalJSON(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"flick","direction":"down","min_velocity":2,"action_activate":{"keys":"a"}}`), &assignment))
+	assert.NotNil(t, assignment.Flick)
+	assert.True(t, assignment.Flick.IsActive(-2))
+	assert.False(t, assignment.Flick.IsActive(2))
+	assert.False(t, assignment.Flick.HasEnded(-1.5))
+	assert.True(t, assignment.Flick.HasEnded(-0.5))
+
+	marshalled, err := json.Marshal(assignment)
+	assert.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"type":"flick"`)
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"flick","direction":"left","min_velocity":2,"action_activate":{"keys":"a"}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"flick","direction":"up","min_velocity":0,"action_activate":{"keys":"a"}}`), &invalid_assignment))
+}
+
+func TestConfigProfile_Assignment_Gestures_UnmarshalJSON(t *testing.T) {
+	var long_press Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"long_press","threshold":0.5,"duration":0.5,"action_activate":{"keys":"a"},"action_press":{"keys":"b"}}`), &long_press))
+	assert.NotNil(t, long_press.LongPress)
+	assert.Equal(t, "long_press", long_press.Type())
+
+	var multi_tap Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"multi_tap","threshold":0.5,"window":0.3,"action_press":{"keys":"a"},"action_triple_tap":{"keys":"c"}}`), &multi_tap))
+	assert.NotNil(t, multi_tap.MultiTap)
+	assert.Equal(t, 3, multi_tap.MultiTap.MaxTaps())
+	assert.Nil(t, multi_tap.MultiTap.ActionForTaps(2))
+
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"chord","threshold":0.5,"controls":["Button2"],"action_activate":{"keys":"a"}}`), &assignment))
+	assert.NotNil(t, assignment.Chord)
+	assert.True(t, assignment.Chord.HasControl("Button2"))
+
+	marshalled, err := json.Marshal(assignment)
+	assert.NoError(t, err)
+	assert.Contains(t, string(marshalled), `"type":"chord"`)
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"long_press","threshold":0.5,"duration":0,"action_activate":{"keys":"a"}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"multi_tap","threshold":0.5,"window":0.3,"action_press":{"keys":"a"}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"chord","threshold":0.5,"controls":[],"action_activate":{"keys":"a"}}`), &invalid_assignment))
+}
+
+func TestConfigProfile_Assignment_Rate_CalculateRate(t *testing.T) {
+	var assignment Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"rate","deadband":0.2,"min_rate":1,"max_rate":9,"action_increase":{"keys":"+"},"action_decrease":{"keys":"-"}}`), &assignment))
+	assert.NotNil(t, assignment.Rate)
+
+	direction, rate := assignment.Rate.CalculateRate(0.1)
+	assert.Equal(t, 0, direction)
+	assert.Equal(t, 0.0, rate)
+	direction, rate = assignment.Rate.CalculateRate(0.6)
+	assert.Equal(t, 1, direction)
+	assert.InDelta(t, 5.0, rate, 0.001)
+	direction, rate = assignment.Rate.CalculateRate(-1)
+	assert.Equal(t, -1, direction)
+	assert.InDelta(t, 9.0, rate, 0.001)
+	assert.Equal(t, "-", assignment.Rate.ActionForDirection(direction).Keys.Keys)
+
+	/* the curve shapes the rate and the center can be moved for 0-1 axes */
+	var curved Config_Controller_Profile_Control_Assignment
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"rate","center":0.5,"min_rate":0,"max_rate":10,"curve":{"points":[[0,0],[0.5,0.1],[1,1]]},"action_increase":{"keys":"+"},"action_decrease":{"keys":"-"}}`), &curved))
+	direction, rate = curved.Rate.CalculateRate(0.25)
+	assert.Equal(t, -1, direction)
+	assert.InDelta(t, 1.0, rate, 0.001)
+
+	var invalid_assignment Config_Controller_Profile_Control_Assignment
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"rate","min_rate":5,"max_rate":1,"action_increase":{"keys":"+"},"action_decrease":{"keys":"-"}}`), &invalid_assignment))
+	assert.Error(t, json.Unmarshal([]byte(`{"type":"rate","max_rate":5,"curve":{"points":[[0,0]]},"action_increase":{"keys":"+"},"action_decrease":{"keys":"-"}}`), &invalid_assignment))
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"tsw_controller_app/logger"
@@ -12,6 +13,42 @@ import (
 const DEFAULT_TSWAPI_SUBSCRIPTION_ID_START = 83211
 const DEFAULT_PREFERRED_CONTROL_MODE = PreferredControlMode_DirectControl
 const DEFAULT_THEME = "system"
+const DEFAULT_KEY_OUTPUT = KeyOutput_Robotgo
+const DEFAULT_SYNC_CONTROL_FEEDBACK = SyncControlFeedback_Both
+
+type KeyOutput = string
+
+const (
+	/* sends keys and mouse input through the desktop session */
+	KeyOutput_Robotgo KeyOutput = "robotgo"
+	/* sends keys and mouse input through a Linux uinput virtual keyboard and mouse; works on Wayland and headless setups */
+	KeyOutput_Uinput KeyOutput = "uinput"
+)
+
+type SyncControlFeedback = string
+
+const (
+	/* reads the current values from the mod */
+	SyncControlFeedback_Mod SyncControlFeedback = "mod"
+	/* reads the current values from the TSW HTTP API; does not require the mod */
+	SyncControlFeedback_Api SyncControlFeedback = "api"
+	/* prefers the mod and falls back to the API when the mod does not report a control */
+	SyncControlFeedback_Both SyncControlFeedback = "both"
+)
+
+/* a persisted profile selection; matched by name and path since the profile ID changes when either changes */
+type Config_ProgramConfig_SelectedProfile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+/* how the profile of a controller is selected */
+type Config_ProgramConfig_DeviceProfilePolicy struct {
+	/* keeps a manually selected profile even when a profile can be auto-selected for the current train; defaults to true */
+	StickyManualSelection *bool `json:"sticky_manual_selection,omitempty"`
+	/* the profile to use when no profile is selected and none can be auto-selected */
+	FallbackProfile *Config_ProgramConfig_SelectedProfile `json:"fallback_profile,omitempty"`
+}
 
 type Config_ProgramConfig struct {
 	LastInstalledModVersion   string               `json:"last_instalaled_mod_version,omitempty" validate:"semver"`
@@ -20,6 +57,14 @@ type Config_ProgramConfig struct {
 	PreferredControlMode      PreferredControlMode `json:"preferred_control_mode,omitempty" validate:"oneof=direct_control sync_control api_control"`
 	Theme                     string               `json:"theme,omitempty" validate:"oneof=system light dark"`
 	AlwaysOnTop               bool                 `json:"always_on_top,omitempty"`
+	KeyOutput                 KeyOutput            `json:"key_output,omitempty" validate:"oneof=robotgo uinput"`
+	SyncControlFeedback       SyncControlFeedback  `json:"sync_control_feedback,omitempty" validate:"oneof=mod api both"`
+	/* user assigned aliases for identical controllers keyed by the hardware key of the device */
+	DeviceAliases map[string]string `json:"device_aliases,omitempty"`
+	/* the selected profiles keyed by the controller GUID */
+	SelectedProfiles map[string]Config_ProgramConfig_SelectedProfile `json:"selected_profiles,omitempty"`
+	/* the profile selection policies keyed by the controller GUID */
+	DeviceProfilePolicies map[string]Config_ProgramConfig_DeviceProfilePolicy `json:"device_profile_policies,omitempty"`
 }
 
 func NewDefaultProgramConfig() *Config_ProgramConfig {
@@ -30,6 +75,8 @@ func NewDefaultProgramConfig() *Config_ProgramConfig {
 		TSWAPISubscriptionIDStart: DEFAULT_TSWAPI_SUBSCRIPTION_ID_START,
 		PreferredControlMode:      DEFAULT_PREFERRED_CONTROL_MODE,
 		Theme:                     DEFAULT_THEME,
+		KeyOutput:                 DEFAULT_KEY_OUTPUT,
+		SyncControlFeedback:       DEFAULT_SYNC_CONTROL_FEEDBACK,
 	}
 }
 
@@ -55,6 +102,17 @@ func LoadProgramConfigFromFile(filepath string) *Config_ProgramConfig {
 	return pc
 }
 
+/*
+Returns an error for unknown sync control feedback values; these would make the program config fail validation when loading it
+*/
+func ValidateSyncControlFeedback(feedback SyncControlFeedback) error {
+	switch feedback {
+	case SyncControlFeedback_Mod, SyncControlFeedback_Api, SyncControlFeedback_Both:
+		return nil
+	}
+	return fmt.Errorf("unknown sync control feedback %q; expected one of mod, api or both", feedback)
+}
+
 func (c *Config_ProgramConfig) AutoDetectTSWAPIKeyLocation() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,15 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSyncControlFeedback(t *testing.T) {
+	for _, feedback := range []SyncControlFeedback{SyncControlFeedback_Mod, SyncControlFeedback_Api, SyncControlFeedback_Both} {
+		assert.NoError(t, ValidateSyncControlFeedback(feedback))
+	}
+	assert.Error(t, ValidateSyncControlFeedback(""))
+	assert.Error(t, ValidateSyncControlFeedback("Mod"))
+}
@@ -12,12 +12,61 @@ type Config_Controller_SDLMap_Control struct {
 	Kind  sdl_mgr.SDLMgr_Control_Kind `json:"kind" validate:"required" example:"button"`
 	Index int                         `json:"index" validate:"required"`
 	Name  string                      `json:"name" validate:"required" example:"Lever1"`
+	/* only for hats; exposes a single direction of the hat as a button which is pressed while the hat points exactly in that direction */
+	Direction *sdl_mgr.SDLMgr_HatDirection `json:"direction,omitempty" validate:"omitempty,oneof=up up_right right down_right down down_left left up_left" example:"up"`
+}
+
+type Config_Controller_SDLMap_DerivedControl_Kind = string
+
+const (
+	/* the sum of the normalized values of the inputs; clamped to [-1, 1] */
+	Config_Controller_SDLMap_DerivedControl_Kind_Sum Config_Controller_SDLMap_DerivedControl_Kind = "sum"
+	/* the average of the normalized values of the inputs */
+	Config_Controller_SDLMap_DerivedControl_Kind_Average Config_Controller_SDLMap_DerivedControl_Kind = "average"
+	/* the first input minus the second input; clamped to [-1, 1] */
+	Config_Controller_SDLMap_DerivedControl_Kind_Difference Config_Controller_SDLMap_DerivedControl_Kind = "difference"
+	/* a button for each zone which is pressed while the input is within the zone */
+	Config_Controller_SDLMap_DerivedControl_Kind_Zones Config_Controller_SDLMap_DerivedControl_Kind = "zones"
+	/* an axis which ramps down while the first input is pressed and up while the second input is pressed */
+	Config_Controller_SDLMap_DerivedControl_Kind_ButtonAxis Config_Controller_SDLMap_DerivedControl_Kind = "button_axis"
+	/* a flip-flop which toggles between 0 and 1 every time the input is pressed */
+	Config_Controller_SDLMap_DerivedControl_Kind_Latch Config_Controller_SDLMap_DerivedControl_Kind = "latch"
+)
+
+/* the default normalized value above which a button_axis or latch input is considered pressed */
+const DERIVED_CONTROL_DEFAULT_THRESHOLD = 0.5
+
+type Config_Controller_SDLMap_DerivedControl_Zone struct {
+	Name string `json:"name" validate:"required" example:"ThrottleNotch1"`
+	/* the zone includes its minimum and excludes its maximum unless the maximum is 1 */
+	Min float64 `json:"min" validate:"gte=0,lte=1"`
+	Max float64 `json:"max" validate:"gte=0,lte=1,gtfield=Min"`
+}
+
+/*
+a control computed from the normalized values of other controls of the same controller; derived controls are published
+like any other control and can be bound by profiles by their name
+*/
+type Config_Controller_SDLMap_DerivedControl struct {
+	Kind Config_Controller_SDLMap_DerivedControl_Kind `json:"kind" validate:"required,oneof=sum average difference zones button_axis latch" example:"difference"`
+	/* the name of the derived control; zones name each of their controls instead */
+	Name string `json:"name,omitempty" example:"SplitThrottle"`
+	/* the names of the controls in the SDL mapping the derived control is computed from */
+	Inputs []string                                       `json:"inputs" validate:"required,min=1,dive,required"`
+	Zones  []Config_Controller_SDLMap_DerivedControl_Zone `json:"zones,omitempty" validate:"omitempty,dive"`
+	/* how much a button_axis changes per second while one of its buttons is held */
+	RampRate *float64 `json:"ramp_rate,omitempty" validate:"omitempty,gt=0" example:"0.5"`
+	/* where a button_axis starts */
+	Initial *float64 `json:"initial,omitempty" validate:"omitempty,gte=0,lte=1"`
+	/* the normalized value above which the inputs of a button_axis or latch are considered pressed */
+	Threshold *float64 `json:"threshold,omitempty" validate:"omitempty,gt=0,lte=1"`
 }
 
 type Config_Controller_SDLMap struct {
-	Name  string                             `json:"name" example:"Thrustmaster Quadrant" validate:"required"`
-	UsbID string                             `json:"usb_id" example:"{0xVENDOR_ID}:{0xPRODUCT_ID}" validate:"required"`
-	Data  []Config_Controller_SDLMap_Control `json:"data" validate:"required"`
+	Name    string                                    `json:"name" example:"Thrustmaster Quadrant" validate:"required"`
+	UsbID   string                                    `json:"usb_id" example:"{0xVENDOR_ID}:{0xPRODUCT_ID}" validate:"required"`
+	Data    []Config_Controller_SDLMap_Control        `json:"data" validate:"required"`
+	Derived []Config_Controller_SDLMap_DerivedControl `json:"derived,omitempty" validate:"omitempty,dive"`
 }
 
 func ControllerSDLMapFromJSON(json_str string) (*Config_Controller_SDLMap, error) {
@@ -30,10 +79,96 @@ func ControllerSDLMapFromJSON(json_str string) (*Config_Controller_SDLMap, error
 	if err := v.Struct(c); err != nil {
 		return nil, err
 	}
+	if err := c.ValidateDerived(); err != nil {
+		return nil, err
+	}
 
 	return &c, nil
 }
 
+/* the names of the controls the derived control publishes */
+func (c *Config_Controller_SDLMap_DerivedControl) ControlNames() []string {
+	if c.Kind == Config_Controller_SDLMap_DerivedControl_Kind_Zones {
+		names := []string{}
+		for _, zone := range c.Zones {
+			names = append(names, zone.Name)
+		}
+		return names
+	}
+	return []string{c.Name}
+}
+
+func (c *Config_Controller_SDLMap_DerivedControl) GetThreshold() float64 {
+	if c.Threshold == nil {
+		return DERIVED_CONTROL_DEFAULT_THRESHOLD
+	}
+	return *c.Threshold
+}
+
+func (c *Config_Controller_SDLMap_DerivedControl) GetInitial() float64 {
+	if c.Initial == nil {
+		return 0.0
+	}
+	return *c.Initial
+}
+
+func (c *Config_Controller_SDLMap_DerivedControl) Validate() error {
+	expected_inputs := map[Config_Controller_SDLMap_DerivedControl_Kind]int{
+		Config_Controller_SDLMap_DerivedControl_Kind_Difference: 2,
+		Config_Controller_SDLMap_DerivedControl_Kind_Zones:      1,
+		Config_Controller_SDLMap_DerivedControl_Kind_ButtonAxis: 2,
+		Config_Controller_SDLMap_DerivedControl_Kind_Latch:      1,
+	}
+	if count, has_count := expected_inputs[c.Kind]; has_count && len(c.Inputs) != count {
+		return fmt.Errorf("the %s derived control %s needs exactly %d inputs", c.Kind, c.Name, count)
+	}
+	if (c.Kind == Config_Controller_SDLMap_DerivedControl_Kind_Sum || c.Kind == Config_Controller_SDLMap_DerivedControl_Kind_Average) && len(c.Inputs) < 2 {
+		return fmt.Errorf("the %s derived control %s needs at least 2 inputs", c.Kind, c.Name)
+	}
+	if c.Kind == Config_Controller_SDLMap_DerivedControl_Kind_Zones {
+		if len(c.Zones) == 0 {
+			return fmt.Errorf("the zones derived control of %s needs at least one zone", c.Inputs[0])
+		}
+		return nil
+	}
+	if c.Name == "" {
+		return fmt.Errorf("the %s derived control needs a name", c.Kind)
+	}
+	if c.Kind == Config_Controller_SDLMap_DerivedControl_Kind_ButtonAxis && c.RampRate == nil {
+		return fmt.Errorf("the button_axis derived control %s needs a ramp_rate", c.Name)
+	}
+	return nil
+}
+
+/*
+validates the derived controls against the controls of the mapping; derived controls can only be computed from the
+controls in the mapping (not from other derived controls) and their names have to be unique
+*/
+func (c *Config_Controller_SDLMap) ValidateDerived() error {
+	names := map[string]bool{}
+	for _, control := range c.Data {
+		names[control.Name] = true
+	}
+	derived_names := map[string]bool{}
+	for _, derived := range c.Derived {
+		if err := derived.Validate(); err != nil {
+			return err
+		}
+		for _, input := range derived.Inputs {
+			if !names[input] {
+				return fmt.Errorf("the input %s of the %s derived control does not exist in the SDL mapping", input, derived.Kind)
+			}
+		}
+		for _, name := range derived.ControlNames() {
+			if names[name] || derived_names[name] {
+				return fmt.Errorf("the name %s is used by multiple controls", name)
+			}
+			derived_names[name] = true
+		}
+	}
+	return nil
+}
+
 func (c *Config_Controller_SDLMap) FindByKindAndIndex(kind sdl_mgr.SDLMgr_Control_Kind, index int) (Config_Controller_SDLMap_Control, error) {
 	for _, control := range c.Data {
 		if control.Kind == kind && control.Index == index {
@@ -43,3 +178,38 @@ func (c *Config_Controller_SDLMap) FindByKindAndIndex(kind sdl_mgr.SDLMgr_Contro
 
 	return Config_Controller_SDLMap_Control{}, fmt.Errorf("could not find control")
 }
+
+/* the hat direction of the control or an empty string for controls reporting the raw value */
+func (c *Config_Controller_SDLMap_Control) HatDirection() sdl_mgr.SDLMgr_HatDirection {
+	if c.Direction == nil {
+		return ""
+	}
+	return *c.Direction
+}
+
+/*
+finds the control by its kind, index and hat direction; an empty direction matches the control without a direction
+*/
+func (c *Config_Controller_SDLMap) FindByKindIndexAndDirection(kind sdl_mgr.SDLMgr_Control_Kind, index int, direction sdl_mgr.SDLMgr_HatDirection) (Config_Controller_SDLMap_Control, error) {
+	for _, control := range c.Data {
+		if control.Kind == kind && control.Index == index && control.HatDirection() == direction {
+			return control, nil
+		}
+	}
+
+	return Config_Controller_SDLMap_Control{}, fmt.Errorf("could not find control")
+}
+
+/*
+the raw value of the hat control for the SDL hat bitmask; 1 while a hat direction control points in its direction
+and 0 otherwise, the bitmask itself for controls without a direction
+*/
+func (c *Config_Controller_SDLMap_Control) RawHatValue(value uint8) float64 {
+	if c.Direction == nil {
+		return float64(value)
+	}
+	if sdl_mgr.HatDirectionFromValue(value) == *c.Direction {
+		return 1.0
+	}
+	return 0.0
+}
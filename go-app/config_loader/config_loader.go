@@ -1,11 +1,13 @@
 package config_loader
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
 )
 
 type ConfigLoader struct{}
@@ -99,3 +101,15 @@ func (c *ConfigLoader) FromDirectory(dir string) ([]config.Config_Controller_SDL
 
 	return parsed_sdl_mappings_files, parsed_calibration_files, parsed_profile_files, errors
 }
+
+/* reads the SDL GameController database of the directory; the database is optional */
+func (c *ConfigLoader) GameControllerDBFromDirectory(dir string) (string, bool, error) {
+	db_bytes, err := os.ReadFile(filepath.Join(dir, sdl_mgr.GAMECONTROLLER_DB_FILENAME))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("could not read game controller database in %s (%e)", dir, err)
+	}
+	return string(db_bytes), true, nil
+}
@@ -0,0 +1,401 @@
+package controller_mgr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/* the full raw range of an axis */
+const CALIBRATION_AXIS_RANGE = float64(math.MaxUint16)
+
+/* how long an axis has to stay within the rest tolerance to count as resting */
+const CALIBRATION_IDLE_DWELL_TIME = time.Second
+
+/* the share of the axis range an axis may move while resting */
+const CALIBRATION_REST_TOLERANCE = 0.02
+
+/* axes which moved less than this share of the axis range are ignored as noise from unused axes */
+const CALIBRATION_MIN_AXIS_RANGE = 0.05
+
+/* axes which moved less than this share of the axis range were probably not moved through their full range */
+const CALIBRATION_PARTIAL_AXIS_RANGE = 0.5
+
+/* the suggested deadzone is the measured noise at rest times this factor */
+const CALIBRATION_DEADZONE_NOISE_FACTOR = 2.0
+
+/* a position the axis rested at */
+type calibrationSession_Rest struct {
+	Value float64
+	Dwell time.Duration
+	/* half of the spread of the values while resting */
+	Noise float64
+}
+
+type calibrationSession_Axis struct {
+	Index int
+	Min   float64
+	Max   float64
+	/* the current rest window; values within the rest tolerance extend it */
+	WindowStart time.Time
+	WindowMin   float64
+	WindowMax   float64
+	Rests       []calibrationSession_Rest
+}
+
+/* the detected calibration of an axis */
+type ControllerManager_CalibrationResult_Axis struct {
+	Index        int
+	Name         string
+	Min          float64
+	Max          float64
+	Idle         float64
+	IdleDetected bool
+	Deadzone     float64
+	Noise        float64
+	/* the axis rests at its maximum; min, max and idle are expressed in the inverted range */
+	Inverted bool
+	/* the axis rests in the middle of its range (eg: a joystick) */
+	Centered bool
+	Warnings []string
+}
+
+/* the detected calibration and SDL mapping of a device to review before saving */
+type ControllerManager_CalibrationResult struct {
+	Calibration config.Config_Controller_Calibration
+	SDLMapping  config.Config_Controller_SDLMap
+	Axes        []ControllerManager_CalibrationResult_Axis
+	Warnings    []string
+}
+
+/*
+records the raw events of a single device to detect its calibration:
+the range of each axis, the idle position by how long the axis rested there, the noise at rest and
+the buttons and the hat directions which were used
+*/
+type ControllerManager_CalibrationSession struct {
+	Joystick *sdl_mgr.SDLMgr_Joystick
+	/* used to propose the names of the controls; may be nil */
+	ExistingSDLMapping *config.Config_Controller_SDLMap
+	Mutex              sync.Mutex
+	axes               map[int]*calibrationSession_Axis
+	buttons            map[int]bool
+	hats               map[int]map[sdl_mgr.SDLMgr_HatDirection]bool
+	cancel             context.CancelFunc
+	done               chan struct{}
+}
+
+func NewCalibrationSession(joystick *sdl_mgr.SDLMgr_Joystick, existing_sdl_mapping *config.Config_Controller_SDLMap) *ControllerManager_CalibrationSession {
+	return &ControllerManager_CalibrationSession{
+		Joystick:           joystick,
+		ExistingSDLMapping: existing_sdl_mapping,
+		Mutex:              sync.Mutex{},
+		axes:               map[int]*calibrationSession_Axis{},
+		buttons:            map[int]bool{},
+		hats:               map[int]map[sdl_mgr.SDLMgr_HatDirection]bool{},
+	}
+}
+
+/*
+starts recording the raw events of the device with the GUID; the current axis positions are recorded first since
+SDL only reports changes and the levers are expected to be at rest when starting
+*/
+func (mgr *ControllerManager) StartCalibrationSession(ctx context.Context, guid JoystickGUIDString) (*ControllerManager_CalibrationSession, error) {
+	device, has_device := mgr.GetDevice(guid)
+	if !has_device {
+		return nil, fmt.Errorf("could not find controller %s", guid)
+	}
+
+	var existing_sdl_mapping *config.Config_Controller_SDLMap
+	if sdl_mapping, has_sdl_mapping := mgr.Config.SDLMappingsByUsbID.Get(device.Joystick.ToString()); has_sdl_mapping {
+		existing_sdl_mapping = &sdl_mapping
+	}
+	session := NewCalibrationSession(device.Joystick, existing_sdl_mapping)
+
+	now := time.Now()
+	if device.Joystick.InternalJoystick != nil {
+		for axis := 0; axis < device.Joystick.InternalJoystick.NumAxes(); axis++ {
+			session.RecordAxis(axis, float64(device.Joystick.InternalJoystick.Axis(axis)), now)
+		}
+	}
+
+	session_ctx, cancel := context.WithCancel(ctx)
+	session.cancel = cancel
+	session.done = make(chan struct{})
+	channel, unsubscribe := mgr.SubscribeRaw()
+	go func() {
+		defer close(session.done)
+		defer unsubscribe()
+		for {
+			select {
+			case <-session_ctx.Done():
+				return
+			case raw_event := <-channel:
+				if raw_event.Joystick.GUID != guid {
+					continue
+				}
+				session.HandleEvent(raw_event.Event, time.Now())
+			}
+		}
+	}()
+	return session, nil
+}
+
+/* stops recording; the result can still be read afterwards */
+func (s *ControllerManager_CalibrationSession) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+func (s *ControllerManager_CalibrationSession) HandleEvent(event sdl.Event, now time.Time) {
+	switch e := event.(type) {
+	case *sdl.JoyAxisEvent:
+		s.RecordAxis(int(e.Axis), float64(e.Value), now)
+	case *sdl.JoyButtonEvent:
+		if e.State == sdl.PRESSED {
+			s.Mutex.Lock()
+			s.buttons[int(e.Button)] = true
+			s.Mutex.Unlock()
+		}
+	case *sdl.JoyHatEvent:
+		s.RecordHat(int(e.Hat), e.Value)
+	}
+}
+
+/* records the direction the hat points in; every direction used becomes its own control */
+func (s *ControllerManager_CalibrationSession) RecordHat(index int, value uint8) {
+	direction := sdl_mgr.HatDirectionFromValue(value)
+	if direction == sdl_mgr.SDLMgr_HatDirection_Centered {
+		return
+	}
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if s.hats[index] == nil {
+		s.hats[index] = map[sdl_mgr.SDLMgr_HatDirection]bool{}
+	}
+	s.hats[index][direction] = true
+}
+
+func (s *ControllerManager_CalibrationSession) RecordAxis(index int, value float64, now time.Time) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	axis, has_axis := s.axes[index]
+	if !has_axis {
+		s.axes[index] = &calibrationSession_Axis{
+			Index:       index,
+			Min:         value,
+			Max:         value,
+			WindowStart: now,
+			WindowMin:   value,
+			WindowMax:   value,
+		}
+		return
+	}
+
+	axis.Min = math.Min(axis.Min, value)
+	axis.Max = math.Max(axis.Max, value)
+	window_min := math.Min(axis.WindowMin, value)
+	window_max := math.Max(axis.WindowMax, value)
+	if window_max-window_min <= CALIBRATION_REST_TOLERANCE*CALIBRATION_AXIS_RANGE {
+		axis.WindowMin = window_min
+		axis.WindowMax = window_max
+		return
+	}
+
+	/* the axis moved; the previous window counts as a rest position when it lasted long enough */
+	axis.Rests = axis.restsWithWindow(now)
+	axis.WindowStart = now
+	axis.WindowMin = value
+	axis.WindowMax = value
+}
+
+/* the rest positions including the current window; rest positions within the rest tolerance are merged */
+func (axis *calibrationSession_Axis) restsWithWindow(now time.Time) []calibrationSession_Rest {
+	rests := append([]calibrationSession_Rest{}, axis.Rests...)
+	dwell := now.Sub(axis.WindowStart)
+	if dwell < CALIBRATION_IDLE_DWELL_TIME {
+		return rests
+	}
+
+	window_rest := calibrationSession_Rest{
+		Value: (axis.WindowMin + axis.WindowMax) / 2,
+		Dwell: dwell,
+		Noise: (axis.WindowMax - axis.WindowMin) / 2,
+	}
+	for index, rest := range rests {
+		if math.Abs(rest.Value-window_rest.Value) <= CALIBRATION_REST_TOLERANCE*CALIBRATION_AXIS_RANGE {
+			rests[index].Dwell += window_rest.Dwell
+			rests[index].Noise = math.Max(rest.Noise, window_rest.Noise)
+			return rests
+		}
+	}
+	return append(rests, window_rest)
+}
+
+func (axis *calibrationSession_Axis) result(name string, now time.Time) ControllerManager_CalibrationResult_Axis {
+	result := ControllerManager_CalibrationResult_Axis{
+		Index:    axis.Index,
+		Name:     name,
+		Min:      axis.Min,
+		Max:      axis.Max,
+		Idle:     axis.Min,
+		Warnings: []string{},
+	}
+
+	/* the idle position is where the axis rested the longest */
+	rests := axis.restsWithWindow(now)
+	for _, rest := range rests {
+		result.Noise = math.Max(result.Noise, rest.Noise)
+	}
+	sort.SliceStable(rests, func(i, j int) bool {
+		return rests[i].Dwell > rests[j].Dwell
+	})
+	if len(rests) > 0 {
+		result.Idle = math.Round(rests[0].Value)
+		result.IdleDetected = true
+	} else {
+		result.Warnings = append(result.Warnings, "No idle position detected; leave the axis at rest for a moment")
+	}
+	result.Deadzone = math.Ceil(result.Noise * CALIBRATION_DEADZONE_NOISE_FACTOR)
+
+	axis_range := axis.Max - axis.Min
+	if axis_range < CALIBRATION_PARTIAL_AXIS_RANGE*CALIBRATION_AXIS_RANGE {
+		result.Warnings = append(result.Warnings, "The axis barely moved; move it through its full range")
+	}
+
+	/* the idle position of a centered axis is in the middle third of its range */
+	relative_idle := (result.Idle - axis.Min) / axis_range
+	result.Centered = result.IdleDetected && relative_idle > 1.0/3.0 && relative_idle < 2.0/3.0
+	if result.IdleDetected && !result.Centered && relative_idle >= 2.0/3.0 {
+		/* normalizing inverts the raw value so the range is inverted as well */
+		result.Inverted = true
+		result.Min, result.Max, result.Idle = -axis.Max, -axis.Min, -result.Idle
+	}
+	return result
+}
+
+/* the name of the control in the existing SDL mapping or a name based on its kind, index and hat direction (eg: Hat1UpRight) */
+func (s *ControllerManager_CalibrationSession) proposeName(kind sdl_mgr.SDLMgr_Control_Kind, index int, direction sdl_mgr.SDLMgr_HatDirection, used_names map[string]bool) string {
+	if s.ExistingSDLMapping != nil {
+		if control, err := s.ExistingSDLMapping.FindByKindIndexAndDirection(kind, index, direction); err == nil && !used_names[control.Name] {
+			return control.Name
+		}
+	}
+
+	prefix := map[sdl_mgr.SDLMgr_Control_Kind]string{
+		sdl_mgr.SDLMgr_Control_Kind_Axis:   "Axis",
+		sdl_mgr.SDLMgr_Control_Kind_Button: "Button",
+		sdl_mgr.SDLMgr_Control_Kind_Hat:    "Hat",
+	}[kind]
+	direction_suffix := ""
+	for _, part := range strings.Split(direction, "_") {
+		if part != "" {
+			direction_suffix += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	name := fmt.Sprintf("%s%d%s", prefix, index+1, direction_suffix)
+	for suffix := 2; used_names[name]; suffix++ {
+		name = fmt.Sprintf("%s%d%s_%d", prefix, index+1, direction_suffix, suffix)
+	}
+	return name
+}
+
+/* the detected calibration so far; valid while the session is running and after it stopped */
+func (s *ControllerManager_CalibrationSession) Result(now time.Time) (ControllerManager_CalibrationResult, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	name := s.Joystick.Name
+	if s.ExistingSDLMapping != nil && s.ExistingSDLMapping.Name != "" {
+		name = s.ExistingSDLMapping.Name
+	}
+	result := ControllerManager_CalibrationResult{
+		Calibration: config.Config_Controller_Calibration{
+			UsbID: s.Joystick.ToString(),
+			Data:  []config.Config_Controller_CalibrationData{},
+		},
+		SDLMapping: config.Config_Controller_SDLMap{
+			Name:  name,
+			UsbID: s.Joystick.ToString(),
+			Data:  []config.Config_Controller_SDLMap_Control{},
+		},
+		Axes:     []ControllerManager_CalibrationResult_Axis{},
+		Warnings: []string{},
+	}
+
+	/* names of existing controls are reserved for their own control */
+	used_names := map[string]bool{}
+	add_control := func(kind sdl_mgr.SDLMgr_Control_Kind, index int, direction sdl_mgr.SDLMgr_HatDirection) string {
+		control_name := s.proposeName(kind, index, direction, used_names)
+		used_names[control_name] = true
+		control := config.Config_Controller_SDLMap_Control{
+			Kind:  kind,
+			Index: index,
+			Name:  control_name,
+		}
+		if direction != "" {
+			control.Direction = &direction
+		}
+		result.SDLMapping.Data = append(result.SDLMapping.Data, control)
+		return control_name
+	}
+
+	for _, index := range sortedKeys(s.axes) {
+		axis := s.axes[index]
+		if axis.Max-axis.Min < CALIBRATION_MIN_AXIS_RANGE*CALIBRATION_AXIS_RANGE {
+			continue
+		}
+		axis_result := axis.result(add_control(sdl_mgr.SDLMgr_Control_Kind_Axis, index, ""), now)
+		result.Axes = append(result.Axes, axis_result)
+
+		idle, deadzone, invert := axis_result.Idle, axis_result.Deadzone, axis_result.Inverted
+		result.Calibration.Data = append(result.Calibration.Data, config.Config_Controller_CalibrationData{
+			Id:          axis_result.Name,
+			Min:         axis_result.Min,
+			Max:         axis_result.Max,
+			Idle:        &idle,
+			Deadzone:    &deadzone,
+			Invert:      &invert,
+			EasingCurve: &[]float64{0.0, 0.0, 1.0, 1.0},
+		})
+	}
+	for _, index := range sortedKeys(s.buttons) {
+		add_control(sdl_mgr.SDLMgr_Control_Kind_Button, index, "")
+	}
+	for _, index := range sortedKeys(s.hats) {
+		for _, direction := range sdl_mgr.SDLMgr_HatDirections {
+			if s.hats[index][direction] {
+				add_control(sdl_mgr.SDLMgr_Control_Kind_Hat, index, direction)
+			}
+		}
+	}
+
+	if len(result.SDLMapping.Data) == 0 {
+		result.Warnings = append(result.Warnings, "No controls were used yet; move every lever through its full range and press every button")
+	}
+	if err := config.ValidateControllerConfiguration(result.SDLMapping, result.Calibration); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func sortedKeys[V any](values map[int]V) []int {
+	keys := []int{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
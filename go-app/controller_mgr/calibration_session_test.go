@@ -0,0 +1,153 @@
+package controller_mgr
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/* moves the axis from one value to another in steps, one step per 10ms */
+func sweepAxis(session *ControllerManager_CalibrationSession, axis int, from float64, to float64, start time.Time) time.Time {
+	steps := 20
+	now := start
+	for step := 0; step <= steps; step++ {
+		now = now.Add(10 * time.Millisecond)
+		session.RecordAxis(axis, from+(to-from)*float64(step)/float64(steps), now)
+	}
+	return now
+}
+
+/* keeps the axis around the value with a little noise */
+func restAxis(session *ControllerManager_CalibrationSession, axis int, value float64, noise float64, duration time.Duration, start time.Time) time.Time {
+	now := start
+	for elapsed := time.Duration(0); elapsed < duration; elapsed += 100 * time.Millisecond {
+		now = now.Add(100 * time.Millisecond)
+		offset := noise
+		if (elapsed/(100*time.Millisecond))%2 == 0 {
+			offset = -noise
+		}
+		session.RecordAxis(axis, value+offset, now)
+	}
+	return now
+}
+
+func TestCalibrationSession_DetectsAxes(t *testing.T) {
+	session := NewCalibrationSession(newTestJoystick(0, "", ""), nil)
+	now := time.Now()
+
+	/* a lever resting at its minimum */
+	session.RecordAxis(0, -32700, now)
+	end := restAxis(session, 0, -32700, 50, 2*time.Second, now)
+	end = sweepAxis(session, 0, -32700, 32767, end)
+	end = sweepAxis(session, 0, 32767, -32768, end)
+
+	/* a lever resting at its maximum (eg: a brake lever) */
+	session.RecordAxis(1, 32700, now)
+	end = restAxis(session, 1, 32700, 20, 2*time.Second, end)
+	end = sweepAxis(session, 1, 32700, -32768, end)
+	end = sweepAxis(session, 1, -32768, 32767, end)
+
+	/* a joystick axis resting in the middle */
+	session.RecordAxis(2, 0, now)
+	end = restAxis(session, 2, 0, 200, 2*time.Second, end)
+	end = sweepAxis(session, 2, 0, 32767, end)
+	end = sweepAxis(session, 2, 32767, -32768, end)
+	end = sweepAxis(session, 2, -32768, 0, end)
+	end = restAxis(session, 2, 0, 200, 500*time.Millisecond, end)
+
+	/* noise from an unused axis */
+	session.RecordAxis(3, 100, now)
+	session.RecordAxis(3, 300, end)
+
+	session.HandleEvent(&sdl.JoyButtonEvent{Button: 4, State: sdl.PRESSED}, end)
+	session.HandleEvent(&sdl.JoyButtonEvent{Button: 2, State: sdl.RELEASED}, end)
+	session.HandleEvent(&sdl.JoyHatEvent{Hat: 0, Value: sdl.HAT_UP}, end)
+	session.HandleEvent(&sdl.JoyHatEvent{Hat: 0, Value: sdl.HAT_CENTERED}, end)
+	session.HandleEvent(&sdl.JoyHatEvent{Hat: 0, Value: sdl.HAT_LEFTUP}, end)
+
+	result, err := session.Result(end.Add(100 * time.Millisecond))
+	require.NoError(t, err)
+	require.Len(t, result.Axes, 3)
+
+	lever := result.Axes[0]
+	assert.Equal(t, "Axis1", lever.Name)
+	assert.True(t, lever.IdleDetected)
+	assert.False(t, lever.Inverted)
+	assert.False(t, lever.Centered)
+	assert.Equal(t, float64(-32768), lever.Min)
+	assert.Equal(t, float64(32767), lever.Max)
+	assert.InDelta(t, -32700, lever.Idle, 1)
+	assert.InDelta(t, 50, lever.Noise, 1)
+	assert.InDelta(t, 100, lever.Deadzone, 1)
+	assert.Empty(t, lever.Warnings)
+
+	brake := result.Axes[1]
+	assert.True(t, brake.Inverted)
+	assert.Equal(t, float64(-32767), brake.Min)
+	assert.Equal(t, float64(32768), brake.Max)
+	assert.InDelta(t, -32700, brake.Idle, 1)
+
+	joystick := result.Axes[2]
+	assert.True(t, joystick.Centered)
+	assert.False(t, joystick.Inverted)
+	assert.InDelta(t, 0, joystick.Idle, 1)
+
+	hat_up, hat_up_left := sdl_mgr.SDLMgr_HatDirection_Up, sdl_mgr.SDLMgr_HatDirection_UpLeft
+
+	assert.Equal(t, []config.Config_Controller_SDLMap_Control{
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Axis, Index: 0, Name: "Axis1"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Axis, Index: 1, Name: "Axis2"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Axis, Index: 2, Name: "Axis3"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Button, Index: 4, Name: "Button5"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Hat, Index: 0, Name: "Hat1Up", Direction: &hat_up},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Hat, Index: 0, Name: "Hat1UpLeft", Direction: &hat_up_left},
+	}, result.SDLMapping.Data)
+	require.Len(t, result.Calibration.Data, 3)
+	assert.True(t, *result.Calibration.Data[1].Invert)
+	assert.Equal(t, "1234:5678", result.Calibration.UsbID)
+}
+
+func TestCalibrationSession_WarnsWithoutIdleAndRange(t *testing.T) {
+	session := NewCalibrationSession(newTestJoystick(0, "", ""), nil)
+	now := time.Now()
+	end := sweepAxis(session, 0, -10000, 10000, now)
+
+	result, err := session.Result(end)
+	require.NoError(t, err)
+	require.Len(t, result.Axes, 1)
+	assert.False(t, result.Axes[0].IdleDetected)
+	assert.Len(t, result.Axes[0].Warnings, 2)
+
+	empty_result, err := NewCalibrationSession(newTestJoystick(0, "", ""), nil).Result(now)
+	require.NoError(t, err)
+	assert.Len(t, empty_result.Warnings, 1)
+}
+
+func TestCalibrationSession_KeepsExistingNames(t *testing.T) {
+	session := NewCalibrationSession(newTestJoystick(0, "", ""), &config.Config_Controller_SDLMap{
+		Name:  "Throttle Quadrant",
+		UsbID: "1234:5678",
+		Data: []config.Config_Controller_SDLMap_Control{
+			{Kind: sdl_mgr.SDLMgr_Control_Kind_Button, Index: 0, Name: "Button2"},
+			{Kind: sdl_mgr.SDLMgr_Control_Kind_Axis, Index: 0, Name: "Throttle"},
+		},
+	})
+	now := time.Now()
+	session.HandleEvent(&sdl.JoyButtonEvent{Button: 0, State: sdl.PRESSED}, now)
+	session.HandleEvent(&sdl.JoyButtonEvent{Button: 1, State: sdl.PRESSED}, now)
+	sweepAxis(session, 0, -32768, 32767, now)
+
+	result, err := session.Result(now)
+	require.NoError(t, err)
+	assert.Equal(t, "Throttle Quadrant", result.SDLMapping.Name)
+	assert.Equal(t, []config.Config_Controller_SDLMap_Control{
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Axis, Index: 0, Name: "Throttle"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Button, Index: 0, Name: "Button2"},
+		{Kind: sdl_mgr.SDLMgr_Control_Kind_Button, Index: 1, Name: "Button2_2"},
+	}, result.SDLMapping.Data)
+}
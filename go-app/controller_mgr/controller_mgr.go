@@ -2,6 +2,9 @@ package controller_mgr
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 	"tsw_controller_app/config"
 	"tsw_controller_app/logger"
@@ -38,6 +41,8 @@ type ControllerManager_Controller_ControlState_DirectionChangeMarker struct {
 	Direction int8
 	/* the value at which the direction changed */
 	ChangeValue float64
+	/* the furthest value reached in the current direction; the direction changes once the value moved back from it by more than the threshold */
+	PeakValue float64
 }
 
 type ControllerManager_Controller_ControlStateValues struct {
@@ -51,6 +56,7 @@ type ControllerManager_Controller_ControlStateValues struct {
 
 type ControllerManager_Controller_ControlState struct {
 	Direction ControllerManager_Controller_ControlState_DirectionChangeMarker
+	Motion    ControllerManager_Controller_ControlState_Motion
 	/* the normalized value states are in 0-1 format */
 	NormalizedValues ControllerManager_Controller_ControlStateValues
 	/* the raw values are in their raw value format coming from sdl */
@@ -67,6 +73,10 @@ type ControllerManager_Controller_Control struct {
 	SDLMapping  config.Config_Controller_SDLMap_Control
 	Calibration config.Config_Controller_CalibrationData
 	State       ControllerManager_Controller_ControlState
+	/* filters the raw values of an axis; shared between the copies of the control */
+	Filter *ControllerManager_InputFilter
+	/* computes the value of a derived control; nil for the controls reported by SDL */
+	Derived *ControllerManager_DerivedControl
 }
 
 type ControllerManager_ConfiguredController struct {
@@ -86,6 +96,10 @@ type ControllerManager_Config struct {
 	SDLMappingsByName   *map_utils.LockMap[string, config.Config_Controller_SDLMap]
 	SDLMappingsByUsbID  *map_utils.LockMap[string, config.Config_Controller_SDLMap]
 	CalibrationsByUsbID *map_utils.LockMap[string, config.Config_Controller_Calibration]
+	/* calibrations for a single device; take precedence over the calibrations by USB ID */
+	CalibrationsByGUID *map_utils.LockMap[JoystickGUIDString, config.Config_Controller_Calibration]
+	/* user assigned device aliases keyed by the hardware key of the device */
+	DeviceAliases *map_utils.LockMap[string, string]
 }
 
 type ControllerManager struct {
@@ -94,6 +108,10 @@ type ControllerManager struct {
 	Config                  ControllerManager_Config
 	ConfiguredControllers   *map_utils.LockMap[JoystickGUIDString, ControllerManager_ConfiguredController]
 	UnconfiguredControllers *map_utils.LockMap[JoystickGUIDString, ControllerManager_UnconfiguredController]
+	/* the connected devices keyed by their SDL instance ID */
+	Devices *map_utils.LockMap[int, ControllerManager_Device]
+	/* guards resolving device identities against concurrent device changes */
+	identity_mutex sync.Mutex
 
 	RawEventChannels          *pubsub_utils.PubSubSlice[ControllerManager_RawEvent]
 	ChangeEventChannels       *pubsub_utils.PubSubSlice[ControllerManager_Control_ChangeEvent]
@@ -104,17 +122,42 @@ func (ctrl *ControllerManager_Controller_Control) Reset() {
 	switch ctrl.SDLMapping.Kind {
 	case sdl_mgr.SDLMgr_Control_Kind_Axis:
 		axis_value := ctrl.Joystick.InternalJoystick.Axis(ctrl.SDLMapping.Index)
+		if ctrl.Filter != nil {
+			ctrl.Filter.Reset(float64(axis_value), time.Now())
+		}
 		ctrl.UpdateValue(float64(axis_value), true)
 	case sdl_mgr.SDLMgr_Control_Kind_Button:
 		button_value := int(ctrl.Joystick.InternalJoystick.Button(ctrl.SDLMapping.Index))
 		ctrl.UpdateValue(float64(int(button_value)), true)
 	case sdl_mgr.SDLMgr_Control_Kind_Hat:
-		hat_value := int(ctrl.Joystick.InternalJoystick.Hat(ctrl.SDLMapping.Index))
-		ctrl.UpdateValue(float64(int(hat_value)), true)
+		hat_value := ctrl.Joystick.InternalJoystick.Hat(ctrl.SDLMapping.Index)
+		ctrl.UpdateValue(ctrl.SDLMapping.RawHatValue(hat_value), true)
+	case sdl_mgr.SDLMgr_Control_Kind_ControllerAxis:
+		axis_value := gameControllerValue(ctrl.Joystick, ctrl.SDLMapping.Kind, ctrl.SDLMapping.Index)
+		if ctrl.Filter != nil {
+			ctrl.Filter.Reset(axis_value, time.Now())
+		}
+		ctrl.UpdateValue(axis_value, true)
+	case sdl_mgr.SDLMgr_Control_Kind_ControllerButton:
+		ctrl.UpdateValue(gameControllerValue(ctrl.Joystick, ctrl.SDLMapping.Kind, ctrl.SDLMapping.Index), true)
 	}
 }
 
 func (ctrl *ControllerManager_Controller_Control) UpdateValue(value float64, is_reset bool) {
+	ctrl.updateState(value, is_reset, time.Now())
+	ctrl.Manager.ChangeEventChannels.EmitTimeout(time.Second, ControllerManager_Control_ChangeEvent{
+		Joystick:     ctrl.Joystick,
+		Controller:   ctrl.Controller,
+		Control:      ctrl,
+		ControlName:  ctrl.Name,
+		ControlState: ctrl.State,
+	})
+}
+
+func (ctrl *ControllerManager_Controller_Control) updateState(value float64, is_reset bool, now time.Time) {
+	/* the normalized value only changes outside of the deadzone */
+	last_normalized_value := ctrl.State.NormalizedValues.Value
+
 	/* update raw values */
 	if is_reset {
 		ctrl.State.RawValues.PreviousValue = value
@@ -137,41 +180,44 @@ func (ctrl *ControllerManager_Controller_Control) UpdateValue(value float64, is_
 		ctrl.State.NormalizedValues.Value = rounded_value
 	}
 
-	/* update direction */
+	/* update direction and motion */
 	if is_reset {
 		ctrl.State.Direction = ControllerManager_Controller_ControlState_DirectionChangeMarker{
 			Direction:   0,
 			ChangeValue: ctrl.State.NormalizedValues.Value,
+			PeakValue:   ctrl.State.NormalizedValues.Value,
 		}
 	} else {
-		last_direction_change_value := ctrl.State.Direction.ChangeValue
-		value_diff := ctrl.State.NormalizedValues.Value - last_direction_change_value
-		if value_diff > DIRECTION_CHANGE_THRESHOLD {
-			ctrl.State.Direction = ControllerManager_Controller_ControlState_DirectionChangeMarker{
-				Direction:   1,
-				ChangeValue: ctrl.State.NormalizedValues.Value,
-			}
-		} else if value_diff < DIRECTION_CHANGE_THRESHOLD {
-			ctrl.State.Direction = ControllerManager_Controller_ControlState_DirectionChangeMarker{
-				Direction:   -1,
-				ChangeValue: ctrl.State.NormalizedValues.Value,
-			}
-		}
+		ctrl.State.Direction.update(ctrl.State.NormalizedValues.Value)
 	}
+	ctrl.State.Motion.update(ctrl.State.NormalizedValues.Value, last_normalized_value, is_reset, now)
+}
 
-	ctrl.Manager.ChangeEventChannels.EmitTimeout(time.Second, ControllerManager_Control_ChangeEvent{
-		Joystick:     ctrl.Joystick,
-		Controller:   ctrl.Controller,
-		Control:      ctrl,
-		ControlName:  ctrl.Name,
-		ControlState: ctrl.State,
-	})
+/* filters the raw axis value and only updates the value when the filter publishes it */
+func (ctrl *ControllerManager_Controller_Control) updateFilteredValue(value float64, now time.Time) {
+	if ctrl.Filter == nil {
+		ctrl.UpdateValue(value, false)
+		return
+	}
+	if filtered_value, should_publish := ctrl.Filter.Apply(value, now); should_publish {
+		ctrl.UpdateValue(filtered_value, false)
+	}
+}
+
+/* publishes the value of a filter which is still smoothing or was held back by the minimum interval */
+func (ctrl *ControllerManager_Controller_Control) settleFilter(now time.Time) {
+	if ctrl.Filter == nil || ctrl.Filter.IsSettled() {
+		return
+	}
+	if filtered_value, should_publish := ctrl.Filter.Settle(now); should_publish {
+		ctrl.UpdateValue(filtered_value, false)
+	}
 }
 
 func (ctrl *ControllerManager_Controller_Control) ProcessEvent(event sdl.Event) {
 	switch e := event.(type) {
 	case *sdl.JoyAxisEvent:
-		ctrl.UpdateValue(float64(e.Value), false)
+		ctrl.updateFilteredValue(float64(e.Value), time.Now())
 	case *sdl.JoyButtonEvent:
 		switch e.State {
 		case sdl.PRESSED:
@@ -180,7 +226,21 @@ func (ctrl *ControllerManager_Controller_Control) ProcessEvent(event sdl.Event)
 			ctrl.UpdateValue(0.0, false)
 		}
 	case *sdl.JoyHatEvent:
-		ctrl.UpdateValue(float64(e.Value), false)
+		hat_value := ctrl.SDLMapping.RawHatValue(e.Value)
+		/* every direction of the hat receives the event; only the directions which were entered or left changed */
+		if ctrl.SDLMapping.Direction != nil && hat_value == ctrl.State.RawValues.Value {
+			return
+		}
+		ctrl.UpdateValue(hat_value, false)
+	case *sdl.ControllerAxisEvent:
+		ctrl.updateFilteredValue(float64(e.Value), time.Now())
+	case *sdl.ControllerButtonEvent:
+		switch e.State {
+		case sdl.PRESSED:
+			ctrl.UpdateValue(1.0, false)
+		case sdl.RELEASED:
+			ctrl.UpdateValue(0.0, false)
+		}
 	}
 }
 
@@ -207,7 +267,30 @@ func (controller *ControllerManager_ConfiguredController) ProcessEvent(event sdl
 			}
 			return maybe_hat
 		})
+	case *sdl.ControllerAxisEvent:
+		controller.Controls.ForEachMap(func(maybe_axis ControllerManager_Controller_Control, _ string) ControllerManager_Controller_Control {
+			if maybe_axis.SDLMapping.Kind == sdl_mgr.SDLMgr_Control_Kind_ControllerAxis && maybe_axis.SDLMapping.Index == int(e.Axis) {
+				maybe_axis.ProcessEvent(event)
+			}
+			return maybe_axis
+		})
+	case *sdl.ControllerButtonEvent:
+		controller.Controls.ForEachMap(func(maybe_button ControllerManager_Controller_Control, _ string) ControllerManager_Controller_Control {
+			if maybe_button.SDLMapping.Kind == sdl_mgr.SDLMgr_Control_Kind_ControllerButton && maybe_button.SDLMapping.Index == int(e.Button) {
+				maybe_button.ProcessEvent(event)
+			}
+			return maybe_button
+		})
 	}
+	controller.updateDerivedControls(time.Now())
+}
+
+func (controller *ControllerManager_ConfiguredController) settleFilters(now time.Time) {
+	controller.Controls.ForEachMap(func(control ControllerManager_Controller_Control, _ string) ControllerManager_Controller_Control {
+		control.settleFilter(now)
+		return control
+	})
+	controller.updateDerivedControls(now)
 }
 
 func New(sdlmgr *sdl_mgr.SDLMgr) *ControllerManager {
@@ -217,9 +300,12 @@ func New(sdlmgr *sdl_mgr.SDLMgr) *ControllerManager {
 			SDLMappingsByName:   map_utils.NewLockMap[string, config.Config_Controller_SDLMap](),
 			SDLMappingsByUsbID:  map_utils.NewLockMap[string, config.Config_Controller_SDLMap](),
 			CalibrationsByUsbID: map_utils.NewLockMap[string, config.Config_Controller_Calibration](),
+			CalibrationsByGUID:  map_utils.NewLockMap[JoystickGUIDString, config.Config_Controller_Calibration](),
+			DeviceAliases:       map_utils.NewLockMap[string, string](),
 		},
 		ConfiguredControllers:   map_utils.NewLockMap[JoystickGUIDString, ControllerManager_ConfiguredController](),
 		UnconfiguredControllers: map_utils.NewLockMap[JoystickGUIDString, ControllerManager_UnconfiguredController](),
+		Devices:                 map_utils.NewLockMap[int, ControllerManager_Device](),
 
 		RawEventChannels:          pubsub_utils.NewPubSubSlice[ControllerManager_RawEvent](),
 		ChangeEventChannels:       pubsub_utils.NewPubSubSlice[ControllerManager_Control_ChangeEvent](),
@@ -258,7 +344,9 @@ func (mgr *ControllerManager) ConfigureJoystick(joystick *sdl_mgr.SDLMgr_Joystic
 		case sdl_mgr.SDLMgr_Control_Kind_Button:
 			current_raw_value = float64(joystick.InternalJoystick.Button(control.Index))
 		case sdl_mgr.SDLMgr_Control_Kind_Hat:
-			current_raw_value = float64(joystick.InternalJoystick.Hat(control.Index))
+			current_raw_value = control.RawHatValue(joystick.InternalJoystick.Hat(control.Index))
+		case sdl_mgr.SDLMgr_Control_Kind_ControllerAxis, sdl_mgr.SDLMgr_Control_Kind_ControllerButton:
+			current_raw_value = gameControllerValue(joystick, control.Kind, control.Index)
 		}
 		current_normal_value := calibration_data.NormalizeRawValue(current_raw_value).Value
 
@@ -271,10 +359,16 @@ func (mgr *ControllerManager) ConfigureJoystick(joystick *sdl_mgr.SDLMgr_Joystic
 			Index:       control.Index,
 			SDLMapping:  control,
 			Calibration: calibration_data,
+			Filter:      newControlFilter(control.Kind, calibration_data),
 			State: ControllerManager_Controller_ControlState{
 				Direction: ControllerManager_Controller_ControlState_DirectionChangeMarker{
 					Direction:   0,
-					ChangeValue: current_raw_value,
+					ChangeValue: current_normal_value,
+					PeakValue:   current_normal_value,
+				},
+				Motion: ControllerManager_Controller_ControlState_Motion{
+					LastMovedAt: time.Now(),
+					UpdatedAt:   time.Now(),
 				},
 				NormalizedValues: ControllerManager_Controller_ControlStateValues{
 					Value:         current_normal_value,
@@ -291,52 +385,201 @@ func (mgr *ControllerManager) ConfigureJoystick(joystick *sdl_mgr.SDLMgr_Joystic
 		control.Reset()
 		controller.Controls.Set(control.Name, control)
 	}
+	controller.configureDerivedControls(sdl_map, time.Now())
 
 	return controller
 }
 
+/* creates the filter of a calibrated axis; the range is converted back to raw values for inverted axes */
+func newControlFilter(kind sdl_mgr.SDLMgr_Control_Kind, calibration_data config.Config_Controller_CalibrationData) *ControllerManager_InputFilter {
+	is_axis := kind == sdl_mgr.SDLMgr_Control_Kind_Axis || kind == sdl_mgr.SDLMgr_Control_Kind_ControllerAxis
+	if !is_axis || !calibration_data.IsCalibrated || calibration_data.Filter == nil {
+		return nil
+	}
+	if calibration_data.Invert != nil && *calibration_data.Invert {
+		return NewInputFilter(*calibration_data.Filter, -calibration_data.Max, -calibration_data.Min)
+	}
+	return NewInputFilter(*calibration_data.Filter, calibration_data.Min, calibration_data.Max)
+}
+
+/* registers a mapping and calibration; calibrations with a GUID only apply to that single device */
 func (mgr *ControllerManager) RegisterConfig(sdl_map config.Config_Controller_SDLMap, calibration config.Config_Controller_Calibration) {
 	mgr.Config.SDLMappingsByName.Set(sdl_map.Name, sdl_map)
 	mgr.Config.SDLMappingsByUsbID.Set(sdl_map.UsbID, sdl_map)
-	mgr.Config.CalibrationsByUsbID.Set(calibration.UsbID, calibration)
-
-	didConfigureJoystick := false
-
-	/* configure unconfigured controller */
-	mgr.UnconfiguredControllers.Mutate(func(unconfigured ControllerManager_UnconfiguredController, guid JoystickGUIDString) map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController] {
-		if unconfigured.Joystick.ToString() == sdl_map.UsbID {
-			configured_controller := mgr.ConfigureJoystick(unconfigured.Joystick, sdl_map, calibration)
-			mgr.ConfiguredControllers.Set(guid, configured_controller)
-			didConfigureJoystick = true
-			return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController]{
-				Action: map_utils.LockMapMutateActionType_Delete,
-				Key:    guid,
-			}
+	if calibration.GUID != nil && *calibration.GUID != "" {
+		mgr.Config.CalibrationsByGUID.Set(*calibration.GUID, calibration)
+	} else {
+		mgr.Config.CalibrationsByUsbID.Set(calibration.UsbID, calibration)
+	}
+
+	did_configure_joystick := false
+	for _, device := range mgr.getDevices() {
+		if device.Joystick.ToString() == sdl_map.UsbID {
+			mgr.configureDevice(device.Joystick)
+			did_configure_joystick = true
 		}
-		return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController]{
-			Action: map_utils.LockMapMutateActionType_Noop,
+	}
+
+	if did_configure_joystick {
+		mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
+	}
+}
+
+/* the SDL mapping for the USB ID of the device; game controllers without an SDL mapping file use the built-in mapping */
+func (mgr *ControllerManager) FindSDLMapping(joystick *sdl_mgr.SDLMgr_Joystick) (config.Config_Controller_SDLMap, bool) {
+	if sdl_map, has_sdl_map := mgr.Config.SDLMappingsByUsbID.Get(joystick.ToString()); has_sdl_map {
+		return sdl_map, true
+	}
+	if joystick.InternalGameController != nil {
+		return BuiltinGamepadSDLMap(joystick), true
+	}
+	return config.Config_Controller_SDLMap{}, false
+}
+
+/* the calibration for the device itself or the shared calibration for its USB ID */
+func (mgr *ControllerManager) findCalibration(joystick *sdl_mgr.SDLMgr_Joystick) (config.Config_Controller_Calibration, bool) {
+	if calibration, has_calibration := mgr.Config.CalibrationsByGUID.Get(joystick.GUID); has_calibration {
+		return calibration, true
+	}
+	return mgr.Config.CalibrationsByUsbID.Get(joystick.ToString())
+}
+
+/* (re)configures a connected device as configured or unconfigured controller depending on the available configuration */
+func (mgr *ControllerManager) configureDevice(joystick *sdl_mgr.SDLMgr_Joystick) {
+	sdl_map, has_sdl_map := mgr.FindSDLMapping(joystick)
+	calibration, has_calibration := mgr.findCalibration(joystick)
+	/* the built-in mapping of game controllers comes with its calibration */
+	if _, has_sdl_map_file := mgr.Config.SDLMappingsByUsbID.Get(joystick.ToString()); !has_sdl_map_file && !has_calibration && has_sdl_map {
+		calibration, has_calibration = BuiltinGamepadCalibration(joystick), true
+	}
+	if has_sdl_map && has_calibration {
+		configured_controller := mgr.ConfigureJoystick(joystick, sdl_map, calibration)
+		mgr.ConfiguredControllers.Set(joystick.GUID, configured_controller)
+		mgr.UnconfiguredControllers.Delete(joystick.GUID)
+		return
+	}
+
+	unconfigured_controller := ControllerManager_UnconfiguredController{
+		Joystick:    joystick,
+		SDLMapping:  nil,
+		Calibration: nil,
+	}
+	if has_sdl_map {
+		unconfigured_controller.SDLMapping = &sdl_map
+	}
+	if has_calibration {
+		unconfigured_controller.Calibration = &calibration
+	}
+	mgr.UnconfiguredControllers.Set(joystick.GUID, unconfigured_controller)
+	mgr.ConfiguredControllers.Delete(joystick.GUID)
+}
+
+/* the connected devices sorted by model and ordinal */
+func (mgr *ControllerManager) getDevices() []ControllerManager_Device {
+	devices := []ControllerManager_Device{}
+	mgr.Devices.ForEach(func(device ControllerManager_Device, _ int) bool {
+		devices = append(devices, device)
+		return true
+	})
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Joystick.SDLGUID != devices[j].Joystick.SDLGUID {
+			return devices[i].Joystick.SDLGUID < devices[j].Joystick.SDLGUID
 		}
+		return devices[i].Ordinal < devices[j].Ordinal
 	})
+	return devices
+}
 
-	/* replace configured controller */
-	mgr.ConfiguredControllers.Mutate(func(configured ControllerManager_ConfiguredController, guid JoystickGUIDString) map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController] {
-		if configured.Joystick.ToString() == sdl_map.UsbID {
-			configured_controller := mgr.ConfigureJoystick(configured.Joystick, sdl_map, calibration)
-			didConfigureJoystick = true
-			return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController]{
-				Action: map_utils.LockMapMutateActionType_Replace,
-				Key:    guid,
-				Value:  configured_controller,
-			}
+func (mgr *ControllerManager) getAliases() map[string]string {
+	aliases := map[string]string{}
+	mgr.Config.DeviceAliases.ForEach(func(alias string, hardware_key string) bool {
+		aliases[hardware_key] = alias
+		return true
+	})
+	return aliases
+}
+
+func (mgr *ControllerManager) GetDevice(guid JoystickGUIDString) (ControllerManager_Device, bool) {
+	for _, device := range mgr.getDevices() {
+		if device.Joystick.GUID == guid {
+			return device, true
 		}
-		return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController]{
-			Action: map_utils.LockMapMutateActionType_Noop,
+	}
+	return ControllerManager_Device{}, false
+}
+
+func (mgr *ControllerManager) getJoystickByInstanceID(instance_id int) (*sdl_mgr.SDLMgr_Joystick, error) {
+	device, has_device := mgr.Devices.Get(instance_id)
+	if !has_device {
+		return nil, fmt.Errorf("no joystick connected with instance ID %d", instance_id)
+	}
+	return device.Joystick, nil
+}
+
+/*
+assigns an alias to a connected device
+for devices identified by their connection order the alias also becomes the identity so the
+configuration follows the alias; returns the devices which received a new GUID
+*/
+func (mgr *ControllerManager) SetDeviceAlias(guid JoystickGUIDString, alias string) ([]ControllerManager_DeviceRename, error) {
+	mgr.identity_mutex.Lock()
+	defer mgr.identity_mutex.Unlock()
+
+	device, has_device := mgr.GetDevice(guid)
+	if !has_device {
+		return nil, fmt.Errorf("controller %s is not connected", guid)
+	}
+
+	aliases := assignDeviceAlias(mgr.getAliases(), device.Joystick.SDLGUID, device.HardwareKey, alias)
+	mgr.Config.DeviceAliases.Clear()
+	for hardware_key, alias := range aliases {
+		mgr.Config.DeviceAliases.Set(hardware_key, alias)
+	}
+
+	renames := mgr.refreshDeviceIdentities(device.Joystick.SDLGUID)
+	mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
+	return renames, nil
+}
+
+/* re-resolves the identities of the connected devices of a model; expects the identity mutex to be held */
+func (mgr *ControllerManager) refreshDeviceIdentities(sdl_guid sdl_mgr.SDLMgr_Guid_Str) []ControllerManager_DeviceRename {
+	aliases := mgr.getAliases()
+	used_guids := map[JoystickGUIDString]bool{}
+	renames := []ControllerManager_DeviceRename{}
+	renamed_devices := []ControllerManager_Device{}
+	for _, device := range mgr.getDevices() {
+		if device.Joystick.SDLGUID != sdl_guid {
+			continue
 		}
-	})
 
-	if didConfigureJoystick {
-		mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
+		resolved_device, resolved_guid := resolveDeviceIdentity(device.Joystick, device.Ordinal, aliases, func(guid JoystickGUIDString) bool {
+			return used_guids[guid]
+		})
+		used_guids[resolved_guid] = true
+		if resolved_guid == device.Joystick.GUID {
+			mgr.Devices.Set(device.Joystick.InstanceID, resolved_device)
+			continue
+		}
+
+		/* the joystick is shared with the emitted events so the GUID is changed on a copy */
+		joystick := *device.Joystick
+		joystick.GUID = resolved_guid
+		resolved_device.Joystick = &joystick
+		renames = append(renames, ControllerManager_DeviceRename{From: device.Joystick.GUID, To: resolved_guid})
+		renamed_devices = append(renamed_devices, resolved_device)
 	}
+
+	/* devices may swap GUIDs so all previous entries are removed before adding the new ones */
+	for _, rename := range renames {
+		mgr.ConfiguredControllers.Delete(rename.From)
+		mgr.UnconfiguredControllers.Delete(rename.From)
+	}
+	for _, device := range renamed_devices {
+		logger.Logger.Info("[ControllerManager::refreshDeviceIdentities] device identity changed", "name", device.Joystick.Name, "guid", device.Joystick.GUID, "source", device.IdentitySource)
+		mgr.Devices.Set(device.Joystick.InstanceID, device)
+		mgr.configureDevice(device.Joystick)
+	}
+	return renames
 }
 
 func (mgr *ControllerManager) Handler_JoyDeviceAdded(event *sdl.JoyDeviceAddedEvent) error {
@@ -351,69 +594,47 @@ func (mgr *ControllerManager) Handler_JoyDeviceAdded(event *sdl.JoyDeviceAddedEv
 		return err
 	}
 
-	sdl_map, has_sdl_map := mgr.Config.SDLMappingsByUsbID.Get(joystick.ToString())
-	calibration, has_calibration := mgr.Config.CalibrationsByUsbID.Get(joystick.ToString())
-	if has_sdl_map && has_calibration {
-		configured_controller := mgr.ConfigureJoystick(joystick, sdl_map, calibration)
-		mgr.ConfiguredControllers.Set(joystick.GUID, configured_controller)
-		mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
-	} else {
-		unconfigured_controller := ControllerManager_UnconfiguredController{
-			Joystick:    joystick,
-			SDLMapping:  nil,
-			Calibration: nil,
-		}
-		if has_sdl_map {
-			unconfigured_controller.SDLMapping = &sdl_map
-		}
-		if has_calibration {
-			unconfigured_controller.Calibration = &calibration
-		}
-		mgr.UnconfiguredControllers.Set(joystick.GUID, unconfigured_controller)
-		mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
+	mgr.identity_mutex.Lock()
+	devices := mgr.getDevices()
+	used_guids := map[JoystickGUIDString]bool{}
+	for _, device := range devices {
+		used_guids[device.Joystick.GUID] = true
 	}
+	device, guid := resolveDeviceIdentity(joystick, nextDeviceOrdinal(devices, joystick.SDLGUID), mgr.getAliases(), func(guid JoystickGUIDString) bool {
+		return used_guids[guid]
+	})
+	joystick.GUID = guid
+	mgr.Devices.Set(joystick.InstanceID, device)
+	mgr.configureDevice(joystick)
+	mgr.identity_mutex.Unlock()
 
+	logger.Logger.Info("[ControllerManager:Handler_JoyDeviceAdded] identified joy device", "name", joystick.Name, "guid", joystick.GUID, "source", device.IdentitySource)
+	mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
 	return nil
 }
 
+/* the removed event refers to the instance ID; the device index of the remaining devices shifts */
 func (mgr *ControllerManager) Handler_JoyDeviceRemoved(event *sdl.JoyDeviceRemovedEvent) error {
-	mgr.ConfiguredControllers.Mutate(func(configured_controller ControllerManager_ConfiguredController, guid JoystickGUIDString) map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController] {
-		if configured_controller.Joystick.Index == int(event.Which) {
-			logger.Logger.Info("[ControllerManager:Handler_JoyDeviceRemoved] Removing joy device", "name", configured_controller.Joystick.Name)
-			defer func() {
-				mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
-			}()
-			return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController]{
-				Action: map_utils.LockMapMutateActionType_Delete,
-				Key:    guid,
-			}
-		}
-		return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_ConfiguredController]{
-			Action: map_utils.LockMapMutateActionType_Noop,
-		}
-	})
+	mgr.identity_mutex.Lock()
+	device, has_device := mgr.Devices.Get(int(event.Which))
+	if !has_device {
+		mgr.identity_mutex.Unlock()
+		return fmt.Errorf("no joystick connected with instance ID %d", event.Which)
+	}
 
-	mgr.UnconfiguredControllers.Mutate(func(unconfigured_controller ControllerManager_UnconfiguredController, guid JoystickGUIDString) map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController] {
-		if unconfigured_controller.Joystick.Index == int(event.Which) {
-			logger.Logger.Info("[ControllerManager:Handler_JoyDeviceRemoved] Removing joy device", "name", unconfigured_controller.Joystick.Name)
-			defer func() {
-				mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
-			}()
-			return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController]{
-				Action: map_utils.LockMapMutateActionType_Delete,
-				Key:    guid,
-			}
-		}
-		return map_utils.LockMapMutateAction[JoystickGUIDString, ControllerManager_UnconfiguredController]{
-			Action: map_utils.LockMapMutateActionType_Noop,
-		}
-	})
+	logger.Logger.Info("[ControllerManager:Handler_JoyDeviceRemoved] Removing joy device", "name", device.Joystick.Name, "guid", device.Joystick.GUID)
+	mgr.Devices.Delete(int(event.Which))
+	mgr.ConfiguredControllers.Delete(device.Joystick.GUID)
+	mgr.UnconfiguredControllers.Delete(device.Joystick.GUID)
+	mgr.identity_mutex.Unlock()
 
+	device.Joystick.Close()
+	mgr.JoyDevicesUpdatedChannels.EmitTimeout(time.Second, ControllerManager_Control_JoyDevicesUpdated{})
 	return nil
 }
 
 func (mgr *ControllerManager) Handler_JoyAxisEvent(event *sdl.JoyAxisEvent) error {
-	joystick, err := mgr.SDL.GetJoystickByIndex(int(event.Which))
+	joystick, err := mgr.getJoystickByInstanceID(int(event.Which))
 	if err != nil {
 		logger.Logger.Error("[ControllerManager::Handler_JoyAxisEvent] could not get joystick", "error", err)
 		return err
@@ -435,7 +656,7 @@ func (mgr *ControllerManager) Handler_JoyAxisEvent(event *sdl.JoyAxisEvent) erro
 }
 
 func (mgr *ControllerManager) Handler_JoyButtonEvent(event *sdl.JoyButtonEvent) error {
-	joystick, err := mgr.SDL.GetJoystickByIndex(int(event.Which))
+	joystick, err := mgr.getJoystickByInstanceID(int(event.Which))
 	if err != nil {
 		logger.Logger.Error("[ControllerManager::Handler_JoyButtonEvent] could not get joystick", "error", err)
 		return err
@@ -460,7 +681,7 @@ func (mgr *ControllerManager) Handler_JoyButtonEvent(event *sdl.JoyButtonEvent)
 }
 
 func (mgr *ControllerManager) Handler_JoyHatEvent(event *sdl.JoyHatEvent) error {
-	joystick, err := mgr.SDL.GetJoystickByIndex(int(event.Which))
+	joystick, err := mgr.getJoystickByInstanceID(int(event.Which))
 	if err != nil {
 		logger.Logger.Error("[ControllerManager::Handler_JoyHatEvent] could not get joystick", "error", err)
 		return err
@@ -481,6 +702,50 @@ func (mgr *ControllerManager) Handler_JoyHatEvent(event *sdl.JoyHatEvent) error
 	return nil
 }
 
+func (mgr *ControllerManager) Handler_ControllerAxisEvent(event *sdl.ControllerAxisEvent) error {
+	joystick, err := mgr.getJoystickByInstanceID(int(event.Which))
+	if err != nil {
+		logger.Logger.Error("[ControllerManager::Handler_ControllerAxisEvent] could not get joystick", "error", err)
+		return err
+	}
+
+	/* the raw joystick events of the device are sent as well; no raw event needed */
+	configured, is_configured := mgr.ConfiguredControllers.Get(joystick.GUID)
+	if is_configured {
+		configured.ProcessEvent(event)
+	}
+
+	return nil
+}
+
+func (mgr *ControllerManager) Handler_ControllerButtonEvent(event *sdl.ControllerButtonEvent) error {
+	joystick, err := mgr.getJoystickByInstanceID(int(event.Which))
+	if err != nil {
+		logger.Logger.Error("[ControllerManager::Handler_ControllerButtonEvent] could not get joystick", "error", err)
+		return err
+	}
+
+	/* the raw joystick events of the device are sent as well; no raw event needed */
+	configured, is_configured := mgr.ConfiguredControllers.Get(joystick.GUID)
+	if is_configured {
+		configured.ProcessEvent(event)
+	}
+
+	return nil
+}
+
+/* rumbles the connected device with the GUID; see SDLMgr_Joystick.Rumble */
+func (mgr *ControllerManager) Rumble(guid JoystickGUIDString, low_frequency float64, high_frequency float64, duration time.Duration) error {
+	device, has_device := mgr.GetDevice(guid)
+	if !has_device {
+		return fmt.Errorf("no controller connected with GUID %s", guid)
+	}
+	if !device.Joystick.HasRumble() {
+		return fmt.Errorf("the controller %s does not support rumble", device.Joystick.Name)
+	}
+	return device.Joystick.Rumble(low_frequency, high_frequency, duration)
+}
+
 func (mgr *ControllerManager) Attach(ctx context.Context) context.CancelFunc {
 	ctx_with_cancel, cancel := context.WithCancel(ctx)
 
@@ -490,8 +755,12 @@ func (mgr *ControllerManager) Attach(ctx context.Context) context.CancelFunc {
 
 		/* returns a cancel but will be cancelled by it's parent context */
 		events_channel, _ := mgr.SDL.StartPolling(ctx_with_cancel)
+		filter_ticker := time.NewTicker(FILTER_TICK_INTERVAL)
+		defer filter_ticker.Stop()
 		for {
 			select {
+			case now := <-filter_ticker.C:
+				mgr.settleFilters(now)
 			case event := <-events_channel:
 				logger.Logger.Debug("[ControllerManager.Attach] Received SDL2 event", "event", event)
 				switch e := event.(type) {
@@ -511,6 +780,14 @@ func (mgr *ControllerManager) Attach(ctx context.Context) context.CancelFunc {
 					if e.GetTimestamp() > initial_events_threshold {
 						mgr.Handler_JoyHatEvent(e)
 					}
+				case *sdl.ControllerAxisEvent:
+					if e.GetTimestamp() > initial_events_threshold {
+						mgr.Handler_ControllerAxisEvent(e)
+					}
+				case *sdl.ControllerButtonEvent:
+					if e.GetTimestamp() > initial_events_threshold {
+						mgr.Handler_ControllerButtonEvent(e)
+					}
 				case *sdl.QuitEvent:
 					cancel()
 				}
@@ -522,6 +799,18 @@ func (mgr *ControllerManager) Attach(ctx context.Context) context.CancelFunc {
 	return cancel
 }
 
+/* the controllers are collected first so no lock is held while the change events are emitted */
+func (mgr *ControllerManager) settleFilters(now time.Time) {
+	controllers := []ControllerManager_ConfiguredController{}
+	mgr.ConfiguredControllers.ForEach(func(controller ControllerManager_ConfiguredController, _ JoystickGUIDString) bool {
+		controllers = append(controllers, controller)
+		return true
+	})
+	for _, controller := range controllers {
+		controller.settleFilters(now)
+	}
+}
+
 func (mgr *ControllerManager) SubscribeRaw() (chan ControllerManager_RawEvent, func()) {
 	return mgr.RawEventChannels.Subscribe()
 }
@@ -0,0 +1,190 @@
+package controller_mgr
+
+import (
+	"math"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/math_utils"
+	"tsw_controller_app/sdl_mgr"
+)
+
+/* the kind of the controls computed from other controls instead of being reported by SDL */
+const ControllerManager_Control_Kind_Derived sdl_mgr.SDLMgr_Control_Kind = "derived"
+
+/*
+the state of a derived control; shared between the copies of the control
+*/
+type ControllerManager_DerivedControl struct {
+	Config config.Config_Controller_SDLMap_DerivedControl
+	/* the zone of the control for zones */
+	Zone *config.Config_Controller_SDLMap_DerivedControl_Zone
+
+	/* latch */
+	is_pressed bool
+	is_latched bool
+
+	/* button_axis */
+	value      float64
+	direction  float64
+	updated_at time.Time
+}
+
+func NewDerivedControl(derived config.Config_Controller_SDLMap_DerivedControl, zone *config.Config_Controller_SDLMap_DerivedControl_Zone, now time.Time) *ControllerManager_DerivedControl {
+	return &ControllerManager_DerivedControl{
+		Config:     derived,
+		Zone:       zone,
+		value:      derived.GetInitial(),
+		updated_at: now,
+	}
+}
+
+/* the name of the control */
+func (d *ControllerManager_DerivedControl) Name() string {
+	if d.Zone != nil {
+		return d.Zone.Name
+	}
+	return d.Config.Name
+}
+
+/*
+computes the value of the derived control from the normalized values of its inputs (in the order of the inputs);
+sums and differences are clamped to the normalized range [-1, 1]
+*/
+func (d *ControllerManager_DerivedControl) Evaluate(inputs []float64, now time.Time) float64 {
+	switch d.Config.Kind {
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_Sum:
+		sum := 0.0
+		for _, input := range inputs {
+			sum += input
+		}
+		return math_utils.Clamp(sum, -1.0, 1.0)
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_Average:
+		sum := 0.0
+		for _, input := range inputs {
+			sum += input
+		}
+		return sum / float64(len(inputs))
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_Difference:
+		return math_utils.Clamp(inputs[0]-inputs[1], -1.0, 1.0)
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_Zones:
+		if d.Zone == nil {
+			return 0.0
+		}
+		value := inputs[0]
+		if value >= d.Zone.Min && (value < d.Zone.Max || (d.Zone.Max >= 1.0 && value <= d.Zone.Max)) {
+			return 1.0
+		}
+		return 0.0
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_Latch:
+		is_pressed := inputs[0] >= d.Config.GetThreshold()
+		if is_pressed && !d.is_pressed {
+			d.is_latched = !d.is_latched
+		}
+		d.is_pressed = is_pressed
+		if d.is_latched {
+			return 1.0
+		}
+		return 0.0
+	case config.Config_Controller_SDLMap_DerivedControl_Kind_ButtonAxis:
+		/* ramp with the direction held until now before taking the new direction */
+		elapsed := now.Sub(d.updated_at).Seconds()
+		if elapsed > 0 && d.Config.RampRate != nil {
+			d.value = math.Max(0.0, math.Min(1.0, d.value+d.direction*(*d.Config.RampRate)*elapsed))
+		}
+		d.updated_at = now
+
+		d.direction = 0.0
+		if inputs[0] >= d.Config.GetThreshold() {
+			d.direction -= 1.0
+		}
+		if inputs[1] >= d.Config.GetThreshold() {
+			d.direction += 1.0
+		}
+		return d.value
+	}
+	return 0.0
+}
+
+/*
+creates the controls of the derived controls of the SDL mapping; their inputs have to be created already
+*/
+func (controller *ControllerManager_ConfiguredController) configureDerivedControls(sdl_map config.Config_Controller_SDLMap, now time.Time) {
+	for _, derived := range sdl_map.Derived {
+		if derived.Kind == config.Config_Controller_SDLMap_DerivedControl_Kind_Zones {
+			for zone_index := range derived.Zones {
+				controller.addDerivedControl(NewDerivedControl(derived, &derived.Zones[zone_index], now), now)
+			}
+		} else {
+			controller.addDerivedControl(NewDerivedControl(derived, nil, now), now)
+		}
+	}
+}
+
+func (controller *ControllerManager_ConfiguredController) addDerivedControl(derived *ControllerManager_DerivedControl, now time.Time) {
+	name := derived.Name()
+	control := ControllerManager_Controller_Control{
+		Manager:    controller.Manager,
+		Joystick:   controller.Joystick,
+		Controller: controller,
+		Name:       name,
+		Kind:       ControllerManager_Control_Kind_Derived,
+		Index:      -1,
+		SDLMapping: config.Config_Controller_SDLMap_Control{
+			Kind:  ControllerManager_Control_Kind_Derived,
+			Index: -1,
+			Name:  name,
+		},
+		/* derived values are already normalized */
+		Calibration: config.Config_Controller_CalibrationData{Id: name, IsCalibrated: false},
+		Derived:     derived,
+	}
+	control.State.Motion = ControllerManager_Controller_ControlState_Motion{LastMovedAt: now, UpdatedAt: now}
+	control.UpdateValue(derived.Evaluate(controller.derivedInputValues(derived, controller.controlValues()), now), true)
+	controller.Controls.Set(name, control)
+}
+
+/* the normalized values of the controls reported by SDL */
+func (controller *ControllerManager_ConfiguredController) controlValues() map[string]float64 {
+	values := map[string]float64{}
+	controller.Controls.ForEach(func(control ControllerManager_Controller_Control, name string) bool {
+		if control.Derived == nil {
+			values[name] = control.State.NormalizedValues.Value
+		}
+		return true
+	})
+	return values
+}
+
+func (controller *ControllerManager_ConfiguredController) derivedInputValues(derived *ControllerManager_DerivedControl, values map[string]float64) []float64 {
+	inputs := make([]float64, len(derived.Config.Inputs))
+	for index, input := range derived.Config.Inputs {
+		inputs[index] = values[input]
+	}
+	return inputs
+}
+
+/*
+re-evaluates the derived controls from the current values of their inputs (and a button_axis from the time its buttons
+were held); only changed values are published
+*/
+func (controller *ControllerManager_ConfiguredController) updateDerivedControls(now time.Time) {
+	has_derived_controls := false
+	controller.Controls.ForEach(func(control ControllerManager_Controller_Control, _ string) bool {
+		has_derived_controls = control.Derived != nil
+		return !has_derived_controls
+	})
+	if !has_derived_controls {
+		return
+	}
+
+	values := controller.controlValues()
+	controller.Controls.ForEachMap(func(control ControllerManager_Controller_Control, _ string) ControllerManager_Controller_Control {
+		if control.Derived == nil {
+			return control
+		}
+		if value := control.Derived.Evaluate(controller.derivedInputValues(control.Derived, values), now); value != control.State.RawValues.Value {
+			control.UpdateValue(value, false)
+		}
+		return control
+	})
+}
@@ -0,0 +1,114 @@
+package controller_mgr
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/map_utils"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDerivedTestController(sdl_map config.Config_Controller_SDLMap, now time.Time) *ControllerManager_ConfiguredController {
+	mgr := New(sdl_mgr.New())
+	controller := &ControllerManager_ConfiguredController{
+		Manager:  mgr,
+		Joystick: newTestJoystick(0, "", ""),
+		Controls: map_utils.NewLockMap[string, ControllerManager_Controller_Control](),
+	}
+	for _, control := range sdl_map.Data {
+		test_control := ControllerManager_Controller_Control{
+			Manager:     mgr,
+			Joystick:    controller.Joystick,
+			Controller:  controller,
+			Name:        control.Name,
+			Kind:        control.Kind,
+			Index:       control.Index,
+			SDLMapping:  control,
+			Calibration: config.Config_Controller_CalibrationData{Id: control.Name},
+		}
+		test_control.updateState(0, true, now)
+		controller.Controls.Set(control.Name, test_control)
+	}
+	controller.configureDerivedControls(sdl_map, now)
+	return controller
+}
+
+func setDerivedTestInput(controller *ControllerManager_ConfiguredController, name string, value float64, now time.Time) {
+	control, _ := controller.Controls.Get(name)
+	control.updateState(value, false, now)
+	controller.Controls.Set(name, control)
+	controller.updateDerivedControls(now)
+}
+
+func derivedTestValue(t *testing.T, controller *ControllerManager_ConfiguredController, name string) float64 {
+	control, has_control := controller.Controls.Get(name)
+	require.True(t, has_control, name)
+	return control.State.NormalizedValues.Value
+}
+
+func TestDerivedControls(t *testing.T) {
+	ramp_rate := 0.5
+	sdl_map := config.Config_Controller_SDLMap{
+		Name:  "Throttle Quadrant",
+		UsbID: "1234:5678",
+		Data: []config.Config_Controller_SDLMap_Control{
+			{Kind: "axis", Index: 0, Name: "Left"},
+			{Kind: "axis", Index: 1, Name: "Right"},
+			{Kind: "button", Index: 0, Name: "Down"},
+			{Kind: "button", Index: 1, Name: "Up"},
+		},
+		Derived: []config.Config_Controller_SDLMap_DerivedControl{
+			{Kind: "average", Name: "Throttle", Inputs: []string{"Left", "Right"}},
+			{Kind: "difference", Name: "Split", Inputs: []string{"Left", "Right"}},
+			{Kind: "sum", Name: "Combined", Inputs: []string{"Left", "Right"}},
+			{Kind: "zones", Inputs: []string{"Left"}, Zones: []config.Config_Controller_SDLMap_DerivedControl_Zone{
+				{Name: "Idle", Min: 0, Max: 0.1},
+				{Name: "Full", Min: 0.9, Max: 1},
+			}},
+			{Kind: "button_axis", Name: "Trim", Inputs: []string{"Down", "Up"}, RampRate: &ramp_rate},
+			{Kind: "latch", Name: "Lights", Inputs: []string{"Up"}},
+		},
+	}
+	require.NoError(t, sdl_map.ValidateDerived())
+
+	now := time.Now()
+	controller := newDerivedTestController(sdl_map, now)
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Idle"))
+	assert.Equal(t, 0.0, derivedTestValue(t, controller, "Full"))
+
+	setDerivedTestInput(controller, "Left", 1.0, now)
+	setDerivedTestInput(controller, "Right", 0.5, now)
+	assert.Equal(t, 0.75, derivedTestValue(t, controller, "Throttle"))
+	assert.Equal(t, 0.5, derivedTestValue(t, controller, "Split"))
+	/* sums and differences stay within the normalized range */
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Combined"))
+	setDerivedTestInput(controller, "Right", -0.5, now)
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Split"))
+	assert.Equal(t, 0.5, derivedTestValue(t, controller, "Combined"))
+	setDerivedTestInput(controller, "Right", 0.5, now)
+	assert.Equal(t, 0.0, derivedTestValue(t, controller, "Idle"))
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Full"))
+
+	/* the trim ramps while up is held and keeps its value once released */
+	setDerivedTestInput(controller, "Up", 1.0, now)
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Lights"))
+	controller.updateDerivedControls(now.Add(time.Second))
+	assert.InDelta(t, 0.5, derivedTestValue(t, controller, "Trim"), 0.001)
+	setDerivedTestInput(controller, "Up", 0.0, now.Add(1500*time.Millisecond))
+	assert.InDelta(t, 0.75, derivedTestValue(t, controller, "Trim"), 0.001)
+	controller.updateDerivedControls(now.Add(3 * time.Second))
+	assert.InDelta(t, 0.75, derivedTestValue(t, controller, "Trim"), 0.001)
+
+	/* the trim stops at its range */
+	setDerivedTestInput(controller, "Down", 1.0, now.Add(3*time.Second))
+	controller.updateDerivedControls(now.Add(10 * time.Second))
+	assert.Equal(t, 0.0, derivedTestValue(t, controller, "Trim"))
+
+	/* the latch only toggles on the next press */
+	assert.Equal(t, 1.0, derivedTestValue(t, controller, "Lights"))
+	setDerivedTestInput(controller, "Up", 1.0, now.Add(11*time.Second))
+	assert.Equal(t, 0.0, derivedTestValue(t, controller, "Lights"))
+}
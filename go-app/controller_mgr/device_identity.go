@@ -0,0 +1,211 @@
+package controller_mgr
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/* what the identity of a device is based on */
+type ControllerManager_IdentitySource = string
+
+const (
+	/* the serial number reported by the device */
+	ControllerManager_IdentitySource_Serial ControllerManager_IdentitySource = "serial"
+	/* the platform device path; stays the same as long as the device is plugged into the same port */
+	ControllerManager_IdentitySource_Path ControllerManager_IdentitySource = "path"
+	/* a user assigned alias bound to the connection order */
+	ControllerManager_IdentitySource_Alias ControllerManager_IdentitySource = "alias"
+	/* the connection order among devices of the same model */
+	ControllerManager_IdentitySource_Order ControllerManager_IdentitySource = "order"
+)
+
+/* the length of the identity hash appended to the SDL GUID */
+const DEVICE_IDENTITY_HASH_LENGTH = 12
+
+/* a connected device and how it is identified */
+type ControllerManager_Device struct {
+	Joystick *sdl_mgr.SDLMgr_Joystick
+	/* 1-based position among the connected devices of the same model */
+	Ordinal int
+	/* identifies the physical device within its model; aliases are bound to it */
+	HardwareKey    string
+	IdentitySource ControllerManager_IdentitySource
+	Alias          string
+}
+
+/* a device which received a new GUID (eg: after assigning an alias) */
+type ControllerManager_DeviceRename struct {
+	From JoystickGUIDString
+	To   JoystickGUIDString
+}
+
+type deviceIdentityCandidate struct {
+	Source      ControllerManager_IdentitySource
+	HardwareKey string
+	Identity    string
+}
+
+func deviceGUID(sdl_guid sdl_mgr.SDLMgr_Guid_Str, identity string) JoystickGUIDString {
+	hash := sha1.Sum([]byte(identity))
+	return fmt.Sprintf("%s-%s", sdl_guid, hex.EncodeToString(hash[:])[:DEVICE_IDENTITY_HASH_LENGTH])
+}
+
+func deviceHardwareKeyPrefix(sdl_guid sdl_mgr.SDLMgr_Guid_Str) string {
+	return fmt.Sprintf("%s/", sdl_guid)
+}
+
+/*
+lists the possible identities of a device from most to least stable
+the alias only replaces the connection order since serial and path based identities are stable already
+*/
+func deviceIdentityCandidates(joystick *sdl_mgr.SDLMgr_Joystick, ordinal int, aliases map[string]string) []deviceIdentityCandidate {
+	prefix := deviceHardwareKeyPrefix(joystick.SDLGUID)
+	candidates := []deviceIdentityCandidate{}
+	if joystick.Serial != "" {
+		key := fmt.Sprintf("%sserial:%s", prefix, joystick.Serial)
+		candidates = append(candidates, deviceIdentityCandidate{Source: ControllerManager_IdentitySource_Serial, HardwareKey: key, Identity: key})
+	}
+	if joystick.Path != "" {
+		key := fmt.Sprintf("%spath:%s", prefix, joystick.Path)
+		candidates = append(candidates, deviceIdentityCandidate{Source: ControllerManager_IdentitySource_Path, HardwareKey: key, Identity: key})
+	}
+
+	order_key := fmt.Sprintf("%sorder:%d", prefix, ordinal)
+	if alias, has_alias := aliases[order_key]; has_alias && alias != "" {
+		candidates = append(candidates, deviceIdentityCandidate{
+			Source:      ControllerManager_IdentitySource_Alias,
+			HardwareKey: order_key,
+			Identity:    fmt.Sprintf("%salias:%s", prefix, alias),
+		})
+	}
+	candidates = append(candidates, deviceIdentityCandidate{Source: ControllerManager_IdentitySource_Order, HardwareKey: order_key, Identity: order_key})
+	return candidates
+}
+
+/*
+resolves the identity of a device; identities already used by other connected devices are skipped
+which happens for devices reporting the same serial number
+*/
+func resolveDeviceIdentity(
+	joystick *sdl_mgr.SDLMgr_Joystick,
+	ordinal int,
+	aliases map[string]string,
+	is_guid_used func(guid JoystickGUIDString) bool,
+) (ControllerManager_Device, JoystickGUIDString) {
+	candidates := deviceIdentityCandidates(joystick, ordinal, aliases)
+	candidate := candidates[len(candidates)-1]
+	for _, c := range candidates {
+		if !is_guid_used(deviceGUID(joystick.SDLGUID, c.Identity)) {
+			candidate = c
+			break
+		}
+	}
+
+	device := ControllerManager_Device{
+		Joystick:       joystick,
+		Ordinal:        ordinal,
+		HardwareKey:    candidate.HardwareKey,
+		IdentitySource: candidate.Source,
+		Alias:          aliases[candidate.HardwareKey],
+	}
+	return device, deviceGUID(joystick.SDLGUID, candidate.Identity)
+}
+
+/* the smallest ordinal not used by a connected device of the same model; a re-plugged device gets its previous ordinal back */
+func nextDeviceOrdinal(devices []ControllerManager_Device, sdl_guid sdl_mgr.SDLMgr_Guid_Str) int {
+	used := map[int]bool{}
+	for _, device := range devices {
+		if device.Joystick.SDLGUID == sdl_guid {
+			used[device.Ordinal] = true
+		}
+	}
+	ordinal := 1
+	for used[ordinal] {
+		ordinal++
+	}
+	return ordinal
+}
+
+/*
+assigns an alias to a hardware key; an alias is unique per model so when another device of the same model
+already has the alias, the two devices swap their aliases
+*/
+func assignDeviceAlias(aliases map[string]string, sdl_guid sdl_mgr.SDLMgr_Guid_Str, hardware_key string, alias string) map[string]string {
+	updated := map[string]string{}
+	for key, value := range aliases {
+		updated[key] = value
+	}
+
+	previous_alias, has_previous_alias := updated[hardware_key]
+	if alias != "" {
+		prefix := deviceHardwareKeyPrefix(sdl_guid)
+		for key, value := range updated {
+			if key == hardware_key || value != alias || !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if has_previous_alias {
+				updated[key] = previous_alias
+			} else {
+				delete(updated, key)
+			}
+		}
+	}
+
+	if alias == "" {
+		delete(updated, hardware_key)
+	} else {
+		updated[hardware_key] = alias
+	}
+	return updated
+}
+
+/* the share of the full axis range an axis has to move to identify a device */
+const IDENTIFY_AXIS_THRESHOLD = 0.25
+
+/*
+waits until a control is moved on one of the connected devices with the USB ID (or any device if empty) and returns its GUID
+buttons and hats identify the device when pressed, axes once they moved far enough from where they were first seen
+*/
+func (mgr *ControllerManager) IdentifyJoystick(ctx context.Context, usb_id string) (JoystickGUIDString, error) {
+	channel, unsubscribe := mgr.SubscribeRaw()
+	defer unsubscribe()
+
+	first_axis_values := map[string]int16{}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("no control was moved")
+		case raw_event := <-channel:
+			if usb_id != "" && raw_event.Joystick.ToString() != usb_id {
+				continue
+			}
+			switch e := raw_event.Event.(type) {
+			case *sdl.JoyButtonEvent:
+				if e.State == sdl.PRESSED {
+					return raw_event.Joystick.GUID, nil
+				}
+			case *sdl.JoyHatEvent:
+				if e.Value != sdl.HAT_CENTERED {
+					return raw_event.Joystick.GUID, nil
+				}
+			case *sdl.JoyAxisEvent:
+				key := fmt.Sprintf("%s:%d", raw_event.Joystick.GUID, e.Axis)
+				first_value, has_first_value := first_axis_values[key]
+				if !has_first_value {
+					first_axis_values[key] = e.Value
+					continue
+				}
+				if math.Abs(float64(e.Value)-float64(first_value)) > IDENTIFY_AXIS_THRESHOLD*math.MaxUint16 {
+					return raw_event.Joystick.GUID, nil
+				}
+			}
+		}
+	}
+}
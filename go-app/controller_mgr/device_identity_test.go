@@ -0,0 +1,186 @@
+package controller_mgr
+
+import (
+	"context"
+	"testing"
+	"time"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func newTestJoystick(instance_id int, serial string, path string) *sdl_mgr.SDLMgr_Joystick {
+	return &sdl_mgr.SDLMgr_Joystick{
+		GUID:       "03000000abcd",
+		SDLGUID:    "03000000abcd",
+		Name:       "Throttle Quadrant",
+		VendorID:   0x1234,
+		ProductID:  0x5678,
+		InstanceID: instance_id,
+		Serial:     serial,
+		Path:       path,
+	}
+}
+
+func noGUIDUsed(guid JoystickGUIDString) bool {
+	return false
+}
+
+func TestResolveDeviceIdentity_Sources(t *testing.T) {
+	serial_device, serial_guid := resolveDeviceIdentity(newTestJoystick(0, "SN1", "/dev/input/event3"), 1, map[string]string{}, noGUIDUsed)
+	assert.Equal(t, ControllerManager_IdentitySource_Serial, serial_device.IdentitySource)
+	assert.Equal(t, "03000000abcd/serial:SN1", serial_device.HardwareKey)
+
+	path_device, path_guid := resolveDeviceIdentity(newTestJoystick(0, "", "/dev/input/event3"), 1, map[string]string{}, noGUIDUsed)
+	assert.Equal(t, ControllerManager_IdentitySource_Path, path_device.IdentitySource)
+
+	order_device, order_guid := resolveDeviceIdentity(newTestJoystick(0, "", ""), 2, map[string]string{}, noGUIDUsed)
+	assert.Equal(t, ControllerManager_IdentitySource_Order, order_device.IdentitySource)
+	assert.Equal(t, "03000000abcd/order:2", order_device.HardwareKey)
+
+	/* the identity is stable and different per source */
+	_, serial_guid_again := resolveDeviceIdentity(newTestJoystick(5, "SN1", "/dev/input/event9"), 3, map[string]string{}, noGUIDUsed)
+	assert.Equal(t, serial_guid, serial_guid_again)
+	assert.NotEqual(t, serial_guid, path_guid)
+	assert.NotEqual(t, path_guid, order_guid)
+	assert.Regexp(t, `^03000000abcd-[0-9a-f]{12}$`, serial_guid)
+}
+
+func TestResolveDeviceIdentity_DuplicateSerial(t *testing.T) {
+	_, first_guid := resolveDeviceIdentity(newTestJoystick(0, "0000", "/dev/input/event3"), 1, map[string]string{}, noGUIDUsed)
+	second_device, second_guid := resolveDeviceIdentity(newTestJoystick(1, "0000", "/dev/input/event4"), 2, map[string]string{}, func(guid JoystickGUIDString) bool {
+		return guid == first_guid
+	})
+	assert.NotEqual(t, first_guid, second_guid)
+	assert.Equal(t, ControllerManager_IdentitySource_Path, second_device.IdentitySource)
+}
+
+func TestResolveDeviceIdentity_Alias(t *testing.T) {
+	aliases := map[string]string{"03000000abcd/order:1": "Left"}
+	device, guid := resolveDeviceIdentity(newTestJoystick(0, "", ""), 1, aliases, noGUIDUsed)
+	assert.Equal(t, ControllerManager_IdentitySource_Alias, device.IdentitySource)
+	assert.Equal(t, "Left", device.Alias)
+	assert.Equal(t, deviceGUID("03000000abcd", "03000000abcd/alias:Left"), guid)
+
+	/* serial based devices only show the alias */
+	aliases["03000000abcd/serial:SN1"] = "Right"
+	serial_device, serial_guid := resolveDeviceIdentity(newTestJoystick(0, "SN1", ""), 1, aliases, noGUIDUsed)
+	assert.Equal(t, ControllerManager_IdentitySource_Serial, serial_device.IdentitySource)
+	assert.Equal(t, "Right", serial_device.Alias)
+	assert.Equal(t, deviceGUID("03000000abcd", "03000000abcd/serial:SN1"), serial_guid)
+}
+
+func TestNextDeviceOrdinal(t *testing.T) {
+	devices := []ControllerManager_Device{
+		{Joystick: newTestJoystick(0, "", ""), Ordinal: 1},
+		{Joystick: newTestJoystick(2, "", ""), Ordinal: 3},
+		{Joystick: &sdl_mgr.SDLMgr_Joystick{SDLGUID: "other"}, Ordinal: 2},
+	}
+	assert.Equal(t, 2, nextDeviceOrdinal(devices, "03000000abcd"))
+	assert.Equal(t, 1, nextDeviceOrdinal(devices, "other-model"))
+}
+
+func TestAssignDeviceAlias_Swap(t *testing.T) {
+	aliases := map[string]string{
+		"03000000abcd/order:1": "Left",
+		"03000000abcd/order:2": "Right",
+		"ffff/order:1":         "Left",
+	}
+	updated := assignDeviceAlias(aliases, "03000000abcd", "03000000abcd/order:2", "Left")
+	assert.Equal(t, map[string]string{
+		"03000000abcd/order:1": "Right",
+		"03000000abcd/order:2": "Left",
+		"ffff/order:1":         "Left",
+	}, updated)
+	/* the input is not modified */
+	assert.Equal(t, "Left", aliases["03000000abcd/order:1"])
+
+	cleared := assignDeviceAlias(updated, "03000000abcd", "03000000abcd/order:1", "")
+	assert.Equal(t, map[string]string{"03000000abcd/order:2": "Left", "ffff/order:1": "Left"}, cleared)
+}
+
+func newTestDeviceManager(joysticks ...*sdl_mgr.SDLMgr_Joystick) *ControllerManager {
+	mgr := New(sdl_mgr.New())
+	for index, joystick := range joysticks {
+		device, guid := resolveDeviceIdentity(joystick, index+1, map[string]string{}, noGUIDUsed)
+		joystick.GUID = guid
+		mgr.Devices.Set(joystick.InstanceID, device)
+		mgr.configureDevice(joystick)
+	}
+	return mgr
+}
+
+func TestControllerManager_SetDeviceAlias(t *testing.T) {
+	first := newTestJoystick(10, "", "")
+	second := newTestJoystick(11, "", "")
+	mgr := newTestDeviceManager(first, second)
+	first_guid, second_guid := first.GUID, second.GUID
+
+	renames, err := mgr.SetDeviceAlias(second_guid, "Right")
+	require.NoError(t, err)
+	require.Len(t, renames, 1)
+	assert.Equal(t, second_guid, renames[0].From)
+
+	device, has_device := mgr.GetDevice(renames[0].To)
+	require.True(t, has_device)
+	assert.Equal(t, "Right", device.Alias)
+	assert.Equal(t, 11, device.Joystick.InstanceID)
+	_, has_previous := mgr.UnconfiguredControllers.Get(second_guid)
+	assert.False(t, has_previous)
+	_, has_renamed := mgr.UnconfiguredControllers.Get(renames[0].To)
+	assert.True(t, has_renamed)
+	/* the joystick of previous events keeps its GUID */
+	assert.Equal(t, second_guid, second.GUID)
+
+	/* taking the alias for the first device swaps the identities */
+	right_guid := renames[0].To
+	renames, err = mgr.SetDeviceAlias(first_guid, "Right")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ControllerManager_DeviceRename{
+		{From: first_guid, To: right_guid},
+		{From: right_guid, To: second_guid},
+	}, renames)
+	first_device, _ := mgr.Devices.Get(10)
+	assert.Equal(t, right_guid, first_device.Joystick.GUID)
+	_, has_second := mgr.UnconfiguredControllers.Get(second_guid)
+	assert.True(t, has_second)
+
+	_, err = mgr.SetDeviceAlias("unknown", "Left")
+	assert.Error(t, err)
+}
+
+func TestControllerManager_IdentifyJoystick(t *testing.T) {
+	first := newTestJoystick(10, "", "")
+	second := newTestJoystick(11, "", "")
+	mgr := newTestDeviceManager(first, second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result := make(chan JoystickGUIDString)
+	go func() {
+		guid, _ := mgr.IdentifyJoystick(ctx, "1234:5678")
+		result <- guid
+	}()
+
+	/* axis noise and released buttons do not identify the device; repeated until the identification subscribed */
+	events := []ControllerManager_RawEvent{
+		{Joystick: first, Event: &sdl.JoyAxisEvent{Axis: 0, Value: 100}},
+		{Joystick: first, Event: &sdl.JoyAxisEvent{Axis: 0, Value: 300}},
+		{Joystick: first, Event: &sdl.JoyButtonEvent{Button: 0, State: sdl.RELEASED}},
+		{Joystick: second, Event: &sdl.JoyAxisEvent{Axis: 1, Value: -20000}},
+		{Joystick: second, Event: &sdl.JoyAxisEvent{Axis: 1, Value: 20000}},
+	}
+	for {
+		for _, event := range events {
+			mgr.RawEventChannels.EmitTimeout(time.Second, event)
+		}
+		select {
+		case guid := <-result:
+			assert.Equal(t, second.GUID, guid)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package controller_mgr
+
+import (
+	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/* the raw range of the game controller axes; the triggers only use the positive half */
+const (
+	GAMEPAD_AXIS_MIN = -32768
+	GAMEPAD_AXIS_MAX = 32767
+)
+
+/* the raw distance from rest which is ignored; worn sticks rarely return exactly to the middle */
+const (
+	GAMEPAD_STICK_DEADZONE   = 4000
+	GAMEPAD_TRIGGER_DEADZONE = 1000
+)
+
+/*
+the SDL mapping used for game controllers without an SDL mapping file; maps the standard named buttons and axes of the
+SDL GameController API so any pad SDL knows works without calibrating
+*/
+func BuiltinGamepadSDLMap(joystick *sdl_mgr.SDLMgr_Joystick) config.Config_Controller_SDLMap {
+	sdl_map := config.Config_Controller_SDLMap{
+		Name:  joystick.Name,
+		UsbID: joystick.ToString(),
+		Data:  []config.Config_Controller_SDLMap_Control{},
+	}
+	for _, controls := range [][]sdl_mgr.SDLMgr_GameControllerControl{sdl_mgr.SDLMgr_GameControllerAxes, sdl_mgr.SDLMgr_GameControllerButtons} {
+		for _, control := range controls {
+			sdl_map.Data = append(sdl_map.Data, config.Config_Controller_SDLMap_Control{
+				Kind:  control.Kind,
+				Index: control.Index,
+				Name:  control.Name,
+			})
+		}
+	}
+	return sdl_map
+}
+
+/*
+the calibration used with the built-in SDL mapping; the sticks are centered (pushing up is positive) and the triggers
+rest at 0, the buttons don't need a calibration
+*/
+func BuiltinGamepadCalibration(joystick *sdl_mgr.SDLMgr_Joystick) config.Config_Controller_Calibration {
+	calibration := config.Config_Controller_Calibration{
+		UsbID: joystick.ToString(),
+		Data:  []config.Config_Controller_CalibrationData{},
+	}
+	for _, axis := range sdl_mgr.SDLMgr_GameControllerAxes {
+		idle, deadzone, invert := 0.0, float64(GAMEPAD_STICK_DEADZONE), axis.IsVertical
+		min, max := float64(GAMEPAD_AXIS_MIN), float64(GAMEPAD_AXIS_MAX)
+		if axis.IsTrigger {
+			min, deadzone = 0.0, GAMEPAD_TRIGGER_DEADZONE
+		}
+		if invert {
+			/* normalizing inverts the raw value so the range is inverted as well */
+			min, max = -max, -min
+		}
+		calibration.Data = append(calibration.Data, config.Config_Controller_CalibrationData{
+			Id:          axis.Name,
+			Min:         min,
+			Max:         max,
+			Idle:        &idle,
+			Deadzone:    &deadzone,
+			Invert:      &invert,
+			EasingCurve: &[]float64{0.0, 0.0, 1.0, 1.0},
+		})
+	}
+	return calibration
+}
+
+/* the current raw value of a game controller button or axis; 0 when the device was not opened as a game controller */
+func gameControllerValue(joystick *sdl_mgr.SDLMgr_Joystick, kind sdl_mgr.SDLMgr_Control_Kind, index int) float64 {
+	if joystick.InternalGameController == nil {
+		return 0.0
+	}
+	switch kind {
+	case sdl_mgr.SDLMgr_Control_Kind_ControllerAxis:
+		return float64(joystick.InternalGameController.Axis(sdl.GameControllerAxis(index)))
+	case sdl_mgr.SDLMgr_Control_Kind_ControllerButton:
+		return float64(joystick.InternalGameController.Button(sdl.GameControllerButton(index)))
+	}
+	return 0.0
+}
@@ -0,0 +1,39 @@
+package controller_mgr
+
+import (
+	"testing"
+	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinGamepadConfiguration(t *testing.T) {
+	joystick := newTestJoystick(0, "", "")
+	sdl_map := BuiltinGamepadSDLMap(joystick)
+	calibration := BuiltinGamepadCalibration(joystick)
+	require.NoError(t, config.ValidateControllerConfiguration(sdl_map, calibration))
+	assert.Len(t, sdl_map.Data, len(sdl_mgr.SDLMgr_GameControllerAxes)+len(sdl_mgr.SDLMgr_GameControllerButtons))
+
+	calibration_data := map[string]config.Config_Controller_CalibrationData{}
+	for _, data := range calibration.Data {
+		data.IsCalibrated = true
+		calibration_data[data.Id] = data
+	}
+
+	/* SDL reports up as negative */
+	left_stick_y := calibration_data["LeftStickY"]
+	assert.Equal(t, 1.0, left_stick_y.NormalizeRawValue(GAMEPAD_AXIS_MIN).Value)
+	assert.Equal(t, -1.0, left_stick_y.NormalizeRawValue(GAMEPAD_AXIS_MAX).Value)
+	assert.True(t, left_stick_y.NormalizeRawValue(GAMEPAD_STICK_DEADZONE-1).IsWithinDeadzone)
+
+	left_stick_x := calibration_data["LeftStickX"]
+	assert.Equal(t, 1.0, left_stick_x.NormalizeRawValue(GAMEPAD_AXIS_MAX).Value)
+	assert.Equal(t, -1.0, left_stick_x.NormalizeRawValue(GAMEPAD_AXIS_MIN).Value)
+
+	/* triggers rest at 0 */
+	right_trigger := calibration_data["RightTrigger"]
+	assert.Equal(t, 0.0, right_trigger.NormalizeRawValue(0).Value)
+	assert.Equal(t, 1.0, right_trigger.NormalizeRawValue(GAMEPAD_AXIS_MAX).Value)
+}
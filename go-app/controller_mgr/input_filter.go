@@ -0,0 +1,203 @@
+package controller_mgr
+
+import (
+	"math"
+	"sort"
+	"time"
+	"tsw_controller_app/config"
+)
+
+/* how often filters which did not publish their latest value yet are re-evaluated */
+const FILTER_TICK_INTERVAL = 20 * time.Millisecond
+
+/* the smoothed value is considered settled when it is this close (in raw units) to the value it is smoothing towards */
+const FILTER_SETTLE_EPSILON = 0.5
+
+const (
+	FILTER_DEFAULT_ONE_EURO_MIN_CUTOFF        = 1.0
+	FILTER_DEFAULT_ONE_EURO_BETA              = 0.001
+	FILTER_DEFAULT_ONE_EURO_DERIVATIVE_CUTOFF = 1.0
+)
+
+/*
+the filter state of an axis; filters the raw values before they are normalized
+*/
+type ControllerManager_InputFilter struct {
+	Config config.Config_Controller_CalibrationData_Filter
+	/* the raw range of the axis; values reaching it are always published */
+	Min float64
+	Max float64
+
+	median_samples  []float64
+	average_samples []float64
+	/* the output of the median, the input of the smoothing */
+	median_value float64
+	/* the output of the smoothing */
+	smoothed_value float64
+
+	one_euro_value      float64
+	one_euro_derivative float64
+	one_euro_updated_at time.Time
+	has_one_euro_value  bool
+
+	published_value float64
+	published_at    time.Time
+	has_published   bool
+	/* a value was held back by the minimum interval */
+	has_pending_value bool
+}
+
+func NewInputFilter(filter config.Config_Controller_CalibrationData_Filter, min float64, max float64) *ControllerManager_InputFilter {
+	return &ControllerManager_InputFilter{Config: filter, Min: min, Max: max}
+}
+
+/* starts the filter over from the value (eg: after the control was reset) */
+func (f *ControllerManager_InputFilter) Reset(value float64, now time.Time) {
+	/* the windows start full so the first values are compared against the resting value */
+	f.median_samples = nil
+	if f.Config.MedianWindow != nil {
+		f.median_samples = repeatValue(value, *f.Config.MedianWindow)
+	}
+	f.average_samples = nil
+	if f.Config.AverageWindow != nil {
+		f.average_samples = repeatValue(value, *f.Config.AverageWindow)
+	}
+	f.median_value = value
+	f.smoothed_value = value
+	f.has_one_euro_value = false
+	f.published_value = value
+	f.published_at = now
+	f.has_published = true
+	f.has_pending_value = false
+}
+
+/*
+filters the raw value; returns the value to publish and whether it should be published
+*/
+func (f *ControllerManager_InputFilter) Apply(value float64, now time.Time) (float64, bool) {
+	f.median_value = f.applyMedian(value)
+	return f.publish(f.applySmoothing(f.median_value, now), now)
+}
+
+/*
+re-evaluates the smoothing and minimum interval without a new raw value; axes do not send events while at rest
+so the smoothed value would otherwise never reach the resting value
+*/
+func (f *ControllerManager_InputFilter) Settle(now time.Time) (float64, bool) {
+	return f.publish(f.applySmoothing(f.median_value, now), now)
+}
+
+func (f *ControllerManager_InputFilter) IsSettled() bool {
+	return !f.has_pending_value && math.Abs(f.smoothed_value-f.median_value) < FILTER_SETTLE_EPSILON
+}
+
+func (f *ControllerManager_InputFilter) applyMedian(value float64) float64 {
+	if f.Config.MedianWindow == nil {
+		return value
+	}
+
+	f.median_samples = append(f.median_samples, value)
+	if len(f.median_samples) > *f.Config.MedianWindow {
+		f.median_samples = f.median_samples[1:]
+	}
+	sorted_samples := append([]float64{}, f.median_samples...)
+	sort.Float64s(sorted_samples)
+	return sorted_samples[len(sorted_samples)/2]
+}
+
+func (f *ControllerManager_InputFilter) applySmoothing(value float64, now time.Time) float64 {
+	if f.Config.Smoothing == nil {
+		f.smoothed_value = value
+		return value
+	}
+
+	switch *f.Config.Smoothing {
+	case "moving_average":
+		f.average_samples = append(f.average_samples, value)
+		if len(f.average_samples) > *f.Config.AverageWindow {
+			f.average_samples = f.average_samples[1:]
+		}
+		total := 0.0
+		for _, sample := range f.average_samples {
+			total += sample
+		}
+		f.smoothed_value = total / float64(len(f.average_samples))
+	case "one_euro":
+		f.smoothed_value = f.applyOneEuro(value, now)
+	default:
+		f.smoothed_value = value
+	}
+	return f.smoothed_value
+}
+
+/*
+one euro filter (Casiez et al.); a low pass filter which cuts off less the faster the axis moves,
+removing jitter at rest without adding lag while moving
+*/
+func (f *ControllerManager_InputFilter) applyOneEuro(value float64, now time.Time) float64 {
+	if !f.has_one_euro_value {
+		f.one_euro_value = value
+		f.one_euro_derivative = 0
+		f.one_euro_updated_at = now
+		f.has_one_euro_value = true
+		return value
+	}
+
+	elapsed := now.Sub(f.one_euro_updated_at).Seconds()
+	if elapsed <= 0 {
+		elapsed = time.Millisecond.Seconds()
+	}
+	f.one_euro_updated_at = now
+
+	min_cutoff := FILTER_DEFAULT_ONE_EURO_MIN_CUTOFF
+	if f.Config.MinCutoff != nil {
+		min_cutoff = *f.Config.MinCutoff
+	}
+	beta := FILTER_DEFAULT_ONE_EURO_BETA
+	if f.Config.Beta != nil {
+		beta = *f.Config.Beta
+	}
+
+	derivative := (value - f.one_euro_value) / elapsed
+	f.one_euro_derivative += oneEuroAlpha(FILTER_DEFAULT_ONE_EURO_DERIVATIVE_CUTOFF, elapsed) * (derivative - f.one_euro_derivative)
+	cutoff := min_cutoff + beta*math.Abs(f.one_euro_derivative)
+	f.one_euro_value += oneEuroAlpha(cutoff, elapsed) * (value - f.one_euro_value)
+	return f.one_euro_value
+}
+
+func oneEuroAlpha(cutoff float64, elapsed float64) float64 {
+	time_constant := 1.0 / (2 * math.Pi * cutoff)
+	return 1.0 / (1.0 + time_constant/elapsed)
+}
+
+func (f *ControllerManager_InputFilter) publish(value float64, now time.Time) (float64, bool) {
+	if f.has_published && value == f.published_value {
+		f.has_pending_value = false
+		return value, false
+	}
+
+	reaches_range := value <= f.Min || value >= f.Max
+	if f.has_published && f.Config.MinChange != nil && !reaches_range && math.Abs(value-f.published_value) < *f.Config.MinChange {
+		f.has_pending_value = false
+		return value, false
+	}
+
+	if f.has_published && f.Config.MinInterval != nil && now.Sub(f.published_at).Seconds() < *f.Config.MinInterval {
+		f.has_pending_value = true
+		return value, false
+	}
+
+	f.published_value = value
+	f.published_at = now
+	f.has_published = true
+	f.has_pending_value = false
+	return value, true
+}
+
+func repeatValue(value float64, count int) []float64 {
+	values := make([]float64, count)
+	for index := range values {
+		values[index] = value
+	}
+	return values
+}
@@ -0,0 +1,115 @@
+package controller_mgr
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestInputFilter_MedianRejectsSpikes(t *testing.T) {
+	now := time.Now()
+	filter := NewInputFilter(config.Config_Controller_CalibrationData_Filter{MedianWindow: ptr(3)}, -32768, 32767)
+	filter.Reset(1000, now)
+
+	_, should_publish := filter.Apply(1000, now)
+	assert.False(t, should_publish)
+	/* a single spike is rejected */
+	_, should_publish = filter.Apply(30000, now)
+	assert.False(t, should_publish)
+	filter.Apply(1000, now)
+	_, should_publish = filter.Apply(1000, now)
+	assert.False(t, should_publish)
+	/* a real movement is published once it is the median */
+	_, should_publish = filter.Apply(5000, now)
+	assert.False(t, should_publish)
+	value, should_publish := filter.Apply(5000, now)
+	assert.True(t, should_publish)
+	assert.Equal(t, 5000.0, value)
+	assert.True(t, filter.IsSettled())
+}
+
+func TestInputFilter_MinChange(t *testing.T) {
+	now := time.Now()
+	filter := NewInputFilter(config.Config_Controller_CalibrationData_Filter{MinChange: ptr(100.0)}, -32768, 32767)
+	filter.Reset(0, now)
+
+	_, should_publish := filter.Apply(60, now)
+	assert.False(t, should_publish)
+	_, should_publish = filter.Apply(-90, now)
+	assert.False(t, should_publish)
+	value, should_publish := filter.Apply(150, now)
+	assert.True(t, should_publish)
+	assert.Equal(t, 150.0, value)
+
+	/* the end of the range is always reached */
+	filter.Reset(32700, now)
+	value, should_publish = filter.Apply(32767, now)
+	assert.True(t, should_publish)
+	assert.Equal(t, 32767.0, value)
+}
+
+func TestInputFilter_MinInterval(t *testing.T) {
+	now := time.Now()
+	filter := NewInputFilter(config.Config_Controller_CalibrationData_Filter{MinInterval: ptr(0.1)}, -32768, 32767)
+	filter.Reset(0, now)
+
+	_, should_publish := filter.Apply(100, now.Add(50*time.Millisecond))
+	assert.False(t, should_publish)
+	_, should_publish = filter.Apply(200, now.Add(60*time.Millisecond))
+	assert.False(t, should_publish)
+	assert.False(t, filter.IsSettled())
+
+	/* the latest value is published once the interval passed */
+	value, should_publish := filter.Settle(now.Add(110 * time.Millisecond))
+	assert.True(t, should_publish)
+	assert.Equal(t, 200.0, value)
+	assert.True(t, filter.IsSettled())
+}
+
+func TestInputFilter_MovingAverage(t *testing.T) {
+	now := time.Now()
+	filter := NewInputFilter(config.Config_Controller_CalibrationData_Filter{Smoothing: ptr("moving_average"), AverageWindow: ptr(4)}, -32768, 32767)
+	filter.Reset(0, now)
+
+	/* the average starts from the resting value */
+	value, should_publish := filter.Apply(400, now)
+	assert.True(t, should_publish)
+	assert.Equal(t, 100.0, value)
+	value, _ = filter.Apply(400, now)
+	assert.Equal(t, 200.0, value)
+	assert.False(t, filter.IsSettled())
+
+	/* settles towards the last value without new events */
+	for i := 0; i < 4 && !filter.IsSettled(); i++ {
+		value, _ = filter.Settle(now)
+	}
+	assert.Equal(t, 400.0, value)
+	assert.True(t, filter.IsSettled())
+}
+
+func TestInputFilter_OneEuro(t *testing.T) {
+	now := time.Now()
+	filter := NewInputFilter(config.Config_Controller_CalibrationData_Filter{Smoothing: ptr("one_euro"), Beta: ptr(0.0)}, -32768, 32767)
+	filter.Reset(0, now)
+
+	filter.Apply(0, now)
+	/* jitter is smoothed */
+	value, _ := filter.Apply(100, now.Add(10*time.Millisecond))
+	assert.Less(t, value, 10.0)
+	assert.Greater(t, value, 0.0)
+
+	/* and settles towards the resting value */
+	settle_time := now.Add(10 * time.Millisecond)
+	for i := 0; i < 1000 && !filter.IsSettled(); i++ {
+		settle_time = settle_time.Add(FILTER_TICK_INTERVAL)
+		value, _ = filter.Settle(settle_time)
+	}
+	assert.True(t, filter.IsSettled())
+	assert.InDelta(t, 100.0, value, FILTER_SETTLE_EPSILON)
+}
@@ -0,0 +1,96 @@
+package controller_mgr
+
+import (
+	"math"
+	"time"
+)
+
+/* the time constant of the exponential smoothing of the velocity and acceleration estimates */
+const MOTION_SMOOTHING_TIME_CONSTANT = 50 * time.Millisecond
+
+/*
+the motion of a control in normalized units; estimated from the normalized values as they change
+*/
+type ControllerManager_Controller_ControlState_Motion struct {
+	/* the smoothed velocity in normalized units per second; positive when the value increases */
+	Velocity float64
+	/* the smoothed acceleration in normalized units per second squared */
+	Acceleration float64
+	/* when the normalized value last changed */
+	LastMovedAt time.Time
+	/* when the estimates were last updated */
+	UpdatedAt time.Time
+}
+
+/*
+updates the estimates with the new normalized value; the estimates start over at rest on reset
+*/
+func (m *ControllerManager_Controller_ControlState_Motion) update(value float64, previous_value float64, is_reset bool, now time.Time) {
+	if is_reset || m.UpdatedAt.IsZero() {
+		*m = ControllerManager_Controller_ControlState_Motion{LastMovedAt: now, UpdatedAt: now}
+		return
+	}
+
+	elapsed := now.Sub(m.UpdatedAt).Seconds()
+	if elapsed <= 0 {
+		elapsed = time.Millisecond.Seconds()
+	}
+	/* a long pause between events means the control was at rest, so the instant velocity is close to 0 and fully replaces the estimate */
+	alpha := 1 - math.Exp(-elapsed/MOTION_SMOOTHING_TIME_CONSTANT.Seconds())
+	velocity := m.Velocity + alpha*((value-previous_value)/elapsed-m.Velocity)
+	m.Acceleration += alpha * ((velocity-m.Velocity)/elapsed - m.Acceleration)
+	m.Velocity = velocity
+	m.UpdatedAt = now
+	if value != previous_value {
+		m.LastMovedAt = now
+	}
+}
+
+/*
+Returns the velocity at the time; controls don't send events at rest so the velocity decays from the last estimate
+*/
+func (m *ControllerManager_Controller_ControlState_Motion) VelocityAt(now time.Time) float64 {
+	elapsed := now.Sub(m.UpdatedAt).Seconds()
+	if elapsed <= 0 {
+		return m.Velocity
+	}
+	return m.Velocity * math.Exp(-elapsed/MOTION_SMOOTHING_TIME_CONSTANT.Seconds())
+}
+
+func (m *ControllerManager_Controller_ControlState_Motion) TimeSinceLastMovement(now time.Time) time.Duration {
+	return now.Sub(m.LastMovedAt)
+}
+
+/*
+updates the direction of travel; the direction only changes once the value moved back from the furthest value
+in the current direction by more than DIRECTION_CHANGE_THRESHOLD so jitter doesn't flip it
+*/
+func (d *ControllerManager_Controller_ControlState_DirectionChangeMarker) update(value float64) {
+	switch d.Direction {
+	case 1:
+		if value > d.PeakValue {
+			d.PeakValue = value
+		} else if value-d.PeakValue < -DIRECTION_CHANGE_THRESHOLD {
+			d.Direction = -1
+			d.ChangeValue = d.PeakValue
+			d.PeakValue = value
+		}
+	case -1:
+		if value < d.PeakValue {
+			d.PeakValue = value
+		} else if value-d.PeakValue > DIRECTION_CHANGE_THRESHOLD {
+			d.Direction = 1
+			d.ChangeValue = d.PeakValue
+			d.PeakValue = value
+		}
+	default:
+		value_diff := value - d.ChangeValue
+		if value_diff > DIRECTION_CHANGE_THRESHOLD {
+			d.Direction = 1
+			d.PeakValue = value
+		} else if value_diff < -DIRECTION_CHANGE_THRESHOLD {
+			d.Direction = -1
+			d.PeakValue = value
+		}
+	}
+}
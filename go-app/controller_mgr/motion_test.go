@@ -0,0 +1,71 @@
+package controller_mgr
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMotionTestControl(now time.Time) *ControllerManager_Controller_Control {
+	control := &ControllerManager_Controller_Control{
+		Calibration: config.Config_Controller_CalibrationData{Id: "Throttle", Min: 0, Max: 1000, IsCalibrated: true},
+	}
+	control.updateState(0, true, now)
+	return control
+}
+
+func TestControlState_Direction(t *testing.T) {
+	now := time.Now()
+	control := newMotionTestControl(now)
+
+	/* small movements don't set a direction */
+	control.updateState(30, false, now)
+	assert.Equal(t, int8(0), control.State.Direction.Direction)
+
+	control.updateState(100, false, now)
+	control.updateState(400, false, now)
+	assert.Equal(t, int8(1), control.State.Direction.Direction)
+
+	/* jitter while moving up doesn't flip the direction */
+	control.updateState(380, false, now)
+	assert.Equal(t, int8(1), control.State.Direction.Direction)
+
+	/* moving back from the peak does */
+	control.updateState(300, false, now)
+	assert.Equal(t, int8(-1), control.State.Direction.Direction)
+	assert.InDelta(t, 0.4, control.State.Direction.ChangeValue, 0.001)
+
+	control.updateState(200, false, now)
+	assert.Equal(t, int8(-1), control.State.Direction.Direction)
+	control.updateState(260, false, now)
+	assert.Equal(t, int8(1), control.State.Direction.Direction)
+	assert.InDelta(t, 0.2, control.State.Direction.ChangeValue, 0.001)
+}
+
+func TestControlState_Motion(t *testing.T) {
+	now := time.Now()
+	control := newMotionTestControl(now)
+
+	/* moving at 1 unit per second */
+	for i := 1; i <= 20; i++ {
+		control.updateState(float64(i*10), false, now.Add(time.Duration(i)*10*time.Millisecond))
+	}
+	last_event_at := now.Add(200 * time.Millisecond)
+	assert.InDelta(t, 1.0, control.State.Motion.Velocity, 0.05)
+	assert.Greater(t, control.State.Motion.Acceleration, 0.0)
+	assert.Equal(t, last_event_at, control.State.Motion.LastMovedAt)
+
+	/* at rest without events the velocity decays */
+	assert.Less(t, control.State.Motion.VelocityAt(last_event_at.Add(250*time.Millisecond)), 0.01)
+	assert.Equal(t, 250*time.Millisecond, control.State.Motion.TimeSinceLastMovement(last_event_at.Add(250*time.Millisecond)))
+
+	/* an event after a pause starts from rest */
+	control.updateState(210, false, last_event_at.Add(time.Second))
+	assert.InDelta(t, 0.01, control.State.Motion.Velocity, 0.001)
+
+	control.updateState(500, true, last_event_at.Add(2*time.Second))
+	assert.Equal(t, 0.0, control.State.Motion.Velocity)
+	assert.Equal(t, 0.0, control.State.Motion.Acceleration)
+}
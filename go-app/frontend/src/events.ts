@@ -1,6 +1,8 @@
 export const events = {
   joydevices_updated: 'joydevices_updated',
   profiles_updated: 'profiles_updated',
+  selected_profiles_updated: 'selected_profiles_updated',
+  profile_selection_changed: 'profile_selection_changed',
   rawevent: 'rawevent',
   synccontrolstate: 'synccontrolstate',
   log: 'log',
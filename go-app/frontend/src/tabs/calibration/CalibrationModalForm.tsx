@@ -6,9 +6,12 @@ import {
   SubscribeRaw,
   GetControllerConfiguration,
   LoadConfiguration,
+  StartCalibrationSession,
+  StopCalibrationSession,
 } from "../../../wailsjs/go/main/App";
 import {
   CalibrationStateControl,
+  controlKey,
   Kind,
   useCalibrationForm,
 } from "./useCalibrationForm";
@@ -20,28 +23,97 @@ type Props = {
   onClose: () => void;
 };
 
+/* describes what was detected for an axis */
+const axisNotes = (axis: main.Interop_CalibrationSession_Axis) =>
+  [
+    axis.Centered && "rests in the middle",
+    axis.Inverted && "rests at its maximum and was inverted",
+    !!axis.Noise && `noise at rest of ${Math.round(axis.Noise)}`,
+    ...(axis.Warnings ?? []),
+  ].filter(Boolean);
+
 export const CalibrationModalForm = ({ controller, onClose }: Props) => {
   const [isRunning, setIsRunning] = useState(false);
+  /* the detected calibration to review before saving */
+  const [detected, setDetected] =
+    useState<main.Interop_CalibrationSessionResult | null>(null);
   const form = useCalibrationForm();
   const controls = form.watch("controls");
 
   const handleStart = () => {
     if (controller) {
-      SubscribeRaw(controller.GUID).then(() => {
-        setIsRunning(true);
-      });
+      setDetected(null);
+      Promise.all([
+        SubscribeRaw(controller.GUID),
+        StartCalibrationSession(controller.GUID),
+      ])
+        .then(() => {
+          setIsRunning(true);
+        })
+        .catch((err) => alert(String(err), "error"));
     }
   };
 
   const handleCancel = () => {
+    StopCalibrationSession().catch(() => {});
     UnsubscribeRaw().then(() => {
       setIsRunning(false);
+      setDetected(null);
       form.reset();
       onClose();
     });
   };
 
-  const handleStopAndSave = () => {
+  /* applies the detected values to all controls which were not overridden manually */
+  const applyDetected = (result: main.Interop_CalibrationSessionResult) => {
+    const controls = [...form.getValues("controls")];
+    for (const control of result.Calibration.Controls) {
+      const existingIndex = controls.findIndex(
+        (c) =>
+          c.kind === control.Kind &&
+          c.index === control.Index &&
+          (c.direction ?? "") === control.Direction,
+      );
+      const existing = controls[existingIndex];
+      if (existing?.override) continue;
+      const detectedControl: CalibrationStateControl = {
+        kind: control.Kind as Kind,
+        index: control.Index,
+        direction: control.Direction || undefined,
+        name: existing?.name || control.Name,
+        min: control.Min,
+        max: control.Max,
+        idle: control.Idle,
+        deadzone: control.Deadzone,
+        invert: control.Invert,
+        value: existing?.value ?? control.Idle,
+        easingCurve: existing?.easingCurve ?? control.EasingCurve,
+        override: false,
+        detents: existing?.detents ?? [],
+        responseCurve: existing?.responseCurve,
+        responseCurveBelowIdle: existing?.responseCurveBelowIdle,
+        filter: existing?.filter,
+      };
+      if (existingIndex === -1) {
+        controls.push(detectedControl);
+      } else {
+        controls[existingIndex] = detectedControl;
+      }
+    }
+    form.setValue("controls", controls, { shouldDirty: true });
+  };
+
+  const handleStopAndReview = () => {
+    Promise.all([StopCalibrationSession(), UnsubscribeRaw()])
+      .then(([result]) => {
+        setIsRunning(false);
+        setDetected(result);
+        applyDetected(result);
+      })
+      .catch((err) => alert(String(err), "error"));
+  };
+
+  const handleSave = () => {
     if (!controller) {
       throw new Error("No controller");
     }
@@ -51,17 +123,29 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
         const data = new main.Interop_ControllerCalibration();
         data.Name = values.name;
         data.UsbId = controller.UsbID;
-        data.Controls = values.controls.map((control) => ({
-          Kind: control.kind,
-          Index: control.index,
-          Name: control.name,
-          Min: control.min,
-          Max: control.max,
-          Idle: control.idle,
-          Deadzone: control.deadzone,
-          Invert: control.invert,
-          EasingCurve: control.easingCurve,
-        }));
+        data.GUID = values.deviceOnly ? controller.GUID : "";
+        data.Controls = values.controls.map((control) =>
+          main.Interop_ControllerCalibration_Control.createFrom({
+            Kind: control.kind,
+            Index: control.index,
+            Direction: control.direction ?? "",
+            Name: control.name,
+            Min: control.min,
+            Max: control.max,
+            Idle: control.idle,
+            Deadzone: control.deadzone,
+            Invert: control.invert,
+            EasingCurve: control.easingCurve,
+            Detents: control.detents.map((detent) => ({
+              Name: detent.name,
+              Value: detent.value,
+              CaptureWidth: detent.captureWidth,
+            })),
+            ResponseCurve: control.responseCurve,
+            ResponseCurveBelowIdle: control.responseCurveBelowIdle,
+            Filter: control.filter,
+          }),
+        );
         SaveCalibration(data)
           .then(() => LoadConfiguration())
           .catch((err) => {
@@ -69,6 +153,7 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
           })
           .finally(() => {
             setIsRunning(false);
+            setDetected(null);
             form.reset();
             onClose();
           });
@@ -80,10 +165,12 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
     GetControllerConfiguration(controller.GUID).then((configuration) => {
       form.reset({
         name: configuration.Calibration.Name,
+        deviceOnly: configuration.Calibration.GUID === controller.GUID,
         controls: configuration.Calibration.Controls.map(
           (control): CalibrationStateControl => ({
             kind: control.Kind as Kind,
             index: control.Index,
+            direction: control.Direction || undefined,
             name: control.Name,
             min: control.Min,
             max: control.Max,
@@ -93,9 +180,17 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
             value: control.Idle,
             easingCurve: control.EasingCurve,
             override: false,
+            detents: (control.Detents ?? []).map((detent) => ({
+              name: detent.Name,
+              value: detent.Value,
+              captureWidth: detent.CaptureWidth,
+            })),
+            responseCurve: control.ResponseCurve,
+            responseCurveBelowIdle: control.ResponseCurveBelowIdle,
+            filter: control.Filter,
           }),
         ).toSorted((a, b) =>
-          `${a.kind}_${a.index}`.localeCompare(`${b.kind}_${b.index}`),
+          controlKey(a).localeCompare(controlKey(b)),
         ),
       });
     });
@@ -103,7 +198,10 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
 
   return (
     <div>
-      <h3 className="font-bold text-base">Configuring {controller?.Name}</h3>
+      <h3 className="font-bold text-base">
+        Configuring {controller?.Name}
+        {!!controller?.Alias && ` (${controller.Alias})`}
+      </h3>
       <div className="py-4 grid grid-cols-1 grid-flow-row auto-rows-max gap-2">
         <div>
           <label className="input input-xs">
@@ -115,10 +213,46 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
             />
           </label>
         </div>
+        <div>
+          <label className="label text-xs">
+            <input
+              type="checkbox"
+              className="checkbox checkbox-xs"
+              {...form.register(`deviceOnly`)}
+            />
+            Only use this calibration for this controller (for identical
+            controllers with different calibrations)
+          </label>
+        </div>
+
+        {!!detected && (
+          <div
+            role="alert"
+            className="alert alert-soft alert-info text-xs items-start"
+          >
+            <div>
+              <div className="font-bold">
+                Review the detected calibration before saving
+              </div>
+              <ul className="list-disc pl-4">
+                {!!detected.Error && <li>{detected.Error}</li>}
+                {detected.Warnings?.map((warning) => (
+                  <li key={warning}>{warning}</li>
+                ))}
+                {detected.Axes?.map((axis) => (
+                  <li key={axis.Index}>
+                    {axis.Name}:{" "}
+                    {axisNotes(axis).join(", ") || "detected without issues"}
+                  </li>
+                ))}
+              </ul>
+            </div>
+          </div>
+        )}
 
         <div>
           {controls.map((control, index) => (
-            <div key={`${control.kind}_${control.index}`}>
+            <div key={controlKey(control)}>
               <CalibrationModalFormControl
                 form={form}
                 index={index}
@@ -134,16 +268,25 @@ export const CalibrationModalForm = ({ controller, onClose }: Props) => {
         </button>
         {!isRunning && (
           <button className="btn btn-sm" onClick={handleStart}>
-            Start
+            {detected ? "Restart" : "Start"}
           </button>
         )}
         {isRunning && (
           <button
             className="btn btn-sm"
             disabled={!controller}
-            onClick={handleStopAndSave}
+            onClick={handleStopAndReview}
+          >
+            Stop & Review
+          </button>
+        )}
+        {!!detected && (
+          <button
+            className="btn btn-sm btn-primary"
+            disabled={!controller}
+            onClick={handleSave}
           >
-            Stop & Save
+            Save
           </button>
         )}
       </div>
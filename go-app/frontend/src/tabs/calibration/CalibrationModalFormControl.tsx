@@ -1,8 +1,12 @@
 import {
   CalibrationStateControl,
+  controlKey,
   UseCalibrationFormType,
 } from "./useCalibrationForm";
 
+/* the default raw distance around a detent which snaps to it */
+const DEFAULT_DETENT_CAPTURE_WIDTH = 500;
+
 type Props = {
   form: UseCalibrationFormType;
   index: number;
@@ -10,9 +14,36 @@ type Props = {
 };
 
 export const CalibrationModalFormControl = ({ form, index, field }: Props) => {
+  const handleRecordDetent = () => {
+    const detents = form.getValues(`controls.${index}.detents`);
+    form.setValue(
+      `controls.${index}.detents`,
+      [
+        ...detents,
+        {
+          name: `notch${detents.length + 1}`,
+          /* detents are compared after inverting like min, max and idle */
+          value: field.invert ? -field.value : field.value,
+          captureWidth: DEFAULT_DETENT_CAPTURE_WIDTH,
+        },
+      ],
+      { shouldDirty: true },
+    );
+  };
+
+  const handleRemoveDetent = (detentIndex: number) => {
+    form.setValue(
+      `controls.${index}.detents`,
+      form
+        .getValues(`controls.${index}.detents`)
+        .filter((_, i) => i !== detentIndex),
+      { shouldDirty: true },
+    );
+  };
+
   return (
     <div
-      key={`${field.kind}_${field.index}`}
+      key={controlKey(field)}
       className="card card-sm shadow-sm"
     >
       <div className="card-body">
@@ -20,13 +51,13 @@ export const CalibrationModalFormControl = ({ form, index, field }: Props) => {
           <div className="flex flex-col basis-full gap-2">
             <div className="flex justify-between items-center">
               <div>
-                {field.kind} {field.index}
+                {field.kind} {field.index} {field.direction}
               </div>
               <div>
                 <kbd className="kbd kbd-sm">{field.value}</kbd>
               </div>
             </div>
-            {field.kind === "axis" && (
+            {(field.kind === "axis" || field.kind === "controller_axis") && (
               <div>
                 {field.invert && (
                   <progress
@@ -60,7 +91,7 @@ export const CalibrationModalFormControl = ({ form, index, field }: Props) => {
                 />
               </label>
             </div>
-            {field.kind === "axis" && (
+            {(field.kind === "axis" || field.kind === "controller_axis") && (
               <>
                 <div className="grid grid-cols-2 grid-flow-row auto-rows-max gap-2">
                   <label className="input input-xs">
@@ -132,6 +163,55 @@ export const CalibrationModalFormControl = ({ form, index, field }: Props) => {
                     Override calibration values
                   </label>
                 </div>
+                <div className="grid grid-cols-1 grid-flow-row auto-rows-max gap-2">
+                  <div className="flex justify-between items-center text-xs">
+                    <div>Detents</div>
+                    <button
+                      type="button"
+                      className="btn btn-xs"
+                      onClick={handleRecordDetent}
+                    >
+                      Record detent at current position
+                    </button>
+                  </div>
+                  {field.detents.map((detent, detentIndex) => (
+                    <div
+                      key={detentIndex}
+                      className="flex flex-row gap-2 items-center"
+                    >
+                      <label className="input input-xs">
+                        Name
+                        <input
+                          type="text"
+                          className="grow"
+                          {...form.register(
+                            `controls.${index}.detents.${detentIndex}.name`,
+                            { required: true },
+                          )}
+                        />
+                      </label>
+                      <kbd className="kbd kbd-sm">{detent.value}</kbd>
+                      <label className="input input-xs">
+                        Capture width
+                        <input
+                          type="number"
+                          className="grow"
+                          {...form.register(
+                            `controls.${index}.detents.${detentIndex}.captureWidth`,
+                            { valueAsNumber: true, required: true },
+                          )}
+                        />
+                      </label>
+                      <button
+                        type="button"
+                        className="btn btn-xs btn-ghost"
+                        onClick={() => handleRemoveDetent(detentIndex)}
+                      >
+                        Remove
+                      </button>
+                    </div>
+                  ))}
+                </div>
               </>
             )}
           </div>
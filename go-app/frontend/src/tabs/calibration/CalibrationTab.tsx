@@ -31,9 +31,12 @@ export const CalibrationTab = () => {
     <div>
       <ul className="list bg-base-100 rounded-box shadow-md">
         {controllers?.map((c) => (
-          <li key={c.Name} className="list-row">
+          <li key={c.GUID} className="list-row">
             <div className="list-col-grow">
               <div>{c.Name}</div>
+              {!!c.Alias && (
+                <div className="text-xs text-base-content/50">{c.Alias}</div>
+              )}
             </div>
             <div>
               {c.IsConfigured && (
@@ -2,12 +2,25 @@ import { useEffect } from "react";
 import { useForm } from "react-hook-form";
 import { EventsOn } from "../../../wailsjs/runtime/runtime";
 import { events } from "../../events";
-import { main } from "../../../wailsjs/go/models";
+import { config, main } from "../../../wailsjs/go/models";
 
-export type Kind = "axis" | "button" | "hat";
+export type Kind =
+  | "axis"
+  | "button"
+  | "hat"
+  | "controller_axis"
+  | "controller_button";
+export type CalibrationStateDetent = {
+  name: string;
+  /* compared after inverting like min, max and idle */
+  value: number;
+  captureWidth: number;
+}
 export type CalibrationStateControl = {
   kind: Kind;
   index: number;
+  /* the hat direction exposed as a button; hats without one report their raw value */
+  direction?: string;
   value: number;
   name: string;
   min: number;
@@ -17,14 +30,28 @@ export type CalibrationStateControl = {
   invert: boolean;
   easingCurve: number[];
   override: boolean;
+  detents: CalibrationStateDetent[];
+  /* kept as is; response curves and filters are edited in the calibration file */
+  responseCurve?: config.Config_Controller_CalibrationData_Curve;
+  responseCurveBelowIdle?: config.Config_Controller_CalibrationData_Curve;
+  filter?: config.Config_Controller_CalibrationData_Filter;
 }
 export type CalibrationState = {
   name: string;
+  /* only applies the calibration to this controller instead of all identical controllers */
+  deviceOnly: boolean;
   controls: CalibrationStateControl[]
 };
 
 export type UseCalibrationFormType = ReturnType<typeof useCalibrationForm>
 
+/* identifies the control; a hat has a control for each of its directions */
+export const controlKey = (control: Pick<CalibrationStateControl, 'kind' | 'index' | 'direction'>) => (
+  control.direction ? `${control.kind}_${control.index}_${control.direction}` : `${control.kind}_${control.index}`
+)
+
+const HAT_CENTERED = "centered"
+
 const EMPTY_CONTROL_STATE: Omit<CalibrationStateControl, 'kind' | 'index'> = {
   deadzone: 0,
   invert: false,
@@ -37,12 +64,14 @@ const EMPTY_CONTROL_STATE: Omit<CalibrationStateControl, 'kind' | 'index'> = {
   max: Number.MIN_SAFE_INTEGER,
   easingCurve: [0.0, 0.0, 1.0, 1.0],
   override: false,
+  detents: [],
 }
 
 export const useCalibrationForm = () => {
   const form = useForm<CalibrationState>({
     defaultValues: {
       name: "",
+      deviceOnly: false,
       controls: [],
     },
   });
@@ -50,23 +79,35 @@ export const useCalibrationForm = () => {
   useEffect(() => {
     return EventsOn(events.rawevent, (data: main.Interop_RawEvent) => {
       const controls = form.getValues('controls')
-      const existingIndex = form.getValues('controls').findIndex((c) => (
-        c.kind === data.Kind && c.index === data.Index
+      if (data.Kind === "hat" && data.Direction) {
+        /* the directions the hat no longer points in are released */
+        controls.forEach((c, i) => {
+          if (c.kind === "hat" && c.index === data.Index && c.direction && c.direction !== data.Direction && c.value !== 0) {
+            form.setValue(`controls.${i}.value`, 0)
+          }
+        })
+        if (data.Direction === HAT_CENTERED) return
+      }
+
+      const direction = data.Kind === "hat" && data.Direction ? data.Direction : undefined
+      const existingIndex = controls.findIndex((c) => (
+        c.kind === data.Kind && c.index === data.Index && c.direction === direction
       ))
+      const value = direction ? 1 : data.Value
 
       const controlState: CalibrationStateControl = existingIndex === -1
-        ? { ...EMPTY_CONTROL_STATE, kind: data.Kind as Kind, index: data.Index }
+        ? { ...EMPTY_CONTROL_STATE, kind: data.Kind as Kind, index: data.Index, direction }
         : { ...controls[existingIndex] }
 
       if (!controlState.override) {
         Object.assign(controlState, {
-          value: data.Value,
-          min: Math.min(controlState.min, data.Value),
-          max: Math.max(controlState.max, data.Value),
-          idle: Math.min(controlState.min, data.Value),
+          value,
+          min: Math.min(controlState.min, value),
+          max: Math.max(controlState.max, value),
+          idle: Math.min(controlState.min, value),
         } as Partial<CalibrationStateControl>)
       } else {
-        controlState.value = data.Value
+        controlState.value = value
       }
 
       if (existingIndex === -1) {
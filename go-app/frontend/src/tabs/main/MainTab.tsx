@@ -6,6 +6,7 @@ import {
   SelectProfile,
   ClearProfile,
   GetSelectedProfiles,
+  GetProfileSelections,
   InstallTrainSimWorldMod,
   OpenConfigDirectory,
   GetLastInstalledModVersion,
@@ -17,12 +18,15 @@ import {
   OpenNewProfileBuilder,
   SaveProfileForSharing,
   ImportProfile,
+  RumbleController,
 } from "../../../wailsjs/go/main/App";
-import { useCallback, useEffect } from "react";
+import { useCallback, useEffect, useMemo, useState } from "react";
 import { BrowserOpenURL, EventsOn } from "../../../wailsjs/runtime/runtime";
 import { events } from "../../events";
 import { useForm } from "react-hook-form";
 import { MainTabControllerProfileSelector } from "./MainTabControllerProfileSelecor";
+import { MainTabControllerIdentifyModal } from "./MainTabControllerIdentifyModal";
+import { MainTabControllerProfilePolicyModal } from "./MainTabControllerProfilePolicyModal";
 import { main } from "../../../wailsjs/go/models";
 import { alert } from "../../utils/alert";
 import { confirm } from "../../utils/confirm";
@@ -31,6 +35,11 @@ type FormValues = {
   profiles: Partial<Awaited<ReturnType<typeof GetSelectedProfiles>>>;
 };
 
+type IdentifyTarget = {
+  controllers: main.Interop_GenericController[];
+  controller?: main.Interop_GenericController;
+};
+
 export const MainTab = () => {
   const { data: versionInfo, mutate: refetchVersionInfo } = useSWR(
     "version-info",
@@ -54,6 +63,28 @@ export const MainTab = () => {
     { revalidateOnMount: true },
   );
 
+  const { data: profileSelections, mutate: refetchProfileSelections } =
+    useSWR("profile-selections", () => GetProfileSelections(), {
+      revalidateOnMount: true,
+    });
+
+  const [policyController, setPolicyController] =
+    useState<main.Interop_GenericController | null>(null);
+  const [identifyTarget, setIdentifyTarget] = useState<IdentifyTarget | null>(
+    null,
+  );
+  /* identical controllers grouped by their USB ID */
+  const identicalControllers = useMemo(() => {
+    const byUsbId = new Map<string, main.Interop_GenericController[]>();
+    for (const controller of controllers ?? []) {
+      byUsbId.set(controller.UsbID, [
+        ...(byUsbId.get(controller.UsbID) ?? []),
+        controller,
+      ]);
+    }
+    return [...byUsbId.values()].filter((group) => group.length > 1);
+  }, [controllers]);
+
   const form = useForm<FormValues>({
     defaultValues: async () => ({
       profiles: await GetSelectedProfiles(),
@@ -65,22 +96,28 @@ export const MainTab = () => {
     BrowserOpenURL(url);
   }, []);
 
-  const trySyncSelectedProfiles = useCallback(() => {
-    const profiles = getValues("profiles");
-    for (const guid in profiles) {
-      if (profiles[guid]) {
-        SelectProfile(guid, profiles[guid].Id).catch(() => {
+  const trySyncSelectedProfile = useCallback(
+    (guid: string) => {
+      const profile = getValues(`profiles.${guid}`);
+      if (profile) {
+        SelectProfile(guid, profile.Id).catch(() => {
           ClearProfile(guid);
           form.setValue(`profiles.${guid}`, undefined);
         });
       } else {
         ClearProfile(guid);
       }
-    }
+    },
+    [form],
+  );
+
+  /* the selections are persisted and restored by the app so the form follows the app */
+  const refreshSelectedProfiles = useCallback(() => {
+    GetSelectedProfiles().then((profiles) => form.reset({ profiles }));
   }, [form]);
 
   const handleReloadConfiguration = () => {
-    LoadConfiguration().then(trySyncSelectedProfiles);
+    LoadConfiguration().then(refreshSelectedProfiles);
   };
 
   const handleBrowseConfig = () => {
@@ -132,6 +169,31 @@ export const MainTab = () => {
     }
   };
 
+  const handleSetAlias = (controller: main.Interop_GenericController) => {
+    setIdentifyTarget({
+      controllers: (controllers ?? []).filter(
+        (c) => c.UsbID === controller.UsbID,
+      ),
+      controller,
+    });
+  };
+
+  const handleRumble = (controller: main.Interop_GenericController) => {
+    RumbleController(controller.GUID).catch((err) => alert(String(err), "error"));
+  };
+
+  const handleAliasSaved = () => {
+    setIdentifyTarget(null);
+    /* the selected profiles move along when the alias changes the controller GUID */
+    refetchControllers();
+    refreshSelectedProfiles();
+  };
+
+  const handlePolicySaved = () => {
+    setPolicyController(null);
+    refetchProfileSelections();
+  };
+
   const handleInstall = () => {
     InstallTrainSimWorldMod()
       .then(() => refetchVersionInfo())
@@ -153,8 +215,33 @@ export const MainTab = () => {
   };
 
   useEffect(() => {
-    return watch(trySyncSelectedProfiles).unsubscribe;
-  }, [trySyncSelectedProfiles]);
+    return watch((_, { name }) => {
+      /* only selections changed by the user are synced; resetting the form has no name */
+      const guid = name?.replace(/^profiles\./, "");
+      if (guid && guid !== name) {
+        trySyncSelectedProfile(guid);
+      }
+    }).unsubscribe;
+  }, [trySyncSelectedProfile]);
+
+  useEffect(() => {
+    return EventsOn(events.selected_profiles_updated, refreshSelectedProfiles);
+  }, [refreshSelectedProfiles]);
+
+  useEffect(() => {
+    return EventsOn(
+      events.profile_selection_changed,
+      (selection: main.Interop_ProfileSelection) => {
+        refetchProfileSelections();
+        if (selection.Reason === "auto_select") {
+          alert(
+            `Auto-selected ${selection.ProfileName}: ${selection.Description}`,
+            "info",
+          );
+        }
+      },
+    );
+  }, []);
 
   useEffect(() => {
     return EventsOn(events.profiles_updated, () => {
@@ -183,12 +270,31 @@ export const MainTab = () => {
           </button>
         </span>
       </div>
+      {identicalControllers.map((group) => (
+        <div
+          key={group[0].UsbID}
+          role="alert"
+          className="alert alert-soft alert-info"
+        >
+          <span>
+            {group.length} identical {group[0].Name} controllers are connected.
+            Give them an alias to keep their profiles and calibrations apart.
+          </span>
+          <button
+            className="btn btn-sm"
+            onClick={() => setIdentifyTarget({ controllers: group })}
+          >
+            Identify
+          </button>
+        </div>
+      ))}
       <div>
         {controllers?.map((c) => (
           <div key={c.GUID}>
             <MainTabControllerProfileSelector
               controller={c}
               profiles={profiles ?? []}
+              selection={profileSelections?.[c.GUID]}
               form={form}
               onBrowseConfiguration={handleBrowseConfig}
               onCreateProfile={handleCreateProfile}
@@ -196,6 +302,9 @@ export const MainTab = () => {
               onSaveControllerProfileForSharing={handleSaveProfileForSharing}
               onOpenProfileForController={handleOpenProfile}
               onDeleteProfileForController={handleDeleteProfile}
+              onSetAliasForController={handleSetAlias}
+              onRumbleController={handleRumble}
+              onSetProfilePolicyForController={setPolicyController}
             />
           </div>
         ))}
@@ -203,6 +312,27 @@ export const MainTab = () => {
       <p className="text-xs text-base-content/50">
         Note: for auto-detection to work it has to be supported by the profile.
       </p>
+      {!!identifyTarget && (
+        <MainTabControllerIdentifyModal
+          key={
+            identifyTarget.controller?.GUID ??
+            identifyTarget.controllers[0]?.UsbID
+          }
+          controllers={identifyTarget.controllers}
+          controller={identifyTarget.controller}
+          onClose={() => setIdentifyTarget(null)}
+          onSaved={handleAliasSaved}
+        />
+      )}
+      {!!policyController && (
+        <MainTabControllerProfilePolicyModal
+          key={policyController.GUID}
+          controller={policyController}
+          profiles={profiles ?? []}
+          onClose={() => setPolicyController(null)}
+          onSaved={handlePolicySaved}
+        />
+      )}
       <div className="divider"></div>
       {/* steam://controllerconfig/2967990/3576092503 */}
       <div className="flex gap-2">
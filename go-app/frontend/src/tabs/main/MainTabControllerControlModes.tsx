@@ -0,0 +1,80 @@
+import { useEffect } from "react";
+import useSWR from "swr";
+import { GetControlModeStatus } from "../../../wailsjs/go/main/App";
+import { main } from "../../../wailsjs/go/models";
+
+type Props = {
+  controller: main.Interop_GenericController;
+};
+
+const controlModeLabels: Record<string, string> = {
+  direct_control: "Direct Control",
+  api_control: "API Control",
+  sync_control: "Sync Control",
+  keys: "Keys",
+};
+
+export function MainTabControllerControlModes({ controller }: Props) {
+  const { data: status, mutate: refetchStatus } = useSWR(
+    `control-mode-status-${controller.GUID}`,
+    () => GetControlModeStatus(controller.GUID),
+    { revalidateOnMount: true },
+  );
+
+  useEffect(() => {
+    const interval = setInterval(() => {
+      refetchStatus();
+    }, 1000);
+    return () => clearInterval(interval);
+  }, [refetchStatus]);
+
+  if (!status) return null;
+
+  const availability = [
+    { label: "Direct Control", available: status.DirectControlAvailable },
+    { label: "API Control", available: status.ApiControlAvailable },
+    { label: "Sync Control", available: status.SyncControlAvailable },
+  ];
+
+  return (
+    <div className="collapse collapse-arrow bg-base-100 border-base-300 border">
+      <input type="checkbox" />
+      <div className="collapse-title text-xs flex flex-wrap gap-2 items-center">
+        <span>Control modes</span>
+        {availability.map(({ label, available }) => (
+          <span
+            key={label}
+            className={`badge badge-sm badge-soft ${available ? "badge-success" : "badge-warning"}`}
+          >
+            {label}: {available ? "available" : "unavailable"}
+          </span>
+        ))}
+      </div>
+      <div className="collapse-content text-xs">
+        {!status.Controls.length && (
+          <p className="text-base-content/50">
+            Move a control to see which control mode is used.
+          </p>
+        )}
+        <ul className="list">
+          {status.Controls.map((control) => (
+            <li key={control.ControlName} className="list-row p-1">
+              <div className="list-col-grow">{control.ControlName}</div>
+              <div>
+                <span className="badge badge-sm badge-info badge-soft">
+                  {controlModeLabels[control.ControlMode] ??
+                    control.ControlMode}
+                </span>
+              </div>
+              <div className="text-base-content/50">
+                {control.Fallback.map(
+                  (mode) => controlModeLabels[mode] ?? mode,
+                ).join(" → ")}
+              </div>
+            </li>
+          ))}
+        </ul>
+      </div>
+    </div>
+  );
+}
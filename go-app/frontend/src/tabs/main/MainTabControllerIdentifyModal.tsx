@@ -0,0 +1,135 @@
+import { FormEvent, useEffect, useState } from "react";
+import { main } from "../../../wailsjs/go/models";
+import {
+  IdentifyController,
+  SetControllerAlias,
+} from "../../../wailsjs/go/main/App";
+import { alert } from "../../utils/alert";
+
+type Props = {
+  /* the identical controllers to tell apart */
+  controllers: main.Interop_GenericController[];
+  /* skips identifying when the controller is already known */
+  controller?: main.Interop_GenericController;
+  onClose: () => void;
+  onSaved: () => void;
+};
+
+const identitySourceLabels: Record<string, string> = {
+  serial: "Recognized by its serial number",
+  path: "Recognized by the USB port it is plugged into",
+  alias: "Recognized by its alias and the order it was connected in",
+  order: "Recognized by the order it was connected in",
+};
+
+export function MainTabControllerIdentifyModal({
+  controllers,
+  controller: knownController,
+  onClose,
+  onSaved,
+}: Props) {
+  const [controller, setController] = useState(knownController);
+  const [alias, setAlias] = useState(knownController?.Alias ?? "");
+  const [attempt, setAttempt] = useState(0);
+  const [error, setError] = useState<string | null>(null);
+  const usbId = knownController?.UsbID ?? controllers[0]?.UsbID;
+
+  useEffect(() => {
+    if (controller || !usbId) return;
+    let cancelled = false;
+    IdentifyController(usbId)
+      .then((guid) => {
+        if (cancelled) return;
+        const identified = controllers.find((c) => c.GUID === guid);
+        setController(identified);
+        setAlias(identified?.Alias ?? "");
+      })
+      .catch((err) => {
+        if (!cancelled) setError(String(err));
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [controller, usbId, attempt]);
+
+  const handleRetry = () => {
+    setError(null);
+    setController(undefined);
+    setAttempt((attempt) => attempt + 1);
+  };
+
+  const handleSave = (event: FormEvent) => {
+    event.preventDefault();
+    if (!controller) return;
+    SetControllerAlias(controller.GUID, alias)
+      .then(onSaved)
+      .catch((err) => alert(String(err), "error"));
+  };
+
+  return (
+    <dialog className="modal" ref={(ref) => ref?.showModal()} onClose={onClose}>
+      <div className="modal-box">
+        <h3 className="text-lg font-bold">Identify controller</h3>
+        {!controller && !error && (
+          <p className="py-4 flex gap-2 items-center">
+            <span className="loading loading-spinner loading-sm" />
+            Move a lever or press a button on the controller you want to
+            identify
+          </p>
+        )}
+        {!!error && (
+          <div role="alert" className="alert alert-soft alert-warning my-4">
+            <span>{error}</span>
+            <button className="btn btn-sm" onClick={handleRetry}>
+              Try again
+            </button>
+          </div>
+        )}
+        {!!controller && (
+          <form id="identify-controller-alias" onSubmit={handleSave}>
+            <p className="py-2">
+              {controller.Name}
+              {!!controller.Alias && ` (${controller.Alias})`}
+            </p>
+            <p className="text-xs text-base-content/50 pb-4">
+              {identitySourceLabels[controller.IdentitySource] ??
+                controller.IdentitySource}
+            </p>
+            <label className="input input-sm w-full">
+              Alias
+              <input
+                type="text"
+                className="grow"
+                placeholder="eg: Left, Right"
+                value={alias}
+                onChange={(event) => setAlias(event.target.value)}
+              />
+            </label>
+            <p className="text-xs text-base-content/50 pt-2">
+              Using an alias of another identical controller swaps the aliases.
+            </p>
+          </form>
+        )}
+        <div className="modal-action">
+          {!!controller && !knownController && (
+            <button className="btn btn-sm" onClick={handleRetry}>
+              Identify another
+            </button>
+          )}
+          <form method="dialog">
+            <button className="btn btn-sm">Close</button>
+          </form>
+          {!!controller && (
+            <button
+              type="submit"
+              form="identify-controller-alias"
+              className="btn btn-sm btn-primary"
+            >
+              Save alias
+            </button>
+          )}
+        </div>
+      </div>
+    </dialog>
+  );
+}
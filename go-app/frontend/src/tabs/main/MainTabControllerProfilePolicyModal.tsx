@@ -0,0 +1,111 @@
+import { FormEvent, useEffect, useState } from "react";
+import { main } from "../../../wailsjs/go/models";
+import {
+  GetControllerProfilePolicy,
+  SetControllerProfilePolicy,
+} from "../../../wailsjs/go/main/App";
+import { alert } from "../../utils/alert";
+
+type Props = {
+  controller: main.Interop_GenericController;
+  profiles: main.Interop_Profile[];
+  onClose: () => void;
+  onSaved: () => void;
+};
+
+export function MainTabControllerProfilePolicyModal({
+  controller,
+  profiles,
+  onClose,
+  onSaved,
+}: Props) {
+  const [policy, setPolicy] = useState<main.Interop_ControllerProfilePolicy>();
+  const supportedProfiles = profiles.filter(
+    (profile) =>
+      !profile.UsbID ||
+      profile.UsbID === "*" ||
+      profile.UsbID === controller.UsbID,
+  );
+
+  useEffect(() => {
+    GetControllerProfilePolicy(controller.GUID)
+      .then(setPolicy)
+      .catch((err) => alert(String(err), "error"));
+  }, [controller.GUID]);
+
+  const handleSave = (event: FormEvent) => {
+    event.preventDefault();
+    if (!policy) return;
+    SetControllerProfilePolicy(controller.GUID, policy)
+      .then(onSaved)
+      .catch((err) => alert(String(err), "error"));
+  };
+
+  return (
+    <dialog className="modal" ref={(ref) => ref?.showModal()} onClose={onClose}>
+      <div className="modal-box">
+        <h3 className="text-lg font-bold">Profile selection</h3>
+        <p className="text-xs text-base-content/50 pb-4">
+          {controller.Name}
+          {!!controller.Alias && ` (${controller.Alias})`}
+        </p>
+        {!!policy && (
+          <form
+            id="controller-profile-policy"
+            className="grid grid-cols-1 gap-4"
+            onSubmit={handleSave}
+          >
+            <label className="label">
+              <input
+                type="checkbox"
+                className="checkbox checkbox-sm"
+                checked={policy.StickyManualSelection}
+                onChange={(event) =>
+                  setPolicy({
+                    ...policy,
+                    StickyManualSelection: event.target.checked,
+                  })
+                }
+              />
+              Keep a manually selected profile when another profile can be
+              auto-selected
+            </label>
+            <label className="select select-sm w-full">
+              <span className="label">Fallback profile</span>
+              <select
+                value={policy.FallbackProfileId}
+                onChange={(event) =>
+                  setPolicy({ ...policy, FallbackProfileId: event.target.value })
+                }
+              >
+                <option value="">None</option>
+                {supportedProfiles.map((profile) => (
+                  <option key={profile.Id} value={profile.Id}>
+                    {profile.Name}
+                  </option>
+                ))}
+              </select>
+            </label>
+            <p className="text-xs text-base-content/50">
+              The fallback profile is used when no profile is selected and no
+              profile can be auto-selected for the current train.
+            </p>
+          </form>
+        )}
+        <div className="modal-action">
+          <form method="dialog">
+            <button className="btn btn-sm">Close</button>
+          </form>
+          <button
+            type="submit"
+            form="controller-profile-policy"
+            className="btn btn-sm btn-primary"
+            disabled={!policy}
+          >
+            Save
+          </button>
+        </div>
+      </div>
+    </dialog>
+  );
+}
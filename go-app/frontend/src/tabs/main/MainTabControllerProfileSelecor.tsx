@@ -1,6 +1,7 @@
 import { Controller, UseFormReturn } from "react-hook-form";
 import { main } from "../../../wailsjs/go/models";
 import { useCallback } from "react";
+import { MainTabControllerControlModes } from "./MainTabControllerControlModes";
 
 type Props = {
   form: UseFormReturn<{
@@ -8,6 +9,8 @@ type Props = {
   }>;
   controller: main.Interop_GenericController;
   profiles: main.Interop_Profile[];
+  /* the profile currently used by the controller and why */
+  selection?: main.Interop_ProfileSelection;
   onReloadConfiguration: () => void;
   onBrowseConfiguration: () => void;
   onCreateProfile: (controller: main.Interop_GenericController) => void;
@@ -20,6 +23,16 @@ type Props = {
   onDeleteProfileForController: (
     controller: main.Interop_GenericController,
   ) => void;
+  onSetAliasForController: (controller: main.Interop_GenericController) => void;
+  onRumbleController: (controller: main.Interop_GenericController) => void;
+  onSetProfilePolicyForController: (
+    controller: main.Interop_GenericController,
+  ) => void;
+};
+
+const selectionReasonLabels: Record<string, string> = {
+  auto_select: "Auto-selected",
+  fallback: "Fallback",
 };
 
 const updatedAtFormatter = new Intl.DateTimeFormat(undefined, {
@@ -37,20 +50,30 @@ export function MainTabControllerProfileSelector({
   form,
   controller,
   profiles,
+  selection,
   onReloadConfiguration,
   onBrowseConfiguration,
   onCreateProfile,
   onSaveControllerProfileForSharing,
   onOpenProfileForController,
   onDeleteProfileForController,
+  onSetAliasForController,
+  onRumbleController,
+  onSetProfilePolicyForController,
 }: Props) {
   const { watch, control } = form;
   const selectedProfile = watch(`profiles.${controller.GUID}`);
   const supportedProfiles = profiles?.filter(
-    (profile) => !profile.UsbID || profile.UsbID === controller.UsbID,
+    (profile) =>
+      !profile.UsbID ||
+      profile.UsbID === "*" ||
+      profile.UsbID === controller.UsbID,
   );
   const unsupportedProfiles = profiles?.filter(
-    (profile) => profile.UsbID && profile.UsbID !== controller.UsbID,
+    (profile) =>
+      profile.UsbID &&
+      profile.UsbID !== "*" &&
+      profile.UsbID !== controller.UsbID,
   );
 
   const unfocusHandlerFactory = useCallback((func: () => void) => {
@@ -66,6 +89,11 @@ export function MainTabControllerProfileSelector({
         htmlFor={`controller_${controller.GUID}`}
         className="fieldset-legend"
       >
+        {!!controller.Alias && (
+          <span className="badge badge-sm badge-soft badge-primary">
+            {controller.Alias}
+          </span>
+        )}
         {controller.Name} ({controller.UsbID})
       </label>
 
@@ -181,6 +209,35 @@ export function MainTabControllerProfileSelector({
                 Create new profile
               </button>
             </li>
+            <li>
+              <button
+                onClick={unfocusHandlerFactory(() =>
+                  onSetAliasForController(controller),
+                )}
+              >
+                Set alias
+              </button>
+            </li>
+            {controller.HasRumble && (
+              <li>
+                <button
+                  onClick={unfocusHandlerFactory(() =>
+                    onRumbleController(controller),
+                  )}
+                >
+                  Test rumble
+                </button>
+              </li>
+            )}
+            <li>
+              <button
+                onClick={unfocusHandlerFactory(() =>
+                  onSetProfilePolicyForController(controller),
+                )}
+              >
+                Profile selection settings
+              </button>
+            </li>
             <li>
               <button
                 disabled={!selectedProfile}
@@ -217,6 +274,13 @@ export function MainTabControllerProfileSelector({
           </ul>
         </div>
       </div>
+      {!!selection && !!selectionReasonLabels[selection.Reason] && (
+        <p className="text-xs text-base-content/50">
+          {selectionReasonLabels[selection.Reason]}: {selection.ProfileName}
+          {!!selection.Description && ` (${selection.Description})`}
+        </p>
+      )}
+      <MainTabControllerControlModes controller={controller} />
     </fieldset>
   );
 }
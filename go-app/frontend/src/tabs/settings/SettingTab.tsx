@@ -8,6 +8,8 @@ import {
   SetAlwaysOnTop,
   GetTheme,
   SetTheme,
+  GetSyncControlFeedback,
+  SetSyncControlFeedback,
 } from "../../../wailsjs/go/main/App";
 import { alert } from "../../utils/alert";
 import { BrowserOpenURL } from "../../../wailsjs/runtime/runtime";
@@ -16,6 +18,7 @@ import { updateTheme } from "../../utils/updateTheme";
 type FormValues = {
   tswApiKeyLocation: string;
   preferredControlMode: "direct_control" | "sync_control" | "api_control";
+  syncControlFeedback: "mod" | "api" | "both";
   alwaysOnTop: boolean;
   theme: "system" | "light" | "dark";
 };
@@ -24,6 +27,8 @@ const getRemoteFormValues = async () => ({
   tswApiKeyLocation: await GetTSWAPIKeyLocation(),
   preferredControlMode:
     (await GetPreferredControlMode()) as FormValues["preferredControlMode"],
+  syncControlFeedback:
+    (await GetSyncControlFeedback()) as FormValues["syncControlFeedback"],
   alwaysOnTop: await GetAlwaysOnTop(),
   theme: (await GetTheme()) as FormValues["theme"],
 });
@@ -61,15 +66,24 @@ export const SettingsTab = () => {
       promises.push(SetPreferredControlMode(values.preferredControlMode));
     }
 
+    if (
+      values.syncControlFeedback &&
+      values.syncControlFeedback !== currentValues.syncControlFeedback
+    ) {
+      promises.push(SetSyncControlFeedback(values.syncControlFeedback));
+    }
+
     if (values.alwaysOnTop !== currentValues.alwaysOnTop) {
       promises.push(SetAlwaysOnTop(values.alwaysOnTop));
     }
 
     if (promises.length) {
-      Promise.all(promises).then(() => {
-        reset(values);
-        alert("Saved settings", "success");
-      });
+      Promise.all(promises)
+        .then(() => {
+          reset(values);
+          alert("Saved settings", "success");
+        })
+        .catch((err) => alert(String(err), "error"));
     }
   };
 
@@ -109,6 +123,24 @@ export const SettingsTab = () => {
           Sets which control mode to prefer if multiple are defined
         </p>
       </fieldset>
+      <fieldset className="fieldset">
+        <label htmlFor="sync-control-feedback" className="fieldset-legend">
+          Sync Control Feedback
+        </label>
+        <select
+          id="sync-control-feedback"
+          className="select w-full"
+          {...register("syncControlFeedback")}
+        >
+          <option value="both">Mod and TSW API</option>
+          <option value="mod">Mod</option>
+          <option value="api">TSW API</option>
+        </select>
+        <p className="fieldset-label whitespace-normal">
+          Where sync control reads the current in-game values from. The TSW API
+          does not require the mod but requires the API key to be set.
+        </p>
+      </fieldset>
       <fieldset className="fieldset">
         <label htmlFor="tsw-api-key-location" className="fieldset-legend">
           TSW API Key Location
@@ -121,7 +153,7 @@ export const SettingsTab = () => {
         <p className="fieldset-label whitespace-normal">
           If the location has not been auto-detected you will need to enter it
           manually here. The API key is only requred for the "api_control"
-          control mode.
+          control mode and for sync control using the TSW API feedback.
         </p>
       </fieldset>
       <fieldset className="fieldset bg-base-100 border-base-300 rounded-box border p-4 w-full">
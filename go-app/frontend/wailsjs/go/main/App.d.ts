@@ -10,8 +10,14 @@ export function GetAlwaysOnTop():Promise<boolean>;
 
 export function GetCabControlState():Promise<main.Interop_Cab_ControlState>;
 
+export function GetCalibrationSessionResult():Promise<main.Interop_CalibrationSessionResult>;
+
+export function GetControlModeStatus(arg1:string):Promise<main.Interop_ControlModeStatus>;
+
 export function GetControllerConfiguration(arg1:string):Promise<main.Interop_ControllerConfiguration>;
 
+export function GetControllerProfilePolicy(arg1:string):Promise<main.Interop_ControllerProfilePolicy>;
+
 export function GetControllers():Promise<Array<main.Interop_GenericController>>;
 
 export function GetLastInstalledModVersion():Promise<string>;
@@ -20,6 +26,8 @@ export function GetLatestReleaseVersion():Promise<string>;
 
 export function GetPreferredControlMode():Promise<string>;
 
+export function GetProfileSelections():Promise<Record<string, main.Interop_ProfileSelection>>;
+
 export function GetProfiles():Promise<Array<main.Interop_Profile>>;
 
 export function GetSelectedProfile():Promise<main.Interop_SelectedProfileInfo>;
@@ -28,6 +36,8 @@ export function GetSelectedProfiles():Promise<Record<string, main.Interop_Select
 
 export function GetSharedProfiles():Promise<Array<main.Interop_SharedProfile>>;
 
+export function GetSyncControlFeedback():Promise<string>;
+
 export function GetTSWAPIKeyLocation():Promise<string>;
 
 export function GetTheme():Promise<string>;
@@ -36,6 +46,8 @@ export function GetVersion():Promise<string>;
 
 export function HasNewerVersion():Promise<boolean>;
 
+export function IdentifyController(arg1:string):Promise<string>;
+
 export function ImportProfile():Promise<void>;
 
 export function ImportSharedProfile(arg1:main.Interop_SharedProfile):Promise<void>;
@@ -52,6 +64,8 @@ export function OpenNewProfileBuilder(arg1:string):Promise<void>;
 
 export function OpenProfileBuilder(arg1:string):Promise<void>;
 
+export function RumbleController(arg1:string):Promise<void>;
+
 export function ResetCabControlState():Promise<void>;
 
 export function SaveCalibration(arg1:main.Interop_ControllerCalibration):Promise<void>;
@@ -64,14 +78,24 @@ export function SelectProfile(arg1:string,arg2:string):Promise<void>;
 
 export function SetAlwaysOnTop(arg1:boolean):Promise<void>;
 
+export function SetControllerAlias(arg1:string,arg2:string):Promise<void>;
+
+export function SetControllerProfilePolicy(arg1:string,arg2:main.Interop_ControllerProfilePolicy):Promise<void>;
+
 export function SetLastInstalledModVersion(arg1:string):Promise<void>;
 
 export function SetPreferredControlMode(arg1:string):Promise<void>;
 
+export function SetSyncControlFeedback(arg1:string):Promise<void>;
+
 export function SetTSWAPIKeyLocation(arg1:string):Promise<void>;
 
 export function SetTheme(arg1:string):Promise<void>;
 
+export function StartCalibrationSession(arg1:string):Promise<void>;
+
+export function StopCalibrationSession():Promise<main.Interop_CalibrationSessionResult>;
+
 export function SubscribeRaw(arg1:string):Promise<void>;
 
 export function UnsubscribeRaw():Promise<void>;
@@ -18,10 +18,22 @@ export function GetCabControlState() {
   return window['go']['main']['App']['GetCabControlState']();
 }
 
+export function GetCalibrationSessionResult() {
+  return window['go']['main']['App']['GetCalibrationSessionResult']();
+}
+
+export function GetControlModeStatus(arg1) {
+  return window['go']['main']['App']['GetControlModeStatus'](arg1);
+}
+
 export function GetControllerConfiguration(arg1) {
   return window['go']['main']['App']['GetControllerConfiguration'](arg1);
 }
 
+export function GetControllerProfilePolicy(arg1) {
+  return window['go']['main']['App']['GetControllerProfilePolicy'](arg1);
+}
+
 export function GetControllers() {
   return window['go']['main']['App']['GetControllers']();
 }
@@ -38,6 +50,10 @@ export function GetPreferredControlMode() {
   return window['go']['main']['App']['GetPreferredControlMode']();
 }
 
+export function GetProfileSelections() {
+  return window['go']['main']['App']['GetProfileSelections']();
+}
+
 export function GetProfiles() {
   return window['go']['main']['App']['GetProfiles']();
 }
@@ -54,6 +70,10 @@ export function GetSharedProfiles() {
   return window['go']['main']['App']['GetSharedProfiles']();
 }
 
+export function GetSyncControlFeedback() {
+  return window['go']['main']['App']['GetSyncControlFeedback']();
+}
+
 export function GetTSWAPIKeyLocation() {
   return window['go']['main']['App']['GetTSWAPIKeyLocation']();
 }
@@ -70,6 +90,10 @@ export function HasNewerVersion() {
   return window['go']['main']['App']['HasNewerVersion']();
 }
 
+export function IdentifyController(arg1) {
+  return window['go']['main']['App']['IdentifyController'](arg1);
+}
+
 export function ImportProfile() {
   return window['go']['main']['App']['ImportProfile']();
 }
@@ -102,6 +126,10 @@ export function OpenProfileBuilder(arg1) {
   return window['go']['main']['App']['OpenProfileBuilder'](arg1);
 }
 
+export function RumbleController(arg1) {
+  return window['go']['main']['App']['RumbleController'](arg1);
+}
+
 export function ResetCabControlState() {
   return window['go']['main']['App']['ResetCabControlState']();
 }
@@ -126,6 +154,14 @@ export function SetAlwaysOnTop(arg1) {
   return window['go']['main']['App']['SetAlwaysOnTop'](arg1);
 }
 
+export function SetControllerAlias(arg1, arg2) {
+  return window['go']['main']['App']['SetControllerAlias'](arg1, arg2);
+}
+
+export function SetControllerProfilePolicy(arg1, arg2) {
+  return window['go']['main']['App']['SetControllerProfilePolicy'](arg1, arg2);
+}
+
 export function SetLastInstalledModVersion(arg1) {
   return window['go']['main']['App']['SetLastInstalledModVersion'](arg1);
 }
@@ -134,6 +170,10 @@ export function SetPreferredControlMode(arg1) {
   return window['go']['main']['App']['SetPreferredControlMode'](arg1);
 }
 
+export function SetSyncControlFeedback(arg1) {
+  return window['go']['main']['App']['SetSyncControlFeedback'](arg1);
+}
+
 export function SetTSWAPIKeyLocation(arg1) {
   return window['go']['main']['App']['SetTSWAPIKeyLocation'](arg1);
 }
@@ -142,6 +182,14 @@ export function SetTheme(arg1) {
   return window['go']['main']['App']['SetTheme'](arg1);
 }
 
+export function StartCalibrationSession(arg1) {
+  return window['go']['main']['App']['StartCalibrationSession'](arg1);
+}
+
+export function StopCalibrationSession() {
+  return window['go']['main']['App']['StopCalibrationSession']();
+}
+
 export function SubscribeRaw(arg1) {
   return window['go']['main']['App']['SubscribeRaw'](arg1);
 }
@@ -1,9 +1,48 @@
 export namespace config {
 	
+	export class Config_Controller_CalibrationData_Curve {
+	    type?: string;
+	    points: number[][];
+	
+	    static createFrom(source: any = {}) {
+	        return new Config_Controller_CalibrationData_Curve(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.points = source["points"];
+	    }
+	}
+	export class Config_Controller_CalibrationData_Filter {
+	    median_window?: number;
+	    smoothing?: string;
+	    average_window?: number;
+	    min_cutoff?: number;
+	    beta?: number;
+	    min_change?: number;
+	    min_interval?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Config_Controller_CalibrationData_Filter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.median_window = source["median_window"];
+	        this.smoothing = source["smoothing"];
+	        this.average_window = source["average_window"];
+	        this.min_cutoff = source["min_cutoff"];
+	        this.beta = source["beta"];
+	        this.min_change = source["min_change"];
+	        this.min_interval = source["min_interval"];
+	    }
+	}
 	export class Config_Controller_SDLMap_Control {
 	    kind: string;
 	    index: number;
 	    name: string;
+	    direction?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Config_Controller_SDLMap_Control(source);
@@ -14,6 +53,7 @@ export namespace config {
 	        this.kind = source["kind"];
 	        this.index = source["index"];
 	        this.name = source["name"];
+	        this.direction = source["direction"];
 	    }
 	}
 	export class Config_Controller_SDLMap {
@@ -106,6 +146,74 @@ export namespace main {
 		}
 	}
 	
+	export class Interop_ControlModeStatus_Control {
+	    ControlName: string;
+	    ControlMode: string;
+	    Fallback: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_ControlModeStatus_Control(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ControlName = source["ControlName"];
+	        this.ControlMode = source["ControlMode"];
+	        this.Fallback = source["Fallback"];
+	    }
+	}
+	export class Interop_ControlModeStatus {
+	    DirectControlAvailable: boolean;
+	    ApiControlAvailable: boolean;
+	    SyncControlAvailable: boolean;
+	    Controls: Interop_ControlModeStatus_Control[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_ControlModeStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.DirectControlAvailable = source["DirectControlAvailable"];
+	        this.ApiControlAvailable = source["ApiControlAvailable"];
+	        this.SyncControlAvailable = source["SyncControlAvailable"];
+	        this.Controls = this.convertValues(source["Controls"], Interop_ControlModeStatus_Control);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Interop_ControllerCalibration_Detent {
+	    Name: string;
+	    Value: number;
+	    CaptureWidth: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_ControllerCalibration_Detent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Name = source["Name"];
+	        this.Value = source["Value"];
+	        this.CaptureWidth = source["CaptureWidth"];
+	    }
+	}
 	export class Interop_ControllerCalibration_Control {
 	    Kind: string;
 	    Index: number;
@@ -116,6 +224,11 @@ export namespace main {
 	    Deadzone: number;
 	    EasingCurve: number[];
 	    Invert: boolean;
+	    Detents: Interop_ControllerCalibration_Detent[];
+	    ResponseCurve?: config.Config_Controller_CalibrationData_Curve;
+	    ResponseCurveBelowIdle?: config.Config_Controller_CalibrationData_Curve;
+	    Filter?: config.Config_Controller_CalibrationData_Filter;
+	    Direction: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Interop_ControllerCalibration_Control(source);
@@ -132,11 +245,35 @@ export namespace main {
 	        this.Deadzone = source["Deadzone"];
 	        this.EasingCurve = source["EasingCurve"];
 	        this.Invert = source["Invert"];
+	        this.Detents = this.convertValues(source["Detents"], Interop_ControllerCalibration_Detent);
+	        this.ResponseCurve = this.convertValues(source["ResponseCurve"], config.Config_Controller_CalibrationData_Curve);
+	        this.ResponseCurveBelowIdle = this.convertValues(source["ResponseCurveBelowIdle"], config.Config_Controller_CalibrationData_Curve);
+	        this.Filter = this.convertValues(source["Filter"], config.Config_Controller_CalibrationData_Filter);
+	        this.Direction = source["Direction"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class Interop_ControllerCalibration {
 	    Name: string;
 	    UsbId: string;
+	    GUID: string;
 	    Controls: Interop_ControllerCalibration_Control[];
 	
 	    static createFrom(source: any = {}) {
@@ -147,6 +284,7 @@ export namespace main {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.Name = source["Name"];
 	        this.UsbId = source["UsbId"];
+	        this.GUID = source["GUID"];
 	        this.Controls = this.convertValues(source["Controls"], Interop_ControllerCalibration_Control);
 	    }
 	
@@ -168,6 +306,66 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class Interop_CalibrationSession_Axis {
+	    Index: number;
+	    Name: string;
+	    IdleDetected: boolean;
+	    Noise: number;
+	    Inverted: boolean;
+	    Centered: boolean;
+	    Warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_CalibrationSession_Axis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Index = source["Index"];
+	        this.Name = source["Name"];
+	        this.IdleDetected = source["IdleDetected"];
+	        this.Noise = source["Noise"];
+	        this.Inverted = source["Inverted"];
+	        this.Centered = source["Centered"];
+	        this.Warnings = source["Warnings"];
+	    }
+	}
+	export class Interop_CalibrationSessionResult {
+	    Calibration: Interop_ControllerCalibration;
+	    Axes: Interop_CalibrationSession_Axis[];
+	    Warnings: string[];
+	    Error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_CalibrationSessionResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Calibration = this.convertValues(source["Calibration"], Interop_ControllerCalibration);
+	        this.Axes = this.convertValues(source["Axes"], Interop_CalibrationSession_Axis);
+	        this.Warnings = source["Warnings"];
+	        this.Error = source["Error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	
 	export class Interop_ControllerConfiguration {
 	    Calibration: Interop_ControllerCalibration;
@@ -201,11 +399,29 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class Interop_ControllerProfilePolicy {
+	    StickyManualSelection: boolean;
+	    FallbackProfileId: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_ControllerProfilePolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.StickyManualSelection = source["StickyManualSelection"];
+	        this.FallbackProfileId = source["FallbackProfileId"];
+	    }
+	}
 	export class Interop_GenericController {
 	    GUID: string;
 	    UsbID: string;
 	    Name: string;
 	    IsConfigured: boolean;
+	    Alias: string;
+	    IdentitySource: string;
+	    IsGameController: boolean;
+	    HasRumble: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Interop_GenericController(source);
@@ -217,6 +433,30 @@ export namespace main {
 	        this.UsbID = source["UsbID"];
 	        this.Name = source["Name"];
 	        this.IsConfigured = source["IsConfigured"];
+	        this.Alias = source["Alias"];
+	        this.IdentitySource = source["IdentitySource"];
+	        this.IsGameController = source["IsGameController"];
+	        this.HasRumble = source["HasRumble"];
+	    }
+	}
+	export class Interop_ProfileSelection {
+	    GUID: string;
+	    ProfileId: string;
+	    ProfileName: string;
+	    Reason: string;
+	    Description: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Interop_ProfileSelection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.GUID = source["GUID"];
+	        this.ProfileId = source["ProfileId"];
+	        this.ProfileName = source["ProfileName"];
+	        this.Reason = source["Reason"];
+	        this.Description = source["Description"];
 	    }
 	}
 	export class Interop_Profile_Metadata {
@@ -281,6 +521,7 @@ export namespace main {
 	    Index: number;
 	    Value: number;
 	    Timestamp: number;
+	    Direction: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Interop_RawEvent(source);
@@ -294,6 +535,7 @@ export namespace main {
 	        this.Index = source["Index"];
 	        this.Value = source["Value"];
 	        this.Timestamp = source["Timestamp"];
+	        this.Direction = source["Direction"];
 	    }
 	}
 	export class Interop_SelectedProfileInfo {
@@ -14,6 +14,7 @@ require (
 	github.com/veandco/go-sdl2 v0.4.40
 	github.com/wailsapp/wails/v2 v2.10.2
 	golang.org/x/exp v0.0.0-20250911091902-df9299821621
+	golang.org/x/sys v0.33.0
 )
 
 require (
@@ -67,7 +68,6 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/image v0.27.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
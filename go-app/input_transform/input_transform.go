@@ -0,0 +1,88 @@
+package input_transform
+
+import (
+	"math"
+	"time"
+)
+
+/* time based transforms never step further than this at once; this prevents a jump after being idle */
+const MAX_STEP_DURATION = 100 * time.Millisecond
+
+/* time based transforms are considered settled when they are this close to their target */
+const SETTLE_EPSILON = 1e-4
+
+type Domain int
+
+const (
+	/* applied to the controller value before it is mapped to the min/max range */
+	Domain_Input Domain = iota
+	/* applied to the mapped game value before it is snapped to the steps */
+	Domain_Output
+)
+
+type Transform interface {
+	Domain() Domain
+	Apply(value float64, now time.Time) float64
+	/* whether the last output reached the last input; time based transforms need to be re-applied until settled */
+	IsSettled() bool
+	Reset()
+}
+
+/*
+An ordered list of transforms; input transforms are applied before output transforms
+*/
+type Pipeline struct {
+	Transforms []Transform
+}
+
+func (p *Pipeline) apply(domain Domain, value float64, now time.Time) float64 {
+	for _, transform := range p.Transforms {
+		if transform.Domain() == domain {
+			value = transform.Apply(value, now)
+		}
+	}
+	return value
+}
+
+func (p *Pipeline) ApplyInput(value float64, now time.Time) float64 {
+	return p.apply(Domain_Input, value, now)
+}
+
+func (p *Pipeline) ApplyOutput(value float64, now time.Time) float64 {
+	return p.apply(Domain_Output, value, now)
+}
+
+func (p *Pipeline) IsSettled() bool {
+	for _, transform := range p.Transforms {
+		if !transform.IsSettled() {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pipeline) Reset() {
+	for _, transform := range p.Transforms {
+		transform.Reset()
+	}
+}
+
+func NewPipeline(transforms ...Transform) *Pipeline {
+	return &Pipeline{Transforms: transforms}
+}
+
+/* returns the elapsed time since the previous step capped to the maximum step duration */
+func stepSeconds(previous time.Time, now time.Time) float64 {
+	elapsed := now.Sub(previous)
+	if elapsed > MAX_STEP_DURATION {
+		elapsed = MAX_STEP_DURATION
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return elapsed.Seconds()
+}
+
+func isSettled(value float64, target float64) bool {
+	return math.Abs(target-value) <= SETTLE_EPSILON*math.Max(1, math.Abs(target))
+}
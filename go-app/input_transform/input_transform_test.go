@@ -0,0 +1,122 @@
+package input_transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurve_Apply(t *testing.T) {
+	curve := NewCurve([]Point{{X: 1, Y: 1}, {X: 0, Y: 0}, {X: 0.5, Y: 0.2}})
+	assert.Equal(t, 0.0, curve.Apply(-0.5, time.Time{}))
+	assert.InDelta(t, 0.1, curve.Apply(0.25, time.Time{}), 1e-9)
+	assert.InDelta(t, 0.6, curve.Apply(0.75, time.Time{}), 1e-9)
+	assert.Equal(t, 1.0, curve.Apply(2, time.Time{}))
+}
+
+func TestSpline_Apply(t *testing.T) {
+	spline := NewSpline([]Point{{X: 1, Y: 1}, {X: 0, Y: 0}, {X: 0.3, Y: 0.05}, {X: 0.6, Y: 0.6}, {X: 0.8, Y: 0.62}})
+	assert.Equal(t, 0.0, spline.Apply(-0.5, time.Time{}))
+	assert.InDelta(t, 0.05, spline.Apply(0.3, time.Time{}), 1e-9)
+	assert.InDelta(t, 0.6, spline.Apply(0.6, time.Time{}), 1e-9)
+	assert.Equal(t, 1.0, spline.Apply(2, time.Time{}))
+
+	/* monotone points never produce a value moving backwards */
+	previous := spline.Apply(0, time.Time{})
+	for value := 0.01; value <= 1; value += 0.01 {
+		current := spline.Apply(value, time.Time{})
+		assert.GreaterOrEqual(t, current, previous-1e-12)
+		previous = current
+	}
+}
+
+func TestSplit_Apply(t *testing.T) {
+	upper := &Split{From: 0.5, To: 1}
+	assert.Equal(t, 0.0, upper.Apply(0.25, time.Time{}))
+	assert.Equal(t, 0.5, upper.Apply(0.75, time.Time{}))
+
+	lower := &Split{From: 0.5, To: 0}
+	assert.Equal(t, 0.0, lower.Apply(0.75, time.Time{}))
+	assert.Equal(t, 0.5, lower.Apply(0.25, time.Time{}))
+	assert.Equal(t, 1.0, lower.Apply(0, time.Time{}))
+}
+
+func TestDetents_Apply(t *testing.T) {
+	detents := &Detents{Detents: []float64{0, 0.5}, CaptureWidth: 0.05}
+	assert.Equal(t, 0.5, detents.Apply(0.53, time.Time{}))
+	assert.Equal(t, 0.0, detents.Apply(-0.02, time.Time{}))
+	assert.Equal(t, 0.3, detents.Apply(0.3, time.Time{}))
+}
+
+func TestHysteresis_Apply(t *testing.T) {
+	hysteresis := NewHysteresis(0.05, []float64{0, 1, 2}, nil)
+	assert.Equal(t, 0.4, hysteresis.Apply(0.4, time.Time{}))
+	/* just past the midpoint keeps the previous step */
+	assert.Equal(t, 0.0, hysteresis.Apply(0.52, time.Time{}))
+	/* past the midpoint and width moves to the next step */
+	assert.Equal(t, 0.6, hysteresis.Apply(0.6, time.Time{}))
+	/* moving back down needs to pass the midpoint by the width as well */
+	assert.Equal(t, 1.0, hysteresis.Apply(0.48, time.Time{}))
+	assert.Equal(t, 0.4, hysteresis.Apply(0.4, time.Time{}))
+
+	hysteresis.Reset()
+	assert.Equal(t, 0.52, hysteresis.Apply(0.52, time.Time{}))
+}
+
+func TestHysteresis_FreeZone(t *testing.T) {
+	hysteresis := NewHysteresis(0.05, []float64{0, 1, 2}, []Zone{{Start: 1, End: 2}})
+	assert.Equal(t, 0.9, hysteresis.Apply(0.9, time.Time{}))
+	assert.Equal(t, 1.3, hysteresis.Apply(1.3, time.Time{}))
+	assert.Equal(t, 0.52, hysteresis.Apply(0.52, time.Time{}))
+}
+
+func TestRateLimit_Apply(t *testing.T) {
+	start := time.Now()
+	rate_limit := &RateLimit{MaxRate: 1}
+	assert.Equal(t, 0.0, rate_limit.Apply(0, start))
+	assert.True(t, rate_limit.IsSettled())
+
+	assert.InDelta(t, 0.05, rate_limit.Apply(1, start.Add(50*time.Millisecond)), 1e-9)
+	assert.False(t, rate_limit.IsSettled())
+	/* long idle periods are capped to the maximum step duration */
+	assert.InDelta(t, 0.15, rate_limit.Apply(1, start.Add(10*time.Second)), 1e-9)
+
+	now := start.Add(10 * time.Second)
+	for i := 0; i < 20 && !rate_limit.IsSettled(); i++ {
+		now = now.Add(MAX_STEP_DURATION)
+		rate_limit.Apply(1, now)
+	}
+	assert.True(t, rate_limit.IsSettled())
+	assert.Equal(t, 1.0, rate_limit.Apply(1, now.Add(MAX_STEP_DURATION)))
+}
+
+func TestSmoothing_Apply(t *testing.T) {
+	start := time.Now()
+	smoothing := &Smoothing{TimeConstant: 0.1}
+	assert.Equal(t, 0.0, smoothing.Apply(0, start))
+	assert.InDelta(t, 0.632, smoothing.Apply(1, start.Add(100*time.Millisecond)), 0.001)
+	assert.False(t, smoothing.IsSettled())
+
+	now := start.Add(100 * time.Millisecond)
+	for i := 0; i < 100 && !smoothing.IsSettled(); i++ {
+		now = now.Add(20 * time.Millisecond)
+		smoothing.Apply(1, now)
+	}
+	assert.True(t, smoothing.IsSettled())
+
+	smoothing.Reset()
+	assert.Equal(t, 5.0, smoothing.Apply(5, now))
+}
+
+func TestPipeline(t *testing.T) {
+	start := time.Now()
+	pipeline := NewPipeline(&RateLimit{MaxRate: 1}, &Split{From: 0, To: 0.5})
+	assert.Equal(t, 1.0, pipeline.ApplyInput(0.75, start))
+	assert.Equal(t, 0.0, pipeline.ApplyOutput(0, start))
+	assert.InDelta(t, 0.1, pipeline.ApplyOutput(1, start.Add(100*time.Millisecond)), 1e-9)
+	assert.False(t, pipeline.IsSettled())
+
+	pipeline.Reset()
+	assert.True(t, pipeline.IsSettled())
+}
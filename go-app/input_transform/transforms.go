@@ -0,0 +1,321 @@
+package input_transform
+
+import (
+	"math"
+	"sort"
+	"time"
+	"tsw_controller_app/math_utils"
+)
+
+type Point struct {
+	X float64
+	Y float64
+}
+
+type Zone struct {
+	Start float64
+	End   float64
+}
+
+/*
+Maps the controller value using a piecewise-linear curve; values outside of the curve are clamped to the first and last point
+*/
+type Curve struct {
+	Points []Point
+}
+
+func (t *Curve) Domain() Domain  { return Domain_Input }
+func (t *Curve) IsSettled() bool { return true }
+func (t *Curve) Reset()          {}
+
+func (t *Curve) Apply(value float64, now time.Time) float64 {
+	if len(t.Points) == 0 {
+		return value
+	}
+	if value <= t.Points[0].X {
+		return t.Points[0].Y
+	}
+	for i := 1; i < len(t.Points); i++ {
+		start, end := t.Points[i-1], t.Points[i]
+		if value <= end.X {
+			if end.X == start.X {
+				return end.Y
+			}
+			return start.Y + (value-start.X)/(end.X-start.X)*(end.Y-start.Y)
+		}
+	}
+	return t.Points[len(t.Points)-1].Y
+}
+
+func NewCurve(points []Point) *Curve {
+	sorted_points := append([]Point{}, points...)
+	sort.SliceStable(sorted_points, func(i, j int) bool {
+		return sorted_points[i].X < sorted_points[j].X
+	})
+	return &Curve{Points: sorted_points}
+}
+
+/*
+Maps the controller value using a monotone cubic spline through the points (Fritsch-Carlson); the spline does not overshoot
+between the points so the value keeps its direction. Values outside of the spline are clamped to the first and last point
+*/
+type Spline struct {
+	Points []Point
+	/* the tangent at each point */
+	slopes []float64
+}
+
+func (t *Spline) Domain() Domain  { return Domain_Input }
+func (t *Spline) IsSettled() bool { return true }
+func (t *Spline) Reset()          {}
+
+func (t *Spline) Apply(value float64, now time.Time) float64 {
+	if len(t.Points) == 0 {
+		return value
+	}
+	if value <= t.Points[0].X {
+		return t.Points[0].Y
+	}
+	for i := 1; i < len(t.Points); i++ {
+		start, end := t.Points[i-1], t.Points[i]
+		if value <= end.X {
+			width := end.X - start.X
+			if width == 0 {
+				return end.Y
+			}
+			/* cubic hermite basis */
+			position := (value - start.X) / width
+			position_2 := position * position
+			position_3 := position_2 * position
+			return (2*position_3-3*position_2+1)*start.Y +
+				(position_3-2*position_2+position)*width*t.slopes[i-1] +
+				(-2*position_3+3*position_2)*end.Y +
+				(position_3-position_2)*width*t.slopes[i]
+		}
+	}
+	return t.Points[len(t.Points)-1].Y
+}
+
+func NewSpline(points []Point) *Spline {
+	sorted_points := append([]Point{}, points...)
+	sort.SliceStable(sorted_points, func(i, j int) bool {
+		return sorted_points[i].X < sorted_points[j].X
+	})
+
+	count := len(sorted_points)
+	slopes := make([]float64, count)
+	if count < 2 {
+		return &Spline{Points: sorted_points, slopes: slopes}
+	}
+
+	secants := make([]float64, count-1)
+	for i := 0; i < count-1; i++ {
+		width := sorted_points[i+1].X - sorted_points[i].X
+		if width != 0 {
+			secants[i] = (sorted_points[i+1].Y - sorted_points[i].Y) / width
+		}
+	}
+	slopes[0] = secants[0]
+	slopes[count-1] = secants[count-2]
+	for i := 1; i < count-1; i++ {
+		if secants[i-1]*secants[i] <= 0 {
+			/* local extremum; keep it flat so the spline does not overshoot */
+			slopes[i] = 0
+		} else {
+			slopes[i] = (secants[i-1] + secants[i]) / 2
+		}
+	}
+
+	/* limit the tangents to keep each segment monotone */
+	for i := 0; i < count-1; i++ {
+		if secants[i] == 0 {
+			slopes[i] = 0
+			slopes[i+1] = 0
+			continue
+		}
+		alpha := slopes[i] / secants[i]
+		beta := slopes[i+1] / secants[i]
+		if magnitude := alpha*alpha + beta*beta; magnitude > 9 {
+			scale := 3 / math.Sqrt(magnitude)
+			slopes[i] = scale * alpha * secants[i]
+			slopes[i+1] = scale * beta * secants[i]
+		}
+	}
+	return &Spline{Points: sorted_points, slopes: slopes}
+}
+
+/*
+Uses only part of the controller range; From maps to 0 and To maps to 1 and values outside of the range are clamped.
+From can be larger than To to reverse the direction (eg: 0.5 -> 0 to use the lower half of a lever)
+*/
+type Split struct {
+	From float64
+	To   float64
+}
+
+func (t *Split) Domain() Domain  { return Domain_Input }
+func (t *Split) IsSettled() bool { return true }
+func (t *Split) Reset()          {}
+
+func (t *Split) Apply(value float64, now time.Time) float64 {
+	if t.From == t.To {
+		return 0
+	}
+	return math_utils.Clamp((value-t.From)/(t.To-t.From), 0, 1)
+}
+
+/*
+Snaps the value to a detent when it is within the capture width of it
+*/
+type Detents struct {
+	Detents      []float64
+	CaptureWidth float64
+}
+
+func (t *Detents) Domain() Domain  { return Domain_Output }
+func (t *Detents) IsSettled() bool { return true }
+func (t *Detents) Reset()          {}
+
+func (t *Detents) Apply(value float64, now time.Time) float64 {
+	closest := value
+	closest_distance := math.Inf(1)
+	for _, detent := range t.Detents {
+		distance := math.Abs(value - detent)
+		if distance <= t.CaptureWidth && distance < closest_distance {
+			closest = detent
+			closest_distance = distance
+		}
+	}
+	return closest
+}
+
+/*
+Keeps the previously selected step until the value passes the midpoint to the adjacent step by the width;
+this avoids flickering between two steps when the lever rests near the midpoint
+*/
+type Hysteresis struct {
+	Width     float64
+	Steps     []float64
+	FreeZones []Zone
+	previous  *float64
+}
+
+func (t *Hysteresis) Domain() Domain  { return Domain_Output }
+func (t *Hysteresis) IsSettled() bool { return true }
+func (t *Hysteresis) Reset()          { t.previous = nil }
+
+func (t *Hysteresis) Apply(value float64, now time.Time) float64 {
+	if len(t.Steps) == 0 {
+		return value
+	}
+	for _, zone := range t.FreeZones {
+		if value >= zone.Start && value <= zone.End {
+			t.previous = nil
+			return value
+		}
+	}
+
+	closest_index := 0
+	for index, step := range t.Steps {
+		if math.Abs(value-step) < math.Abs(value-t.Steps[closest_index]) {
+			closest_index = index
+		}
+	}
+	closest := t.Steps[closest_index]
+	if t.previous == nil || *t.previous == closest {
+		t.previous = &closest
+		return value
+	}
+
+	previous_index := sort.SearchFloat64s(t.Steps, *t.previous)
+	if previous_index >= len(t.Steps) || t.Steps[previous_index] != *t.previous {
+		/* the previous step is unknown */
+		t.previous = &closest
+		return value
+	}
+
+	if closest > *t.previous && previous_index+1 < len(t.Steps) {
+		boundary := (*t.previous + t.Steps[previous_index+1]) / 2
+		if value < boundary+t.Width {
+			return *t.previous
+		}
+	} else if closest < *t.previous && previous_index > 0 {
+		boundary := (*t.previous + t.Steps[previous_index-1]) / 2
+		if value > boundary-t.Width {
+			return *t.previous
+		}
+	}
+	t.previous = &closest
+	return value
+}
+
+func NewHysteresis(width float64, steps []float64, free_zones []Zone) *Hysteresis {
+	sorted_steps := append([]float64{}, steps...)
+	sort.Float64s(sorted_steps)
+	return &Hysteresis{
+		Width:     width,
+		Steps:     sorted_steps,
+		FreeZones: free_zones,
+	}
+}
+
+/*
+Limits how fast the value can change in units per second
+*/
+type RateLimit struct {
+	MaxRate   float64
+	has_value bool
+	value     float64
+	target    float64
+	last_at   time.Time
+}
+
+func (t *RateLimit) Domain() Domain  { return Domain_Output }
+func (t *RateLimit) IsSettled() bool { return !t.has_value || isSettled(t.value, t.target) }
+func (t *RateLimit) Reset()          { t.has_value = false }
+
+func (t *RateLimit) Apply(value float64, now time.Time) float64 {
+	t.target = value
+	if !t.has_value {
+		t.has_value = true
+		t.value = value
+		t.last_at = now
+		return value
+	}
+	max_delta := t.MaxRate * stepSeconds(t.last_at, now)
+	t.value = t.value + math_utils.Clamp(value-t.value, -max_delta, max_delta)
+	t.last_at = now
+	return t.value
+}
+
+/*
+Smooths the value using an exponential moving average; the time constant is the time in seconds to reach ~63% of a change
+*/
+type Smoothing struct {
+	TimeConstant float64
+	has_value    bool
+	value        float64
+	target       float64
+	last_at      time.Time
+}
+
+func (t *Smoothing) Domain() Domain  { return Domain_Output }
+func (t *Smoothing) IsSettled() bool { return !t.has_value || isSettled(t.value, t.target) }
+func (t *Smoothing) Reset()          { t.has_value = false }
+
+func (t *Smoothing) Apply(value float64, now time.Time) float64 {
+	t.target = value
+	if !t.has_value || t.TimeConstant <= 0 {
+		t.has_value = true
+		t.value = value
+		t.last_at = now
+		return value
+	}
+	alpha := 1 - math.Exp(-stepSeconds(t.last_at, now)/t.TimeConstant)
+	t.value = t.value + (value-t.value)*alpha
+	if isSettled(t.value, t.target) {
+		t.value = t.target
+	}
+	t.last_at = now
+	return t.value
+}
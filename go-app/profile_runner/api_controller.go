@@ -3,11 +3,17 @@ package profile_runner
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 	"tsw_controller_app/tswapi"
 )
 
 const API_CONTROLLER_QUEUE_BUFFER_SIZE = 32
 
+/* how often an unreachable API is probed so it becomes available again without sending commands to it */
+const API_CONTROLLER_PROBE_INTERVAL = 5 * time.Second
+
 type ApiController_Command struct {
 	Controls   string
 	InputValue float64
@@ -16,22 +22,53 @@ type ApiController_Command struct {
 type ApiController struct {
 	API            *tswapi.TSWAPI
 	ControlChannel chan ApiController_Command
+	/* the requests currently in flight */
+	pending sync.WaitGroup
+	probing atomic.Bool
 }
 
 func (c *ApiController_Command) ToString() string {
 	return fmt.Sprintf("api_control_command:%s:%f", c.Controls, c.InputValue)
 }
 
+func (controller *ApiController) IsAvailable() bool {
+	return controller.API.CanConnect()
+}
+
+/*
+Probes the API in the background when a key is loaded but the API could not be reached
+*/
+func (controller *ApiController) probe() {
+	if !controller.API.Enabled() || controller.API.CanConnect() {
+		return
+	}
+	if !controller.probing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer controller.probing.Store(false)
+		controller.API.Probe()
+	}()
+}
+
 func (controller *ApiController) Run(ctx context.Context) func() {
 	ctx_with_cancel, cancel := context.WithCancel(ctx)
 
 	go func() {
+		probe_ticker := time.NewTicker(API_CONTROLLER_PROBE_INTERVAL)
+		defer probe_ticker.Stop()
 		for {
 			select {
 			case <-ctx_with_cancel.Done():
 				return
+			case <-probe_ticker.C:
+				controller.probe()
 			case command := <-controller.ControlChannel:
-				go controller.API.SetInputValue(command.Controls, command.InputValue)
+				controller.pending.Add(1)
+				go func() {
+					defer controller.pending.Done()
+					controller.API.SetInputValue(command.Controls, command.InputValue)
+				}()
 			}
 		}
 	}()
@@ -39,6 +76,26 @@ func (controller *ApiController) Run(ctx context.Context) func() {
 	return cancel
 }
 
+/*
+Waits until the queued commands are sent and their requests are completed or the timeout is reached
+*/
+func (controller *ApiController) Flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for len(controller.ControlChannel) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		controller.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+	}
+}
+
 func NewAPIController(twapi *tswapi.TSWAPI) *ApiController {
 	controller := ApiController{
 		API:            twapi,
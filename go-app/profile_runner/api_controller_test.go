@@ -0,0 +1,37 @@
+package profile_runner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"tsw_controller_app/tswapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiController_IsAvailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	api := tswapi.NewTSWAPI(tswapi.TSWAPIConfig{BaseURL: server.URL})
+	controller := NewAPIController(api)
+	assert.False(t, controller.IsAvailable())
+
+	/* a loaded key is available before any request was sent */
+	api.Config.CommAPIKey = "key"
+	assert.True(t, controller.IsAvailable())
+
+	/* a request which can not reach the API makes it unavailable */
+	api.Config.BaseURL = "http://127.0.0.1:1"
+	assert.Error(t, api.Probe())
+	assert.False(t, controller.IsAvailable())
+
+	/* the probe makes it available again once the API can be reached */
+	api.Config.BaseURL = server.URL
+	controller.probe()
+	require.Eventually(t, controller.IsAvailable, time.Second, 10*time.Millisecond)
+}
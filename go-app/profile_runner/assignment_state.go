@@ -0,0 +1,51 @@
+package profile_runner
+
+import (
+	"fmt"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/map_utils"
+)
+
+/*
+The last call of an assignment for a joystick; used to detect threshold crossings, toggles and releases.
+The call is replaced as a whole and never modified once stored
+*/
+type ProfileRunner_AssignmentState struct {
+	GUID            controller_mgr.JoystickGUIDString
+	ControlName     string
+	AssignmentIndex int
+	PreviousCall    *ProfileRunnerAssignmentCall
+}
+
+func assignmentStateKey(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int) string {
+	return fmt.Sprintf("%s:%s:%d", guid, control_name, assignment_index)
+}
+
+/*
+Returns the last call of the assignment by the joystick or nil if it was not called yet
+*/
+func (p *ProfileRunner) getPreviousAssignmentCall(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int) *ProfileRunnerAssignmentCall {
+	state, has_state := p.AssignmentStates.Get(assignmentStateKey(guid, control_name, assignment_index))
+	if !has_state {
+		return nil
+	}
+	return state.PreviousCall
+}
+
+func (p *ProfileRunner) setPreviousAssignmentCall(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int, call *ProfileRunnerAssignmentCall) {
+	p.AssignmentStates.Set(assignmentStateKey(guid, control_name, assignment_index), ProfileRunner_AssignmentState{
+		GUID:            guid,
+		ControlName:     control_name,
+		AssignmentIndex: assignment_index,
+		PreviousCall:    call,
+	})
+}
+
+func (p *ProfileRunner) clearAssignmentStates(match func(guid controller_mgr.JoystickGUIDString) bool) {
+	p.AssignmentStates.Mutate(func(state ProfileRunner_AssignmentState, key string) map_utils.LockMapMutateAction[string, ProfileRunner_AssignmentState] {
+		if match(state.GUID) {
+			return map_utils.LockMapMutateAction[string, ProfileRunner_AssignmentState]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, ProfileRunner_AssignmentState]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
@@ -0,0 +1,251 @@
+package profile_runner
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"tsw_controller_app/action_sequencer"
+	"tsw_controller_app/cabdebugger"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/sdl_mgr"
+	"tsw_controller_app/tswapi"
+	"tsw_controller_app/tswconnector"
+	"tsw_controller_app/virtual_joystick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConnector struct{}
+
+func (c *testConnector) Start() error { return nil }
+func (c *testConnector) Stop() error  { return nil }
+func (c *testConnector) Subscribe() (chan tswconnector.TSWConnector_Message, func()) {
+	return make(chan tswconnector.TSWConnector_Message), func() {}
+}
+func (c *testConnector) Send(m tswconnector.TSWConnector_Message) error { return nil }
+func (c *testConnector) IsConnected() bool                              { return true }
+
+const testAssignmentStateProfile = `{
+	"name": "Identical controllers",
+	"controls": [
+		{
+			"name": "Lever1",
+			"assignments": [
+				{
+					"type": "momentary",
+					"threshold": 0.5,
+					"action_activate": { "controls": "Horn", "value": 1 },
+					"action_deactivate": { "controls": "Horn", "value": 0 }
+				},
+				{
+					"type": "linear",
+					"thresholds": [
+						{ "value": 0.3, "action_activate": { "controls": "Notch", "value": 1 }, "action_deactivate": { "controls": "Notch", "value": 0 } }
+					]
+				}
+			]
+		},
+		{
+			"name": "Button1",
+			"assignment": {
+				"type": "toggle",
+				"threshold": 0.5,
+				"action_activate": { "controls": "Lights", "value": 1 },
+				"action_deactivate": { "controls": "Lights", "value": 0 }
+			}
+		}
+	]
+}`
+
+/* a runner with the same profile selected for two controllers of the same model */
+func newTestAssignmentStateRunner(t *testing.T) (*ProfileRunner, *sdl_mgr.SDLMgr_Joystick, *sdl_mgr.SDLMgr_Joystick) {
+	return newTestProfileRunner(t, testAssignmentStateProfile)
+}
+
+func newTestProfileRunner(t *testing.T, profile_json string) (*ProfileRunner, *sdl_mgr.SDLMgr_Joystick, *sdl_mgr.SDLMgr_Joystick) {
+	var profile config.Config_Controller_Profile
+	require.NoError(t, json.Unmarshal([]byte(profile_json), &profile))
+
+	connector := &testConnector{}
+	sequencer := action_sequencer.New(connector, action_sequencer.NewRecordingKeyOutput(), action_sequencer.NewRecordingMouseOutput())
+	t.Cleanup(sequencer.Run(context.Background()))
+
+	virtual_joystick_controller := NewVirtualJoystickController(virtual_joystick.NewRecordingOutput())
+	t.Cleanup(virtual_joystick_controller.Run(context.Background()))
+
+	api := tswapi.NewTSWAPI(tswapi.TSWAPIConfig{})
+	runner := New(
+		sequencer,
+		nil,
+		NewDirectController(connector),
+		NewSyncController(),
+		NewAPIController(api),
+		virtual_joystick_controller,
+		cabdebugger.NewCabDebugger(api, connector, cabdebugger.CabDebugger_Config{}),
+	)
+	runner.RegisterProfile(profile)
+
+	joystick_a := &sdl_mgr.SDLMgr_Joystick{GUID: "controller-a", Name: "Throttle", VendorID: 1, ProductID: 2}
+	joystick_b := &sdl_mgr.SDLMgr_Joystick{GUID: "controller-b", Name: "Throttle", VendorID: 1, ProductID: 2}
+	require.NoError(t, runner.SetProfile(joystick_a.GUID, profile.Id()))
+	require.NoError(t, runner.SetProfile(joystick_b.GUID, profile.Id()))
+	return runner, joystick_a, joystick_b
+}
+
+func newTestChangeEvent(joystick *sdl_mgr.SDLMgr_Joystick, control_name string, previous_value float64, value float64) *controller_mgr.ControllerManager_Control_ChangeEvent {
+	state := controller_mgr.ControllerManager_Controller_ControlState{
+		NormalizedValues: controller_mgr.ControllerManager_Controller_ControlStateValues{
+			Value:         value,
+			PreviousValue: previous_value,
+		},
+	}
+	return &controller_mgr.ControllerManager_Control_ChangeEvent{
+		Joystick:     joystick,
+		Control:      &controller_mgr.ControllerManager_Controller_Control{Joystick: joystick, Name: control_name, State: state},
+		ControlName:  control_name,
+		ControlState: state,
+	}
+}
+
+func drainDirectControlCommands(runner *ProfileRunner) []DirectController_Command {
+	commands := []DirectController_Command{}
+	for {
+		select {
+		case command := <-runner.DirectController.ControlChannel:
+			commands = append(commands, DirectController_Command{Controls: command.Controls, InputValue: command.InputValue})
+		default:
+			return commands
+		}
+	}
+}
+
+func TestAssignmentState_Momentary_IdenticalControllers(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+
+	/* both controllers activate; the second one is not mistaken for the first one still being active */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0, 0.2))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Lever1", 0, 0.2))
+	assert.Empty(t, drainDirectControlCommands(runner))
+
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0.2, 0.6))
+	assert.Equal(t, []DirectController_Command{{Controls: "Notch", InputValue: 1}, {Controls: "Horn", InputValue: 1}}, sortDirectControlCommands(drainDirectControlCommands(runner)))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Lever1", 0.2, 0.6))
+	assert.Equal(t, []DirectController_Command{{Controls: "Notch", InputValue: 1}, {Controls: "Horn", InputValue: 1}}, sortDirectControlCommands(drainDirectControlCommands(runner)))
+
+	/* the first controller deactivating does not affect the second controller */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0.6, 0.4))
+	assert.Equal(t, []DirectController_Command{{Controls: "Horn", InputValue: 0}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Lever1", 0.6, 0.7))
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Lever1", 0.7, 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Notch", InputValue: 0}, {Controls: "Horn", InputValue: 0}}, sortDirectControlCommands(drainDirectControlCommands(runner)))
+}
+
+func TestAssignmentState_Toggle_IdenticalControllers(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Button1", 0, 1))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Button1", 1, 0))
+	/* the second controller toggles on as well instead of toggling off what the first controller turned on */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Button1", 0, 1))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Button1", 1, 0))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Button1", 0, 1))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "Lights", InputValue: 1},
+		{Controls: "Lights", InputValue: 1},
+		{Controls: "Lights", InputValue: 0},
+	}, drainDirectControlCommands(runner))
+}
+
+func TestAssignmentState_ProfileChangeOnlyClearsJoystick(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Button1", 0, 1))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Button1", 0, 1))
+	drainDirectControlCommands(runner)
+
+	selected_profile, _ := runner.Settings.GetSelectedProfiles().Get(joystick_a.GUID)
+	require.NoError(t, runner.SetProfile(joystick_a.GUID, selected_profile.Profile.Id()))
+	assert.Nil(t, runner.getPreviousAssignmentCall(joystick_a.GUID, "Button1", 0))
+	assert.NotNil(t, runner.getPreviousAssignmentCall(joystick_b.GUID, "Button1", 0))
+}
+
+func TestAssignmentState_ConcurrentIdenticalControllers(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+	const presses = 50
+
+	commands := []DirectController_Command{}
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for len(commands) < 2*presses {
+			command := <-runner.DirectController.ControlChannel
+			commands = append(commands, command)
+		}
+	}()
+
+	wait_group := sync.WaitGroup{}
+	for _, joystick := range []*sdl_mgr.SDLMgr_Joystick{joystick_a, joystick_b} {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			for range presses {
+				runner.handleChangeEvent(newTestChangeEvent(joystick, "Button1", 0, 1))
+				runner.handleChangeEvent(newTestChangeEvent(joystick, "Button1", 1, 0))
+			}
+		}()
+	}
+	wait_group.Wait()
+	<-collected
+
+	/* every press toggles the lights of its own controller */
+	counts := map[float64]int{}
+	for _, command := range commands {
+		counts[command.InputValue]++
+	}
+	assert.Equal(t, map[float64]int{1: presses, 0: presses}, counts)
+}
+
+/* the assignments of a control are executed in order but the tests only care about the set of commands */
+func sortDirectControlCommands(commands []DirectController_Command) []DirectController_Command {
+	sorted := []DirectController_Command{}
+	for _, controls := range []string{"Notch", "Horn", "Lights"} {
+		for _, command := range commands {
+			if command.Controls == controls {
+				sorted = append(sorted, command)
+			}
+		}
+	}
+	return sorted
+}
+
+func TestRenameJoysticks_SwapsSelections(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+	var other config.Config_Controller_Profile
+	require.NoError(t, json.Unmarshal([]byte(`{ "name": "Other", "controls": [] }`), &other))
+	runner.RegisterProfile(other)
+	require.NoError(t, runner.SetProfile(joystick_b.GUID, other.Id()))
+
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Button1", 0, 1))
+	drainDirectControlCommands(runner)
+
+	runner.RenameJoysticks([]controller_mgr.ControllerManager_DeviceRename{
+		{From: joystick_a.GUID, To: joystick_b.GUID},
+		{From: joystick_b.GUID, To: joystick_a.GUID},
+	})
+	selected_a, _ := runner.Settings.GetSelectedProfiles().Get(joystick_a.GUID)
+	selected_b, _ := runner.Settings.GetSelectedProfiles().Get(joystick_b.GUID)
+	assert.Equal(t, "Other", selected_a.Profile.Name)
+	assert.Equal(t, "Identical controllers", selected_b.Profile.Name)
+	assert.Nil(t, runner.getPreviousAssignmentCall(joystick_a.GUID, "Button1", 0))
+
+	/* a selection stored for the new GUID is kept */
+	runner.RenameJoysticks([]controller_mgr.ControllerManager_DeviceRename{{From: joystick_a.GUID, To: joystick_b.GUID}})
+	selected_b, _ = runner.Settings.GetSelectedProfiles().Get(joystick_b.GUID)
+	_, has_selected_a := runner.Settings.GetSelectedProfiles().Get(joystick_a.GUID)
+	assert.Equal(t, "Identical controllers", selected_b.Profile.Name)
+	assert.False(t, has_selected_a)
+}
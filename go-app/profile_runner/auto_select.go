@@ -0,0 +1,235 @@
+package profile_runner
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/logger"
+	"tsw_controller_app/sdl_mgr"
+)
+
+/* why a profile is used for a joystick */
+type ProfileRunner_SelectionReason = string
+
+const (
+	/* the profile was selected by the user */
+	ProfileRunner_SelectionReason_Manual ProfileRunner_SelectionReason = "manual"
+	/* the profile matched the current train */
+	ProfileRunner_SelectionReason_AutoSelect ProfileRunner_SelectionReason = "auto_select"
+	/* the fallback profile of the joystick */
+	ProfileRunner_SelectionReason_Fallback ProfileRunner_SelectionReason = "fallback"
+	/* no profile is used anymore */
+	ProfileRunner_SelectionReason_None ProfileRunner_SelectionReason = "none"
+)
+
+/* how the profile of a joystick is selected */
+type ProfileRunner_DevicePolicy struct {
+	/* a manually selected profile is kept even when a profile can be auto-selected for the current train */
+	StickyManualSelection bool
+	/* used when no profile is selected and none can be auto-selected; matched by name and path so it survives reloads */
+	FallbackProfile *config.Config_ProgramConfig_SelectedProfile
+}
+
+/* the profile used for a joystick and why */
+type ProfileRunner_ProfileSelection struct {
+	GUID        controller_mgr.JoystickGUIDString
+	ProfileId   string
+	ProfileName string
+	Reason      ProfileRunner_SelectionReason
+	/* explains the selection (eg: the rail class rule which matched) */
+	Description string
+}
+
+type profileRunner_AutoSelectMatch struct {
+	Profile  config.Config_Controller_Profile
+	Priority int
+	/* the profile is limited to the USB ID of the joystick; preferred over profiles usable with any controller */
+	Specific    bool
+	Description string
+}
+
+func DefaultDevicePolicy() ProfileRunner_DevicePolicy {
+	return ProfileRunner_DevicePolicy{StickyManualSelection: true}
+}
+
+func (s *ProfileRunnerSettings) GetDevicePolicy(guid controller_mgr.JoystickGUIDString) ProfileRunner_DevicePolicy {
+	s.Mutex.RLock()
+	defer s.Mutex.RUnlock()
+	if policy, has_policy := s.DevicePoliciesByGUID.Get(guid); has_policy {
+		return policy
+	}
+	return DefaultDevicePolicy()
+}
+
+func (p *ProfileRunner) SetDevicePolicy(guid controller_mgr.JoystickGUIDString, policy ProfileRunner_DevicePolicy) {
+	p.Settings.Update(func(s *ProfileRunnerSettings) {
+		s.DevicePoliciesByGUID.Set(guid, policy)
+	})
+}
+
+/*
+checks whether the rail class entry matches the current train; cab variable conditions are read from the cab debugger
+and an entry with a condition on a cab variable which does not exist does not match
+*/
+func (p *ProfileRunner) matchesRailClassEntry(entry *config.Config_Controller_Profile_RailClassInformationEntry, rail_class string) bool {
+	if !entry.MatchesClassName(rail_class) {
+		return false
+	}
+	if entry.Conditions == nil {
+		return true
+	}
+	for _, condition := range *entry.Conditions {
+		if condition.CabVariable == nil {
+			return false
+		}
+		cab_control, has_cab_control := p.CabDebugger.State.Controls.Get(*condition.CabVariable)
+		if !has_cab_control || !condition.Matches(cab_control.CurrentNormalizedValue) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+finds the profile to auto-select for the joystick on the current train
+the profile with the highest priority wins, then profiles limited to the USB ID of the joystick over profiles for any controller ("*"); remaining ties are broken
+by the profile name and ID so the result does not depend on the load order
+*/
+func (p *ProfileRunner) matchAutoSelectProfile(joystick sdl_mgr.SDLMgr_Joystick) (profileRunner_AutoSelectMatch, bool) {
+	current_rail_class := p.CabDebugger.State.DrivableActorName
+	if current_rail_class == "" {
+		return profileRunner_AutoSelectMatch{}, false
+	}
+
+	matches := []profileRunner_AutoSelectMatch{}
+	p.Profiles.ForEach(func(profile config.Config_Controller_Profile, id string) bool {
+		if profile.AutoSelect == nil || !*profile.AutoSelect || profile.RailClassInformation == nil {
+			return true
+		}
+		/* auto-selection requires the usb_id of the controller; "*" has to be used explicitly to match any controller */
+		if profile.Controller == nil || profile.Controller.UsbID == nil {
+			return true
+		}
+		is_specific := *profile.Controller.UsbID != config.Config_Controller_Profile_Controller_AnyUsbID
+		if is_specific && *profile.Controller.UsbID != joystick.ToString() {
+			return true
+		}
+
+		var best_match *profileRunner_AutoSelectMatch
+		for _, rc_info := range *profile.RailClassInformation {
+			if !p.matchesRailClassEntry(&rc_info, current_rail_class) {
+				continue
+			}
+			priority := rc_info.GetPriority(&profile)
+			if best_match == nil || priority > best_match.Priority {
+				best_match = &profileRunner_AutoSelectMatch{
+					Profile:     profile,
+					Priority:    priority,
+					Specific:    is_specific,
+					Description: fmt.Sprintf("%s matched %s (priority %d)", current_rail_class, rc_info.Describe(), priority),
+				}
+			}
+		}
+		if best_match != nil {
+			matches = append(matches, *best_match)
+		}
+		return true
+	})
+	if len(matches) == 0 {
+		return profileRunner_AutoSelectMatch{}, false
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Priority != matches[j].Priority {
+			return matches[i].Priority > matches[j].Priority
+		}
+		if matches[i].Specific != matches[j].Specific {
+			return matches[i].Specific
+		}
+		if matches[i].Profile.Name != matches[j].Profile.Name {
+			return matches[i].Profile.Name < matches[j].Profile.Name
+		}
+		return matches[i].Profile.Id() < matches[j].Profile.Id()
+	})
+	return matches[0], true
+}
+
+/*
+resolves the profile to use for the joystick:
+a sticky manual selection, the auto-selected profile, a non-sticky manual selection and finally the fallback profile
+*/
+func (p *ProfileRunner) resolveProfileForJoystick(joystick sdl_mgr.SDLMgr_Joystick) (ProfileRunnerSettings_SelectedProfile, ProfileRunner_ProfileSelection, bool) {
+	selection := ProfileRunner_ProfileSelection{GUID: joystick.GUID, Reason: ProfileRunner_SelectionReason_None}
+	policy := p.Settings.GetDevicePolicy(joystick.GUID)
+	selected_profile, has_selected_profile := p.Settings.GetSelectedProfiles().Get(joystick.GUID)
+	resolved := func(profile config.Config_Controller_Profile, reason ProfileRunner_SelectionReason, description string) (ProfileRunnerSettings_SelectedProfile, ProfileRunner_ProfileSelection, bool) {
+		selection.ProfileId = profile.Id()
+		selection.ProfileName = profile.Name
+		selection.Reason = reason
+		selection.Description = description
+		return ProfileRunnerSettings_SelectedProfile{Profile: profile}, selection, true
+	}
+
+	if has_selected_profile && policy.StickyManualSelection {
+		return resolved(selected_profile.Profile, ProfileRunner_SelectionReason_Manual, "selected manually")
+	}
+	if match, has_match := p.matchAutoSelectProfile(joystick); has_match {
+		return resolved(match.Profile, ProfileRunner_SelectionReason_AutoSelect, match.Description)
+	}
+	if has_selected_profile {
+		return resolved(selected_profile.Profile, ProfileRunner_SelectionReason_Manual, "selected manually; no profile matched the current train")
+	}
+	if policy.FallbackProfile != nil {
+		if profile, has_profile := p.FindProfileByNameAndPath(policy.FallbackProfile.Name, policy.FallbackProfile.Path); has_profile {
+			return resolved(profile, ProfileRunner_SelectionReason_Fallback, "no profile matched the current train")
+		}
+	}
+	return ProfileRunnerSettings_SelectedProfile{}, selection, false
+}
+
+func (p *ProfileRunner) getSelectedProfileForJoystick(joystick sdl_mgr.SDLMgr_Joystick) (ProfileRunnerSettings_SelectedProfile, bool) {
+	selected_profile, _, has_selected_profile := p.resolveProfileForJoystick(joystick)
+	return selected_profile, has_selected_profile
+}
+
+/*
+remembers the profile used for the joystick and announces it when it changed
+switching between profiles without the user selecting them puts the outputs of the previous profile in their safe state
+*/
+func (p *ProfileRunner) recordProfileSelection(selection ProfileRunner_ProfileSelection) {
+	previous_selection, has_previous_selection := p.ProfileSelections.Get(selection.GUID)
+	did_change := selection.Reason != ProfileRunner_SelectionReason_None
+	if has_previous_selection {
+		did_change = previous_selection.ProfileId != selection.ProfileId || previous_selection.Reason != selection.Reason
+	}
+	if !did_change {
+		return
+	}
+	p.ProfileSelections.Set(selection.GUID, selection)
+
+	is_manual_switch := previous_selection.Reason == ProfileRunner_SelectionReason_Manual && selection.Reason == ProfileRunner_SelectionReason_Manual
+	if has_previous_selection && previous_selection.ProfileId != "" && previous_selection.ProfileId != selection.ProfileId && !is_manual_switch {
+		/* manual selections already applied the safe state when selected */
+		p.ApplySafeState(selection.GUID)
+		p.clearControlModeSelections(selection.GUID)
+	}
+
+	logger.Logger.Info("[ProfileRunner::recordProfileSelection] profile selection changed", "guid", selection.GUID, "profile", selection.ProfileName, "reason", selection.Reason, "description", selection.Description)
+	p.ProfileSelectionChannels.EmitTimeout(time.Second, selection)
+}
+
+/* the profile last used for each joystick and why */
+func (p *ProfileRunner) GetProfileSelections() map[controller_mgr.JoystickGUIDString]ProfileRunner_ProfileSelection {
+	selections := map[controller_mgr.JoystickGUIDString]ProfileRunner_ProfileSelection{}
+	p.ProfileSelections.ForEach(func(selection ProfileRunner_ProfileSelection, guid controller_mgr.JoystickGUIDString) bool {
+		selections[guid] = selection
+		return true
+	})
+	return selections
+}
+
+func (p *ProfileRunner) SubscribeProfileSelection() (chan ProfileRunner_ProfileSelection, func()) {
+	return p.ProfileSelectionChannels.Subscribe()
+}
@@ -0,0 +1,175 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/cabdebugger"
+	"tsw_controller_app/config"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAutoSelectProfile(name string, usb_id *string, priority *int, entries ...config.Config_Controller_Profile_RailClassInformationEntry) config.Config_Controller_Profile {
+	auto_select := true
+	profile := newTestProfile(name, "/profiles/"+name+".json")
+	profile.AutoSelect = &auto_select
+	profile.AutoSelectPriority = priority
+	profile.RailClassInformation = &entries
+	if usb_id != nil {
+		profile.Controller = &config.Config_Controller_Profile_Controller{UsbID: usb_id}
+	}
+	return profile
+}
+
+func newTestAutoSelectRunner(t *testing.T, rail_class string) (*ProfileRunner, sdl_mgr.SDLMgr_Joystick) {
+	runner, joystick, _ := newTestAssignmentStateRunner(t)
+	runner.ClearProfile(joystick.GUID)
+	runner.CabDebugger.State.DrivableActorName = rail_class
+	return runner, *joystick
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestMatchAutoSelectProfile_Priority(t *testing.T) {
+	runner, joystick := newTestAutoSelectRunner(t, "RVM_DTG_Class101_DMBS_C")
+	other_usb_id := "ffff:ffff"
+
+	/* an entry without any class matcher never matches */
+	runner.RegisterProfile(newTestAutoSelectProfile("Empty", ptr("*"), ptr(100), config.Config_Controller_Profile_RailClassInformationEntry{}))
+	runner.RegisterProfile(newTestAutoSelectProfile("Other controller", &other_usb_id, ptr(100), config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("*")}))
+	runner.RegisterProfile(newTestAutoSelectProfile("B generic", ptr("*"), nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("RVM_DTG_Class10?_*")}))
+	runner.RegisterProfile(newTestAutoSelectProfile("A generic", ptr("*"), nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassNameRegex: ptr(`Class101`)}))
+
+	/* profiles without a usb_id are never auto-selected */
+	runner.RegisterProfile(newTestAutoSelectProfile("0 without usb_id", nil, ptr(100), config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("*")}))
+
+	/* ties are broken by name */
+	match, has_match := runner.matchAutoSelectProfile(joystick)
+	require.True(t, has_match)
+	assert.Equal(t, "A generic", match.Profile.Name)
+
+	/* profiles for the USB ID are preferred over profiles for any controller */
+	usb_id := joystick.ToString()
+	runner.RegisterProfile(newTestAutoSelectProfile("Z specific", &usb_id, nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassName: ptr("RVM_DTG_Class101_DMBS_C")}))
+	match, _ = runner.matchAutoSelectProfile(joystick)
+	assert.Equal(t, "Z specific", match.Profile.Name)
+
+	/* the priority wins; entries can override the profile priority */
+	runner.RegisterProfile(newTestAutoSelectProfile("Prioritized", ptr("*"), ptr(1),
+		config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("*")},
+		config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("*Class101*"), Priority: ptr(5)},
+	))
+	match, _ = runner.matchAutoSelectProfile(joystick)
+	assert.Equal(t, "Prioritized", match.Profile.Name)
+	assert.Equal(t, 5, match.Priority)
+	assert.Contains(t, match.Description, "*Class101*")
+
+	runner.CabDebugger.State.DrivableActorName = ""
+	_, has_match = runner.matchAutoSelectProfile(joystick)
+	assert.False(t, has_match)
+}
+
+func TestMatchAutoSelectProfile_CabVariableConditions(t *testing.T) {
+	runner, joystick := newTestAutoSelectRunner(t, "RVM_DTG_Class101_DMBS_C")
+	runner.RegisterProfile(newTestAutoSelectProfile("Variant", ptr("*"), ptr(1), config.Config_Controller_Profile_RailClassInformationEntry{
+		ClassNamePattern: ptr("*Class101*"),
+		Conditions: &[]config.Config_Controller_Profile_Control_Assignment_Condition{
+			{CabVariable: ptr("PantographSwitch"), Operator: "gte", Value: 0.5},
+		},
+	}))
+	runner.RegisterProfile(newTestAutoSelectProfile("Base", ptr("*"), nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassNamePattern: ptr("*Class101*")}))
+
+	/* a missing cab variable does not match */
+	match, _ := runner.matchAutoSelectProfile(joystick)
+	assert.Equal(t, "Base", match.Profile.Name)
+
+	runner.CabDebugger.State.Controls.Set("PantographSwitch", cabdebugger.CabDebugger_ControlState_Control{CurrentNormalizedValue: 0})
+	match, _ = runner.matchAutoSelectProfile(joystick)
+	assert.Equal(t, "Base", match.Profile.Name)
+
+	runner.CabDebugger.State.Controls.Set("PantographSwitch", cabdebugger.CabDebugger_ControlState_Control{CurrentNormalizedValue: 1})
+	match, _ = runner.matchAutoSelectProfile(joystick)
+	assert.Equal(t, "Variant", match.Profile.Name)
+}
+
+func TestResolveProfileForJoystick_Policy(t *testing.T) {
+	runner, joystick := newTestAutoSelectRunner(t, "RVM_DTG_Class101_DMBS_C")
+	manual_profile := newTestProfile("Manual", "/profiles/manual.json")
+	fallback_profile := newTestProfile("Fallback", "/profiles/fallback.json")
+	auto_profile := newTestAutoSelectProfile("Auto", ptr("*"), nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassName: ptr("RVM_DTG_Class101_DMBS_C")})
+	runner.RegisterProfile(manual_profile)
+	runner.RegisterProfile(fallback_profile)
+	runner.RegisterProfile(auto_profile)
+
+	_, selection, _ := runner.resolveProfileForJoystick(joystick)
+	assert.Equal(t, ProfileRunner_SelectionReason_AutoSelect, selection.Reason)
+	assert.Equal(t, "Auto", selection.ProfileName)
+
+	/* manual selections are sticky by default */
+	require.NoError(t, runner.SetProfile(joystick.GUID, manual_profile.Id()))
+	_, selection, _ = runner.resolveProfileForJoystick(joystick)
+	assert.Equal(t, ProfileRunner_SelectionReason_Manual, selection.Reason)
+	assert.Equal(t, "Manual", selection.ProfileName)
+
+	runner.SetDevicePolicy(joystick.GUID, ProfileRunner_DevicePolicy{
+		StickyManualSelection: false,
+		FallbackProfile:       &config.Config_ProgramConfig_SelectedProfile{Name: "Fallback", Path: "/profiles/fallback.json"},
+	})
+	_, selection, _ = runner.resolveProfileForJoystick(joystick)
+	assert.Equal(t, ProfileRunner_SelectionReason_AutoSelect, selection.Reason)
+
+	/* the manual selection is used on trains without an auto-selected profile */
+	runner.CabDebugger.State.DrivableActorName = "RVM_Other_C"
+	_, selection, _ = runner.resolveProfileForJoystick(joystick)
+	assert.Equal(t, ProfileRunner_SelectionReason_Manual, selection.Reason)
+
+	runner.ClearProfile(joystick.GUID)
+	selected_profile, selection, has_selected_profile := runner.resolveProfileForJoystick(joystick)
+	assert.True(t, has_selected_profile)
+	assert.Equal(t, ProfileRunner_SelectionReason_Fallback, selection.Reason)
+	assert.Equal(t, "Fallback", selected_profile.Profile.Name)
+
+	runner.SetDevicePolicy(joystick.GUID, DefaultDevicePolicy())
+	_, selection, has_selected_profile = runner.resolveProfileForJoystick(joystick)
+	assert.False(t, has_selected_profile)
+	assert.Equal(t, ProfileRunner_SelectionReason_None, selection.Reason)
+}
+
+func TestRecordProfileSelection_EmitsChanges(t *testing.T) {
+	runner, joystick := newTestAutoSelectRunner(t, "RVM_DTG_Class101_DMBS_C")
+	runner.RegisterProfile(newTestAutoSelectProfile("Auto", ptr("*"), nil, config.Config_Controller_Profile_RailClassInformationEntry{ClassName: ptr("RVM_DTG_Class101_DMBS_C")}))
+	channel, unsubscribe := runner.SubscribeProfileSelection()
+	defer unsubscribe()
+
+	received := make(chan ProfileRunner_ProfileSelection, 4)
+	go func() {
+		for selection := range channel {
+			received <- selection
+		}
+	}()
+
+	record := func() {
+		_, selection, _ := runner.resolveProfileForJoystick(joystick)
+		runner.recordProfileSelection(selection)
+	}
+	record()
+	record()
+	runner.CabDebugger.State.DrivableActorName = "RVM_Other_C"
+	record()
+
+	first := <-received
+	assert.Equal(t, ProfileRunner_SelectionReason_AutoSelect, first.Reason)
+	assert.Equal(t, "Auto", first.ProfileName)
+	second := <-received
+	assert.Equal(t, ProfileRunner_SelectionReason_None, second.Reason)
+	select {
+	case selection := <-received:
+		assert.Fail(t, "unexpected selection", selection)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, ProfileRunner_SelectionReason_None, runner.GetProfileSelections()[joystick.GUID].Reason)
+}
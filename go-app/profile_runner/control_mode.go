@@ -0,0 +1,119 @@
+package profile_runner
+
+import (
+	"slices"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+)
+
+/* the order in which the control modes are tried after the preferred control mode when no fallback chain is defined */
+var DEFAULT_CONTROL_MODE_ORDER = []config.PreferredControlMode{
+	config.PreferredControlMode_DirectControl,
+	config.PreferredControlMode_ApiControl,
+	config.PreferredControlMode_SyncControl,
+}
+
+type ProfileRunner_ControlModeAvailability struct {
+	/* the mod is connected */
+	DirectControl bool
+	/* the API key is loaded and the API is reachable */
+	ApiControl bool
+	/* at least one of the sync control feedback sources is available */
+	SyncControl bool
+}
+
+/* the control mode used for the last event of a control; reported in the UI */
+type ProfileRunner_ControlModeSelection struct {
+	GUID        controller_mgr.JoystickGUIDString
+	ControlName string
+	ControlMode config.PreferredControlMode
+	Fallback    []config.PreferredControlMode
+	UpdatedAt   time.Time
+}
+
+func (a ProfileRunner_ControlModeAvailability) IsAvailable(mode config.PreferredControlMode) bool {
+	switch mode {
+	case config.PreferredControlMode_DirectControl:
+		return a.DirectControl
+	case config.PreferredControlMode_ApiControl:
+		return a.ApiControl
+	case config.PreferredControlMode_SyncControl:
+		return a.SyncControl
+	case config.PreferredControlMode_Keys:
+		return true
+	}
+	return false
+}
+
+/*
+Returns the control mode an assignment belongs to; combined assignments belong to the control mode of their outputs.
+Other assignments are not bound to a control mode
+*/
+func AssignmentControlMode(assignment config.Config_Controller_Profile_Control_Assignment) (config.PreferredControlMode, bool) {
+	switch {
+	case assignment.DirectControl != nil:
+		return config.PreferredControlMode_DirectControl, true
+	case assignment.SyncControl != nil:
+		return config.PreferredControlMode_SyncControl, true
+	case assignment.ApiControl != nil:
+		return config.PreferredControlMode_ApiControl, true
+	case assignment.Combined != nil:
+		if zones := assignment.Combined.Zones(); len(zones) > 0 {
+			return AssignmentControlMode(zones[0].Zone.Output)
+		}
+	}
+	return "", false
+}
+
+/*
+Returns the control mode fallback chain for a control; the control chain takes precedence over the profile chain.
+Without a chain the preferred control mode is tried first followed by the default order
+*/
+func ControlModeFallback(
+	profile *config.Config_Controller_Profile,
+	control *config.Config_Controller_Profile_Control,
+	preferred_control_mode config.PreferredControlMode,
+) []config.PreferredControlMode {
+	if control != nil && control.ControlModeFallback != nil && len(*control.ControlModeFallback) > 0 {
+		return *control.ControlModeFallback
+	}
+	if profile != nil && profile.ControlModeFallback != nil && len(*profile.ControlModeFallback) > 0 {
+		return *profile.ControlModeFallback
+	}
+	fallback := []config.PreferredControlMode{preferred_control_mode}
+	for _, mode := range DEFAULT_CONTROL_MODE_ORDER {
+		if mode != preferred_control_mode {
+			fallback = append(fallback, mode)
+		}
+	}
+	return fallback
+}
+
+/*
+Picks the first control mode in the chain which is available and has assignments;
+"keys" (or running out of modes) means only the key based assignments are used
+*/
+func SelectControlMode(
+	fallback []config.PreferredControlMode,
+	availability ProfileRunner_ControlModeAvailability,
+	modes_with_assignments []config.PreferredControlMode,
+) config.PreferredControlMode {
+	for _, mode := range fallback {
+		if mode == config.PreferredControlMode_Keys {
+			break
+		}
+		if availability.IsAvailable(mode) && slices.Contains(modes_with_assignments, mode) {
+			return mode
+		}
+	}
+	return config.PreferredControlMode_Keys
+}
+
+func (p *ProfileRunner) GetControlModeAvailability() ProfileRunner_ControlModeAvailability {
+	return ProfileRunner_ControlModeAvailability{
+		DirectControl: p.DirectController.IsAvailable(),
+		ApiControl:    p.ApiController.IsAvailable(),
+		SyncControl:   p.SyncController.IsAvailable(),
+	}
+}
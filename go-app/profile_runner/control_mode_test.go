@@ -0,0 +1,76 @@
+package profile_runner
+
+import (
+	"testing"
+	"tsw_controller_app/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControlModeFallback_Default(t *testing.T) {
+	fallback := ControlModeFallback(&config.Config_Controller_Profile{}, &config.Config_Controller_Profile_Control{}, config.PreferredControlMode_SyncControl)
+	assert.Equal(t, []config.PreferredControlMode{
+		config.PreferredControlMode_SyncControl,
+		config.PreferredControlMode_DirectControl,
+		config.PreferredControlMode_ApiControl,
+	}, fallback)
+}
+
+func TestControlModeFallback_ControlOverridesProfile(t *testing.T) {
+	profile_fallback := []config.PreferredControlMode{config.PreferredControlMode_ApiControl, config.PreferredControlMode_Keys}
+	control_fallback := []config.PreferredControlMode{config.PreferredControlMode_DirectControl}
+	profile := config.Config_Controller_Profile{ControlModeFallback: &profile_fallback}
+
+	assert.Equal(t, profile_fallback, ControlModeFallback(&profile, &config.Config_Controller_Profile_Control{}, config.PreferredControlMode_SyncControl))
+	assert.Equal(t, control_fallback, ControlModeFallback(&profile, &config.Config_Controller_Profile_Control{ControlModeFallback: &control_fallback}, config.PreferredControlMode_SyncControl))
+}
+
+func TestSelectControlMode(t *testing.T) {
+	fallback := []config.PreferredControlMode{
+		config.PreferredControlMode_DirectControl,
+		config.PreferredControlMode_ApiControl,
+		config.PreferredControlMode_Keys,
+		config.PreferredControlMode_SyncControl,
+	}
+	all_modes := []config.PreferredControlMode{
+		config.PreferredControlMode_DirectControl,
+		config.PreferredControlMode_ApiControl,
+		config.PreferredControlMode_SyncControl,
+	}
+
+	/* the first available mode wins */
+	assert.Equal(t, config.PreferredControlMode_DirectControl, SelectControlMode(fallback, ProfileRunner_ControlModeAvailability{DirectControl: true, ApiControl: true}, all_modes))
+	/* the mod is not connected */
+	assert.Equal(t, config.PreferredControlMode_ApiControl, SelectControlMode(fallback, ProfileRunner_ControlModeAvailability{ApiControl: true}, all_modes))
+	/* modes without assignments are skipped */
+	assert.Equal(t, config.PreferredControlMode_ApiControl, SelectControlMode(fallback, ProfileRunner_ControlModeAvailability{DirectControl: true, ApiControl: true}, []config.PreferredControlMode{config.PreferredControlMode_ApiControl}))
+	/* keys stops the chain even when a later mode is available */
+	assert.Equal(t, config.PreferredControlMode_Keys, SelectControlMode(fallback, ProfileRunner_ControlModeAvailability{SyncControl: true}, all_modes))
+	/* nothing available */
+	assert.Equal(t, config.PreferredControlMode_Keys, SelectControlMode([]config.PreferredControlMode{config.PreferredControlMode_DirectControl}, ProfileRunner_ControlModeAvailability{}, all_modes))
+}
+
+func TestAssignmentControlMode(t *testing.T) {
+	mode, has_mode := AssignmentControlMode(config.Config_Controller_Profile_Control_Assignment{
+		ApiControl: &config.Config_Controller_Profile_Control_Assignment_ApiControl{},
+	})
+	assert.True(t, has_mode)
+	assert.Equal(t, config.PreferredControlMode_ApiControl, mode)
+
+	mode, has_mode = AssignmentControlMode(config.Config_Controller_Profile_Control_Assignment{
+		Combined: &config.Config_Controller_Profile_Control_Assignment_Combined{
+			Power: &config.Config_Controller_Profile_Control_Assignment_Combined_Zone{
+				Output: config.Config_Controller_Profile_Control_Assignment{
+					SyncControl: &config.Config_Controller_Profile_Control_Assignment_SyncControl{},
+				},
+			},
+		},
+	})
+	assert.True(t, has_mode)
+	assert.Equal(t, config.PreferredControlMode_SyncControl, mode)
+
+	_, has_mode = AssignmentControlMode(config.Config_Controller_Profile_Control_Assignment{
+		Momentary: &config.Config_Controller_Profile_Control_Assignment_Momentary{},
+	})
+	assert.False(t, has_mode)
+}
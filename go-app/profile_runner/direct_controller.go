@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 	"tsw_controller_app/tswconnector"
 )
 
@@ -31,6 +32,10 @@ func (command *DirectController_Command) ToSocketMessage() tswconnector.TSWConne
 	}
 }
 
+func (controller *DirectController) IsAvailable() bool {
+	return controller.Connector.IsConnected()
+}
+
 func (controller *DirectController) Run(ctx context.Context) func() {
 	ctx_with_cancel, cancel := context.WithCancel(ctx)
 
@@ -48,6 +53,16 @@ func (controller *DirectController) Run(ctx context.Context) func() {
 	return cancel
 }
 
+/*
+Waits until the queued commands are sent or the timeout is reached
+*/
+func (controller *DirectController) Flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for len(controller.ControlChannel) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func NewDirectController(connection tswconnector.TSWConnector) *DirectController {
 	controller := DirectController{
 		Connector:      connection,
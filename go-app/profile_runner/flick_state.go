@@ -0,0 +1,99 @@
+package profile_runner
+
+import (
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/map_utils"
+)
+
+/*
+the state of a flick assignment; only modified by the runner loop
+*/
+type ProfileRunner_FlickState struct {
+	GUID            controller_mgr.JoystickGUIDString
+	ControlName     string
+	AssignmentIndex int
+	Assignment      config.Config_Controller_Profile_Control_Assignment
+	/* the last event of the control; its motion is used to check whether the flick ended while no events are sent */
+	ChangeEvent controller_mgr.ControllerManager_Control_ChangeEvent
+	/* whether the flick was performed and did not end yet */
+	Active bool
+}
+
+/*
+Performs the flick action once the velocity reaches the min velocity and the deactivate action once the flick ended
+*/
+func (p *ProfileRunner) executeFlickAssignment(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+) {
+	key := assignmentStateKey(change_event.Joystick.GUID, control_name, assignment_index)
+	state, has_state := p.FlickStates.Get(key)
+	if !has_state || state.Assignment.Flick != assignment.Flick {
+		state = &ProfileRunner_FlickState{
+			GUID:            change_event.Joystick.GUID,
+			ControlName:     control_name,
+			AssignmentIndex: assignment_index,
+			Assignment:      assignment,
+		}
+		p.FlickStates.Set(key, state)
+	}
+	state.ChangeEvent = *change_event
+	p.updateFlick(state, change_event.ControlState.Motion.Velocity)
+}
+
+func (p *ProfileRunner) updateFlick(state *ProfileRunner_FlickState, velocity float64) {
+	flick := state.Assignment.Flick
+	control_state := state.ChangeEvent.ControlState
+	if !state.Active && flick.IsActive(velocity) {
+		state.Active = true
+		if flick.ActionDeactivate == nil {
+			/* a flick has no "held" state to release keys and buttons on */
+			p.tapAssignmentAction(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, flick.ActionActivate)
+		} else {
+			action_to_call := p.AssignmentActionToAssignmentCall(control_state, flick.ActionActivate, false)
+			p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, action_to_call)
+		}
+	} else if state.Active && flick.HasEnded(velocity) {
+		state.Active = false
+		if flick.ActionDeactivate != nil {
+			p.cancelAssignmentSequence(state.GUID, state.ControlName, state.AssignmentIndex, "", flick.ActionActivate)
+			action_to_call := p.AssignmentActionToAssignmentCall(control_state, *flick.ActionDeactivate, false)
+			p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, action_to_call)
+		} else {
+			p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, nil)
+		}
+	}
+}
+
+/*
+Ends the active flicks of the controls which stopped moving; the controls don't send events while held still
+*/
+func (p *ProfileRunner) settleFlicks(now time.Time) {
+	var active_states []*ProfileRunner_FlickState
+	p.FlickStates.ForEach(func(state *ProfileRunner_FlickState, key string) bool {
+		if state.Active {
+			active_states = append(active_states, state)
+		}
+		return true
+	})
+
+	for _, state := range active_states {
+		/* the state may have been cleared by a profile switch in the meantime */
+		if current_state, has_state := p.FlickStates.Get(assignmentStateKey(state.GUID, state.ControlName, state.AssignmentIndex)); has_state && current_state == state {
+			p.updateFlick(state, state.ChangeEvent.ControlState.Motion.VelocityAt(now))
+		}
+	}
+}
+
+func (p *ProfileRunner) clearFlickStates(match func(guid controller_mgr.JoystickGUIDString) bool) {
+	p.FlickStates.Mutate(func(state *ProfileRunner_FlickState, key string) map_utils.LockMapMutateAction[string, *ProfileRunner_FlickState] {
+		if match(state.GUID) {
+			return map_utils.LockMapMutateAction[string, *ProfileRunner_FlickState]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, *ProfileRunner_FlickState]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
@@ -0,0 +1,85 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFlickProfile = `{
+	"name": "Flicks",
+	"controls": [
+		{
+			"name": "Lever1",
+			"assignment": {
+				"type": "flick",
+				"direction": "up",
+				"min_velocity": 1,
+				"action_activate": { "controls": "Reverser", "value": 1, "relative": true }
+			}
+		},
+		{
+			"name": "Lever2",
+			"assignment": {
+				"type": "flick",
+				"direction": "down",
+				"min_velocity": 1,
+				"action_activate": { "controls": "Horn", "value": 1 },
+				"action_deactivate": { "controls": "Horn", "value": 0 }
+			}
+		}
+	]
+}`
+
+func newTestFlickChangeEvent(joystick *sdl_mgr.SDLMgr_Joystick, control_name string, velocity float64, now time.Time) *controller_mgr.ControllerManager_Control_ChangeEvent {
+	change_event := newTestChangeEvent(joystick, control_name, 0, 0)
+	change_event.ControlState.Motion.Velocity = velocity
+	change_event.ControlState.Motion.UpdatedAt = now
+	change_event.Control.State.Motion = change_event.ControlState.Motion
+	return change_event
+}
+
+func TestFlick_FiresOncePerFlick(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testFlickProfile)
+	now := time.Now()
+
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 0.5, now))
+	assert.Empty(t, drainDirectControlCommands(runner))
+	/* moving the other way doesn't flick */
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", -3, now))
+	assert.Empty(t, drainDirectControlCommands(runner))
+
+	/* fires once per flick */
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 2, now))
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 3, now))
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 0.8, now))
+	assert.Equal(t, []DirectController_Command{{Controls: "Reverser", InputValue: 1}}, drainDirectControlCommands(runner))
+
+	/* and again once the flick ended */
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 0.2, now))
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever1", 1.2, now))
+	assert.Equal(t, []DirectController_Command{{Controls: "Reverser", InputValue: 1}}, drainDirectControlCommands(runner))
+}
+
+func TestFlick_EndsOnceTheControlStopsMoving(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testFlickProfile)
+	now := time.Now()
+
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever2", -2, now))
+	assert.Equal(t, []DirectController_Command{{Controls: "Horn", InputValue: 1}}, drainDirectControlCommands(runner))
+
+	/* the control sends no more events once it stopped; the velocity decays until the flick ended */
+	runner.settleFlicks(now.Add(10 * time.Millisecond))
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.settleFlicks(now.Add(time.Second))
+	assert.Equal(t, []DirectController_Command{{Controls: "Horn", InputValue: 0}}, drainDirectControlCommands(runner))
+	runner.settleFlicks(now.Add(2 * time.Second))
+	assert.Empty(t, drainDirectControlCommands(runner))
+
+	/* the next flick triggers again */
+	runner.handleChangeEvent(newTestFlickChangeEvent(joystick, "Lever2", -2, now.Add(2*time.Second)))
+	assert.Equal(t, []DirectController_Command{{Controls: "Horn", InputValue: 1}}, drainDirectControlCommands(runner))
+}
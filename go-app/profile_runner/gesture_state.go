@@ -0,0 +1,275 @@
+package profile_runner
+
+import (
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/map_utils"
+)
+
+/*
+the press state of a long press, multi tap or chord assignment; only modified by the runner loop
+*/
+type ProfileRunner_GestureState struct {
+	GUID            controller_mgr.JoystickGUIDString
+	ControlName     string
+	AssignmentIndex int
+	Assignment      config.Config_Controller_Profile_Control_Assignment
+	/* the last event of the control; the actions performed by the tick are called with its control state */
+	ChangeEvent controller_mgr.ControllerManager_Control_ChangeEvent
+	IsPressed   bool
+	PressedAt   time.Time
+	ReleasedAt  time.Time
+	/* the taps of a multi tap which were not resolved yet */
+	Taps int
+	/* the long press or chord was activated and needs to be deactivated */
+	IsActive bool
+	/* the current press completed a gesture (or was part of a chord) so its single press action is not performed */
+	IsSuppressed bool
+}
+
+func gestureStateKey(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int) string {
+	return assignmentStateKey(guid, control_name, assignment_index)
+}
+
+func isSameGestureAssignment(a config.Config_Controller_Profile_Control_Assignment, b config.Config_Controller_Profile_Control_Assignment) bool {
+	return a.LongPress == b.LongPress && a.MultiTap == b.MultiTap && a.Chord == b.Chord
+}
+
+/*
+Returns the gesture state for the assignment; the state is recreated when a different assignment is at the same index
+*/
+func (p *ProfileRunner) getGestureState(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+) *ProfileRunner_GestureState {
+	key := gestureStateKey(change_event.Joystick.GUID, control_name, assignment_index)
+	state, has_state := p.GestureStates.Get(key)
+	if !has_state || !isSameGestureAssignment(state.Assignment, assignment) {
+		state = &ProfileRunner_GestureState{
+			GUID:            change_event.Joystick.GUID,
+			ControlName:     control_name,
+			AssignmentIndex: assignment_index,
+			Assignment:      assignment,
+		}
+		p.GestureStates.Set(key, state)
+	}
+	state.ChangeEvent = *change_event
+	return state
+}
+
+func (p *ProfileRunner) activateGestureAction(state *ProfileRunner_GestureState, action config.Config_Controller_Profile_Control_Assignment_Action) {
+	control_state := state.ChangeEvent.ControlState
+	action_to_call := p.AssignmentActionToAssignmentCall(control_state, action, false)
+	p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, action_to_call)
+}
+
+func (p *ProfileRunner) tapGestureAction(state *ProfileRunner_GestureState, action *config.Config_Controller_Profile_Control_Assignment_Action) {
+	if action == nil {
+		return
+	}
+	p.tapAssignmentAction(state.GUID, state.ControlName, state.AssignmentIndex, state.ChangeEvent.ControlState, state.Assignment, *action)
+}
+
+func (p *ProfileRunner) deactivateGestureAction(
+	state *ProfileRunner_GestureState,
+	action_activate config.Config_Controller_Profile_Control_Assignment_Action,
+	action_deactivate *config.Config_Controller_Profile_Control_Assignment_Action,
+) {
+	control_state := state.ChangeEvent.ControlState
+	if action_deactivate != nil {
+		p.cancelAssignmentSequence(state.GUID, state.ControlName, state.AssignmentIndex, "", action_activate)
+		action_to_call := p.AssignmentActionToAssignmentCall(control_state, *action_deactivate, false)
+		p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, action_to_call)
+	} else if action_activate.IsReleasable() {
+		/* only release if keys, sequences (cancels the sequence) or virtual buttons -> can't "release" direct control actions */
+		action_to_call := p.AssignmentActionToAssignmentCall(control_state, action_activate, true)
+		p.CallAssignmentActionForControl(state.GUID, state.ControlName, state.AssignmentIndex, control_state, state.Assignment, action_to_call)
+	}
+}
+
+/*
+Updates the press state of the gesture assignment from the change event of its control
+*/
+func (p *ProfileRunner) executeGestureAssignment(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	now time.Time,
+) {
+	state := p.getGestureState(control_name, assignment_index, change_event, assignment)
+	value := change_event.ControlState.NormalizedValues.Value
+	switch {
+	case assignment.LongPress != nil:
+		p.updateGesturePress(state, value >= assignment.LongPress.Threshold, now)
+	case assignment.MultiTap != nil:
+		p.updateGesturePress(state, value >= assignment.MultiTap.Threshold, now)
+	case assignment.Chord != nil:
+		p.updateGesturePress(state, value >= assignment.Chord.Threshold, now)
+	}
+	p.evaluateGesture(state, now)
+}
+
+func (p *ProfileRunner) updateGesturePress(state *ProfileRunner_GestureState, is_pressed bool, now time.Time) {
+	if is_pressed == state.IsPressed {
+		return
+	}
+	state.IsPressed = is_pressed
+	assignment := state.Assignment
+
+	if is_pressed {
+		if assignment.MultiTap != nil && state.Taps > 0 && now.Sub(state.ReleasedAt).Seconds() > assignment.MultiTap.Window {
+			/* the window passed before the tick resolved the previous taps */
+			p.tapGestureAction(state, assignment.MultiTap.ActionForTaps(state.Taps))
+			state.Taps = 0
+		}
+		state.PressedAt = now
+		state.IsSuppressed = false
+		if assignment.MultiTap != nil {
+			state.Taps++
+			if state.Taps >= assignment.MultiTap.MaxTaps() {
+				/* no further tap can follow; no need to wait for the window */
+				p.tapGestureAction(state, assignment.MultiTap.ActionForTaps(state.Taps))
+				state.Taps = 0
+				state.IsSuppressed = true
+			}
+		}
+		return
+	}
+
+	state.ReleasedAt = now
+	switch {
+	case assignment.LongPress != nil:
+		if state.IsActive {
+			state.IsActive = false
+			p.deactivateGestureAction(state, assignment.LongPress.ActionActivate, assignment.LongPress.ActionDeactivate)
+		} else if !state.IsSuppressed {
+			p.tapGestureAction(state, assignment.LongPress.ActionPress)
+		}
+	case assignment.MultiTap != nil:
+		if state.IsSuppressed {
+			/* the press completed the gesture or was part of a chord */
+			state.Taps = 0
+		}
+	case assignment.Chord != nil:
+		if !state.IsActive && !state.IsSuppressed {
+			p.tapGestureAction(state, assignment.Chord.ActionPress)
+		}
+	}
+}
+
+/*
+Performs the actions which depend on time or other controls: long presses held for their duration, multi taps whose window passed
+and chords whose controls are all pressed (or no longer are)
+*/
+func (p *ProfileRunner) evaluateGesture(state *ProfileRunner_GestureState, now time.Time) {
+	assignment := state.Assignment
+	switch {
+	case assignment.LongPress != nil:
+		if state.IsPressed && !state.IsActive && !state.IsSuppressed && now.Sub(state.PressedAt).Seconds() >= assignment.LongPress.Duration {
+			state.IsActive = true
+			p.activateGestureAction(state, assignment.LongPress.ActionActivate)
+		}
+	case assignment.MultiTap != nil:
+		if !state.IsPressed && state.Taps > 0 && now.Sub(state.ReleasedAt).Seconds() >= assignment.MultiTap.Window {
+			p.tapGestureAction(state, assignment.MultiTap.ActionForTaps(state.Taps))
+			state.Taps = 0
+		}
+	case assignment.Chord != nil:
+		is_chord_pressed := state.IsPressed && p.areChordControlsPressed(&state.ChangeEvent, assignment.Chord)
+		if is_chord_pressed && !state.IsActive {
+			state.IsActive = true
+			state.IsSuppressed = true
+			p.suppressGesturePresses(state.GUID, assignment.Chord.Controls)
+			p.activateGestureAction(state, assignment.Chord.ActionActivate)
+		} else if !is_chord_pressed && state.IsActive {
+			state.IsActive = false
+			p.deactivateGestureAction(state, assignment.Chord.ActionActivate, assignment.Chord.ActionDeactivate)
+		}
+	}
+}
+
+func (p *ProfileRunner) areChordControlsPressed(change_event *controller_mgr.ControllerManager_Control_ChangeEvent, chord *config.Config_Controller_Profile_Control_Assignment_Chord) bool {
+	if change_event.Controller == nil {
+		return false
+	}
+	for _, control_name := range chord.Controls {
+		control, has_control := change_event.Controller.Controls.Get(control_name)
+		if !has_control || control.State.NormalizedValues.Value < chord.Threshold {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+The single press actions of the other controls of a chord are not performed for the press completing the chord
+*/
+func (p *ProfileRunner) suppressGesturePresses(guid controller_mgr.JoystickGUIDString, control_names []string) {
+	p.GestureStates.ForEach(func(state *ProfileRunner_GestureState, key string) bool {
+		if state.GUID != guid || !state.IsPressed {
+			return true
+		}
+		for _, control_name := range control_names {
+			if state.ControlName == control_name {
+				state.IsSuppressed = true
+			}
+		}
+		return true
+	})
+}
+
+/*
+Re-evaluates the chords of the other controls referencing the changed control
+*/
+func (p *ProfileRunner) executeChordMemberAssignments(
+	control_name string,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	now time.Time,
+) {
+	var chord_states []*ProfileRunner_GestureState
+	p.GestureStates.ForEach(func(state *ProfileRunner_GestureState, key string) bool {
+		if state.GUID == change_event.Joystick.GUID && state.ControlName != control_name && state.Assignment.Chord != nil && state.Assignment.Chord.HasControl(control_name) {
+			chord_states = append(chord_states, state)
+		}
+		return true
+	})
+
+	for _, state := range chord_states {
+		p.evaluateGesture(state, now)
+	}
+}
+
+/*
+Re-evaluates the gestures waiting for their duration or window to pass
+*/
+func (p *ProfileRunner) settleGestures(now time.Time) {
+	var pending_states []*ProfileRunner_GestureState
+	p.GestureStates.ForEach(func(state *ProfileRunner_GestureState, key string) bool {
+		is_pending_long_press := state.Assignment.LongPress != nil && state.IsPressed && !state.IsActive && !state.IsSuppressed
+		is_pending_multi_tap := state.Assignment.MultiTap != nil && !state.IsPressed && state.Taps > 0
+		if is_pending_long_press || is_pending_multi_tap {
+			pending_states = append(pending_states, state)
+		}
+		return true
+	})
+
+	for _, state := range pending_states {
+		/* the state may have been cleared by a profile switch in the meantime */
+		if current_state, has_state := p.GestureStates.Get(gestureStateKey(state.GUID, state.ControlName, state.AssignmentIndex)); has_state && current_state == state {
+			p.evaluateGesture(state, now)
+		}
+	}
+}
+
+func (p *ProfileRunner) clearGestureStates(match func(guid controller_mgr.JoystickGUIDString) bool) {
+	p.GestureStates.Mutate(func(state *ProfileRunner_GestureState, key string) map_utils.LockMapMutateAction[string, *ProfileRunner_GestureState] {
+		if match(state.GUID) {
+			return map_utils.LockMapMutateAction[string, *ProfileRunner_GestureState]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, *ProfileRunner_GestureState]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
@@ -0,0 +1,166 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/map_utils"
+	"tsw_controller_app/sdl_mgr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGestureProfile = `{
+	"name": "Gestures",
+	"controls": [
+		{
+			"name": "Button1",
+			"assignment": {
+				"type": "long_press",
+				"threshold": 0.5,
+				"duration": 0.2,
+				"action_activate": { "controls": "Long", "value": 1 },
+				"action_deactivate": { "controls": "Long", "value": 0 },
+				"action_press": { "controls": "Short", "value": 1 }
+			}
+		},
+		{
+			"name": "Button2",
+			"assignment": {
+				"type": "multi_tap",
+				"threshold": 0.5,
+				"window": 0.2,
+				"action_press": { "controls": "Single", "value": 1 },
+				"action_double_tap": { "controls": "Double", "value": 1 }
+			}
+		},
+		{
+			"name": "Button3",
+			"assignment": {
+				"type": "chord",
+				"threshold": 0.5,
+				"controls": ["Button4"],
+				"action_activate": { "controls": "Chord", "value": 1 },
+				"action_deactivate": { "controls": "Chord", "value": 0 },
+				"action_press": { "controls": "Three", "value": 1 }
+			}
+		},
+		{
+			"name": "Button4",
+			"assignment": {
+				"type": "long_press",
+				"threshold": 0.5,
+				"duration": 0.2,
+				"action_activate": { "controls": "FourLong", "value": 1 },
+				"action_press": { "controls": "Four", "value": 1 }
+			}
+		},
+		{
+			"name": "Button5",
+			"assignment": {
+				"type": "chord",
+				"threshold": 0.5,
+				"controls": ["Button6"],
+				"action_activate": { "controls": "Shifted", "value": 1 },
+				"action_deactivate": { "controls": "Shifted", "value": 0 }
+			}
+		}
+	]
+}`
+
+/* a button event of a controller which keeps the state of all its buttons for chords */
+func newTestButtonEvent(joystick *sdl_mgr.SDLMgr_Joystick, controller *controller_mgr.ControllerManager_ConfiguredController, control_name string, value float64) *controller_mgr.ControllerManager_Control_ChangeEvent {
+	previous_value := 1 - value
+	change_event := newTestChangeEvent(joystick, control_name, previous_value, value)
+	change_event.Controller = controller
+	controller.Controls.Set(control_name, *change_event.Control)
+	return change_event
+}
+
+func TestGesture_LongPress(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testGestureProfile)
+	controller := &controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick, Controls: map_utils.NewLockMap[string, controller_mgr.ControllerManager_Controller_Control]()}
+
+	/* a short press only performs the press action on release */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button1", 1))
+	runner.settleGestures(time.Now())
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button1", 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Short", InputValue: 1}}, drainDirectControlCommands(runner))
+
+	/* a long press suppresses the press action */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button1", 1))
+	runner.settleGestures(time.Now().Add(300 * time.Millisecond))
+	assert.Equal(t, []DirectController_Command{{Controls: "Long", InputValue: 1}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button1", 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Long", InputValue: 0}}, drainDirectControlCommands(runner))
+}
+
+func TestGesture_MultiTap(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testGestureProfile)
+	controller := &controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick, Controls: map_utils.NewLockMap[string, controller_mgr.ControllerManager_Controller_Control]()}
+
+	/* a single tap waits for the window */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 1))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 0))
+	runner.settleGestures(time.Now())
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.settleGestures(time.Now().Add(300 * time.Millisecond))
+	assert.Equal(t, []DirectController_Command{{Controls: "Single", InputValue: 1}}, drainDirectControlCommands(runner))
+
+	/* the last tap performs the action right away without the single tap */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 1))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 0))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 1))
+	assert.Equal(t, []DirectController_Command{{Controls: "Double", InputValue: 1}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 0))
+	runner.settleGestures(time.Now().Add(300 * time.Millisecond))
+	assert.Empty(t, drainDirectControlCommands(runner))
+}
+
+func TestGesture_Chord(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testGestureProfile)
+	controller := &controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick, Controls: map_utils.NewLockMap[string, controller_mgr.ControllerManager_Controller_Control]()}
+
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button4", 1))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button3", 1))
+	assert.Equal(t, []DirectController_Command{{Controls: "Chord", InputValue: 1}}, drainDirectControlCommands(runner))
+
+	/* the chord suppresses the long press and the press actions of its controls */
+	runner.settleGestures(time.Now().Add(300 * time.Millisecond))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button4", 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Chord", InputValue: 0}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button3", 0))
+	assert.Empty(t, drainDirectControlCommands(runner))
+
+	/* pressed alone it is a normal press */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button3", 1))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button3", 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Three", InputValue: 1}}, drainDirectControlCommands(runner))
+}
+
+func TestGesture_ChordMemberWithoutAssignment(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testGestureProfile)
+	controller := &controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick, Controls: map_utils.NewLockMap[string, controller_mgr.ControllerManager_Controller_Control]()}
+
+	/* Button6 is not in the profile but still completes and releases the chord */
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button5", 1))
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button6", 1))
+	assert.Equal(t, []DirectController_Command{{Controls: "Shifted", InputValue: 1}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button6", 0))
+	assert.Equal(t, []DirectController_Command{{Controls: "Shifted", InputValue: 0}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button5", 0))
+	assert.Empty(t, drainDirectControlCommands(runner))
+}
+
+func TestGesture_ProfileSwitchClearsPendingGestures(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testGestureProfile)
+	controller := &controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick, Controls: map_utils.NewLockMap[string, controller_mgr.ControllerManager_Controller_Control]()}
+
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 1))
+	runner.handleChangeEvent(newTestButtonEvent(joystick, controller, "Button2", 0))
+	runner.ApplySafeState(joystick.GUID)
+	runner.settleGestures(time.Now().Add(300 * time.Millisecond))
+	assert.Empty(t, drainDirectControlCommands(runner))
+}
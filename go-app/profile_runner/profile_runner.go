@@ -3,7 +3,6 @@ package profile_runner
 import (
 	"context"
 	"fmt"
-	"math"
 	"sort"
 	"sync"
 	"time"
@@ -14,20 +13,14 @@ import (
 	"tsw_controller_app/controller_mgr"
 	"tsw_controller_app/logger"
 	"tsw_controller_app/map_utils"
-	"tsw_controller_app/sdl_mgr"
+	"tsw_controller_app/pubsub_utils"
 )
 
-type ProfileRunner_AssignmentScore = int
-
-const ASSIGNMENT_SCORE_IS_PREFERRED_CONTROL_MODE ProfileRunner_AssignmentScore = 10
-const ASSIGNMENT_SCORE_DIRECT_CONTROL_MODE ProfileRunner_AssignmentScore = 3
-const ASSIGNMENT_SCORE_API_CONTROL_MODE ProfileRunner_AssignmentScore = 2
-const ASSIGNMENT_SCORE_SYNC_CONTROL_MODE ProfileRunner_AssignmentScore = 1
-
-type ProfileRunner_ScoredAssignmentsListEntry struct {
-	Score       int
-	Assignments []config.Config_Controller_Profile_Control_Assignment
-}
+/*
+how often the time based assignments are re-evaluated while the controls don't send events:
+pending gestures (eg: a held long press or the window of a multi tap), active flicks and the repeats of rate assignments
+*/
+const RUNNER_TICK_INTERVAL = 10 * time.Millisecond
 
 type ProfileRunnerSettings_SelectedProfile struct {
 	Profile config.Config_Controller_Profile
@@ -36,26 +29,46 @@ type ProfileRunnerSettings_SelectedProfile struct {
 type ProfileRunnerSettings struct {
 	Mutex                  sync.RWMutex
 	SelectedProfilesByGUID *map_utils.LockMap[controller_mgr.JoystickGUIDString, ProfileRunnerSettings_SelectedProfile]
+	DevicePoliciesByGUID   *map_utils.LockMap[controller_mgr.JoystickGUIDString, ProfileRunner_DevicePolicy]
 	PreferredControlMode   config.PreferredControlMode
 }
 
 type ProfileRunnerAssignmentCall struct {
-	ControlState          controller_mgr.ControllerManager_Controller_ControlState
-	ActionSequencerAction *action_sequencer.ActionSequencerAction
-	DirectControlCommand  *DirectController_Command
-	ApiControlCommand     *ApiController_Command
+	ControlState           controller_mgr.ControllerManager_Controller_ControlState
+	ActionSequencerAction  *action_sequencer.ActionSequencerAction
+	DirectControlCommand   *DirectController_Command
+	ApiControlCommand      *ApiController_Command
+	SequenceAction         *action_sequencer.ActionSequencerSequence
+	VirtualJoystickCommand *VirtualJoystickController_Command
 }
 
 type ProfileRunner struct {
-	ActionSequencer                   *action_sequencer.ActionSequencer
-	ControllerManager                 *controller_mgr.ControllerManager
-	DirectController                  *DirectController
-	SyncController                    *SyncController
-	ApiController                     *ApiController
-	CabDebugger                       *cabdebugger.CabDebugger
-	Profiles                          *map_utils.LockMap[string, config.Config_Controller_Profile]
-	Settings                          ProfileRunnerSettings
-	PreviousControlAssignmentCallList *map_utils.LockMap[string, *[]*ProfileRunnerAssignmentCall]
+	ActionSequencer           *action_sequencer.ActionSequencer
+	ControllerManager         *controller_mgr.ControllerManager
+	DirectController          *DirectController
+	SyncController            *SyncController
+	ApiController             *ApiController
+	VirtualJoystickController *VirtualJoystickController
+	CabDebugger               *cabdebugger.CabDebugger
+	Profiles                  *map_utils.LockMap[string, config.Config_Controller_Profile]
+	Settings                  ProfileRunnerSettings
+	/* keyed by the joystick GUID, control name and assignment index */
+	AssignmentStates *map_utils.LockMap[string, ProfileRunner_AssignmentState]
+	/* keyed by the joystick GUID and control name */
+	ControlModeSelections *map_utils.LockMap[string, ProfileRunner_ControlModeSelection]
+	/* keyed by the joystick GUID, control name and assignment index */
+	TransformStates *map_utils.LockMap[string, *ProfileRunner_TransformState]
+	/* keyed by the joystick GUID, control name and assignment index */
+	GestureStates *map_utils.LockMap[string, *ProfileRunner_GestureState]
+	/* keyed by the joystick GUID, control name and assignment index */
+	RateStates *map_utils.LockMap[string, *ProfileRunner_RateState]
+	/* keyed by the joystick GUID, control name and assignment index */
+	FlickStates *map_utils.LockMap[string, *ProfileRunner_FlickState]
+	/* keyed by the joystick GUID, output kind and name */
+	ActiveOutputs *map_utils.LockMap[string, ProfileRunner_ActiveOutput]
+	/* the profile last used by each joystick and why */
+	ProfileSelections        *map_utils.LockMap[controller_mgr.JoystickGUIDString, ProfileRunner_ProfileSelection]
+	ProfileSelectionChannels *pubsub_utils.PubSubSlice[ProfileRunner_ProfileSelection]
 }
 
 func (s *ProfileRunnerSettings) Update(mutator func(s *ProfileRunnerSettings)) {
@@ -88,27 +101,41 @@ func New(
 	direct_controller *DirectController,
 	sync_controller *SyncController,
 	api_controller *ApiController,
+	virtual_joystick_controller *VirtualJoystickController,
 	cab_debugger *cabdebugger.CabDebugger,
 ) *ProfileRunner {
 	return &ProfileRunner{
-		ActionSequencer:   action_sequencer,
-		ControllerManager: controller_manager,
-		DirectController:  direct_controller,
-		SyncController:    sync_controller,
-		ApiController:     api_controller,
-		CabDebugger:       cab_debugger,
-		Profiles:          map_utils.NewLockMap[string, config.Config_Controller_Profile](),
+		ActionSequencer:           action_sequencer,
+		ControllerManager:         controller_manager,
+		DirectController:          direct_controller,
+		SyncController:            sync_controller,
+		ApiController:             api_controller,
+		VirtualJoystickController: virtual_joystick_controller,
+		CabDebugger:               cab_debugger,
+		Profiles:                  map_utils.NewLockMap[string, config.Config_Controller_Profile](),
 		Settings: ProfileRunnerSettings{
 			Mutex:                  sync.RWMutex{},
 			SelectedProfilesByGUID: map_utils.NewLockMap[controller_mgr.JoystickGUIDString, ProfileRunnerSettings_SelectedProfile](),
+			DevicePoliciesByGUID:   map_utils.NewLockMap[controller_mgr.JoystickGUIDString, ProfileRunner_DevicePolicy](),
 			PreferredControlMode:   config.PreferredControlMode_DirectControl,
 		},
-		PreviousControlAssignmentCallList: map_utils.NewLockMap[string, *[]*ProfileRunnerAssignmentCall](),
+		AssignmentStates:         map_utils.NewLockMap[string, ProfileRunner_AssignmentState](),
+		ControlModeSelections:    map_utils.NewLockMap[string, ProfileRunner_ControlModeSelection](),
+		TransformStates:          map_utils.NewLockMap[string, *ProfileRunner_TransformState](),
+		GestureStates:            map_utils.NewLockMap[string, *ProfileRunner_GestureState](),
+		RateStates:               map_utils.NewLockMap[string, *ProfileRunner_RateState](),
+		FlickStates:              map_utils.NewLockMap[string, *ProfileRunner_FlickState](),
+		ActiveOutputs:            map_utils.NewLockMap[string, ProfileRunner_ActiveOutput](),
+		ProfileSelections:        map_utils.NewLockMap[controller_mgr.JoystickGUIDString, ProfileRunner_ProfileSelection](),
+		ProfileSelectionChannels: pubsub_utils.NewPubSubSlice[ProfileRunner_ProfileSelection](),
 	}
 }
 
 func (pc *ProfileRunnerAssignmentCall) ToString() string {
 	if pc.ActionSequencerAction != nil {
+		if pc.ActionSequencerAction.Mouse != nil {
+			return pc.ActionSequencerAction.Mouse.ToString()
+		}
 		return pc.ActionSequencerAction.Keys
 	}
 	if pc.DirectControlCommand != nil {
@@ -117,32 +144,13 @@ func (pc *ProfileRunnerAssignmentCall) ToString() string {
 	if pc.ApiControlCommand != nil {
 		return pc.ApiControlCommand.ToString()
 	}
-	return ""
-}
-
-func (p *ProfileRunner) getSelectedProfileForJoystick(joystick sdl_mgr.SDLMgr_Joystick) (ProfileRunnerSettings_SelectedProfile, bool) {
-	selected_profile, has_selected_profile := p.Settings.GetSelectedProfiles().Get(joystick.GUID)
-
-	/* try auto-selection */
-	current_rail_class := p.CabDebugger.State.DrivableActorName
-	if !has_selected_profile && current_rail_class != "" {
-		p.Profiles.ForEach(func(profile config.Config_Controller_Profile, id string) bool {
-			if profile.AutoSelect != nil && *profile.AutoSelect && profile.Controller != nil && *profile.Controller.UsbID == joystick.ToString() && profile.RailClassInformation != nil {
-				for _, rc_info := range *profile.RailClassInformation {
-					if *rc_info.ClassName == current_rail_class {
-						has_selected_profile = true
-						selected_profile = ProfileRunnerSettings_SelectedProfile{
-							Profile: profile,
-						}
-						return false
-					}
-				}
-			}
-			return true
-		})
+	if pc.SequenceAction != nil {
+		return pc.SequenceAction.Id
 	}
-
-	return selected_profile, has_selected_profile
+	if pc.VirtualJoystickCommand != nil {
+		return pc.VirtualJoystickCommand.ToString()
+	}
+	return ""
 }
 
 func (p *ProfileRunner) GetProfileNameToIdMap() map[string][]string {
@@ -158,6 +166,74 @@ func (p *ProfileRunner) GetProfileNameToIdMap() map[string][]string {
 	return id_map_by_name
 }
 
+/*
+finds a profile by its name and path; the profile ID changes when either changes so a profile renamed within
+the same file or a file moved elsewhere is still found as long as only one profile matches
+*/
+func (p *ProfileRunner) FindProfileByNameAndPath(name string, path string) (config.Config_Controller_Profile, bool) {
+	by_path := []config.Config_Controller_Profile{}
+	by_name := []config.Config_Controller_Profile{}
+	var exact_match *config.Config_Controller_Profile
+	p.Profiles.ForEach(func(profile config.Config_Controller_Profile, id string) bool {
+		if profile.Name == name && profile.Metadata.Path == path {
+			exact_match = &profile
+			return false
+		}
+		if profile.Metadata.Path == path {
+			by_path = append(by_path, profile)
+		}
+		if profile.Name == name {
+			by_name = append(by_name, profile)
+		}
+		return true
+	})
+
+	if exact_match != nil {
+		return *exact_match, true
+	}
+	if len(by_path) == 1 {
+		return by_path[0], true
+	}
+	if len(by_name) == 1 {
+		return by_name[0], true
+	}
+	return config.Config_Controller_Profile{}, false
+}
+
+/*
+replaces the selected profiles with their reloaded version after the profiles were reloaded
+selections of profiles which no longer exist are cleared; returns the GUIDs of all previously selected profiles
+*/
+func (p *ProfileRunner) RefreshSelectedProfiles() []controller_mgr.JoystickGUIDString {
+	guids := []controller_mgr.JoystickGUIDString{}
+	cleared_guids := []controller_mgr.JoystickGUIDString{}
+	p.Settings.Update(func(s *ProfileRunnerSettings) {
+		s.SelectedProfilesByGUID.Mutate(func(selected_profile ProfileRunnerSettings_SelectedProfile, guid controller_mgr.JoystickGUIDString) map_utils.LockMapMutateAction[controller_mgr.JoystickGUIDString, ProfileRunnerSettings_SelectedProfile] {
+			guids = append(guids, guid)
+			profile, has_profile := p.FindProfileByNameAndPath(selected_profile.Profile.Name, selected_profile.Profile.Metadata.Path)
+			if !has_profile {
+				cleared_guids = append(cleared_guids, guid)
+				return map_utils.LockMapMutateAction[controller_mgr.JoystickGUIDString, ProfileRunnerSettings_SelectedProfile]{
+					Action: map_utils.LockMapMutateActionType_Delete,
+					Key:    guid,
+				}
+			}
+			return map_utils.LockMapMutateAction[controller_mgr.JoystickGUIDString, ProfileRunnerSettings_SelectedProfile]{
+				Action: map_utils.LockMapMutateActionType_Replace,
+				Key:    guid,
+				Value:  ProfileRunnerSettings_SelectedProfile{Profile: profile},
+			}
+		})
+	})
+
+	for _, guid := range cleared_guids {
+		logger.Logger.Info("[ProfileRunner::RefreshSelectedProfiles] selected profile no longer exists", "guid", guid)
+		p.ApplySafeState(guid)
+		p.clearControlModeSelections(guid)
+	}
+	return guids
+}
+
 func (p *ProfileRunner) RegisterProfile(profile config.Config_Controller_Profile) {
 	p.Profiles.Set(profile.Id(), profile)
 }
@@ -197,6 +273,9 @@ func (p *ProfileRunner) Resolve() {
 				if profile.Controller == nil && extend_from_profile.Controller != nil {
 					profile.Controller = extend_from_profile.Controller
 				}
+				if profile.ControlModeFallback == nil && resolved_extend_from_profile.ControlModeFallback != nil {
+					profile.ControlModeFallback = resolved_extend_from_profile.ControlModeFallback
+				}
 			}
 		}
 		return profile
@@ -208,12 +287,64 @@ func (p *ProfileRunner) Resolve() {
 }
 
 func (p *ProfileRunner) ClearProfile(guid controller_mgr.JoystickGUIDString) {
+	/* whatever the joystick controlled should not stay held once its profile is gone */
+	p.ApplySafeState(guid)
 	p.Settings.Update(func(s *ProfileRunnerSettings) {
 		s.SelectedProfilesByGUID.Delete(guid)
 	})
+	p.clearControlModeSelections(guid)
+}
+
+/*
+moves the selected profiles of devices which received a new GUID (eg: after assigning an alias)
+a selection already stored for the new GUID is kept so the configuration follows the alias; devices may also swap GUIDs
+*/
+func (p *ProfileRunner) RenameJoysticks(renames []controller_mgr.ControllerManager_DeviceRename) {
+	for _, rename := range renames {
+		p.ApplySafeState(rename.From)
+		p.clearControlModeSelections(rename.From)
+	}
+	p.Settings.Update(func(s *ProfileRunnerSettings) {
+		previous_selections := map[controller_mgr.JoystickGUIDString]ProfileRunnerSettings_SelectedProfile{}
+		for _, rename := range renames {
+			if selected_profile, has_selected_profile := s.SelectedProfilesByGUID.Get(rename.From); has_selected_profile {
+				previous_selections[rename.From] = selected_profile
+				s.SelectedProfilesByGUID.Delete(rename.From)
+			}
+		}
+		for _, rename := range renames {
+			selected_profile, has_selected_profile := previous_selections[rename.From]
+			if _, has_new_selection := s.SelectedProfilesByGUID.Get(rename.To); has_selected_profile && !has_new_selection {
+				s.SelectedProfilesByGUID.Set(rename.To, selected_profile)
+			}
+		}
+
+		previous_policies := map[controller_mgr.JoystickGUIDString]ProfileRunner_DevicePolicy{}
+		for _, rename := range renames {
+			if policy, has_policy := s.DevicePoliciesByGUID.Get(rename.From); has_policy {
+				previous_policies[rename.From] = policy
+				s.DevicePoliciesByGUID.Delete(rename.From)
+			}
+		}
+		for _, rename := range renames {
+			policy, has_policy := previous_policies[rename.From]
+			if _, has_new_policy := s.DevicePoliciesByGUID.Get(rename.To); has_policy && !has_new_policy {
+				s.DevicePoliciesByGUID.Set(rename.To, policy)
+			}
+		}
+	})
+	for _, rename := range renames {
+		p.ProfileSelections.Delete(rename.From)
+	}
 }
 
 func (p *ProfileRunner) SetProfile(guid controller_mgr.JoystickGUIDString, id string) error {
+	if _, is_valid_profile := p.Profiles.Get(id); !is_valid_profile {
+		return fmt.Errorf("could not find profile by ID %s", id)
+	}
+
+	/* keys, buttons and controls held by the previous profile should not stay held */
+	p.ApplySafeState(guid)
 	var err error = nil
 	p.Settings.Update(func(s *ProfileRunnerSettings) {
 		profile, is_valid_profile := p.Profiles.Get(id)
@@ -225,6 +356,9 @@ func (p *ProfileRunner) SetProfile(guid controller_mgr.JoystickGUIDString, id st
 			err = fmt.Errorf("could not find profile by ID %s", id)
 		}
 	})
+	if err == nil {
+		p.clearControlModeSelections(guid)
+	}
 	return err
 }
 
@@ -235,63 +369,161 @@ func (p *ProfileRunner) SetPreferredControlMode(mode config.PreferredControlMode
 }
 
 func (p *ProfileRunner) CallAssignmentActionForControl(
+	guid controller_mgr.JoystickGUIDString,
 	control_name string,
 	assignment_index int,
 	control_state_at_call controller_mgr.ControllerManager_Controller_ControlState,
 	assignment config.Config_Controller_Profile_Control_Assignment,
 	action *ProfileRunnerAssignmentCall,
 ) error {
-	if action != nil {
-		logger.Logger.Info("[ProfileRunner::CallAssignmentActionForControl] executing assignment action", "sequencer_action", action.ActionSequencerAction, "direct_control_action", action.DirectControlCommand, "api_control_action", action.ApiControlCommand)
+	if action != nil && action.ActionSequencerAction != nil && action.ActionSequencerAction.Source == "" {
+		/* keys from the same control are sequenced in order; other controls are not held up by them */
+		sourced_sequencer_action := *action.ActionSequencerAction
+		sourced_sequencer_action.Source = joystickSource(guid, control_name)
+		sourced_action := *action
+		sourced_action.ActionSequencerAction = &sourced_sequencer_action
+		action = &sourced_action
 	}
-	previous_control_assignments_call_list, has_previous_control_call := p.PreviousControlAssignmentCallList.Get(control_name)
-	if !has_previous_control_call {
-		previous_control_assignments_call_list = &[]*ProfileRunnerAssignmentCall{}
-		p.PreviousControlAssignmentCallList.Set(control_name, previous_control_assignments_call_list)
+	if action != nil && action.VirtualJoystickCommand != nil && action.VirtualJoystickCommand.Source == "" {
+		/* virtual buttons are held per control like keys */
+		sourced_command := *action.VirtualJoystickCommand
+		sourced_command.Source = joystickSource(guid, control_name)
+		sourced_action := *action
+		sourced_action.VirtualJoystickCommand = &sourced_command
+		action = &sourced_action
 	}
-	for len(*previous_control_assignments_call_list) <= assignment_index {
-		*previous_control_assignments_call_list = append(*previous_control_assignments_call_list, nil)
+	if action != nil {
+		logger.Logger.Info("[ProfileRunner::CallAssignmentActionForControl] executing assignment action", "sequencer_action", action.ActionSequencerAction, "direct_control_action", action.DirectControlCommand, "api_control_action", action.ApiControlCommand, "sequence_action", action.SequenceAction, "virtual_joystick_action", action.VirtualJoystickCommand)
 	}
-
-	if action == nil && (*previous_control_assignments_call_list)[assignment_index] == nil {
+	previous_assignment_call := p.getPreviousAssignmentCall(guid, control_name, assignment_index)
+	if action == nil && previous_assignment_call == nil {
 		/* no action and no previous call - don't do anything */
 		return fmt.Errorf("no action or previous call list entry")
 	}
 
-	/* add updated call entry in previous assignment call list */
+	/* store the call as the previous call of the assignment for this joystick */
 	assignment_call := &ProfileRunnerAssignmentCall{
-		ControlState:          control_state_at_call,
-		ActionSequencerAction: nil,
-		DirectControlCommand:  nil,
-		ApiControlCommand:     nil,
+		ControlState:           control_state_at_call,
+		ActionSequencerAction:  nil,
+		DirectControlCommand:   nil,
+		ApiControlCommand:      nil,
+		SequenceAction:         nil,
+		VirtualJoystickCommand: nil,
 	}
 	if action != nil {
 		assignment_call.ActionSequencerAction = action.ActionSequencerAction
 		assignment_call.DirectControlCommand = action.DirectControlCommand
 		assignment_call.ApiControlCommand = action.ApiControlCommand
+		assignment_call.SequenceAction = action.SequenceAction
+		assignment_call.VirtualJoystickCommand = action.VirtualJoystickCommand
 	} else {
 		/* should always be available - None action should only be set as none for deactivation calls */
-		assignment_call.ActionSequencerAction = (*previous_control_assignments_call_list)[assignment_index].ActionSequencerAction
-		assignment_call.DirectControlCommand = (*previous_control_assignments_call_list)[assignment_index].DirectControlCommand
-		assignment_call.ApiControlCommand = (*previous_control_assignments_call_list)[assignment_index].ApiControlCommand
+		assignment_call.ActionSequencerAction = previous_assignment_call.ActionSequencerAction
+		assignment_call.DirectControlCommand = previous_assignment_call.DirectControlCommand
+		assignment_call.ApiControlCommand = previous_assignment_call.ApiControlCommand
+		assignment_call.SequenceAction = previous_assignment_call.SequenceAction
+		assignment_call.VirtualJoystickCommand = previous_assignment_call.VirtualJoystickCommand
 	}
-	(*previous_control_assignments_call_list)[assignment_index] = assignment_call
+	p.setPreviousAssignmentCall(guid, control_name, assignment_index, assignment_call)
 
 	if action != nil {
-		if action.ActionSequencerAction != nil {
-			logger.Logger.Debug("[ProfileRunner::CallAssignmentActionForControl] queueing sequencer action", "action", action.ActionSequencerAction)
-			p.ActionSequencer.Enqueue(*action.ActionSequencerAction)
-		} else if action.DirectControlCommand != nil {
-			logger.Logger.Debug("[ProfileRunner::CallAssignmentActionForControl] sending direct control command", "command", action.DirectControlCommand)
-			chan_utils.SendTimeout(p.DirectController.ControlChannel, time.Second, *action.DirectControlCommand)
-		} else if action.ApiControlCommand != nil {
-			logger.Logger.Debug("[ProfileRunner::CallAssignmentActionForControl] sending api control command", "command", action.ApiControlCommand)
-			chan_utils.SendTimeout(p.ApiController.ControlChannel, time.Second, *action.ApiControlCommand)
+		p.recordActiveOutput(guid, assignment, action)
+		if action.SequenceAction != nil {
+			/* scope the sequence to the control and assignment so releasing the control only cancels its own sequence */
+			scoped_sequence := *action.SequenceAction
+			scoped_sequence.Id = scopedSequenceId(guid, control_name, assignment_index, action.SequenceAction.Id)
+			scoped_action := *action
+			scoped_action.SequenceAction = &scoped_sequence
+			p.dispatchAssignmentCall(&scoped_action)
+		} else {
+			p.dispatchAssignmentCall(action)
 		}
 	}
 	return nil
 }
 
+/*
+Performs the action and releases it right away if it holds keys or buttons; used by assignments without a held state
+*/
+func (p *ProfileRunner) tapAssignmentAction(
+	guid controller_mgr.JoystickGUIDString,
+	control_name string,
+	assignment_index int,
+	control_state_at_call controller_mgr.ControllerManager_Controller_ControlState,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	action config.Config_Controller_Profile_Control_Assignment_Action,
+) {
+	action_to_call := p.AssignmentActionToAssignmentCall(control_state_at_call, action, false)
+	p.CallAssignmentActionForControl(guid, control_name, assignment_index, control_state_at_call, assignment, action_to_call)
+	/* sequences run to completion */
+	if action.Sequence == nil && action.IsReleasable() {
+		action_to_call := p.AssignmentActionToAssignmentCall(control_state_at_call, action, true)
+		p.CallAssignmentActionForControl(guid, control_name, assignment_index, control_state_at_call, assignment, action_to_call)
+	}
+}
+
+func scopedSequenceId(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int, id string) string {
+	return joystickSource(guid, fmt.Sprintf("%s:%d:%s", control_name, assignment_index, id))
+}
+
+/* the scope of the sequences of a linear threshold; identical sequences on other thresholds must not cancel each other */
+func thresholdSequenceScope(threshold_index int) string {
+	return fmt.Sprintf("threshold%d", threshold_index)
+}
+
+func sequenceIdForScope(scope string, id string) string {
+	if scope == "" {
+		return id
+	}
+	return fmt.Sprintf("%s:%s", scope, id)
+}
+
+/*
+Scopes the sequence of the call to a part of the assignment (eg: a linear threshold); other calls are returned as is
+*/
+func scopeAssignmentSequence(action *ProfileRunnerAssignmentCall, scope string) *ProfileRunnerAssignmentCall {
+	if action == nil || action.SequenceAction == nil {
+		return action
+	}
+	scoped_sequence := *action.SequenceAction
+	scoped_sequence.Id = sequenceIdForScope(scope, scoped_sequence.Id)
+	scoped_action := *action
+	scoped_action.SequenceAction = &scoped_sequence
+	return &scoped_action
+}
+
+/*
+Cancels a running sequence started by the given action (if it is a sequence which should be cancelled on release); the
+scope is the one the sequence was started with, empty when it was not scoped
+*/
+func (p *ProfileRunner) cancelAssignmentSequence(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int, scope string, action config.Config_Controller_Profile_Control_Assignment_Action) {
+	if action.Sequence != nil && action.Sequence.ShouldCancelOnRelease() {
+		p.ActionSequencer.CancelSequence(scopedSequenceId(guid, control_name, assignment_index, sequenceIdForScope(scope, action.ToString())))
+	}
+}
+
+/*
+Sends the assignment call to the appropriate controller or sequencer
+*/
+func (p *ProfileRunner) dispatchAssignmentCall(action *ProfileRunnerAssignmentCall) {
+	if action.ActionSequencerAction != nil {
+		logger.Logger.Debug("[ProfileRunner::dispatchAssignmentCall] queueing sequencer action", "action", action.ActionSequencerAction)
+		p.ActionSequencer.Enqueue(*action.ActionSequencerAction)
+	} else if action.DirectControlCommand != nil {
+		logger.Logger.Debug("[ProfileRunner::dispatchAssignmentCall] sending direct control command", "command", action.DirectControlCommand)
+		chan_utils.SendTimeout(p.DirectController.ControlChannel, time.Second, *action.DirectControlCommand)
+	} else if action.ApiControlCommand != nil {
+		logger.Logger.Debug("[ProfileRunner::dispatchAssignmentCall] sending api control command", "command", action.ApiControlCommand)
+		chan_utils.SendTimeout(p.ApiController.ControlChannel, time.Second, *action.ApiControlCommand)
+	} else if action.SequenceAction != nil {
+		logger.Logger.Debug("[ProfileRunner::dispatchAssignmentCall] running sequence action", "id", action.SequenceAction.Id, "cancel", action.SequenceAction.Cancel)
+		p.ActionSequencer.RunSequence(*action.SequenceAction)
+	} else if action.VirtualJoystickCommand != nil {
+		logger.Logger.Debug("[ProfileRunner::dispatchAssignmentCall] sending virtual joystick command", "command", action.VirtualJoystickCommand)
+		chan_utils.SendTimeout(p.VirtualJoystickController.ControlChannel, time.Second, *action.VirtualJoystickCommand)
+	}
+}
+
 func (p *ProfileRunner) AssignmentKeysActionToSequencerAction(keys_action config.Config_Controller_Profile_Control_Assignment_Action_Keys, release bool) action_sequencer.ActionSequencerAction {
 	var press_time_value float64 = 0
 	var wait_time_value float64 = 0
@@ -310,6 +542,49 @@ func (p *ProfileRunner) AssignmentKeysActionToSequencerAction(keys_action config
 	}
 }
 
+/*
+Converts a mouse action to a sequencer action; returns nil when releasing an action which doesn't hold a button
+*/
+func (p *ProfileRunner) AssignmentMouseActionToSequencerAction(mouse_action config.Config_Controller_Profile_Control_Assignment_Action_Mouse, release bool) *action_sequencer.ActionSequencerAction {
+	params := mouse_action.Mouse
+	if release && params.Button == nil {
+		/* nothing is held; moving or scrolling can't be released */
+		return nil
+	}
+
+	sequencer_mouse_action := action_sequencer.ActionSequencerMouseAction{}
+	if params.MoveX != nil {
+		sequencer_mouse_action.MoveX = *params.MoveX
+	}
+	if params.MoveY != nil {
+		sequencer_mouse_action.MoveY = *params.MoveY
+	}
+	if params.Absolute != nil {
+		sequencer_mouse_action.Absolute = *params.Absolute
+	}
+	if params.Button != nil {
+		sequencer_mouse_action.Button = *params.Button
+	}
+	if params.ScrollX != nil {
+		sequencer_mouse_action.ScrollX = *params.ScrollX
+	}
+	if params.ScrollY != nil {
+		sequencer_mouse_action.ScrollY = *params.ScrollY
+	}
+
+	sequencer_action := action_sequencer.ActionSequencerAction{
+		Mouse:   &sequencer_mouse_action,
+		Release: release,
+	}
+	if mouse_action.PressTime != nil {
+		sequencer_action.PressTime = *mouse_action.PressTime
+	}
+	if mouse_action.WaitTime != nil {
+		sequencer_action.WaitTime = *mouse_action.WaitTime
+	}
+	return &sequencer_action
+}
+
 func (p *ProfileRunner) AssignmentActionToAssignmentCall(
 	control_state controller_mgr.ControllerManager_Controller_ControlState,
 	action config.Config_Controller_Profile_Control_Assignment_Action,
@@ -358,13 +633,87 @@ func (p *ProfileRunner) AssignmentActionToAssignmentCall(
 			},
 		}
 	}
+	if action.Mouse != nil {
+		sequencer_action := p.AssignmentMouseActionToSequencerAction(*action.Mouse, release_if_keys)
+		if sequencer_action == nil {
+			return nil
+		}
+		return &ProfileRunnerAssignmentCall{
+			ControlState:          control_state,
+			ActionSequencerAction: sequencer_action,
+		}
+	}
+	if action.VirtualJoystick != nil {
+		command := VirtualJoystickController_Command{
+			Axis:   action.VirtualJoystick.Axis,
+			Button: action.VirtualJoystick.Button,
+		}
+		if command.Axis != nil {
+			if release_if_keys {
+				/* axes can't be released; they keep their last value */
+				return nil
+			}
+			command.Value = *action.VirtualJoystick.Value
+		} else if !release_if_keys {
+			command.Value = 1
+		}
+		return &ProfileRunnerAssignmentCall{
+			ControlState:           control_state,
+			VirtualJoystickCommand: &command,
+		}
+	}
+	if action.Sequence != nil {
+		sequence := action_sequencer.ActionSequencerSequence{
+			Id:     action.ToString(),
+			Steps:  []action_sequencer.ActionSequencerSequence_Step{},
+			Cancel: false,
+		}
+		if release_if_keys {
+			if !action.Sequence.ShouldCancelOnRelease() {
+				/* nothing to release; let the sequence finish */
+				return nil
+			}
+			sequence.Cancel = true
+		} else {
+			for _, step := range action.Sequence.Sequence {
+				sequence_step := action_sequencer.ActionSequencerSequence_Step{}
+				if step.Delay != nil {
+					sequence_step.Delay = *step.Delay
+				}
+				if step.Action != nil {
+					if step_call := p.AssignmentActionToAssignmentCall(control_state, *step.Action, false); step_call != nil {
+						if step_call.ActionSequencerAction != nil {
+							sequence_step.Action = step_call.ActionSequencerAction
+						} else {
+							sequence_step.Callback = func() {
+								p.dispatchAssignmentCall(step_call)
+							}
+						}
+					}
+				}
+				sequence.Steps = append(sequence.Steps, sequence_step)
+			}
+		}
+		return &ProfileRunnerAssignmentCall{
+			ControlState:          control_state,
+			ActionSequencerAction: nil,
+			DirectControlCommand:  nil,
+			ApiControlCommand:     nil,
+			SequenceAction:        &sequence,
+		}
+	}
 	return nil
 }
 
+/*
+Returns the assignments to execute for a control and the control mode they were selected for.
+Conditional assignments are filtered out and only the assignments of the first available control mode in the fallback chain are kept
+*/
 func (p *ProfileRunner) GetAssignments(
+	profile *config.Config_Controller_Profile,
 	control *config.Config_Controller_Profile_Control,
 	source_event *controller_mgr.ControllerManager_Control_ChangeEvent,
-) []config.Config_Controller_Profile_Control_Assignment {
+) ([]config.Config_Controller_Profile_Control_Assignment, config.PreferredControlMode) {
 	var assignments []config.Config_Controller_Profile_Control_Assignment
 	if control.Assignment != nil {
 		assignments = append(assignments, *control.Assignment)
@@ -374,13 +723,8 @@ func (p *ProfileRunner) GetAssignments(
 	}
 
 	/* filter out conditional assignments */
-	preferred_control_mode := p.Settings.GetPreferredControlMode()
 	non_control_asssignments := []config.Config_Controller_Profile_Control_Assignment{}
-	scored_control_assignments := map[config.PreferredControlMode]*ProfileRunner_ScoredAssignmentsListEntry{}
-	scored_control_assignments[config.PreferredControlMode_DirectControl] = &ProfileRunner_ScoredAssignmentsListEntry{Score: 3, Assignments: []config.Config_Controller_Profile_Control_Assignment{}}
-	scored_control_assignments[config.PreferredControlMode_ApiControl] = &ProfileRunner_ScoredAssignmentsListEntry{Score: 2, Assignments: []config.Config_Controller_Profile_Control_Assignment{}}
-	scored_control_assignments[config.PreferredControlMode_SyncControl] = &ProfileRunner_ScoredAssignmentsListEntry{Score: 1, Assignments: []config.Config_Controller_Profile_Control_Assignment{}}
-	scored_control_assignments[preferred_control_mode].Score = scored_control_assignments[preferred_control_mode].Score + 10
+	control_assignments := map[config.PreferredControlMode][]config.Config_Controller_Profile_Control_Assignment{}
 
 check_assignments_loop:
 	for _, assignment := range assignments {
@@ -416,58 +760,266 @@ check_assignments_loop:
 				}
 
 				/* evaluate condition operator */
-				if has_condition_value {
-					switch condition.Operator {
-					case "gte":
-						if condition_value < condition.Value {
-							/* condition doesn't match -> skip */
-							continue check_assignments_loop
-						}
-					case "lte":
-						if condition_value > condition.Value {
-							/* condition doesn't match -> skip */
-							continue check_assignments_loop
-						}
-					case "gt":
-						if condition_value <= condition.Value {
-							/* condition doesn't match -> skip */
-							continue check_assignments_loop
-						}
-					case "lt":
-						if condition_value >= condition.Value {
-							/* condition doesn't match -> skip */
-							continue check_assignments_loop
-						}
-					}
+				if has_condition_value && !condition.Matches(condition_value) {
+					/* condition doesn't match -> skip */
+					continue check_assignments_loop
 				}
 			}
 		}
 
-		if assignment.DirectControl != nil {
-			scored_control_assignments[config.PreferredControlMode_DirectControl].Assignments = append(scored_control_assignments[config.PreferredControlMode_DirectControl].Assignments, assignment)
-		} else if assignment.SyncControl != nil {
-			scored_control_assignments[config.PreferredControlMode_SyncControl].Assignments = append(scored_control_assignments[config.PreferredControlMode_SyncControl].Assignments, assignment)
-		} else if assignment.ApiControl != nil {
-			scored_control_assignments[config.PreferredControlMode_ApiControl].Assignments = append(scored_control_assignments[config.PreferredControlMode_ApiControl].Assignments, assignment)
+		if assignment_control_mode, has_control_mode := AssignmentControlMode(assignment); has_control_mode {
+			control_assignments[assignment_control_mode] = append(control_assignments[assignment_control_mode], assignment)
 		} else {
 			non_control_asssignments = append(non_control_asssignments, assignment)
 		}
 	}
 
-	scored_control_assignments_values_list := []*ProfileRunner_ScoredAssignmentsListEntry{}
-	for _, entry := range scored_control_assignments {
-		if len(entry.Assignments) > 0 {
-			scored_control_assignments_values_list = append(scored_control_assignments_values_list, entry)
+	modes_with_assignments := []config.PreferredControlMode{}
+	for mode := range control_assignments {
+		modes_with_assignments = append(modes_with_assignments, mode)
+	}
+	control_mode := SelectControlMode(
+		ControlModeFallback(profile, control, p.Settings.GetPreferredControlMode()),
+		p.GetControlModeAvailability(),
+		modes_with_assignments,
+	)
+	return append(control_assignments[control_mode], non_control_asssignments...), control_mode
+}
+
+func (p *ProfileRunner) findSyncControlAssignment(
+	profile *config.Config_Controller_Profile,
+	sync_control_state SyncController_ControlState,
+	include_unselected bool,
+) *config.Config_Controller_Profile_Control_Assignment {
+	for _, cp := range profile.Controls {
+		var assignments []config.Config_Controller_Profile_Control_Assignment
+		if include_unselected {
+			if cp.Assignment != nil {
+				assignments = append(assignments, *cp.Assignment)
+			} else if cp.Assignments != nil {
+				assignments = append(assignments, *cp.Assignments...)
+			}
+		} else {
+			assignments, _ = p.GetAssignments(profile, &cp, sync_control_state.SourceEvent)
+		}
+		for _, assignment := range assignments {
+			if assignment.SyncControl != nil && assignment.SyncControl.Identifier == sync_control_state.Identifier {
+				return &assignment
+			}
+			/* the outputs of combined assignments can be sync controls as well */
+			if assignment.Combined != nil {
+				for _, zone := range assignment.Combined.Zones() {
+					output := zone.Zone.Output
+					if output.SyncControl != nil && output.SyncControl.Identifier == sync_control_state.Identifier {
+						return &output
+					}
+				}
+			}
 		}
 	}
-	sort.Slice(scored_control_assignments_values_list, func(i, j int) bool {
-		return scored_control_assignments_values_list[i].Score > scored_control_assignments_values_list[j].Score
+	return nil
+}
+
+func (p *ProfileRunner) recordControlModeSelection(
+	guid controller_mgr.JoystickGUIDString,
+	profile *config.Config_Controller_Profile,
+	control *config.Config_Controller_Profile_Control,
+	control_mode config.PreferredControlMode,
+) {
+	p.ControlModeSelections.Set(fmt.Sprintf("%s:%s", guid, control.Name), ProfileRunner_ControlModeSelection{
+		GUID:        guid,
+		ControlName: control.Name,
+		ControlMode: control_mode,
+		Fallback:    ControlModeFallback(profile, control, p.Settings.GetPreferredControlMode()),
+		UpdatedAt:   time.Now(),
 	})
-	if len(scored_control_assignments_values_list) > 0 {
-		return append(scored_control_assignments_values_list[0].Assignments, non_control_asssignments...)
+}
+
+/*
+Returns the control modes used for the last events of the controls of a joystick
+*/
+func (p *ProfileRunner) GetControlModeSelections(guid controller_mgr.JoystickGUIDString) []ProfileRunner_ControlModeSelection {
+	selections := []ProfileRunner_ControlModeSelection{}
+	p.ControlModeSelections.ForEach(func(selection ProfileRunner_ControlModeSelection, key string) bool {
+		if selection.GUID == guid {
+			selections = append(selections, selection)
+		}
+		return true
+	})
+	sort.Slice(selections, func(i, j int) bool {
+		return selections[i].ControlName < selections[j].ControlName
+	})
+	return selections
+}
+
+func (p *ProfileRunner) clearControlModeSelections(guid controller_mgr.JoystickGUIDString) {
+	p.ControlModeSelections.Mutate(func(selection ProfileRunner_ControlModeSelection, key string) map_utils.LockMapMutateAction[string, ProfileRunner_ControlModeSelection] {
+		if selection.GUID == guid {
+			return map_utils.LockMapMutateAction[string, ProfileRunner_ControlModeSelection]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, ProfileRunner_ControlModeSelection]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
+
+/*
+Executes the assignments of the changed control using the profile selected for the joystick
+*/
+func (p *ProfileRunner) handleChangeEvent(change_event *controller_mgr.ControllerManager_Control_ChangeEvent) {
+	logger.Logger.Debug("[ProfileRunner::handleChangeEvent] received change event", "event", change_event)
+
+	selected_profile, selection, has_selected_profile := p.resolveProfileForJoystick(*change_event.Joystick)
+	p.recordProfileSelection(selection)
+	if !has_selected_profile {
+		logger.Logger.Debug("[ProfileRunner::handleChangeEvent] skipping event, no profile selected", "event", change_event)
+		return
+	}
+
+	control_name := change_event.ControlName
+	if selected_profile.Profile.Controller != nil && selected_profile.Profile.Controller.Mapping != nil {
+		root_mapping := change_event.Control.SDLMapping
+		override_mapping := selected_profile.Profile.Controller.Mapping
+		override_control, find_override_control_err := override_mapping.FindByKindIndexAndDirection(root_mapping.Kind, root_mapping.Index, root_mapping.HatDirection())
+		if find_override_control_err == nil {
+			control_name = override_control.Name
+		}
+	}
+	/* the control can be a member of a chord without having assignments of its own */
+	defer p.executeChordMemberAssignments(control_name, change_event, time.Now())
+
+	control_profile := selected_profile.Profile.FindControlByName(control_name)
+	if control_profile == nil {
+		logger.Logger.Debug("[ProfileRunner::handleChangeEvent] skipping event, control not found in profile", "event", change_event)
+		return
+	}
+
+	assignments, control_mode := p.GetAssignments(&selected_profile.Profile, control_profile, change_event)
+	p.recordControlModeSelection(change_event.Joystick.GUID, &selected_profile.Profile, control_profile, control_mode)
+	for assignment_index, control_assignment_item := range assignments {
+		logger.Logger.Debug("[ProfileRunner::handleChangeEvent] executing assignment", "assignment", control_assignment_item)
+		previous_assignment_call := p.getPreviousAssignmentCall(change_event.Joystick.GUID, control_name, assignment_index)
+
+		if control_assignment_item.Momentary != nil {
+			if change_event.ControlState.NormalizedValues.Value >= control_assignment_item.Momentary.Threshold {
+				// call if there was no prior call or if the prior call was not this threshold
+				should_call_activation := previous_assignment_call == nil || previous_assignment_call.ControlState.NormalizedValues.Value < control_assignment_item.Momentary.Threshold
+				if should_call_activation {
+					action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Momentary.ActionActivate, false)
+					p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+				}
+			} else if previous_assignment_call != nil && previous_assignment_call.ControlState.NormalizedValues.Value >= control_assignment_item.Momentary.Threshold {
+				// when below the threshold only call action if the last call was above or equal to the threshold
+				if control_assignment_item.Momentary.ActionDeactivate != nil {
+					p.cancelAssignmentSequence(change_event.Joystick.GUID, control_name, assignment_index, "", control_assignment_item.Momentary.ActionActivate)
+					action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, *control_assignment_item.Momentary.ActionDeactivate, false)
+					p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+				} else if control_assignment_item.Momentary.ActionActivate.IsReleasable() {
+					/* only release if keys, sequences (cancels the sequence) or virtual buttons -> can't "release" direct control actions */
+					action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Momentary.ActionActivate, true)
+					p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+				} else {
+					/* clear previuous call so momentary can be re-triggered */
+					p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, nil)
+				}
+			}
+		}
+		if control_assignment_item.Linear != nil {
+			linear, missing_detents := control_assignment_item.Linear.ResolveDetents(change_event.Control.Calibration.NormalizedDetents())
+			if len(missing_detents) > 0 {
+				logger.Logger.Debug("[ProfileRunner::handleChangeEvent] skipping thresholds referencing detents which are not calibrated", "control", control_name, "detents", missing_detents)
+			}
+			initial_state_value := linear.CalculateNeutralizedValue(change_event.ControlState.NormalizedValues.InitialValue)
+			control_state_value := linear.CalculateNeutralizedValue(change_event.ControlState.NormalizedValues.Value)
+			/* the thresholds are tracked by their index in the generated thresholds */
+			thresholds := linear.GenerateThresholds()
+			var thresholds_currently_exceeding []int
+			var thresholds_previously_passed []int
+			for threshold_index, threshold := range thresholds {
+				if threshold.IsExceedingThreshold(control_state_value) {
+					thresholds_currently_exceeding = append(thresholds_currently_exceeding, threshold_index)
+				}
+				/* threshold was previously passed if the last assignment call was exceeding the threshold OR if there was no last call if the initial value exceeded it*/
+				if previous_assignment_call != nil && threshold.IsExceedingThreshold(
+					linear.CalculateNeutralizedValue(previous_assignment_call.ControlState.NormalizedValues.Value),
+				) || previous_assignment_call == nil && threshold.IsExceedingThreshold(initial_state_value) {
+					thresholds_previously_passed = append(thresholds_previously_passed, threshold_index)
+				}
+			}
+
+			if len(thresholds_currently_exceeding) > len(thresholds_previously_passed) {
+				// activate the intermediate thresholds
+				thresholds_to_activate := thresholds_currently_exceeding[len(thresholds_previously_passed):]
+				for _, threshold_index := range thresholds_to_activate {
+					threshold := thresholds[threshold_index]
+					action_to_call := scopeAssignmentSequence(p.AssignmentActionToAssignmentCall(change_event.ControlState, threshold.ActionActivate, false), thresholdSequenceScope(threshold_index))
+					p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+				}
+			} else if len(thresholds_currently_exceeding) < len(thresholds_previously_passed) {
+				// deactivate the intermediate thresholds by iterating from end of previously passed up until but not including the currently exceeding threshold
+				for i := len(thresholds_previously_passed) - 1; i > len(thresholds_currently_exceeding)-1; i-- {
+					threshold_index := thresholds_previously_passed[i]
+					threshold := thresholds[threshold_index]
+					if threshold.ActionDeactivate != nil {
+						p.cancelAssignmentSequence(change_event.Joystick.GUID, control_name, assignment_index, thresholdSequenceScope(threshold_index), threshold.ActionActivate)
+						action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, *threshold.ActionDeactivate, false)
+						p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+					} else if threshold.ActionActivate.IsReleasable() {
+						/* only release if keys, sequences (cancels the sequence) or virtual buttons -> can't "release" direct control actions */
+						action_to_call := scopeAssignmentSequence(p.AssignmentActionToAssignmentCall(change_event.ControlState, threshold.ActionActivate, true), thresholdSequenceScope(threshold_index))
+						p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+					} else {
+						/* clear previuous call so threshold can be re-triggered */
+						p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, nil)
+					}
+				}
+			}
+		}
+		if control_assignment_item.Toggle != nil {
+			if change_event.ControlState.NormalizedValues.Value >= control_assignment_item.Toggle.Threshold {
+				// call if there was no prior call or if the prior call was not this threshold
+				action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Toggle.ActionActivate, false)
+				if previous_assignment_call != nil && previous_assignment_call.ToString() == action_to_call.ToString() {
+					/* if the previous call is the same as the activation call -> toggle to deactivation action */
+					action_to_call = p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Toggle.ActionDeactivate, false)
+				}
+				p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
+			} else if previous_assignment_call != nil && previous_assignment_call.ControlState.NormalizedValues.Value >= control_assignment_item.Toggle.Threshold && previous_assignment_call.ActionSequencerAction != nil {
+				// when below the threshold only call action if the last call was above or equal to the threshold
+				// this is only used for releasing key (and mouse button) actions
+				release_action := *previous_assignment_call.ActionSequencerAction
+				release_action.Release = true
+				p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, control_assignment_item, &ProfileRunnerAssignmentCall{
+					ControlState:          change_event.ControlState,
+					ActionSequencerAction: &release_action,
+					ApiControlCommand:     nil,
+					DirectControlCommand:  nil,
+				})
+			}
+		}
+		if control_assignment_item.Flick != nil {
+			p.executeFlickAssignment(control_name, assignment_index, change_event, control_assignment_item)
+		}
+		if control_assignment_item.LongPress != nil || control_assignment_item.MultiTap != nil || control_assignment_item.Chord != nil {
+			p.executeGestureAssignment(control_name, assignment_index, change_event, control_assignment_item, time.Now())
+		}
+		if control_assignment_item.Rate != nil {
+			p.executeRateAssignment(control_name, assignment_index, change_event, control_assignment_item, time.Now())
+		}
+		if control_assignment_item.GetInputValue() != nil {
+			p.executeInputValueAssignment(control_name, assignment_index, "", change_event, control_assignment_item, change_event.Control.State.NormalizedValues.Value, time.Now())
+		}
+		if control_assignment_item.Combined != nil {
+			p.executeCombinedAssignment(control_name, assignment_index, change_event, control_assignment_item.Combined, time.Now())
+		}
 	}
+}
 
-	return non_control_asssignments
+/*
+Re-evaluates the gestures, flicks and rate assignments which depend on the time passed
+*/
+func (p *ProfileRunner) tick(now time.Time) {
+	p.settleGestures(now)
+	p.settleFlicks(now)
+	p.settleRateAssignments(now)
 }
 
 func (p *ProfileRunner) Run(ctx context.Context) context.CancelFunc {
@@ -482,169 +1034,22 @@ func (p *ProfileRunner) Run(ctx context.Context) context.CancelFunc {
 	go func() {
 		channel, unsubscribe := p.ControllerManager.SubscribeChangeEvent()
 		defer unsubscribe()
+		ticker := time.NewTicker(RUNNER_TICK_INTERVAL)
+		defer ticker.Stop()
+		var transforms_settled_at time.Time
 
 		for {
 			select {
 			case <-context_with_cancel.Done():
 				return
-			case change_event := <-channel:
-				logger.Logger.Debug("[ProfileRunner::Run] received change event", "event", change_event)
-
-				selected_profile, has_selected_profile := p.getSelectedProfileForJoystick(*change_event.Joystick)
-				if !has_selected_profile {
-					logger.Logger.Debug("[ProfileRunner::Run] skipping event, no profile selected", "event", change_event)
-					continue
-				}
-
-				control_name := change_event.ControlName
-				if selected_profile.Profile.Controller != nil && selected_profile.Profile.Controller.Mapping != nil {
-					root_mapping := change_event.Control.SDLMapping
-					override_mapping := selected_profile.Profile.Controller.Mapping
-					override_control, find_override_control_err := override_mapping.FindByKindAndIndex(root_mapping.Kind, root_mapping.Index)
-					if find_override_control_err == nil {
-						control_name = override_control.Name
-					}
-				}
-
-				control_profile := selected_profile.Profile.FindControlByName(control_name)
-				if control_profile == nil {
-					logger.Logger.Debug("[ProfileRunner::Run] skipping event, control not found in profile", "event", change_event)
-					continue
-				}
-
-				assignments := p.GetAssignments(control_profile, &change_event)
-				previous_control_assignments_call_list, has_previous_control_assignments_call_list := p.PreviousControlAssignmentCallList.Get(control_name)
-				for assignment_index, control_assignment_item := range assignments {
-					logger.Logger.Debug("[ProfileRunner::Run] executing assignment", "assignment", control_assignment_item)
-					var previous_assignment_call *ProfileRunnerAssignmentCall = nil
-					if has_previous_control_assignments_call_list && len(*previous_control_assignments_call_list) > assignment_index {
-						previous_assignment_call = (*previous_control_assignments_call_list)[assignment_index]
-					}
-
-					if control_assignment_item.Momentary != nil {
-						if change_event.ControlState.NormalizedValues.Value >= control_assignment_item.Momentary.Threshold {
-							// call if there was no prior call or if the prior call was not this threshold
-							should_call_activation := previous_assignment_call == nil || previous_assignment_call.ControlState.NormalizedValues.Value < control_assignment_item.Momentary.Threshold
-							if should_call_activation {
-								action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Momentary.ActionActivate, false)
-								p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-							}
-						} else if previous_assignment_call != nil && previous_assignment_call.ControlState.NormalizedValues.Value >= control_assignment_item.Momentary.Threshold {
-							// when below the threshold only call action if the last call was above or equal to the threshold
-							if control_assignment_item.Momentary.ActionDeactivate != nil {
-								action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, *control_assignment_item.Momentary.ActionDeactivate, false)
-								p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-							} else if control_assignment_item.Momentary.ActionActivate.Keys != nil {
-								/* only release if keys -> can't "release" direct control actions */
-								action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Momentary.ActionActivate, true)
-								p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-							} else {
-								/* clear previuous call so momentary can be re-triggered */
-								p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, nil)
-							}
-						}
-					}
-					if control_assignment_item.Linear != nil {
-						initial_state_value := control_assignment_item.Linear.CalculateNeutralizedValue(change_event.ControlState.NormalizedValues.InitialValue)
-						control_state_value := control_assignment_item.Linear.CalculateNeutralizedValue(change_event.ControlState.NormalizedValues.Value)
-						var thresholds_currently_exceeding []config.Config_Controller_Profile_Control_Assignment_Linear_Threshold
-						var thresholds_previously_passed []config.Config_Controller_Profile_Control_Assignment_Linear_Threshold
-						for _, threshold := range control_assignment_item.Linear.GenerateThresholds() {
-							if threshold.IsExceedingThreshold(control_state_value) {
-								thresholds_currently_exceeding = append(thresholds_currently_exceeding, threshold)
-							}
-							/* threshold was previously passed if the last assignment call was exceeding the threshold OR if there was no last call if the initial value exceeded it*/
-							if previous_assignment_call != nil && threshold.IsExceedingThreshold(
-								control_assignment_item.Linear.CalculateNeutralizedValue(previous_assignment_call.ControlState.NormalizedValues.Value),
-							) || previous_assignment_call == nil && threshold.IsExceedingThreshold(initial_state_value) {
-								thresholds_previously_passed = append(thresholds_previously_passed, threshold)
-							}
-						}
-
-						if len(thresholds_currently_exceeding) > len(thresholds_previously_passed) {
-							// activate the intermediate thresholds
-							thresholds_to_activate := thresholds_currently_exceeding[len(thresholds_previously_passed):]
-							for _, threshold := range thresholds_to_activate {
-								action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, threshold.ActionActivate, false)
-								p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-							}
-						} else if len(thresholds_currently_exceeding) < len(thresholds_previously_passed) {
-							// deactivate the intermediate thresholds by iterating from end of previously passed up until but not including the currently exceeding threshold
-							for i := len(thresholds_previously_passed) - 1; i > len(thresholds_currently_exceeding)-1; i-- {
-								threshold := thresholds_previously_passed[i]
-								if threshold.ActionDeactivate != nil {
-									action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, *threshold.ActionDeactivate, false)
-									p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-								} else if threshold.ActionActivate.Keys != nil {
-									/* only release if keys -> can't "release" direct control actions */
-									action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, threshold.ActionActivate, true)
-									p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-								} else {
-									/* clear previuous call so threshold can be re-triggered */
-									p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, nil)
-								}
-							}
-						}
-					}
-					if control_assignment_item.Toggle != nil {
-						if change_event.ControlState.NormalizedValues.Value >= control_assignment_item.Toggle.Threshold {
-							// call if there was no prior call or if the prior call was not this threshold
-							action_to_call := p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Toggle.ActionActivate, false)
-							if previous_assignment_call != nil && previous_assignment_call.ToString() == action_to_call.ToString() {
-								/* if the previous call is the same as the activation call -> toggle to deactivation action */
-								action_to_call = p.AssignmentActionToAssignmentCall(change_event.ControlState, control_assignment_item.Toggle.ActionDeactivate, false)
-							}
-							p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, action_to_call)
-						} else if previous_assignment_call != nil && previous_assignment_call.ControlState.NormalizedValues.Value >= control_assignment_item.Toggle.Threshold && previous_assignment_call.ActionSequencerAction != nil {
-							// when below the threshold only call action if the last call was above or equal to the threshold
-							// this is only used for releasing key actions
-							p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, &ProfileRunnerAssignmentCall{
-								ControlState: change_event.ControlState,
-								ActionSequencerAction: &action_sequencer.ActionSequencerAction{
-									Keys:      previous_assignment_call.ActionSequencerAction.Keys,
-									PressTime: previous_assignment_call.ActionSequencerAction.PressTime,
-									WaitTime:  previous_assignment_call.ActionSequencerAction.WaitTime,
-									Release:   true,
-								},
-								ApiControlCommand:    nil,
-								DirectControlCommand: nil,
-							})
-						}
-					}
-					if control_assignment_item.DirectControl != nil {
-						output_value := control_assignment_item.DirectControl.InputValue.CalculateOutputValue(change_event.Control.State.NormalizedValues.Value)
-						flags := []string{}
-						if control_assignment_item.DirectControl.Hold != nil && *control_assignment_item.DirectControl.Hold {
-							flags = append(flags, "hold")
-						}
-						p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, &ProfileRunnerAssignmentCall{
-							ControlState:          change_event.ControlState,
-							ActionSequencerAction: nil,
-							ApiControlCommand:     nil,
-							DirectControlCommand: &DirectController_Command{
-								Controls:   control_assignment_item.DirectControl.Controls,
-								InputValue: output_value,
-								Flags:      flags,
-							},
-						})
-					}
-					if control_assignment_item.ApiControl != nil {
-						output_value := control_assignment_item.ApiControl.InputValue.CalculateOutputValue(change_event.Control.State.NormalizedValues.Value)
-						p.CallAssignmentActionForControl(control_name, assignment_index, change_event.ControlState, control_assignment_item, &ProfileRunnerAssignmentCall{
-							ControlState:          change_event.ControlState,
-							ActionSequencerAction: nil,
-							DirectControlCommand:  nil,
-							ApiControlCommand: &ApiController_Command{
-								Controls:   control_assignment_item.ApiControl.Controls,
-								InputValue: output_value,
-							},
-						})
-					}
-					if control_assignment_item.SyncControl != nil {
-						output_value := control_assignment_item.SyncControl.InputValue.CalculateOutputValue(change_event.Control.State.NormalizedValues.Value)
-						p.SyncController.UpdateControlStateTargetValue(control_assignment_item.SyncControl.Identifier, output_value, control_assignment_item.SyncControl, &change_event)
-					}
+			case now := <-ticker.C:
+				p.tick(now)
+				if now.Sub(transforms_settled_at) >= TRANSFORM_TICK_INTERVAL {
+					transforms_settled_at = now
+					p.settleTransformPipelines(now)
 				}
+			case change_event := <-channel:
+				p.handleChangeEvent(&change_event)
 			}
 		}
 	}()
@@ -659,72 +1064,67 @@ func (p *ProfileRunner) Run(ctx context.Context) context.CancelFunc {
 			case <-context_with_cancel.Done():
 				return
 			case sync_control_state := <-channel:
-				/* sync control only works when a profile is distinctly selected - also skip if not in sync control */
-				if sync_control_state.SourceEvent == nil || p.Settings.GetPreferredControlMode() != config.PreferredControlMode_SyncControl {
+				/* sync control only works when a profile is distinctly selected */
+				if sync_control_state.SourceEvent == nil {
 					continue
 				}
 
-				selected_profile, has_selected_profile := p.getSelectedProfileForJoystick(*sync_control_state.SourceEvent.Joystick)
-				if !has_selected_profile {
-					/* skip if no profile selected for controller */
-					continue
-				}
+				var sync_control_assignment *config.Config_Controller_Profile_Control_Assignment
+				if sync_control_state.SafeState && sync_control_state.ControlProfile != nil {
+					/* moving to the safe value; the profile is not necessarily selected anymore */
+					sync_control_assignment = &config.Config_Controller_Profile_Control_Assignment{SyncControl: sync_control_state.ControlProfile}
+				} else {
+					selected_profile, has_selected_profile := p.getSelectedProfileForJoystick(*sync_control_state.SourceEvent.Joystick)
+					if !has_selected_profile {
+						/* skip if no profile selected for controller */
+						continue
+					}
 
-				var sync_control_assignment *config.Config_Controller_Profile_Control_Assignment = nil
-			control_loop:
-				for _, cp := range selected_profile.Profile.Controls {
-					assignments := p.GetAssignments(&cp, sync_control_state.SourceEvent)
-					for _, assignment := range assignments {
-						if assignment.SyncControl != nil && assignment.SyncControl.Identifier == sync_control_state.Identifier {
-							sync_control_assignment = &assignment
-							break control_loop
-						}
+					/* only press if sync control is the selected control mode for the control; releasing is always allowed so no keys are left pressed after switching modes */
+					sync_control_assignment = p.findSyncControlAssignment(&selected_profile.Profile, sync_control_state, false)
+					if sync_control_assignment == nil && sync_control_state.Command.Release != 0 {
+						sync_control_assignment = p.findSyncControlAssignment(&selected_profile.Profile, sync_control_state, true)
+						sync_control_state.Command.Press = 0
 					}
 				}
-
-				/* only act if a sync control assignment exists for this identifier and is the current preferred control mode */
 				if sync_control_assignment == nil {
 					continue
 				}
+				if !p.SyncController.IsTargeting(sync_control_state.Identifier) {
+					/* stopped in the meantime (eg: by the safe state); don't press again */
+					sync_control_state.Command.Press = 0
+				}
 
-				const MARGIN_OF_ERROR = 0.005
-				should_stop_moving := (
-				/* was increasing and has now exceeded value */
-				sync_control_state.CurrentValue >= sync_control_state.TargetValue && sync_control_state.Moving == 1 ||
-					/* was decreasing and has now subceeded value */
-					sync_control_state.CurrentValue <= sync_control_state.TargetValue && sync_control_state.Moving == -1 ||
-					/* otherwise is within margin of error and was moving */
-					math.Abs(sync_control_state.CurrentValue-sync_control_state.TargetValue) < MARGIN_OF_ERROR && sync_control_state.Moving != 0)
-				should_start_increasing := sync_control_state.TargetValue > sync_control_state.CurrentValue && math.Abs(sync_control_state.TargetValue-sync_control_state.CurrentValue) > MARGIN_OF_ERROR && sync_control_state.Moving == 0
-				should_start_decreasing := sync_control_state.TargetValue < sync_control_state.CurrentValue && math.Abs(sync_control_state.TargetValue-sync_control_state.CurrentValue) > MARGIN_OF_ERROR && sync_control_state.Moving == 0
-
-				release_previous_action := func() {
-					if sync_control_state.Moving == -1 {
-						p.ActionSequencer.Enqueue(p.AssignmentKeysActionToSequencerAction(sync_control_assignment.SyncControl.ActionDecrease, true))
-					} else {
-						p.ActionSequencer.Enqueue(p.AssignmentKeysActionToSequencerAction(sync_control_assignment.SyncControl.ActionIncrease, true))
+				enqueue_keys_action := func(direction int, release bool, pulse bool) {
+					keys_action := sync_control_assignment.SyncControl.ActionIncrease
+					if direction == -1 {
+						keys_action = sync_control_assignment.SyncControl.ActionDecrease
+					}
+					sequencer_action := p.AssignmentKeysActionToSequencerAction(keys_action, release)
+					sequencer_action.Source = joystickSource(sync_control_state.SourceEvent.Joystick.GUID, fmt.Sprintf("sync_control:%s", sync_control_state.Identifier))
+					if pulse {
+						sequencer_action.PressTime = sync_control_state.Loop.Settings.PulseTime
 					}
+					p.ActionSequencer.Enqueue(sequencer_action)
 				}
 
-				if should_stop_moving {
-					release_previous_action()
-					p.SyncController.UpdateControlStateMoving(sync_control_state.Identifier, 0)
+				command := sync_control_state.Command
+				logger.Logger.Debug("[ProfileRunner::Run] executing sync control command", "identifier", sync_control_state.Identifier, "command", command, "current", sync_control_state.CurrentValue, "target", sync_control_state.TargetValue, "stuck", sync_control_state.Loop.Stuck)
+				if command.Release != 0 {
+					enqueue_keys_action(command.Release, true, false)
 				}
-
-				if should_start_increasing {
-					release_previous_action()
-					p.ActionSequencer.Enqueue(p.AssignmentKeysActionToSequencerAction(sync_control_assignment.SyncControl.ActionIncrease, false))
-					p.SyncController.UpdateControlStateMoving(sync_control_state.Identifier, 1)
+				if command.Press != 0 {
+					enqueue_keys_action(command.Press, false, command.Pulse)
 				}
-
-				if should_start_decreasing {
-					release_previous_action()
-					p.ActionSequencer.Enqueue(p.AssignmentKeysActionToSequencerAction(sync_control_assignment.SyncControl.ActionDecrease, false))
-					p.SyncController.UpdateControlStateMoving(sync_control_state.Identifier, -1)
+				if sync_control_state.Loop.Stuck && command.Release != 0 {
+					logger.Logger.Error("[ProfileRunner::Run] sync control is not moving; stopped pressing", "identifier", sync_control_state.Identifier)
 				}
 			}
 		}
 	}()
 
+	/* removed joysticks and lost connections */
+	go p.runSafeStateChecks(context_with_cancel)
+
 	return cancel
 }
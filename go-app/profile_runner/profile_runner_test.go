@@ -0,0 +1,113 @@
+package profile_runner
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"tsw_controller_app/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProfile(name string, path string) config.Config_Controller_Profile {
+	return config.Config_Controller_Profile{
+		Name:     name,
+		Metadata: config.Config_Controller_Profile_Metadata{Path: path},
+	}
+}
+
+func TestFindProfileByNameAndPath(t *testing.T) {
+	runner := New(nil, nil, nil, nil, nil, nil, nil)
+	runner.RegisterProfile(newTestProfile("Class 101", "/profiles/class101.json"))
+	runner.RegisterProfile(newTestProfile("Class 101 (Renamed)", "/profiles/renamed.json"))
+	runner.RegisterProfile(newTestProfile("Acela", "/profiles/moved/acela.json"))
+	runner.RegisterProfile(newTestProfile("Shared A", "/profiles/shared.json"))
+	runner.RegisterProfile(newTestProfile("Shared B", "/profiles/shared.json"))
+	runner.RegisterProfile(newTestProfile("Duplicate", "/profiles/duplicate1.json"))
+	runner.RegisterProfile(newTestProfile("Duplicate", "/profiles/duplicate2.json"))
+
+	profile, found := runner.FindProfileByNameAndPath("Class 101", "/profiles/class101.json")
+	assert.True(t, found)
+	assert.Equal(t, "/profiles/class101.json", profile.Metadata.Path)
+
+	/* renamed within the same file */
+	profile, found = runner.FindProfileByNameAndPath("Class 101", "/profiles/renamed.json")
+	assert.True(t, found)
+	assert.Equal(t, "Class 101 (Renamed)", profile.Name)
+
+	/* moved to another file */
+	profile, found = runner.FindProfileByNameAndPath("Acela", "/profiles/acela.json")
+	assert.True(t, found)
+	assert.Equal(t, "/profiles/moved/acela.json", profile.Metadata.Path)
+
+	/* ambiguous or deleted profiles are not guessed */
+	_, found = runner.FindProfileByNameAndPath("Shared C", "/profiles/shared.json")
+	assert.False(t, found)
+	_, found = runner.FindProfileByNameAndPath("Duplicate", "/profiles/duplicate3.json")
+	assert.False(t, found)
+	_, found = runner.FindProfileByNameAndPath("Deleted", "/profiles/deleted.json")
+	assert.False(t, found)
+}
+
+func TestRefreshSelectedProfiles(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestAssignmentStateRunner(t)
+	selected_profile, _ := runner.Settings.GetSelectedProfiles().Get(joystick_a.GUID)
+
+	/* the profile was renamed within its file */
+	renamed := selected_profile.Profile
+	renamed.Name = "Identical controllers (renamed)"
+	runner.Profiles.Clear()
+	runner.RegisterProfile(renamed)
+	guids := runner.RefreshSelectedProfiles()
+	assert.ElementsMatch(t, []string{joystick_a.GUID, joystick_b.GUID}, guids)
+
+	refreshed, has_refreshed := runner.Settings.GetSelectedProfiles().Get(joystick_a.GUID)
+	assert.True(t, has_refreshed)
+	assert.Equal(t, renamed.Id(), refreshed.Profile.Id())
+
+	runner.Profiles.Clear()
+	runner.RefreshSelectedProfiles()
+	_, has_selected_profile := runner.Settings.GetSelectedProfiles().Get(joystick_b.GUID)
+	assert.False(t, has_selected_profile)
+}
+
+const testSequenceThresholdsProfile = `{
+	"name": "Sequences",
+	"controls": [
+		{
+			"name": "Lever1",
+			"assignment": {
+				"type": "linear",
+				"thresholds": [
+					{ "value": 0.3, "action_activate": { "sequence": [{ "keys": "a", "press_time": 0.01 }, { "delay": 10 }] } },
+					{ "value": 0.6, "action_activate": { "sequence": [{ "keys": "a", "press_time": 0.01 }, { "delay": 10 }] } }
+				]
+			}
+		}
+	]
+}`
+
+func runningSequenceIds(runner *ProfileRunner) []string {
+	ids := []string{}
+	runner.ActionSequencer.Sequences.ForEach(func(_ context.CancelFunc, id string) bool {
+		ids = append(ids, id)
+		return true
+	})
+	sort.Strings(ids)
+	return ids
+}
+
+func TestLinearThresholdSequences_AreScopedToTheirThreshold(t *testing.T) {
+	runner, joystick_a, _ := newTestProfileRunner(t, testSequenceThresholdsProfile)
+
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0, 0.7))
+	ids := runningSequenceIds(runner)
+	require.Len(t, ids, 2)
+	assert.Contains(t, ids[0], ":threshold0:")
+	assert.Contains(t, ids[1], ":threshold1:")
+
+	/* leaving the second threshold only cancels the sequence of the second threshold */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0.7, 0.4))
+	assert.Equal(t, ids[:1], runningSequenceIds(runner))
+}
@@ -0,0 +1,113 @@
+package profile_runner
+
+import (
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/map_utils"
+)
+
+/*
+the repeat state of a rate assignment; only modified by the runner loop
+*/
+type ProfileRunner_RateState struct {
+	GUID            controller_mgr.JoystickGUIDString
+	ControlName     string
+	AssignmentIndex int
+	Assignment      config.Config_Controller_Profile_Control_Assignment
+	/* the last event of the control; the repeats are called with its control state */
+	ChangeEvent controller_mgr.ControllerManager_Control_ChangeEvent
+	/* 1 to increase, -1 to decrease and 0 while within the deadband */
+	Direction int
+	/* repeats per second */
+	Rate         float64
+	LastRepeatAt time.Time
+}
+
+func (s *ProfileRunner_RateState) period() time.Duration {
+	return time.Duration(float64(time.Second) / s.Rate)
+}
+
+/* the next repeat is due once a period of the current rate passed since the last repeat; there is none without a rate */
+func (s *ProfileRunner_RateState) isRepeatDue(now time.Time) bool {
+	if s.Direction == 0 || s.Rate <= 0 {
+		return false
+	}
+	return !now.Before(s.LastRepeatAt.Add(s.period()))
+}
+
+/*
+Updates the direction and rate of the assignment from the change event; entering a direction performs its action right away,
+changing the deflection only changes when the next repeat is due
+*/
+func (p *ProfileRunner) executeRateAssignment(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	now time.Time,
+) {
+	key := assignmentStateKey(change_event.Joystick.GUID, control_name, assignment_index)
+	state, has_state := p.RateStates.Get(key)
+	if !has_state || state.Assignment.Rate != assignment.Rate {
+		state = &ProfileRunner_RateState{
+			GUID:            change_event.Joystick.GUID,
+			ControlName:     control_name,
+			AssignmentIndex: assignment_index,
+			Assignment:      assignment,
+		}
+		p.RateStates.Set(key, state)
+	}
+	state.ChangeEvent = *change_event
+
+	direction, rate := assignment.Rate.CalculateRate(change_event.ControlState.NormalizedValues.Value)
+	previous_direction := state.Direction
+	state.Direction = direction
+	state.Rate = rate
+	if direction != 0 && direction != previous_direction {
+		p.repeatRateAction(state, now)
+		return
+	}
+	if state.isRepeatDue(now) {
+		p.repeatRateAction(state, now)
+	}
+}
+
+func (p *ProfileRunner) repeatRateAction(state *ProfileRunner_RateState, now time.Time) {
+	state.LastRepeatAt = now
+	p.tapAssignmentAction(state.GUID, state.ControlName, state.AssignmentIndex, state.ChangeEvent.ControlState, state.Assignment, state.Assignment.Rate.ActionForDirection(state.Direction))
+}
+
+/*
+Repeats the actions of the rate assignments which are due; the controls don't send events while held still
+*/
+func (p *ProfileRunner) settleRateAssignments(now time.Time) {
+	var due_states []*ProfileRunner_RateState
+	p.RateStates.ForEach(func(state *ProfileRunner_RateState, key string) bool {
+		if state.isRepeatDue(now) {
+			due_states = append(due_states, state)
+		}
+		return true
+	})
+
+	for _, state := range due_states {
+		/* the state may have been cleared by a profile switch in the meantime */
+		if current_state, has_state := p.RateStates.Get(assignmentStateKey(state.GUID, state.ControlName, state.AssignmentIndex)); has_state && current_state == state {
+			/* keep the rate independent of the tick interval unless the repeat is more than a period late */
+			repeat_at := state.LastRepeatAt.Add(state.period())
+			if now.Sub(repeat_at) >= state.period() {
+				repeat_at = now
+			}
+			p.repeatRateAction(state, repeat_at)
+		}
+	}
+}
+
+func (p *ProfileRunner) clearRateStates(match func(guid controller_mgr.JoystickGUIDString) bool) {
+	p.RateStates.Mutate(func(state *ProfileRunner_RateState, key string) map_utils.LockMapMutateAction[string, *ProfileRunner_RateState] {
+		if match(state.GUID) {
+			return map_utils.LockMapMutateAction[string, *ProfileRunner_RateState]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, *ProfileRunner_RateState]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
@@ -0,0 +1,54 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRateProfile = `{
+	"name": "Rate",
+	"controls": [
+		{
+			"name": "Lever1",
+			"assignment": {
+				"type": "rate",
+				"deadband": 0.1,
+				"min_rate": 1,
+				"max_rate": 10,
+				"action_increase": { "controls": "CruiseSpeed", "value": 1, "relative": true },
+				"action_decrease": { "controls": "CruiseSpeed", "value": -1, "relative": true }
+			}
+		}
+	]
+}`
+
+func TestRate_RepeatsWhileHeld(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testRateProfile)
+
+	/* nothing within the deadband */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0, 0.05))
+	assert.Empty(t, drainDirectControlCommands(runner))
+
+	/* leaving the deadband increases right away */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0.05, 1))
+	assert.Equal(t, []DirectController_Command{{Controls: "CruiseSpeed", InputValue: 1}}, drainDirectControlCommands(runner))
+	state, has_state := runner.RateStates.Get(assignmentStateKey(joystick.GUID, "Lever1", 0))
+	assert.True(t, has_state)
+	started_at := state.LastRepeatAt
+
+	/* and repeats at the max rate while held still */
+	runner.settleRateAssignments(started_at.Add(50 * time.Millisecond))
+	assert.Empty(t, drainDirectControlCommands(runner))
+	runner.settleRateAssignments(started_at.Add(105 * time.Millisecond))
+	runner.settleRateAssignments(started_at.Add(205 * time.Millisecond))
+	assert.Len(t, drainDirectControlCommands(runner), 2)
+
+	/* reversing decreases right away and the deadband stops the repeats */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 1, -0.5))
+	assert.Equal(t, []DirectController_Command{{Controls: "CruiseSpeed", InputValue: -1}}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", -0.5, 0))
+	runner.settleRateAssignments(time.Now().Add(2 * time.Second))
+	assert.Empty(t, drainDirectControlCommands(runner))
+}
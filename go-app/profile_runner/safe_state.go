@@ -0,0 +1,303 @@
+package profile_runner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/logger"
+	"tsw_controller_app/map_utils"
+)
+
+/* how often the connected devices and the control mode availability are checked for lost outputs */
+const SAFE_STATE_CHECK_INTERVAL = 500 * time.Millisecond
+
+/* how long to wait for the safe state commands to be sent when shutting down */
+const SAFE_STATE_FLUSH_TIMEOUT = time.Second
+
+type ProfileRunner_ActiveOutput_Kind = string
+
+const (
+	ProfileRunner_ActiveOutput_Kind_DirectControl ProfileRunner_ActiveOutput_Kind = "direct_control"
+	ProfileRunner_ActiveOutput_Kind_ApiControl    ProfileRunner_ActiveOutput_Kind = "api_control"
+	ProfileRunner_ActiveOutput_Kind_VirtualAxis   ProfileRunner_ActiveOutput_Kind = "virtual_axis"
+	ProfileRunner_ActiveOutput_Kind_VirtualButton ProfileRunner_ActiveOutput_Kind = "virtual_button"
+)
+
+/*
+An output set by a joystick which needs to be released or reset to its safe value when the joystick stops controlling it.
+Keys and sync controls are tracked by the action sequencer and sync controller respectively
+*/
+type ProfileRunner_ActiveOutput struct {
+	GUID      controller_mgr.JoystickGUIDString
+	ProfileId string
+	Kind      ProfileRunner_ActiveOutput_Kind
+	/* the direct/api control name or the virtual axis/button */
+	Name      string
+	Value     float64
+	Flags     []string
+	SafeValue *float64
+	UpdatedAt time.Time
+}
+
+/* scopes a sequencer source (or sequence ID) to a joystick */
+func joystickSource(guid controller_mgr.JoystickGUIDString, name string) string {
+	return fmt.Sprintf("%s/%s", guid, name)
+}
+
+func activeOutputKey(guid controller_mgr.JoystickGUIDString, kind ProfileRunner_ActiveOutput_Kind, name string) string {
+	return fmt.Sprintf("%s:%s:%s", guid, kind, name)
+}
+
+/*
+Records the direct control, API control and virtual joystick outputs of an assignment call
+*/
+func (p *ProfileRunner) recordActiveOutput(
+	guid controller_mgr.JoystickGUIDString,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	action *ProfileRunnerAssignmentCall,
+) {
+	if action == nil {
+		return
+	}
+
+	output := ProfileRunner_ActiveOutput{GUID: guid, UpdatedAt: time.Now()}
+	if selected_profile, has_selected_profile := p.Settings.GetSelectedProfiles().Get(guid); has_selected_profile {
+		output.ProfileId = selected_profile.Profile.Id()
+	}
+	if input_value := assignment.GetInputValue(); input_value != nil {
+		output.SafeValue = input_value.SafeValue
+	}
+
+	switch {
+	case action.DirectControlCommand != nil:
+		output.Kind = ProfileRunner_ActiveOutput_Kind_DirectControl
+		output.Name = action.DirectControlCommand.Controls
+		output.Value = action.DirectControlCommand.InputValue
+		output.Flags = action.DirectControlCommand.Flags
+	case action.ApiControlCommand != nil:
+		output.Kind = ProfileRunner_ActiveOutput_Kind_ApiControl
+		output.Name = action.ApiControlCommand.Controls
+		output.Value = action.ApiControlCommand.InputValue
+	case action.VirtualJoystickCommand != nil && action.VirtualJoystickCommand.Axis != nil:
+		output.Kind = ProfileRunner_ActiveOutput_Kind_VirtualAxis
+		output.Name = *action.VirtualJoystickCommand.Axis
+		output.Value = action.VirtualJoystickCommand.Value
+	case action.VirtualJoystickCommand != nil && action.VirtualJoystickCommand.Button != nil:
+		output.Kind = ProfileRunner_ActiveOutput_Kind_VirtualButton
+		output.Name = *action.VirtualJoystickCommand.Button
+		output.Value = action.VirtualJoystickCommand.Value
+		if output.Value < 0.5 {
+			/* released; nothing left to do */
+			p.ActiveOutputs.Delete(activeOutputKey(guid, output.Kind, output.Name))
+			return
+		}
+	default:
+		return
+	}
+	p.ActiveOutputs.Set(activeOutputKey(guid, output.Kind, output.Name), output)
+}
+
+/*
+Returns the active outputs of a joystick
+*/
+func (p *ProfileRunner) GetActiveOutputs(guid controller_mgr.JoystickGUIDString) []ProfileRunner_ActiveOutput {
+	outputs := []ProfileRunner_ActiveOutput{}
+	p.ActiveOutputs.ForEach(func(output ProfileRunner_ActiveOutput, key string) bool {
+		if output.GUID == guid {
+			outputs = append(outputs, output)
+		}
+		return true
+	})
+	return outputs
+}
+
+/*
+Returns the command which puts the output in its safe state; held direct controls are released at their current value
+*/
+func (output *ProfileRunner_ActiveOutput) safeStateCall() *ProfileRunnerAssignmentCall {
+	switch output.Kind {
+	case ProfileRunner_ActiveOutput_Kind_DirectControl:
+		/* sending the value without the hold flag makes the mod release the control once reached */
+		flags := slices.DeleteFunc(slices.Clone(output.Flags), func(flag string) bool { return flag == "hold" })
+		if output.SafeValue != nil {
+			return &ProfileRunnerAssignmentCall{DirectControlCommand: &DirectController_Command{
+				Controls:   output.Name,
+				InputValue: *output.SafeValue,
+				Flags:      slices.DeleteFunc(flags, func(flag string) bool { return flag == "relative" }),
+			}}
+		}
+		if slices.Contains(output.Flags, "hold") {
+			return &ProfileRunnerAssignmentCall{DirectControlCommand: &DirectController_Command{
+				Controls:   output.Name,
+				InputValue: output.Value,
+				Flags:      flags,
+			}}
+		}
+	case ProfileRunner_ActiveOutput_Kind_ApiControl:
+		if output.SafeValue != nil {
+			return &ProfileRunnerAssignmentCall{ApiControlCommand: &ApiController_Command{
+				Controls:   output.Name,
+				InputValue: *output.SafeValue,
+			}}
+		}
+	case ProfileRunner_ActiveOutput_Kind_VirtualAxis:
+		if output.SafeValue != nil {
+			axis := output.Name
+			return &ProfileRunnerAssignmentCall{VirtualJoystickCommand: &VirtualJoystickController_Command{
+				Axis:  &axis,
+				Value: *output.SafeValue,
+			}}
+		}
+	}
+	/* virtual buttons are released by their holders together with the keys */
+	return nil
+}
+
+/*
+Releases or neutralizes everything the matching joysticks are controlling:
+keys, sequences and virtual buttons are released, sync controls are stopped (or moved to their safe value) and
+held direct controls are released (or set to their safe value)
+*/
+func (p *ProfileRunner) applySafeState(match func(guid controller_mgr.JoystickGUIDString) bool, move_sync_controls bool) {
+	match_source := func(source string) bool {
+		guid, _, is_joystick_source := strings.Cut(source, "/")
+		return is_joystick_source && match(controller_mgr.JoystickGUIDString(guid))
+	}
+	p.ActionSequencer.ReleaseSources(match_source)
+	p.VirtualJoystickController.ReleaseSources(match_source)
+
+	match_sync_control := func(state SyncController_ControlState) bool {
+		return state.SourceEvent != nil && match(state.SourceEvent.Joystick.GUID)
+	}
+	sync_safe_values := map[string]float64{}
+	p.SyncController.ControlState.ForEach(func(state SyncController_ControlState, identifier string) bool {
+		if match_sync_control(state) && state.ControlProfile != nil && state.ControlProfile.InputValue.SafeValue != nil {
+			sync_safe_values[identifier] = *state.ControlProfile.InputValue.SafeValue
+		}
+		return true
+	})
+	p.SyncController.Stop(match_sync_control)
+	if move_sync_controls {
+		for identifier, safe_value := range sync_safe_values {
+			p.SyncController.UpdateControlStateSafeValue(identifier, safe_value)
+		}
+	}
+
+	safe_state_calls := []*ProfileRunnerAssignmentCall{}
+	p.ActiveOutputs.Mutate(func(output ProfileRunner_ActiveOutput, key string) map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput] {
+		if !match(output.GUID) {
+			return map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput]{Action: map_utils.LockMapMutateActionType_Noop}
+		}
+		if call := output.safeStateCall(); call != nil {
+			safe_state_calls = append(safe_state_calls, call)
+		}
+		return map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+	})
+	for _, call := range safe_state_calls {
+		logger.Logger.Info("[ProfileRunner::applySafeState] applying safe state", "call", call.ToString())
+		p.dispatchAssignmentCall(call)
+	}
+
+	p.clearTransformStates(match)
+	p.clearGestureStates(match)
+	p.clearRateStates(match)
+	p.clearFlickStates(match)
+	/* the previous calls would otherwise trigger deactivations on the next profile */
+	p.clearAssignmentStates(match)
+}
+
+/*
+Puts everything controlled by the joystick in its safe state
+*/
+func (p *ProfileRunner) ApplySafeState(guid controller_mgr.JoystickGUIDString) {
+	p.applySafeState(func(output_guid controller_mgr.JoystickGUIDString) bool {
+		return output_guid == guid
+	}, true)
+}
+
+/*
+Puts everything in its safe state and waits for the commands to be sent; sync controls are stopped since there is no time to move them
+*/
+func (p *ProfileRunner) Shutdown() {
+	p.applySafeState(func(guid controller_mgr.JoystickGUIDString) bool { return true }, false)
+	p.ActionSequencer.ReleaseAll()
+	p.VirtualJoystickController.ReleaseAll()
+	p.DirectController.Flush(SAFE_STATE_FLUSH_TIMEOUT)
+	p.ApiController.Flush(SAFE_STATE_FLUSH_TIMEOUT)
+}
+
+/* the connected joysticks and the control mode availability seen by the previous safe state check */
+type ProfileRunner_SafeStateCheck struct {
+	ConnectedGUIDs map[controller_mgr.JoystickGUIDString]bool
+	Availability   ProfileRunner_ControlModeAvailability
+}
+
+/*
+Checks for removed joysticks and lost control modes:
+the outputs of removed joysticks (including their held keys and sequences) are put in their safe state and sync controls are stopped when their feedback is lost
+*/
+func (p *ProfileRunner) checkSafeState(previous ProfileRunner_SafeStateCheck) ProfileRunner_SafeStateCheck {
+	connected_guids := map[controller_mgr.JoystickGUIDString]bool{}
+	p.ControllerManager.ConfiguredControllers.ForEach(func(controller controller_mgr.ControllerManager_ConfiguredController, guid controller_mgr.JoystickGUIDString) bool {
+		connected_guids[guid] = true
+		return true
+	})
+	removed_guids := map[controller_mgr.JoystickGUIDString]bool{}
+	for guid := range previous.ConnectedGUIDs {
+		if !connected_guids[guid] {
+			removed_guids[guid] = true
+		}
+	}
+	/* outputs of joysticks which were removed before the first check */
+	p.ActiveOutputs.ForEach(func(output ProfileRunner_ActiveOutput, key string) bool {
+		if !connected_guids[output.GUID] {
+			removed_guids[output.GUID] = true
+		}
+		return true
+	})
+	p.SyncController.ControlState.ForEach(func(state SyncController_ControlState, identifier string) bool {
+		if state.SourceEvent != nil && state.Loop.HasTarget && !state.SafeState && !connected_guids[state.SourceEvent.Joystick.GUID] {
+			removed_guids[state.SourceEvent.Joystick.GUID] = true
+		}
+		return true
+	})
+	for guid := range removed_guids {
+		logger.Logger.Info("[ProfileRunner::checkSafeState] joystick removed; applying safe state", "guid", guid)
+		p.ApplySafeState(guid)
+	}
+
+	availability := p.GetControlModeAvailability()
+	if previous.Availability.SyncControl && !availability.SyncControl {
+		logger.Logger.Info("[ProfileRunner::checkSafeState] sync control feedback lost; stopping sync controls")
+		p.SyncController.Stop(func(state SyncController_ControlState) bool { return true })
+	}
+	if previous.Availability.DirectControl && !availability.DirectControl {
+		/* the held controls are gone together with the connection */
+		logger.Logger.Info("[ProfileRunner::checkSafeState] direct control connection lost")
+		p.ActiveOutputs.Mutate(func(output ProfileRunner_ActiveOutput, key string) map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput] {
+			if output.Kind == ProfileRunner_ActiveOutput_Kind_DirectControl {
+				return map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+			}
+			return map_utils.LockMapMutateAction[string, ProfileRunner_ActiveOutput]{Action: map_utils.LockMapMutateActionType_Noop}
+		})
+	}
+	return ProfileRunner_SafeStateCheck{ConnectedGUIDs: connected_guids, Availability: availability}
+}
+
+func (p *ProfileRunner) runSafeStateChecks(ctx context.Context) {
+	ticker := time.NewTicker(SAFE_STATE_CHECK_INTERVAL)
+	defer ticker.Stop()
+	check := ProfileRunner_SafeStateCheck{Availability: p.GetControlModeAvailability()}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check = p.checkSafeState(check)
+		}
+	}
+}
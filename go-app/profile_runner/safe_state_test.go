@@ -0,0 +1,142 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/sdl_mgr"
+	"tsw_controller_app/virtual_joystick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveOutput_SafeStateCall(t *testing.T) {
+	safe_value := 0.0
+
+	/* held direct controls are released at their current value */
+	held := ProfileRunner_ActiveOutput{Kind: ProfileRunner_ActiveOutput_Kind_DirectControl, Name: "Throttle", Value: 0.7, Flags: []string{"hold", "normalized"}}
+	call := held.safeStateCall()
+	assert.Equal(t, &DirectController_Command{Controls: "Throttle", InputValue: 0.7, Flags: []string{"normalized"}}, call.DirectControlCommand)
+	assert.Equal(t, []string{"hold", "normalized"}, held.Flags)
+
+	/* the safe value takes precedence and is never relative */
+	held.SafeValue = &safe_value
+	held.Flags = []string{"hold", "relative"}
+	call = held.safeStateCall()
+	assert.Equal(t, &DirectController_Command{Controls: "Throttle", InputValue: 0, Flags: []string{}}, call.DirectControlCommand)
+
+	/* not held and no safe value; nothing to do */
+	assert.Nil(t, (&ProfileRunner_ActiveOutput{Kind: ProfileRunner_ActiveOutput_Kind_DirectControl, Name: "Throttle", Value: 0.7}).safeStateCall())
+	assert.Nil(t, (&ProfileRunner_ActiveOutput{Kind: ProfileRunner_ActiveOutput_Kind_ApiControl, Name: "Throttle", Value: 0.7}).safeStateCall())
+
+	api := ProfileRunner_ActiveOutput{Kind: ProfileRunner_ActiveOutput_Kind_ApiControl, Name: "Throttle", Value: 0.7, SafeValue: &safe_value}
+	assert.Equal(t, &ApiController_Command{Controls: "Throttle", InputValue: 0}, api.safeStateCall().ApiControlCommand)
+
+	/* virtual buttons are released through their holders */
+	button := ProfileRunner_ActiveOutput{Kind: ProfileRunner_ActiveOutput_Kind_VirtualButton, Name: "1", Value: 1}
+	assert.Nil(t, button.safeStateCall())
+}
+
+const testSafeStateProfile = `{
+	"name": "Safe state",
+	"controls": [
+		{
+			"name": "Button1",
+			"assignment": {
+				"type": "momentary",
+				"threshold": 0.5,
+				"action_activate": { "keys": "h" }
+			}
+		},
+		{
+			"name": "Button2",
+			"assignment": {
+				"type": "momentary",
+				"threshold": 0.5,
+				"action_activate": { "virtual_button": "3" }
+			}
+		},
+		{
+			"name": "Lever1",
+			"assignment": {
+				"type": "direct_control",
+				"controls": "Throttle1",
+				"hold": true,
+				"input_value": { "min": 0, "max": 1 }
+			}
+		},
+		{
+			"name": "Lever2",
+			"assignment": {
+				"type": "direct_control",
+				"controls": "TrainBrake1",
+				"input_value": { "min": 0, "max": 1, "safe_value": 1 }
+			}
+		}
+	]
+}`
+
+func pressedVirtualButtons(runner *ProfileRunner) []virtual_joystick.RecordingOutput_Event {
+	return runner.VirtualJoystickController.Output.(*virtual_joystick.RecordingOutput).Events()
+}
+
+func TestApplySafeState_ReleasesTheJoystickOutputs(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestProfileRunner(t, testSafeStateProfile)
+	for _, joystick := range []*sdl_mgr.SDLMgr_Joystick{joystick_a, joystick_b} {
+		runner.handleChangeEvent(newTestChangeEvent(joystick, "Button1", 0, 1))
+		runner.handleChangeEvent(newTestChangeEvent(joystick, "Button2", 0, 1))
+	}
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever1", 0, 0.5))
+	runner.handleChangeEvent(newTestChangeEvent(joystick_a, "Lever2", 0, 0.25))
+	require.Eventually(t, func() bool { return runner.ActionSequencer.PressedKeys()["h"] == 2 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return len(pressedVirtualButtons(runner)) == 1 }, time.Second, 5*time.Millisecond)
+	drainDirectControlCommands(runner)
+
+	runner.ApplySafeState(joystick_a.GUID)
+
+	/* the held direct control is released at its value and the safe value is applied */
+	assert.ElementsMatch(t, []DirectController_Command{
+		{Controls: "Throttle1", InputValue: 0.5},
+		{Controls: "TrainBrake1", InputValue: 1},
+	}, drainDirectControlCommands(runner))
+	assert.Empty(t, runner.GetActiveOutputs(joystick_a.GUID))
+
+	/* the key and virtual button are still held by the other joystick */
+	assert.Equal(t, 1, runner.ActionSequencer.PressedKeys()["h"])
+	assert.Len(t, pressedVirtualButtons(runner), 1)
+
+	runner.ApplySafeState(joystick_b.GUID)
+	assert.Empty(t, runner.ActionSequencer.PressedKeys())
+	require.Eventually(t, func() bool { return len(pressedVirtualButtons(runner)) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, virtual_joystick.RecordingOutput_Event{Button: 3, Value: 0}, pressedVirtualButtons(runner)[1])
+}
+
+func TestCheckSafeState_ReleasesKeysOfRemovedJoysticks(t *testing.T) {
+	runner, joystick_a, joystick_b := newTestProfileRunner(t, testSafeStateProfile)
+	runner.ControllerManager = controller_mgr.New(sdl_mgr.New())
+	for _, joystick := range []*sdl_mgr.SDLMgr_Joystick{joystick_a, joystick_b} {
+		runner.ControllerManager.ConfiguredControllers.Set(joystick.GUID, controller_mgr.ControllerManager_ConfiguredController{Joystick: joystick})
+	}
+	check := runner.checkSafeState(ProfileRunner_SafeStateCheck{})
+	assert.Equal(t, map[controller_mgr.JoystickGUIDString]bool{joystick_a.GUID: true, joystick_b.GUID: true}, check.ConnectedGUIDs)
+
+	/* the joystick only holds a key; there is no active output tracking it */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Button1", 0, 1))
+	require.Eventually(t, func() bool { return runner.ActionSequencer.PressedKeys()["h"] == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, runner.GetActiveOutputs(joystick_b.GUID))
+
+	check = runner.checkSafeState(check)
+	assert.Equal(t, 1, runner.ActionSequencer.PressedKeys()["h"])
+
+	runner.ControllerManager.ConfiguredControllers.Delete(joystick_b.GUID)
+	check = runner.checkSafeState(check)
+	assert.Empty(t, runner.ActionSequencer.PressedKeys())
+	assert.Equal(t, map[controller_mgr.JoystickGUIDString]bool{joystick_a.GUID: true}, check.ConnectedGUIDs)
+
+	/* outputs of joysticks which are not connected are put in their safe state as well */
+	runner.handleChangeEvent(newTestChangeEvent(joystick_b, "Lever2", 0, 0.25))
+	drainDirectControlCommands(runner)
+	runner.checkSafeState(check)
+	assert.Equal(t, []DirectController_Command{{Controls: "TrainBrake1", InputValue: 1}}, drainDirectControlCommands(runner))
+}
@@ -0,0 +1,325 @@
+package profile_runner
+
+import (
+	"math"
+	"time"
+	"tsw_controller_app/config"
+)
+
+const SYNC_CONTROL_DEFAULT_TOLERANCE = 0.005
+const SYNC_CONTROL_DEFAULT_PULSE_TIME = 0.05
+const SYNC_CONTROL_DEFAULT_PULSE_INTERVAL = 0.2
+const SYNC_CONTROL_DEFAULT_STUCK_TIMEOUT = 2.0
+
+/* the expected delay between releasing a key and the game reacting to it */
+const SYNC_CONTROL_RELEASE_LATENCY = 0.03
+
+/* time to wait after releasing before pressing again, so the final value can be observed */
+const SYNC_CONTROL_SETTLE_TIME = 100 * time.Millisecond
+
+/* the window after releasing in which the value is still considered to be coasting */
+const SYNC_CONTROL_COAST_WINDOW = 400 * time.Millisecond
+
+/* the current value is never extrapolated further than this */
+const SYNC_CONTROL_MAX_EXTRAPOLATION = 250 * time.Millisecond
+
+const SYNC_CONTROL_MAX_COAST_TIME = 1.0
+
+/* the weight of a new sample for the learned values */
+const SYNC_CONTROL_LEARNING_RATE = 0.3
+
+type SyncControlLoop_Mode = string
+
+const (
+	/* hold the key until the target value is (about to be) reached */
+	SyncControlLoop_Mode_Hold SyncControlLoop_Mode = "hold"
+	/* tap the key and wait for the value to settle; for notched controls */
+	SyncControlLoop_Mode_Pulse SyncControlLoop_Mode = "pulse"
+)
+
+type SyncControlLoop_Settings struct {
+	Mode      SyncControlLoop_Mode
+	Tolerance float64
+	/* in seconds */
+	PulseTime     float64
+	PulseInterval float64
+	StuckTimeout  float64
+}
+
+/*
+What the sync loop wants to happen with the increase or decrease keys.
+Directions are 1 for increasing and -1 for decreasing; 0 means nothing to do
+*/
+type SyncControlLoop_Command struct {
+	Release int
+	Press   int
+	/* the press is a short tap (using the pulse time) instead of holding */
+	Pulse bool
+}
+
+/*
+The closed-loop controller for a single sync control identifier.
+It learns the movement rate of the control while pressing and how far the control keeps moving after releasing,
+which is used to release the key early so the control comes to rest at the target.
+*/
+type SyncControlLoop struct {
+	Settings     SyncControlLoop_Settings
+	CurrentValue float64
+	TargetValue  float64
+	HasValue     bool
+	HasTarget    bool
+	LastValueAt  time.Time
+	/** [-1,0,1] -> decreasing, idle, increasing */
+	Moving         int
+	LastProgressAt time.Time
+	/* the control did not move while pressing; stays stuck until the target changes */
+	Stuck bool
+
+	/* learned movement in value per second while holding */
+	Rate float64
+	/* learned time the control keeps moving after releasing */
+	CoastTime float64
+	/* learned value change per pulse */
+	StepSize float64
+
+	releasedAt        time.Time
+	releasedValue     float64
+	releasedDirection int
+	coastDistance     float64
+
+	lastPulseAt    time.Time
+	pulseValue     float64
+	pulseDirection int
+}
+
+func (c SyncControlLoop_Command) IsEmpty() bool {
+	return c.Release == 0 && c.Press == 0
+}
+
+func SyncControlLoopSettingsFromAssignment(assignment *config.Config_Controller_Profile_Control_Assignment_SyncControl) SyncControlLoop_Settings {
+	settings := SyncControlLoop_Settings{
+		Mode:          SyncControlLoop_Mode_Hold,
+		Tolerance:     SYNC_CONTROL_DEFAULT_TOLERANCE,
+		PulseTime:     SYNC_CONTROL_DEFAULT_PULSE_TIME,
+		PulseInterval: SYNC_CONTROL_DEFAULT_PULSE_INTERVAL,
+		StuckTimeout:  SYNC_CONTROL_DEFAULT_STUCK_TIMEOUT,
+	}
+	if assignment == nil {
+		return settings
+	}
+	if assignment.Mode != nil {
+		settings.Mode = *assignment.Mode
+	}
+	if assignment.Tolerance != nil {
+		settings.Tolerance = *assignment.Tolerance
+	}
+	if assignment.PulseTime != nil {
+		settings.PulseTime = *assignment.PulseTime
+	}
+	if assignment.PulseInterval != nil {
+		settings.PulseInterval = *assignment.PulseInterval
+	}
+	if assignment.StuckTimeout != nil {
+		settings.StuckTimeout = *assignment.StuckTimeout
+	}
+	return settings
+}
+
+func NewSyncControlLoop(settings SyncControlLoop_Settings) SyncControlLoop {
+	return SyncControlLoop{Settings: settings}
+}
+
+func syncControlLearn(previous float64, sample float64) float64 {
+	if previous == 0 {
+		return sample
+	}
+	return previous + (sample-previous)*SYNC_CONTROL_LEARNING_RATE
+}
+
+func syncControlDirection(value float64) int {
+	if value > 0 {
+		return 1
+	}
+	if value < 0 {
+		return -1
+	}
+	return 0
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+/*
+Records a value reported by the game
+*/
+func (l *SyncControlLoop) ObserveValue(value float64, now time.Time) {
+	if l.HasValue {
+		delta := value - l.CurrentValue
+		if l.Moving != 0 && delta*float64(l.Moving) > 0 {
+			l.LastProgressAt = now
+			if elapsed := now.Sub(l.LastValueAt).Seconds(); elapsed > 0 {
+				l.Rate = syncControlLearn(l.Rate, math.Abs(delta)/elapsed)
+			}
+		}
+		if l.pulseDirection != 0 && delta*float64(l.pulseDirection) > 0 {
+			l.LastProgressAt = now
+		}
+		if l.Moving == 0 && l.releasedDirection != 0 && now.Sub(l.releasedAt) <= SYNC_CONTROL_COAST_WINDOW {
+			l.coastDistance = math.Max(l.coastDistance, (value-l.releasedValue)*float64(l.releasedDirection))
+		}
+	}
+	l.CurrentValue = value
+	l.LastValueAt = now
+	l.HasValue = true
+}
+
+/*
+Updates the target value; a changed target clears the stuck state
+*/
+func (l *SyncControlLoop) SetTarget(target float64) {
+	if !l.HasTarget || math.Abs(target-l.TargetValue) > l.Settings.Tolerance {
+		l.Stuck = false
+	}
+	l.TargetValue = target
+	l.HasTarget = true
+}
+
+/* the current value extrapolated using the learned rate while moving */
+func (l *SyncControlLoop) predictedValue(now time.Time) float64 {
+	if l.Moving == 0 || l.Rate == 0 {
+		return l.CurrentValue
+	}
+	elapsed := now.Sub(l.LastValueAt)
+	if elapsed > SYNC_CONTROL_MAX_EXTRAPOLATION {
+		elapsed = SYNC_CONTROL_MAX_EXTRAPOLATION
+	}
+	return l.CurrentValue + float64(l.Moving)*l.Rate*elapsed.Seconds()
+}
+
+/* folds the observed coasting after the last release into the learned coast time */
+func (l *SyncControlLoop) finishCoast() {
+	if l.releasedDirection == 0 {
+		return
+	}
+	if l.Rate > 0 {
+		l.CoastTime = math.Min(syncControlLearn(l.CoastTime, l.coastDistance/l.Rate), SYNC_CONTROL_MAX_COAST_TIME)
+	}
+	l.releasedDirection = 0
+	l.coastDistance = 0
+}
+
+func (l *SyncControlLoop) release(now time.Time) SyncControlLoop_Command {
+	direction := l.Moving
+	l.Moving = 0
+	l.releasedAt = now
+	l.releasedValue = l.CurrentValue
+	l.releasedDirection = direction
+	l.coastDistance = 0
+	return SyncControlLoop_Command{Release: direction}
+}
+
+/*
+Clears the target so the loop stops moving; returns the command to release the key when pressing
+*/
+func (l *SyncControlLoop) Stop(now time.Time) SyncControlLoop_Command {
+	command := SyncControlLoop_Command{}
+	if l.Moving != 0 {
+		command = l.release(now)
+	}
+	l.pulseDirection = 0
+	l.HasTarget = false
+	return command
+}
+
+/*
+Returns whether the loop needs to be stepped periodically (ie: it is pressing or has not reached the target yet)
+*/
+func (l *SyncControlLoop) IsActive() bool {
+	if !l.HasValue || !l.HasTarget {
+		return false
+	}
+	return l.Moving != 0 || l.pulseDirection != 0 || l.releasedDirection != 0 || (!l.Stuck && math.Abs(l.TargetValue-l.CurrentValue) > l.Settings.Tolerance)
+}
+
+/*
+Evaluates the loop and returns what should happen with the keys; the returned command is considered executed
+*/
+func (l *SyncControlLoop) Step(now time.Time) SyncControlLoop_Command {
+	if !l.HasValue || !l.HasTarget {
+		return SyncControlLoop_Command{}
+	}
+	if l.releasedDirection != 0 && now.Sub(l.releasedAt) > SYNC_CONTROL_COAST_WINDOW {
+		l.finishCoast()
+	}
+
+	remaining := l.TargetValue - l.predictedValue(now)
+	tolerance := l.Settings.Tolerance
+
+	if l.Moving != 0 {
+		if now.Sub(l.LastProgressAt) > secondsToDuration(l.Settings.StuckTimeout) {
+			l.Stuck = true
+			return l.release(now)
+		}
+		remaining_in_direction := remaining * float64(l.Moving)
+		/* reached or passed the target */
+		if remaining_in_direction <= tolerance {
+			return l.release(now)
+		}
+		/* predicted to arrive once the key is released and the control comes to a rest */
+		if l.Rate > 0 && remaining_in_direction/l.Rate <= SYNC_CONTROL_RELEASE_LATENCY+l.CoastTime {
+			return l.release(now)
+		}
+		return SyncControlLoop_Command{}
+	}
+
+	if l.Stuck {
+		return SyncControlLoop_Command{}
+	}
+
+	if l.Settings.Mode == SyncControlLoop_Mode_Pulse {
+		return l.stepPulse(now, remaining)
+	}
+
+	if math.Abs(remaining) <= tolerance || now.Sub(l.releasedAt) < SYNC_CONTROL_SETTLE_TIME {
+		return SyncControlLoop_Command{}
+	}
+	l.finishCoast()
+	l.Moving = syncControlDirection(remaining)
+	l.LastProgressAt = now
+	return SyncControlLoop_Command{Press: l.Moving}
+}
+
+func (l *SyncControlLoop) stepPulse(now time.Time, remaining float64) SyncControlLoop_Command {
+	if now.Sub(l.lastPulseAt) < secondsToDuration(l.Settings.PulseInterval) {
+		return SyncControlLoop_Command{}
+	}
+
+	/* learn how far a single pulse moves the control */
+	if l.pulseDirection != 0 {
+		if moved := math.Abs(l.CurrentValue - l.pulseValue); moved > 0 {
+			l.StepSize = syncControlLearn(l.StepSize, moved)
+		}
+	}
+
+	/* when the target is between two notches, stop at the closest one instead of oscillating around it */
+	tolerance := math.Max(l.Settings.Tolerance, l.StepSize/2)
+	if math.Abs(remaining) <= tolerance {
+		l.pulseDirection = 0
+		return SyncControlLoop_Command{}
+	}
+
+	direction := syncControlDirection(remaining)
+	if l.pulseDirection != direction {
+		l.LastProgressAt = now
+	} else if now.Sub(l.LastProgressAt) > secondsToDuration(l.Settings.StuckTimeout) {
+		l.Stuck = true
+		l.pulseDirection = 0
+		return SyncControlLoop_Command{}
+	}
+
+	l.pulseDirection = direction
+	l.pulseValue = l.CurrentValue
+	l.lastPulseAt = now
+	return SyncControlLoop_Command{Press: direction, Pulse: true}
+}
@@ -0,0 +1,163 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+A simulated game control which moves at a constant rate while a key is held,
+keeps moving for a short while after releasing and reports its value at a fixed interval
+*/
+type syncControlLoopTestPlant struct {
+	Value       float64
+	Rate        float64
+	CoastTime   time.Duration
+	Notch       float64
+	Pressed     int
+	ReleasedAt  time.Time
+	ReleasedDir int
+	Presses     int
+}
+
+func (p *syncControlLoopTestPlant) apply(command SyncControlLoop_Command, now time.Time) {
+	if command.Release != 0 {
+		p.Pressed = 0
+		p.ReleasedAt = now
+		p.ReleasedDir = command.Release
+	}
+	if command.Press != 0 {
+		p.Presses++
+		if command.Pulse {
+			p.Value += float64(command.Press) * p.Notch
+		} else {
+			p.Pressed = command.Press
+		}
+	}
+}
+
+func (p *syncControlLoopTestPlant) advance(now time.Time, step time.Duration) {
+	if p.Pressed != 0 {
+		p.Value += float64(p.Pressed) * p.Rate * step.Seconds()
+	} else if p.ReleasedDir != 0 && now.Sub(p.ReleasedAt) < p.CoastTime {
+		p.Value += float64(p.ReleasedDir) * p.Rate * step.Seconds()
+	}
+}
+
+/* runs the loop against the plant; the plant reports its value every 50ms and the loop ticks every 10ms */
+func runSyncControlLoop(loop *SyncControlLoop, plant *syncControlLoopTestPlant, start time.Time, duration time.Duration) time.Time {
+	const step = 10 * time.Millisecond
+	now := start
+	for elapsed := time.Duration(0); elapsed < duration; elapsed += step {
+		now = now.Add(step)
+		plant.advance(now, step)
+		if elapsed%(50*time.Millisecond) == 0 {
+			loop.ObserveValue(plant.Value, now)
+		}
+		plant.apply(loop.Step(now), now)
+	}
+	return now
+}
+
+func TestSyncControlLoop_ReachesTarget(t *testing.T) {
+	loop := NewSyncControlLoop(SyncControlLoopSettingsFromAssignment(nil))
+	plant := &syncControlLoopTestPlant{Rate: 0.5}
+	start := time.Now()
+	loop.ObserveValue(plant.Value, start)
+	loop.SetTarget(0.6)
+
+	runSyncControlLoop(&loop, plant, start, 3*time.Second)
+	assert.InDelta(t, 0.6, plant.Value, 0.05)
+	assert.Equal(t, 0, loop.Moving)
+	assert.False(t, loop.Stuck)
+	assert.InDelta(t, 0.5, loop.Rate, 0.05)
+}
+
+func TestSyncControlLoop_LearnsCoastingAndReleasesEarly(t *testing.T) {
+	loop := NewSyncControlLoop(SyncControlLoopSettingsFromAssignment(nil))
+	plant := &syncControlLoopTestPlant{Rate: 0.5, CoastTime: 100 * time.Millisecond}
+	now := time.Now()
+	loop.ObserveValue(plant.Value, now)
+
+	/* move back and forth a few times so the loop can learn the coasting */
+	for _, target := range []float64{0.5, 0.0, 0.5, 0.0, 0.5} {
+		loop.SetTarget(target)
+		now = runSyncControlLoop(&loop, plant, now, 3*time.Second)
+		assert.InDelta(t, target, plant.Value, 0.03)
+	}
+
+	/* the plant coasts for 100ms after releasing */
+	assert.InDelta(t, 0.1, loop.CoastTime, 0.03)
+}
+
+func TestSyncControlLoop_PulseMode(t *testing.T) {
+	settings := SyncControlLoopSettingsFromAssignment(nil)
+	settings.Mode = SyncControlLoop_Mode_Pulse
+	loop := NewSyncControlLoop(settings)
+	plant := &syncControlLoopTestPlant{Notch: 0.2}
+	start := time.Now()
+	loop.ObserveValue(plant.Value, start)
+	/* the target is in between two notches; the loop should settle on the closest notch instead of oscillating */
+	loop.SetTarget(0.65)
+
+	runSyncControlLoop(&loop, plant, start, 3*time.Second)
+	assert.InDelta(t, 0.6, plant.Value, 0.001)
+	assert.Equal(t, 3, plant.Presses)
+	assert.InDelta(t, 0.2, loop.StepSize, 0.001)
+	assert.False(t, loop.Stuck)
+}
+
+func TestSyncControlLoop_StuckTimeout(t *testing.T) {
+	settings := SyncControlLoopSettingsFromAssignment(nil)
+	settings.StuckTimeout = 0.5
+	loop := NewSyncControlLoop(settings)
+	/* the control does not move at all */
+	plant := &syncControlLoopTestPlant{Rate: 0}
+	start := time.Now()
+	loop.ObserveValue(plant.Value, start)
+	loop.SetTarget(1.0)
+
+	runSyncControlLoop(&loop, plant, start, 2*time.Second)
+	assert.True(t, loop.Stuck)
+	assert.Equal(t, 0, loop.Moving)
+	assert.Equal(t, 0, plant.Pressed)
+	assert.Equal(t, 1, plant.Presses)
+
+	/* a new target clears the stuck state */
+	loop.SetTarget(0.5)
+	assert.False(t, loop.Stuck)
+}
+
+func TestSyncControlLoop_Tolerance(t *testing.T) {
+	settings := SyncControlLoopSettingsFromAssignment(nil)
+	settings.Tolerance = 0.1
+	loop := NewSyncControlLoop(settings)
+	start := time.Now()
+	loop.ObserveValue(0.5, start)
+	loop.SetTarget(0.55)
+
+	assert.True(t, loop.Step(start.Add(time.Millisecond)).IsEmpty())
+	assert.False(t, loop.IsActive())
+}
+
+func TestSyncControlLoop_Stop(t *testing.T) {
+	loop := NewSyncControlLoop(SyncControlLoopSettingsFromAssignment(nil))
+	plant := &syncControlLoopTestPlant{Rate: 0.5}
+	start := time.Now()
+	loop.ObserveValue(plant.Value, start)
+	loop.SetTarget(1)
+
+	now := runSyncControlLoop(&loop, plant, start, 500*time.Millisecond)
+	assert.Equal(t, 1, plant.Pressed)
+
+	/* stopping releases the held key and the loop does not press again */
+	plant.apply(loop.Stop(now), now)
+	assert.Equal(t, 0, plant.Pressed)
+	assert.False(t, loop.IsActive())
+	value := plant.Value
+	runSyncControlLoop(&loop, plant, now, 500*time.Millisecond)
+	assert.Equal(t, 0, plant.Pressed)
+	assert.Equal(t, value, plant.Value)
+}
@@ -2,15 +2,17 @@ package profile_runner
 
 import (
 	"context"
-	"strconv"
+	"sync"
 	"time"
 	"tsw_controller_app/config"
 	"tsw_controller_app/controller_mgr"
 	"tsw_controller_app/map_utils"
 	"tsw_controller_app/pubsub_utils"
-	"tsw_controller_app/tswconnector"
 )
 
+/* how often the control loops are evaluated in between value updates */
+const SYNC_CONTROLLER_TICK_INTERVAL = 20 * time.Millisecond
+
 type SyncController_ControlState struct {
 	Identifier             string
 	PropertyName           string
@@ -21,82 +23,177 @@ type SyncController_ControlState struct {
 	Moving         int
 	ControlProfile *config.Config_Controller_Profile_Control_Assignment_SyncControl
 	SourceEvent    *controller_mgr.ControllerManager_Control_ChangeEvent
+	Loop           SyncControlLoop
+	/* what should happen with the keys as a result of this state change */
+	Command SyncControlLoop_Command
+	/* the control is moving to its safe value; the keys of the control profile are used regardless of the selected profile */
+	SafeState bool
 }
 
 type SyncController struct {
-	Connector                   tswconnector.TSWConnector
+	/* in order of priority */
+	Sources                     []SyncFeedbackSource
+	Arbiter                     *SyncFeedbackArbiter
 	ControlState                *map_utils.LockMap[string, SyncController_ControlState]
 	ControlStateChangedChannels *pubsub_utils.PubSubSlice[SyncController_ControlState]
+	/* serializes the read-modify-write of the control states */
+	mutex sync.Mutex
 }
 
-func (c *SyncController) UpdateControlStateMoving(identifier string, moving int) {
-	if state, has_state := c.ControlState.Get(identifier); has_state {
-		state.Moving = moving
-		c.ControlState.Set(identifier, state)
-	}
-}
-
-func (c *SyncController) UpdateControlStateTargetValue(identifier string, targetValue float64, profile *config.Config_Controller_Profile_Control_Assignment_SyncControl, event *controller_mgr.ControllerManager_Control_ChangeEvent) {
+/*
+Applies the mutation to the control state, steps its loop and emits the state if there is something to do
+*/
+func (c *SyncController) update(identifier string, now time.Time, mutator func(state *SyncController_ControlState)) {
+	c.mutex.Lock()
 	state, has_state := c.ControlState.Get(identifier)
 	if !has_state {
 		state = SyncController_ControlState{
-			Identifier:             identifier,
-			TargetValue:            targetValue,
-			CurrentValue:           0.0,
-			CurrentNormalizedValue: 0.0,
-			Moving:                 0,
-			/* we don't have the property name yet at this point */
-			PropertyName:   "",
-			ControlProfile: profile,
-			SourceEvent:    event,
+			Identifier: identifier,
+			Loop:       NewSyncControlLoop(SyncControlLoopSettingsFromAssignment(nil)),
 		}
 	}
-	state.TargetValue = targetValue
+	mutator(&state)
+	state.Command = state.Loop.Step(now)
+	state.CurrentValue = state.Loop.CurrentValue
+	state.TargetValue = state.Loop.TargetValue
+	state.Moving = state.Loop.Moving
 	c.ControlState.Set(identifier, state)
 
-	c.ControlStateChangedChannels.EmitTimeout(time.Second, state)
+	/* emitted while locked so the commands are received in order */
+	if !state.Command.IsEmpty() {
+		c.ControlStateChangedChannels.EmitTimeout(time.Second, state)
+	}
+	c.mutex.Unlock()
+}
+
+func (c *SyncController) UpdateControlStateTargetValue(identifier string, targetValue float64, profile *config.Config_Controller_Profile_Control_Assignment_SyncControl, event *controller_mgr.ControllerManager_Control_ChangeEvent) {
+	c.update(identifier, time.Now(), func(state *SyncController_ControlState) {
+		state.ControlProfile = profile
+		state.SourceEvent = event
+		state.SafeState = false
+		state.Loop.Settings = SyncControlLoopSettingsFromAssignment(profile)
+		state.Loop.SetTarget(targetValue)
+	})
+}
+
+/*
+Moves the control to its safe value using the keys of its last control profile
+*/
+func (c *SyncController) UpdateControlStateSafeValue(identifier string, safe_value float64) {
+	c.update(identifier, time.Now(), func(state *SyncController_ControlState) {
+		state.SafeState = true
+		state.Loop.SetTarget(safe_value)
+	})
+}
+
+/*
+Stops moving the matching controls; a release is emitted for controls which are currently pressing
+*/
+func (c *SyncController) Stop(match func(state SyncController_ControlState) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now()
+	released_states := []SyncController_ControlState{}
+	c.ControlState.ForEachMap(func(state SyncController_ControlState, identifier string) SyncController_ControlState {
+		if !match(state) {
+			return state
+		}
+		state.Command = state.Loop.Stop(now)
+		state.Moving = state.Loop.Moving
+		state.SafeState = false
+		if !state.Command.IsEmpty() {
+			released_states = append(released_states, state)
+		}
+		return state
+	})
+	/* emitted outside of the map lock; the subscribers read the control states */
+	for _, state := range released_states {
+		c.ControlStateChangedChannels.EmitTimeout(time.Second, state)
+	}
+}
+
+/*
+Returns whether the control is still moving towards a target; presses of stopped controls should be ignored
+*/
+func (c *SyncController) IsTargeting(identifier string) bool {
+	state, has_state := c.ControlState.Get(identifier)
+	return has_state && state.Loop.HasTarget
+}
+
+/*
+Returns whether any of the sources can currently report values
+*/
+func (c *SyncController) IsAvailable() bool {
+	for _, source := range c.Sources {
+		if source.IsAvailable() {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *SyncController) Subscribe() (chan SyncController_ControlState, func()) {
 	return c.ControlStateChangedChannels.Subscribe()
 }
 
+func (c *SyncController) tick(now time.Time) {
+	active_identifiers := []string{}
+	c.ControlState.ForEach(func(state SyncController_ControlState, identifier string) bool {
+		if state.Loop.IsActive() {
+			active_identifiers = append(active_identifiers, identifier)
+		}
+		return true
+	})
+	for _, identifier := range active_identifiers {
+		c.update(identifier, now, func(state *SyncController_ControlState) {})
+	}
+}
+
 func (c *SyncController) Run(ctx context.Context) func() {
 	ctx_with_cancel, cancel := context.WithCancel(ctx)
 
+	/* merge the values of all sources into a single channel */
+	incoming_channel := make(chan SyncFeedback_Value, pubsub_utils.PUBSUB_SLICE_DEFAULT_BUFFER_SIZE)
+	for _, source := range c.Sources {
+		source_channel, unsubscribe := source.Subscribe()
+		cancel_source := source.Run(ctx_with_cancel)
+		go func() {
+			defer cancel_source()
+			defer unsubscribe()
+			for {
+				select {
+				case <-ctx_with_cancel.Done():
+					return
+				case value := <-source_channel:
+					select {
+					case incoming_channel <- value:
+					case <-ctx_with_cancel.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
 	go func() {
-		incoming_channel, unsubscribe := c.Connector.Subscribe()
-		defer unsubscribe()
+		ticker := time.NewTicker(SYNC_CONTROLLER_TICK_INTERVAL)
+		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx_with_cancel.Done():
 				return
-			case msg := <-incoming_channel:
-				/* skip message if not sync_control message */
-				if msg.EventName != "sync_control_value" {
+			case now := <-ticker.C:
+				c.tick(now)
+			case value := <-incoming_channel:
+				if !c.Arbiter.Accept(value) {
 					continue
 				}
-
-				control_state, has_control_state := c.ControlState.Get(msg.Properties["name"])
-				if !has_control_state {
-					control_state = SyncController_ControlState{
-						Identifier:             msg.Properties["name"],
-						PropertyName:           msg.Properties["property"],
-						CurrentValue:           0.0,
-						CurrentNormalizedValue: 0.0,
-						TargetValue:            0.0,
-						Moving:                 0,
-					}
-				}
-
-				current_value, _ := strconv.ParseFloat(msg.Properties["value"], 64)
-				current_normalized_value, _ := strconv.ParseFloat(msg.Properties["normalized_value"], 64)
-				control_state.PropertyName = msg.Properties["property"]
-				control_state.CurrentValue = current_value
-				control_state.CurrentNormalizedValue = current_normalized_value
-				c.ControlState.Set(msg.Properties["name"], control_state)
-				c.ControlStateChangedChannels.EmitTimeout(time.Second, control_state)
+				c.update(value.Identifier, value.ReceivedAt, func(state *SyncController_ControlState) {
+					state.PropertyName = value.PropertyName
+					state.CurrentNormalizedValue = value.CurrentNormalizedValue
+					state.Loop.ObserveValue(value.CurrentValue, value.ReceivedAt)
+				})
 			}
 		}
 	}()
@@ -104,9 +201,17 @@ func (c *SyncController) Run(ctx context.Context) func() {
 	return cancel
 }
 
-func NewSyncController(connection tswconnector.TSWConnector) *SyncController {
+/*
+Creates a sync controller reading the current values from the given sources; earlier sources take priority over later ones
+*/
+func NewSyncController(sources ...SyncFeedbackSource) *SyncController {
+	source_names := []SyncFeedback_SourceName{}
+	for _, source := range sources {
+		source_names = append(source_names, source.Name())
+	}
 	controller := SyncController{
-		Connector:                   connection,
+		Sources:                     sources,
+		Arbiter:                     NewSyncFeedbackArbiter(source_names...),
 		ControlState:                map_utils.NewLockMap[string, SyncController_ControlState](),
 		ControlStateChangedChannels: pubsub_utils.NewPubSubSlice[SyncController_ControlState](),
 	}
@@ -0,0 +1,160 @@
+package profile_runner
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"tsw_controller_app/pubsub_utils"
+	"tsw_controller_app/tswconnector"
+)
+
+/* a value from a lower priority source is only accepted once the higher priority source has not reported the control for this long */
+const SYNC_FEEDBACK_STALE_TIMEOUT = 500 * time.Millisecond
+
+type SyncFeedback_SourceName = string
+
+const (
+	/* the sync_control_value messages sent by the UE4SS mod */
+	SyncFeedback_SourceName_Mod SyncFeedback_SourceName = "mod"
+	/* the TSW HTTP API subscription */
+	SyncFeedback_SourceName_Api SyncFeedback_SourceName = "api"
+)
+
+type SyncFeedback_Value struct {
+	Source                 SyncFeedback_SourceName
+	Identifier             string
+	PropertyName           string
+	CurrentValue           float64
+	CurrentNormalizedValue float64
+	ReceivedAt             time.Time
+}
+
+/*
+A source of the current in-game values for the sync controller
+*/
+type SyncFeedbackSource interface {
+	Name() SyncFeedback_SourceName
+	/* disabled sources don't emit any values */
+	SetEnabled(enabled bool)
+	/* whether the source is enabled and currently able to report values */
+	IsAvailable() bool
+	Subscribe() (chan SyncFeedback_Value, func())
+	Run(ctx context.Context) func()
+}
+
+type syncFeedbackArbiter_Entry struct {
+	Priority   int
+	ReceivedAt time.Time
+}
+
+/*
+Decides which source is used for each control when multiple sources report values.
+Sources earlier in the list have priority; a lower priority source takes over once the values from the higher priority source go stale
+*/
+type SyncFeedbackArbiter struct {
+	mutex        sync.Mutex
+	priorities   map[SyncFeedback_SourceName]int
+	last         map[string]syncFeedbackArbiter_Entry
+	StaleTimeout time.Duration
+}
+
+func (a *SyncFeedbackArbiter) Accept(value SyncFeedback_Value) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	priority, has_priority := a.priorities[value.Source]
+	if !has_priority {
+		return false
+	}
+	last, has_last := a.last[value.Identifier]
+	if has_last && last.Priority < priority && value.ReceivedAt.Sub(last.ReceivedAt) < a.StaleTimeout {
+		return false
+	}
+	a.last[value.Identifier] = syncFeedbackArbiter_Entry{
+		Priority:   priority,
+		ReceivedAt: value.ReceivedAt,
+	}
+	return true
+}
+
+func NewSyncFeedbackArbiter(source_names ...SyncFeedback_SourceName) *SyncFeedbackArbiter {
+	priorities := map[SyncFeedback_SourceName]int{}
+	for index, name := range source_names {
+		priorities[name] = index
+	}
+	return &SyncFeedbackArbiter{
+		priorities:   priorities,
+		last:         map[string]syncFeedbackArbiter_Entry{},
+		StaleTimeout: SYNC_FEEDBACK_STALE_TIMEOUT,
+	}
+}
+
+/*
+Reads the sync_control_value messages sent by the mod over the socket connection
+*/
+type ModSyncFeedbackSource struct {
+	Connector     tswconnector.TSWConnector
+	ValueChannels *pubsub_utils.PubSubSlice[SyncFeedback_Value]
+	enabled       atomic.Bool
+}
+
+func (s *ModSyncFeedbackSource) Name() SyncFeedback_SourceName {
+	return SyncFeedback_SourceName_Mod
+}
+
+func (s *ModSyncFeedbackSource) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+func (s *ModSyncFeedbackSource) IsAvailable() bool {
+	return s.enabled.Load() && s.Connector.IsConnected()
+}
+
+func (s *ModSyncFeedbackSource) Subscribe() (chan SyncFeedback_Value, func()) {
+	return s.ValueChannels.Subscribe()
+}
+
+func (s *ModSyncFeedbackSource) Run(ctx context.Context) func() {
+	ctx_with_cancel, cancel := context.WithCancel(ctx)
+
+	go func() {
+		incoming_channel, unsubscribe := s.Connector.Subscribe()
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx_with_cancel.Done():
+				return
+			case msg := <-incoming_channel:
+				/* skip message if not sync_control message */
+				if msg.EventName != "sync_control_value" || !s.enabled.Load() {
+					continue
+				}
+
+				current_value, _ := strconv.ParseFloat(msg.Properties["value"], 64)
+				current_normalized_value, _ := strconv.ParseFloat(msg.Properties["normalized_value"], 64)
+				s.ValueChannels.EmitTimeout(time.Second, SyncFeedback_Value{
+					Source:                 SyncFeedback_SourceName_Mod,
+					Identifier:             msg.Properties["name"],
+					PropertyName:           msg.Properties["property"],
+					CurrentValue:           current_value,
+					CurrentNormalizedValue: current_normalized_value,
+					ReceivedAt:             time.Now(),
+				})
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func NewModSyncFeedbackSource(connector tswconnector.TSWConnector) *ModSyncFeedbackSource {
+	source := ModSyncFeedbackSource{
+		Connector:     connector,
+		ValueChannels: pubsub_utils.NewPubSubSlice[SyncFeedback_Value](),
+	}
+	source.enabled.Store(true)
+	return &source
+}
@@ -0,0 +1,148 @@
+package profile_runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+	"tsw_controller_app/logger"
+	"tsw_controller_app/pubsub_utils"
+	"tsw_controller_app/tswapi"
+)
+
+const API_SYNC_FEEDBACK_POLL_INTERVAL = 100 * time.Millisecond
+
+/* how long to wait before polling again after the API returned an error */
+const API_SYNC_FEEDBACK_ERROR_BACKOFF = 5 * time.Second
+
+type ApiSyncFeedbackSource_Config struct {
+	/* has to differ from the subscription used by the cab debugger */
+	SubscriptionID int
+}
+
+/*
+Polls the current values of the controls using a TSW HTTP API subscription; this works without the mod installed
+*/
+type ApiSyncFeedbackSource struct {
+	API           *tswapi.TSWAPI
+	ValueChannels *pubsub_utils.PubSubSlice[SyncFeedback_Value]
+	mutex         sync.Mutex
+	config        ApiSyncFeedbackSource_Config
+	enabled       atomic.Bool
+	/* the drivable actor the current subscription was created for */
+	object_class string
+}
+
+func (s *ApiSyncFeedbackSource) Name() SyncFeedback_SourceName {
+	return SyncFeedback_SourceName_Api
+}
+
+func (s *ApiSyncFeedbackSource) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+func (s *ApiSyncFeedbackSource) IsAvailable() bool {
+	return s.enabled.Load() && s.API.CanConnect()
+}
+
+func (s *ApiSyncFeedbackSource) UpdateConfig(config ApiSyncFeedbackSource_Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.config = config
+	/* forces the subscription to be re-created */
+	s.object_class = ""
+}
+
+func (s *ApiSyncFeedbackSource) Subscribe() (chan SyncFeedback_Value, func()) {
+	return s.ValueChannels.Subscribe()
+}
+
+func (s *ApiSyncFeedbackSource) recreateSubscription() (tswapi.TSWAPI_GetCurrentDrivableActorSubscriptionResponse, error) {
+	s.API.DeleteSubscription(s.config.SubscriptionID)
+	if err := s.API.CreateCurrentDrivableActorSubscription(s.config.SubscriptionID); err != nil {
+		return tswapi.TSWAPI_GetCurrentDrivableActorSubscriptionResponse{}, err
+	}
+	return s.API.GetCurrentDrivableActorSubscription(s.config.SubscriptionID)
+}
+
+func (s *ApiSyncFeedbackSource) poll() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	subscription_result, err := s.API.GetCurrentDrivableActorSubscription(s.config.SubscriptionID)
+	if errors.Is(err, tswapi.ErrMissingCommAPIKey) || errors.As(err, new(*net.OpError)) {
+		/* the API is not available (yet) */
+		s.object_class = ""
+		return nil
+	}
+
+	/* the subscription does not exist yet or the drivable actor changed since creating it */
+	if err != nil || subscription_result.ObjectClass == "" || subscription_result.ObjectClass != s.object_class {
+		object_class, err := s.API.GetCurrentDrivableActorObjectClass()
+		if err != nil {
+			/* not driving anything */
+			s.object_class = ""
+			return nil
+		}
+		if subscription_result, err = s.recreateSubscription(); err != nil {
+			return err
+		}
+		s.object_class = object_class
+	}
+
+	now := time.Now()
+	for _, control := range subscription_result.Controls {
+		if control.Identifier == "" {
+			continue
+		}
+		s.ValueChannels.EmitTimeout(time.Second, SyncFeedback_Value{
+			Source:                 SyncFeedback_SourceName_Api,
+			Identifier:             control.Identifier,
+			PropertyName:           control.PropertyName,
+			CurrentValue:           control.CurrentValue,
+			CurrentNormalizedValue: control.CurrentNormalizedValue,
+			ReceivedAt:             now,
+		})
+	}
+	return nil
+}
+
+func (s *ApiSyncFeedbackSource) Run(ctx context.Context) func() {
+	ctx_with_cancel, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(API_SYNC_FEEDBACK_POLL_INTERVAL)
+		defer ticker.Stop()
+
+		backoff_until := time.Time{}
+
+		for {
+			select {
+			case <-ctx_with_cancel.Done():
+				return
+			case now := <-ticker.C:
+				if !s.enabled.Load() || !s.API.Enabled() || now.Before(backoff_until) {
+					continue
+				}
+				if err := s.poll(); err != nil {
+					logger.Logger.Error("[ApiSyncFeedbackSource::Run] could not read the API subscription", "error", err)
+					backoff_until = now.Add(API_SYNC_FEEDBACK_ERROR_BACKOFF)
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func NewApiSyncFeedbackSource(api *tswapi.TSWAPI, config ApiSyncFeedbackSource_Config) *ApiSyncFeedbackSource {
+	source := ApiSyncFeedbackSource{
+		API:           api,
+		ValueChannels: pubsub_utils.NewPubSubSlice[SyncFeedback_Value](),
+		config:        config,
+	}
+	source.enabled.Store(true)
+	return &source
+}
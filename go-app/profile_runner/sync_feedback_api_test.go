@@ -0,0 +1,109 @@
+package profile_runner
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tsw_controller_app/tswapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/* a minimal TSW HTTP API with a single control which only reports values once the subscription was created */
+type testTSWAPIServer struct {
+	mutex        sync.Mutex
+	object_class string
+	subscribed   map[string]bool
+	deletes      int
+}
+
+func (s *testTSWAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	respond := func(data any) {
+		json.NewEncoder(w).Encode(data)
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/list/CurrentDrivableActor":
+		respond(map[string]any{"Nodes": []any{map[string]any{"Name": "Throttle"}}})
+	case r.Method == "GET" && r.URL.Path == "/get/CurrentDrivableActor.ObjectClass":
+		respond(map[string]any{"Values": map[string]any{"ObjectClass": s.object_class}})
+	case r.Method == "GET" && r.URL.Path == "/get/CurrentDrivableActor/Throttle.InputValue":
+		respond(map[string]any{"Values": map[string]any{"InputValue": 0.5}})
+	case r.Method == "DELETE" && r.URL.Path == "/subscription":
+		s.deletes++
+		s.subscribed = map[string]bool{}
+		respond(map[string]any{})
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/subscription/"):
+		s.subscribed[strings.TrimPrefix(r.URL.Path, "/subscription/")] = true
+		respond(map[string]any{})
+	case r.Method == "GET" && r.URL.Path == "/subscription":
+		if len(s.subscribed) == 0 {
+			respond(map[string]any{"errorCode": "dtg.comm.InvalidSubscription", "errorMessage": "no such subscription"})
+			return
+		}
+		entry := func(path string, values map[string]any) map[string]any {
+			return map[string]any{"Path": path, "NodeValid": s.subscribed[path], "Values": values}
+		}
+		respond(map[string]any{"Entries": []any{
+			entry("CurrentDrivableActor.ObjectClass", map[string]any{"ObjectClass": s.object_class}),
+			entry("CurrentDrivableActor/Throttle.Property.InputIdentifier", map[string]any{"identifier": "Throttle1"}),
+			entry("CurrentDrivableActor/Throttle.InputValue", map[string]any{"InputValue": 0.5}),
+			entry("CurrentDrivableActor/Throttle.Function.GetNormalisedInputValue", map[string]any{"ReturnValue": 0.25}),
+		}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestApiSyncFeedbackSource_Poll(t *testing.T) {
+	server_state := &testTSWAPIServer{object_class: "Class101", subscribed: map[string]bool{}}
+	server := httptest.NewServer(server_state)
+	t.Cleanup(server.Close)
+
+	api := tswapi.NewTSWAPI(tswapi.TSWAPIConfig{BaseURL: server.URL})
+	source := NewApiSyncFeedbackSource(api, ApiSyncFeedbackSource_Config{SubscriptionID: 1})
+	values, unsubscribe := source.Subscribe()
+	t.Cleanup(unsubscribe)
+
+	/* without an API key nothing is polled */
+	require.NoError(t, source.poll())
+	assert.Empty(t, values)
+	assert.False(t, source.IsAvailable())
+
+	/* the subscription is created on the first poll and the values are emitted */
+	api.Config.CommAPIKey = "key"
+	require.NoError(t, source.poll())
+	require.Len(t, values, 1)
+	value := <-values
+	assert.Equal(t, SyncFeedback_SourceName_Api, value.Source)
+	assert.Equal(t, "Throttle1", value.Identifier)
+	assert.Equal(t, "Throttle", value.PropertyName)
+	assert.Equal(t, 0.5, value.CurrentValue)
+	assert.Equal(t, 0.25, value.CurrentNormalizedValue)
+	assert.WithinDuration(t, time.Now(), value.ReceivedAt, time.Second)
+	assert.True(t, source.IsAvailable())
+
+	/* the existing subscription is reused */
+	require.NoError(t, source.poll())
+	assert.Len(t, values, 1)
+	assert.Equal(t, 1, server_state.deletes)
+	<-values
+
+	/* changing the train re-creates the subscription */
+	server_state.mutex.Lock()
+	server_state.object_class = "Class166"
+	server_state.mutex.Unlock()
+	require.NoError(t, source.poll())
+	assert.Len(t, values, 1)
+	assert.Equal(t, 2, server_state.deletes)
+
+	source.SetEnabled(false)
+	assert.False(t, source.IsAvailable())
+}
@@ -0,0 +1,29 @@
+package profile_runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncFeedbackArbiter_PrefersFreshHigherPrioritySource(t *testing.T) {
+	arbiter := NewSyncFeedbackArbiter(SyncFeedback_SourceName_Mod, SyncFeedback_SourceName_Api)
+	start := time.Now()
+
+	assert.True(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Mod, Identifier: "Throttle1", ReceivedAt: start}))
+	/* the mod value is still fresh */
+	assert.False(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Api, Identifier: "Throttle1", ReceivedAt: start.Add(100 * time.Millisecond)}))
+	/* other controls are not affected */
+	assert.True(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Api, Identifier: "Reverser1", ReceivedAt: start.Add(100 * time.Millisecond)}))
+	/* the mod value went stale */
+	assert.True(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Api, Identifier: "Throttle1", ReceivedAt: start.Add(SYNC_FEEDBACK_STALE_TIMEOUT + time.Millisecond)}))
+	/* the mod always takes over again */
+	assert.True(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Mod, Identifier: "Throttle1", ReceivedAt: start.Add(SYNC_FEEDBACK_STALE_TIMEOUT + 2*time.Millisecond)}))
+	assert.False(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Api, Identifier: "Throttle1", ReceivedAt: start.Add(SYNC_FEEDBACK_STALE_TIMEOUT + 3*time.Millisecond)}))
+}
+
+func TestSyncFeedbackArbiter_IgnoresUnknownSource(t *testing.T) {
+	arbiter := NewSyncFeedbackArbiter(SyncFeedback_SourceName_Api)
+	assert.False(t, arbiter.Accept(SyncFeedback_Value{Source: SyncFeedback_SourceName_Mod, Identifier: "Throttle1", ReceivedAt: time.Now()}))
+}
@@ -0,0 +1,213 @@
+package profile_runner
+
+import (
+	"fmt"
+	"time"
+	"tsw_controller_app/action_sequencer"
+	"tsw_controller_app/config"
+	"tsw_controller_app/controller_mgr"
+	"tsw_controller_app/input_transform"
+	"tsw_controller_app/logger"
+	"tsw_controller_app/map_utils"
+)
+
+/* how often the unsettled transform pipelines (eg: rate limit, smoothing) are re-evaluated by the runner tick while the control is not moving */
+const TRANSFORM_TICK_INTERVAL = 50 * time.Millisecond
+
+/* the transform pipeline of a direct-like assignment and the last event and value it was evaluated for */
+type ProfileRunner_TransformState struct {
+	GUID            controller_mgr.JoystickGUIDString
+	ControlName     string
+	AssignmentIndex int
+	/* the zone name for the outputs of combined assignments */
+	Output     string
+	Assignment config.Config_Controller_Profile_Control_Assignment
+	InputValue *config.Config_Controller_Profile_Control_Assignment_DirectLike_InputValue
+	/* the input value with the detents of the control resolved */
+	ResolvedInputValue *config.Config_Controller_Profile_Control_Assignment_DirectLike_InputValue
+	Pipeline           *input_transform.Pipeline
+	ChangeEvent        controller_mgr.ControllerManager_Control_ChangeEvent
+	Value              float64
+	/* whether the output was returned to its neutral value (min) because the zone of a combined assignment is inactive */
+	Neutral bool
+}
+
+func transformStateKey(guid controller_mgr.JoystickGUIDString, control_name string, assignment_index int, output string) string {
+	return fmt.Sprintf("%s:%s:%d:%s", guid, control_name, assignment_index, output)
+}
+
+/*
+Returns the transform state for the assignment; the state is recreated when a different assignment is at the same index
+*/
+func (p *ProfileRunner) getTransformState(
+	control_name string,
+	assignment_index int,
+	output string,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+) *ProfileRunner_TransformState {
+	input_value := assignment.GetInputValue()
+	key := transformStateKey(change_event.Joystick.GUID, control_name, assignment_index, output)
+	state, has_state := p.TransformStates.Get(key)
+	if !has_state || state.InputValue != input_value {
+		resolved_input_value, missing_detents := input_value.ResolveDetents(change_event.Control.Calibration.NormalizedDetents())
+		if len(missing_detents) > 0 {
+			logger.Logger.Error("[ProfileRunner::getTransformState] skipping steps referencing detents which are not calibrated", "control", control_name, "detents", missing_detents)
+		}
+		state = &ProfileRunner_TransformState{
+			GUID:               change_event.Joystick.GUID,
+			ControlName:        control_name,
+			AssignmentIndex:    assignment_index,
+			Output:             output,
+			Assignment:         assignment,
+			InputValue:         input_value,
+			ResolvedInputValue: resolved_input_value,
+			Pipeline:           resolved_input_value.NewTransformPipeline(),
+		}
+		p.TransformStates.Set(key, state)
+	}
+	state.ChangeEvent = *change_event
+	return state
+}
+
+/*
+Calculates the output value of a direct-like assignment for the (normalized) value and sends it to the respective controller
+*/
+func (p *ProfileRunner) executeInputValueAssignment(
+	control_name string,
+	assignment_index int,
+	output string,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	value float64,
+	now time.Time,
+) {
+	state := p.getTransformState(control_name, assignment_index, output, change_event, assignment)
+	state.Value = value
+	state.Neutral = false
+	output_value := state.ResolvedInputValue.CalculateTransformedOutputValue(value, state.Pipeline, now)
+	p.sendInputValueAssignmentOutput(control_name, assignment_index, change_event, assignment, output_value)
+}
+
+/*
+Sends the output value of a direct-like assignment to the respective controller
+*/
+func (p *ProfileRunner) sendInputValueAssignmentOutput(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	assignment config.Config_Controller_Profile_Control_Assignment,
+	output_value float64,
+) {
+	if assignment.DirectControl != nil {
+		flags := []string{}
+		if assignment.DirectControl.Hold != nil && *assignment.DirectControl.Hold {
+			flags = append(flags, "hold")
+		}
+		p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, assignment, &ProfileRunnerAssignmentCall{
+			ControlState:          change_event.ControlState,
+			ActionSequencerAction: nil,
+			ApiControlCommand:     nil,
+			DirectControlCommand: &DirectController_Command{
+				Controls:   assignment.DirectControl.Controls,
+				InputValue: output_value,
+				Flags:      flags,
+			},
+		})
+	}
+	if assignment.ApiControl != nil {
+		p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, assignment, &ProfileRunnerAssignmentCall{
+			ControlState:          change_event.ControlState,
+			ActionSequencerAction: nil,
+			DirectControlCommand:  nil,
+			ApiControlCommand: &ApiController_Command{
+				Controls:   assignment.ApiControl.Controls,
+				InputValue: output_value,
+			},
+		})
+	}
+	if assignment.VirtualAxis != nil {
+		p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, assignment, &ProfileRunnerAssignmentCall{
+			ControlState: change_event.ControlState,
+			VirtualJoystickCommand: &VirtualJoystickController_Command{
+				Axis:  &assignment.VirtualAxis.Axis,
+				Value: output_value,
+			},
+		})
+	}
+	if assignment.MouseScroll != nil {
+		velocity := action_sequencer.ActionSequencerScrollVelocity{Y: output_value}
+		if assignment.MouseScroll.Direction != nil && *assignment.MouseScroll.Direction == "horizontal" {
+			velocity = action_sequencer.ActionSequencerScrollVelocity{X: output_value}
+		}
+		p.CallAssignmentActionForControl(change_event.Joystick.GUID, control_name, assignment_index, change_event.ControlState, assignment, &ProfileRunnerAssignmentCall{
+			ControlState: change_event.ControlState,
+			ActionSequencerAction: &action_sequencer.ActionSequencerAction{
+				Mouse: &action_sequencer.ActionSequencerMouseAction{
+					ScrollVelocity: &velocity,
+				},
+			},
+		})
+	}
+	if assignment.SyncControl != nil {
+		p.SyncController.UpdateControlStateTargetValue(assignment.SyncControl.Identifier, output_value, assignment.SyncControl, change_event)
+	}
+}
+
+/*
+Re-evaluates the transform pipelines which did not reach their target yet (eg: rate limited or smoothed values)
+*/
+func (p *ProfileRunner) settleTransformPipelines(now time.Time) {
+	var unsettled_states []*ProfileRunner_TransformState
+	p.TransformStates.ForEach(func(state *ProfileRunner_TransformState, key string) bool {
+		if !state.Pipeline.IsSettled() {
+			unsettled_states = append(unsettled_states, state)
+		}
+		return true
+	})
+
+	for _, state := range unsettled_states {
+		change_event := state.ChangeEvent
+		p.executeInputValueAssignment(state.ControlName, state.AssignmentIndex, state.Output, &change_event, state.Assignment, state.Value, now)
+	}
+}
+
+/*
+Sends the zone value to the output of the zone the lever is in; the outputs of the other zones are returned to their neutral value once
+
+The neutral value is the min of the output regardless of invert; the zone value 0 would be inverted to the max of the output
+*/
+func (p *ProfileRunner) executeCombinedAssignment(
+	control_name string,
+	assignment_index int,
+	change_event *controller_mgr.ControllerManager_Control_ChangeEvent,
+	combined *config.Config_Controller_Profile_Control_Assignment_Combined,
+	now time.Time,
+) {
+	active_zone, zone_value := combined.FindZone(change_event.Control.State.NormalizedValues.Value)
+	for _, zone := range combined.Zones() {
+		if zone.Name == active_zone {
+			p.executeInputValueAssignment(control_name, assignment_index, zone.Name, change_event, zone.Zone.Output, zone_value, now)
+			continue
+		}
+
+		state := p.getTransformState(control_name, assignment_index, zone.Name, change_event, zone.Zone.Output)
+		if state.Neutral {
+			continue
+		}
+		state.Neutral = true
+		state.Value = 0
+		/* the time based transforms start over once the zone is entered again */
+		state.Pipeline.Reset()
+		p.sendInputValueAssignmentOutput(control_name, assignment_index, change_event, zone.Zone.Output, state.ResolvedInputValue.Min)
+	}
+}
+
+func (p *ProfileRunner) clearTransformStates(match func(guid controller_mgr.JoystickGUIDString) bool) {
+	p.TransformStates.Mutate(func(state *ProfileRunner_TransformState, key string) map_utils.LockMapMutateAction[string, *ProfileRunner_TransformState] {
+		if match(state.GUID) {
+			return map_utils.LockMapMutateAction[string, *ProfileRunner_TransformState]{Action: map_utils.LockMapMutateActionType_Delete, Key: key}
+		}
+		return map_utils.LockMapMutateAction[string, *ProfileRunner_TransformState]{Action: map_utils.LockMapMutateActionType_Noop}
+	})
+}
@@ -0,0 +1,62 @@
+package profile_runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCombinedProfile = `{
+	"name": "Combined lever",
+	"controls": [
+		{
+			"name": "Lever1",
+			"assignment": {
+				"type": "combined",
+				"brake": {
+					"from": 0.5,
+					"to": 0,
+					"output": { "type": "direct_control", "controls": "TrainBrake1", "input_value": { "min": 0.25, "max": 1, "invert": true } }
+				},
+				"power": {
+					"from": 0.5,
+					"to": 1,
+					"output": { "type": "direct_control", "controls": "Throttle1", "input_value": { "min": 0, "max": 1 } }
+				}
+			}
+		}
+	]
+}`
+
+func TestCombined_NeutralizesInactiveZones(t *testing.T) {
+	runner, joystick, _ := newTestProfileRunner(t, testCombinedProfile)
+
+	/* the power output is neutralized once while braking */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0.5, 0.25))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "TrainBrake1", InputValue: 0.625},
+		{Controls: "Throttle1", InputValue: 0},
+	}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0.25, 0))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "TrainBrake1", InputValue: 0.25},
+	}, drainDirectControlCommands(runner))
+
+	/* crossing into the power zone returns the inverted brake output to its min instead of its max */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0, 0.75))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "TrainBrake1", InputValue: 0.25},
+		{Controls: "Throttle1", InputValue: 0.5},
+	}, drainDirectControlCommands(runner))
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 0.75, 1))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "Throttle1", InputValue: 1},
+	}, drainDirectControlCommands(runner))
+
+	/* and back into the brake zone */
+	runner.handleChangeEvent(newTestChangeEvent(joystick, "Lever1", 1, 0.25))
+	assert.Equal(t, []DirectController_Command{
+		{Controls: "TrainBrake1", InputValue: 0.625},
+		{Controls: "Throttle1", InputValue: 0},
+	}, drainDirectControlCommands(runner))
+}
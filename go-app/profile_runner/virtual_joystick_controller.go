@@ -0,0 +1,178 @@
+package profile_runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tsw_controller_app/chan_utils"
+	"tsw_controller_app/logger"
+	"tsw_controller_app/virtual_joystick"
+)
+
+const VIRTUAL_JOYSTICK_CONTROLLER_QUEUE_BUFFER_SIZE = 32
+
+type VirtualJoystickController_Command struct {
+	/* either an axis or a button is set */
+	Axis   *string
+	Button *string
+	/* the axis value (0 to 1) or 1 for pressing and 0 for releasing a button */
+	Value float64
+	/* identifies who holds a button (eg: the joystick and control); a button is only released once all of its holders released it */
+	Source string
+	/* releases the buttons held by the matching sources; used when switching profiles or removing a joystick */
+	ReleaseSources func(source string) bool
+}
+
+/* a virtual button held down by one or more sources */
+type VirtualJoystickController_PressedButton struct {
+	Button  virtual_joystick.Button
+	Holders map[string]int
+}
+
+type VirtualJoystickController struct {
+	Output         virtual_joystick.Output
+	ControlChannel chan VirtualJoystickController_Command
+	/* only accessed from the controller go-routine */
+	pressed map[int]*VirtualJoystickController_PressedButton
+	/* commands are dropped while the output is not available; only logged once */
+	unavailable_logged bool
+}
+
+func (c *VirtualJoystickController_Command) ToString() string {
+	if c.Axis != nil {
+		return fmt.Sprintf("virtual_axis:%s:%f", *c.Axis, c.Value)
+	}
+	if c.Button != nil {
+		return fmt.Sprintf("virtual_button:%s:%f", *c.Button, c.Value)
+	}
+	return "virtual_release"
+}
+
+/* whether the virtual joystick can be used on this platform */
+func (controller *VirtualJoystickController) IsAvailable() bool {
+	return controller.Output.IsAvailable()
+}
+
+func (controller *VirtualJoystickController) ReleaseAll() {
+	controller.ReleaseSources(func(source string) bool { return true })
+}
+
+/*
+Releases the buttons held by the matching sources; buttons which are also held by other sources stay pressed
+*/
+func (controller *VirtualJoystickController) ReleaseSources(match func(source string) bool) {
+	chan_utils.SendTimeout(controller.ControlChannel, time.Second, VirtualJoystickController_Command{ReleaseSources: match})
+}
+
+func (controller *VirtualJoystickController) setButton(button virtual_joystick.Button, pressed bool) {
+	if err := controller.Output.SetButton(button, pressed); err != nil {
+		logger.Logger.Error("[VirtualJoystickController::setButton] could not set button", "button", button.Number, "pressed", pressed, "error", err)
+	}
+}
+
+func (controller *VirtualJoystickController) releaseSources(match func(source string) bool) {
+	for number, pressed_button := range controller.pressed {
+		for source := range pressed_button.Holders {
+			if match(source) {
+				delete(pressed_button.Holders, source)
+			}
+		}
+		if len(pressed_button.Holders) == 0 {
+			controller.setButton(pressed_button.Button, false)
+			delete(controller.pressed, number)
+		}
+	}
+}
+
+func (controller *VirtualJoystickController) pressButton(button virtual_joystick.Button, source string) {
+	pressed_button, is_pressed := controller.pressed[button.Number]
+	if !is_pressed {
+		pressed_button = &VirtualJoystickController_PressedButton{Button: button, Holders: map[string]int{}}
+		controller.pressed[button.Number] = pressed_button
+		controller.setButton(button, true)
+	}
+	pressed_button.Holders[source]++
+}
+
+func (controller *VirtualJoystickController) releaseButton(button virtual_joystick.Button, source string) {
+	pressed_button, is_pressed := controller.pressed[button.Number]
+	if !is_pressed || pressed_button.Holders[source] == 0 {
+		/* this source is not holding the button; releasing would break other holders */
+		return
+	}
+	pressed_button.Holders[source]--
+	if pressed_button.Holders[source] == 0 {
+		delete(pressed_button.Holders, source)
+	}
+	if len(pressed_button.Holders) == 0 {
+		controller.setButton(button, false)
+		delete(controller.pressed, button.Number)
+	}
+}
+
+func (controller *VirtualJoystickController) handleCommand(command VirtualJoystickController_Command) {
+	if command.ReleaseSources != nil {
+		controller.releaseSources(command.ReleaseSources)
+		return
+	}
+
+	if !controller.Output.IsAvailable() {
+		if !controller.unavailable_logged {
+			logger.Logger.Error("[VirtualJoystickController::handleCommand] the virtual joystick is not available on this system; skipping virtual joystick actions", "command", command.ToString())
+			controller.unavailable_logged = true
+		}
+		return
+	}
+
+	if command.Axis != nil {
+		axis, err := virtual_joystick.ParseAxis(*command.Axis)
+		if err != nil {
+			logger.Logger.Error("[VirtualJoystickController::handleCommand] skipping command", "error", err)
+			return
+		}
+		if err := controller.Output.SetAxis(axis, command.Value); err != nil {
+			logger.Logger.Error("[VirtualJoystickController::handleCommand] could not set axis", "axis", axis.Name, "error", err)
+		}
+		return
+	}
+
+	if command.Button != nil {
+		button, err := virtual_joystick.ParseButton(*command.Button)
+		if err != nil {
+			logger.Logger.Error("[VirtualJoystickController::handleCommand] skipping command", "error", err)
+			return
+		}
+		if command.Value >= 0.5 {
+			controller.pressButton(button, command.Source)
+		} else {
+			controller.releaseButton(button, command.Source)
+		}
+	}
+}
+
+func (controller *VirtualJoystickController) Run(ctx context.Context) func() {
+	ctx_with_cancel, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer controller.releaseSources(func(source string) bool { return true })
+		for {
+			select {
+			case <-ctx_with_cancel.Done():
+				return
+			case command := <-controller.ControlChannel:
+				controller.handleCommand(command)
+			}
+		}
+	}()
+
+	return cancel
+}
+
+func NewVirtualJoystickController(output virtual_joystick.Output) *VirtualJoystickController {
+	controller := VirtualJoystickController{
+		Output:         output,
+		ControlChannel: make(chan VirtualJoystickController_Command, VIRTUAL_JOYSTICK_CONTROLLER_QUEUE_BUFFER_SIZE),
+		pressed:        map[int]*VirtualJoystickController_PressedButton{},
+	}
+	return &controller
+}
@@ -0,0 +1,69 @@
+package profile_runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"tsw_controller_app/virtual_joystick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestVirtualJoystickController(t *testing.T) (*VirtualJoystickController, *virtual_joystick.RecordingOutput) {
+	output := virtual_joystick.NewRecordingOutput()
+	controller := NewVirtualJoystickController(output)
+	t.Cleanup(controller.Run(context.Background()))
+	return controller, output
+}
+
+func virtualButtonCommand(button string, value float64, source string) VirtualJoystickController_Command {
+	return VirtualJoystickController_Command{Button: &button, Value: value, Source: source}
+}
+
+func TestVirtualJoystickController_SharedButtonIsReleasedByLastHolder(t *testing.T) {
+	controller, output := newTestVirtualJoystickController(t)
+	controller.ControlChannel <- virtualButtonCommand("3", 1, "a/Horn")
+	controller.ControlChannel <- virtualButtonCommand("3", 1, "b/Horn")
+	/* a source which is not holding the button can't release it */
+	controller.ControlChannel <- virtualButtonCommand("3", 0, "c/Horn")
+	controller.ControlChannel <- virtualButtonCommand("3", 0, "a/Horn")
+	assert.Eventually(t, func() bool { return len(controller.ControlChannel) == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []virtual_joystick.RecordingOutput_Event{{Button: 3, Value: 1}}, output.Events())
+
+	controller.ControlChannel <- virtualButtonCommand("3", 0, "b/Horn")
+	assert.Eventually(t, func() bool { return len(output.Events()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, virtual_joystick.RecordingOutput_Event{Button: 3, Value: 0}, output.Events()[1])
+}
+
+func TestVirtualJoystickController_ReleaseSources(t *testing.T) {
+	controller, output := newTestVirtualJoystickController(t)
+	controller.ControlChannel <- virtualButtonCommand("1", 1, "a/Horn")
+	controller.ControlChannel <- virtualButtonCommand("2", 1, "a/Bell")
+	controller.ControlChannel <- virtualButtonCommand("2", 1, "b/Bell")
+	controller.ReleaseSources(func(source string) bool { return strings.HasPrefix(source, "a/") })
+	assert.Eventually(t, func() bool { return len(output.Events()) == 3 }, time.Second, 5*time.Millisecond)
+	/* button 2 is still held by the other joystick */
+	assert.Equal(t, virtual_joystick.RecordingOutput_Event{Button: 1, Value: 0}, output.Events()[2])
+
+	controller.ReleaseAll()
+	assert.Eventually(t, func() bool { return len(output.Events()) == 4 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, virtual_joystick.RecordingOutput_Event{Button: 2, Value: 0}, output.Events()[3])
+}
+
+func TestVirtualJoystickController_SkipsCommandsWhenUnavailable(t *testing.T) {
+	output := virtual_joystick.NewRecordingOutput()
+	controller := NewVirtualJoystickController(output)
+	output.SetAvailable(false)
+	assert.False(t, controller.IsAvailable())
+
+	axis := "x"
+	controller.handleCommand(VirtualJoystickController_Command{Axis: &axis, Value: 0.5})
+	controller.handleCommand(virtualButtonCommand("1", 1, "a/Horn"))
+	assert.Empty(t, output.Events())
+	assert.Empty(t, controller.pressed)
+
+	output.SetAvailable(true)
+	controller.handleCommand(VirtualJoystickController_Command{Axis: &axis, Value: 0.25})
+	assert.Equal(t, []virtual_joystick.RecordingOutput_Event{{Axis: "x", Value: 0.25}}, output.Events())
+}
@@ -0,0 +1,72 @@
+package sdl_mgr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/* the name of the SDL GameController database file in the configuration directories */
+const GAMECONTROLLER_DB_FILENAME = "gamecontrollerdb.txt"
+
+/* a named button or axis of the standard gamepad layout of the SDL GameController API */
+type SDLMgr_GameControllerControl struct {
+	Kind  SDLMgr_Control_Kind
+	Index int
+	Name  string
+	/* triggers rest at 0 and only report positive values; the sticks rest in the middle */
+	IsTrigger bool
+	/* SDL reports up as negative on the vertical stick axes */
+	IsVertical bool
+}
+
+var SDLMgr_GameControllerAxes = []SDLMgr_GameControllerControl{
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_LEFTX, Name: "LeftStickX"},
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_LEFTY, Name: "LeftStickY", IsVertical: true},
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_RIGHTX, Name: "RightStickX"},
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_RIGHTY, Name: "RightStickY", IsVertical: true},
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_TRIGGERLEFT, Name: "LeftTrigger", IsTrigger: true},
+	{Kind: SDLMgr_Control_Kind_ControllerAxis, Index: sdl.CONTROLLER_AXIS_TRIGGERRIGHT, Name: "RightTrigger", IsTrigger: true},
+}
+
+var SDLMgr_GameControllerButtons = []SDLMgr_GameControllerControl{
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_A, Name: "A"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_B, Name: "B"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_X, Name: "X"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_Y, Name: "Y"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_BACK, Name: "Back"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_GUIDE, Name: "Guide"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_START, Name: "Start"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_LEFTSTICK, Name: "LeftStick"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_RIGHTSTICK, Name: "RightStick"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_LEFTSHOULDER, Name: "LeftShoulder"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_RIGHTSHOULDER, Name: "RightShoulder"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_DPAD_UP, Name: "DPadUp"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_DPAD_DOWN, Name: "DPadDown"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_DPAD_LEFT, Name: "DPadLeft"},
+	{Kind: SDLMgr_Control_Kind_ControllerButton, Index: sdl.CONTROLLER_BUTTON_DPAD_RIGHT, Name: "DPadRight"},
+}
+
+/*
+Adds the mappings of an SDL GameController database (eg: the community gamecontrollerdb.txt) so SDL recognizes more
+devices as game controllers; empty lines and comments are skipped. Returns how many mappings were added or updated and
+the lines SDL rejected
+*/
+func AddGameControllerMappings(db string) (int, []error) {
+	added := 0
+	var errors []error
+	for line_index, line := range strings.Split(db, "\n") {
+		mapping := strings.TrimSpace(line)
+		if mapping == "" || strings.HasPrefix(mapping, "#") {
+			continue
+		}
+		/* 1 when added, 0 when an existing mapping was updated and -1 on error */
+		if sdl.GameControllerAddMapping(mapping) < 0 {
+			errors = append(errors, fmt.Errorf("could not add the game controller mapping on line %d (%v)", line_index+1, sdl.GetError()))
+			continue
+		}
+		added++
+	}
+	return added, errors
+}
@@ -0,0 +1,55 @@
+package sdl_mgr
+
+import "github.com/veandco/go-sdl2/sdl"
+
+/* the named position of a hat switch */
+type SDLMgr_HatDirection = string
+
+const (
+	SDLMgr_HatDirection_Centered  SDLMgr_HatDirection = "centered"
+	SDLMgr_HatDirection_Up        SDLMgr_HatDirection = "up"
+	SDLMgr_HatDirection_UpRight   SDLMgr_HatDirection = "up_right"
+	SDLMgr_HatDirection_Right     SDLMgr_HatDirection = "right"
+	SDLMgr_HatDirection_DownRight SDLMgr_HatDirection = "down_right"
+	SDLMgr_HatDirection_Down      SDLMgr_HatDirection = "down"
+	SDLMgr_HatDirection_DownLeft  SDLMgr_HatDirection = "down_left"
+	SDLMgr_HatDirection_Left      SDLMgr_HatDirection = "left"
+	SDLMgr_HatDirection_UpLeft    SDLMgr_HatDirection = "up_left"
+)
+
+/* the 8 directions of a hat switch in clockwise order starting at up */
+var SDLMgr_HatDirections = []SDLMgr_HatDirection{
+	SDLMgr_HatDirection_Up,
+	SDLMgr_HatDirection_UpRight,
+	SDLMgr_HatDirection_Right,
+	SDLMgr_HatDirection_DownRight,
+	SDLMgr_HatDirection_Down,
+	SDLMgr_HatDirection_DownLeft,
+	SDLMgr_HatDirection_Left,
+	SDLMgr_HatDirection_UpLeft,
+}
+
+/*
+decodes the SDL hat bitmask into its direction; impossible combinations (eg: up and down) are considered centered
+*/
+func HatDirectionFromValue(value uint8) SDLMgr_HatDirection {
+	switch value {
+	case sdl.HAT_UP:
+		return SDLMgr_HatDirection_Up
+	case sdl.HAT_RIGHTUP:
+		return SDLMgr_HatDirection_UpRight
+	case sdl.HAT_RIGHT:
+		return SDLMgr_HatDirection_Right
+	case sdl.HAT_RIGHTDOWN:
+		return SDLMgr_HatDirection_DownRight
+	case sdl.HAT_DOWN:
+		return SDLMgr_HatDirection_Down
+	case sdl.HAT_LEFTDOWN:
+		return SDLMgr_HatDirection_DownLeft
+	case sdl.HAT_LEFT:
+		return SDLMgr_HatDirection_Left
+	case sdl.HAT_LEFTUP:
+		return SDLMgr_HatDirection_UpLeft
+	}
+	return SDLMgr_HatDirection_Centered
+}
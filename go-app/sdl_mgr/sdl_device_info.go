@@ -0,0 +1,59 @@
+//go:build cgo
+
+package sdl_mgr
+
+/*
+#cgo windows LDFLAGS: -lSDL2
+#cgo linux freebsd darwin openbsd pkg-config: sdl2
+#if defined(_WIN32)
+	#include <SDL2/SDL.h>
+#else
+	#include <SDL.h>
+#endif
+
+static const char *tsw_joystick_path_for_index(int index) {
+#if SDL_VERSION_ATLEAST(2, 24, 0)
+	return SDL_JoystickPathForIndex(index);
+#else
+	return NULL;
+#endif
+}
+
+static const char *tsw_joystick_serial(void *joystick) {
+#if SDL_VERSION_ATLEAST(2, 0, 14)
+	return SDL_JoystickGetSerial((SDL_Joystick *)joystick);
+#else
+	return NULL;
+#endif
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+go-sdl2 does not wrap SDL_JoystickPathForIndex and SDL_JoystickGetSerial
+both return an empty string when the SDL version or the device does not support it
+*/
+func joystickPathForIndex(index int) string {
+	path := C.tsw_joystick_path_for_index(C.int(index))
+	if path == nil {
+		return ""
+	}
+	return C.GoString(path)
+}
+
+func joystickSerial(joystick *sdl.Joystick) string {
+	if joystick == nil {
+		return ""
+	}
+	serial := C.tsw_joystick_serial(unsafe.Pointer(joystick))
+	if serial == nil {
+		return ""
+	}
+	return C.GoString(serial)
+}
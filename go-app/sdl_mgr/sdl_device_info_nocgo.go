@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package sdl_mgr
+
+import "github.com/veandco/go-sdl2/sdl"
+
+/* the device path and serial are only available through cgo */
+func joystickPathForIndex(index int) string {
+	return ""
+}
+
+func joystickSerial(joystick *sdl.Joystick) string {
+	return ""
+}
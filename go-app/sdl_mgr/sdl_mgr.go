@@ -3,6 +3,7 @@ package sdl_mgr
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 	"tsw_controller_app/chan_utils"
 	"tsw_controller_app/logger"
@@ -21,17 +22,34 @@ const (
 	SDLMgr_Control_Kind_Button SDLMgr_Control_Kind = "button"
 	SDLMgr_Control_Kind_Hat    SDLMgr_Control_Kind = "hat"
 	SDLMgr_Control_Kind_Axis   SDLMgr_Control_Kind = "axis"
+	/* the named buttons and axes of the SDL GameController API; the index is the SDL GameController button or axis */
+	SDLMgr_Control_Kind_ControllerButton SDLMgr_Control_Kind = "controller_button"
+	SDLMgr_Control_Kind_ControllerAxis   SDLMgr_Control_Kind = "controller_axis"
 )
 
 type SDLMgr_Joystick struct {
-	GUID      SDLMgr_Guid_Str
+	/* the identity of the device within the app; defaults to the SDL GUID until resolved by the controller manager */
+	GUID SDLMgr_Guid_Str
+	/* the SDL GUID; identical for all devices of the same model */
+	SDLGUID   SDLMgr_Guid_Str
 	Name      string
 	VendorID  int
 	ProductID int
-	Index     int
+	/* the device index; shifts when other devices are removed */
+	Index int
+	/* the SDL instance ID used by the joystick events; stays the same while the device is connected */
+	InstanceID int
+	/* the platform dependent device path (eg: the USB port); empty if not supported */
+	Path string
+	/* the serial number of the device; only available once opened and empty if not reported */
+	Serial string
 
-	IsOpen           bool
-	InternalJoystick *sdl.Joystick
+	/* SDL knows the layout of the device (eg: Xbox or PlayStation pads) and reports its standard named buttons and axes */
+	IsGameController bool
+
+	IsOpen                 bool
+	InternalJoystick       *sdl.Joystick
+	InternalGameController *sdl.GameController
 }
 
 type SDLMgr struct {
@@ -77,17 +95,22 @@ func (mgr *SDLMgr) GetJoystickByIndex(index int) (*SDLMgr_Joystick, error) {
 	}
 
 	name := sdl.JoystickNameForIndex(index)
-	guid := sdl.JoystickGetDeviceGUID(index)
+	guid := sdl.JoystickGetGUIDString(sdl.JoystickGetDeviceGUID(index))
 	usb_vendor := sdl.JoystickGetDeviceVendor(index)
 	usb_product := sdl.JoystickGetDeviceProduct(index)
 
 	return &SDLMgr_Joystick{
-		GUID:      sdl.JoystickGetGUIDString(guid),
-		Name:      name,
-		VendorID:  usb_vendor,
-		ProductID: usb_product,
-		Index:     index,
-		IsOpen:    false,
+		GUID:       guid,
+		SDLGUID:    guid,
+		Name:       name,
+		VendorID:   usb_vendor,
+		ProductID:  usb_product,
+		Index:      index,
+		InstanceID: int(sdl.JoystickGetDeviceInstanceID(index)),
+		Path:       joystickPathForIndex(index),
+		IsOpen:     false,
+
+		IsGameController: sdl.IsGameController(index),
 	}, nil
 }
 
@@ -145,6 +168,22 @@ func (mgr *SDLMgr) StartPolling(ctx context.Context) (chan sdl.Event, context.Ca
 						Axis:      e.Axis,
 						Value:     e.Value,
 					})
+				case *sdl.ControllerButtonEvent:
+					chan_utils.SendTimeout[sdl.Event](event_channel, time.Second, &sdl.ControllerButtonEvent{
+						Type:      e.Type,
+						Timestamp: e.Timestamp,
+						Which:     e.Which,
+						Button:    e.Button,
+						State:     e.State,
+					})
+				case *sdl.ControllerAxisEvent:
+					chan_utils.SendTimeout[sdl.Event](event_channel, time.Second, &sdl.ControllerAxisEvent{
+						Type:      e.Type,
+						Timestamp: e.Timestamp,
+						Which:     e.Which,
+						Axis:      e.Axis,
+						Value:     e.Value,
+					})
 				}
 			}
 		}
@@ -167,9 +206,41 @@ func (joystick *SDLMgr_Joystick) Open() error {
 		return fmt.Errorf("could not open joystick for use")
 	}
 	joystick.IsOpen = true
+	joystick.Serial = joystickSerial(joystick.InternalJoystick)
+
+	/* the game controller shares the opened device; it only adds the standard named buttons and axes */
+	if joystick.IsGameController {
+		joystick.InternalGameController = sdl.GameControllerOpen(joystick.Index)
+		if joystick.InternalGameController == nil {
+			logger.Logger.Error("[SDLMgr_Joystick::Open] could not open game controller, using the joystick only", "joystick", joystick.ToString(), "error", sdl.GetError())
+			joystick.IsGameController = false
+		}
+	}
 	return nil
 }
 
+/* whether the device can rumble; only known for game controllers */
+func (joystick *SDLMgr_Joystick) HasRumble() bool {
+	return joystick.InternalGameController != nil && joystick.InternalGameController.HasRumble()
+}
+
+/*
+Rumbles the device with the strength (0-1) of the low and high frequency motors for the duration; a new rumble replaces
+the current one
+*/
+func (joystick *SDLMgr_Joystick) Rumble(low_frequency float64, high_frequency float64, duration time.Duration) error {
+	if !joystick.IsOpen {
+		return fmt.Errorf("joystick is not open")
+	}
+	low := uint16(math.Max(0, math.Min(1, low_frequency)) * math.MaxUint16)
+	high := uint16(math.Max(0, math.Min(1, high_frequency)) * math.MaxUint16)
+	duration_ms := uint32(duration.Milliseconds())
+	if joystick.InternalGameController != nil {
+		return joystick.InternalGameController.Rumble(low, high, duration_ms)
+	}
+	return joystick.InternalJoystick.Rumble(low, high, duration_ms)
+}
+
 func (joystick *SDLMgr_Joystick) Close() error {
 	if !joystick.IsOpen {
 		return fmt.Errorf("joystick is not open")
@@ -179,6 +250,10 @@ func (joystick *SDLMgr_Joystick) Close() error {
 		return fmt.Errorf("internal joystick not assigned")
 	}
 
+	if joystick.InternalGameController != nil {
+		joystick.InternalGameController.Close()
+		joystick.InternalGameController = nil
+	}
 	joystick.InternalJoystick.Close()
 	joystick.IsOpen = false
 	joystick.InternalJoystick = nil
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,6 +21,8 @@ type TSWAPI struct {
 	transport *http.Transport
 	client    *http.Client
 	Config    TSWAPIConfig
+	/* whether the last request failed to reach the API */
+	unreachable atomic.Bool
 }
 
 var ErrMissingCommAPIKey = errors.New("missing CommAPIKey")
@@ -59,8 +62,10 @@ func (c *TSWAPI) executeTswApiRequest(req *http.Request) (map[string]any, error)
 				continue
 			}
 
+			c.unreachable.Store(true)
 			return nil, fmt.Errorf("api error: %w", err)
 		}
+		c.unreachable.Store(false)
 		if resp.StatusCode >= 300 {
 			return nil, ErrNonSuccessStatusCode
 		}
@@ -244,12 +249,26 @@ func (c *TSWAPI) LoadAPIKey(path string) error {
 	}
 
 	c.Config.CommAPIKey = string(key_bytes)
+	/* a newly loaded key is considered usable until a request fails */
+	c.unreachable.Store(false)
 	return nil
 }
 
+/*
+Sends a request to check whether the API can be reached, the result is reflected by CanConnect
+*/
+func (c *TSWAPI) Probe() error {
+	req_url := fmt.Sprintf("%s/info", c.Config.BaseURL)
+	probe_req, _ := http.NewRequest("GET", req_url, nil)
+	_, err := c.executeTswApiRequest(probe_req)
+	return err
+}
+
+/*
+Returns whether the API can be used; ie: the API key is loaded and the last request did not fail to reach the API
+*/
 func (c *TSWAPI) CanConnect() bool {
-	// @TODO
-	return true
+	return c.Enabled() && !c.unreachable.Load()
 }
 
 func (c *TSWAPI) Enabled() bool {
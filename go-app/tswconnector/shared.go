@@ -16,6 +16,8 @@ type TSWConnector interface {
 	Stop() error
 	Subscribe() (chan TSWConnector_Message, func())
 	Send(m TSWConnector_Message) error
+	/* whether the connection to the game (mod) is currently established */
+	IsConnected() bool
 }
 
 func TSWConnector_Message_FromString(msg string) TSWConnector_Message {
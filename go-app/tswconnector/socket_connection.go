@@ -86,6 +86,15 @@ func (c *SocketConnection) Subscribe() (chan TSWConnector_Message, func()) {
 	return c.Subscribers.Subscribe()
 }
 
+func (c *SocketConnection) IsConnected() bool {
+	is_connected := false
+	c.OutgoingChannels.ForEach(func(channel chan TSWConnector_Message, key uuid.UUID) bool {
+		is_connected = true
+		return false
+	})
+	return is_connected
+}
+
 func (c *SocketConnection) Stop() error {
 	return c.Server.Close()
 }
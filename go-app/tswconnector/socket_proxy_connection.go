@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"sync/atomic"
 	"time"
 	"tsw_controller_app/chan_utils"
 	"tsw_controller_app/logger"
@@ -35,6 +36,7 @@ type SocketProxyConnection struct {
 	ServerAddr      string
 	OutgoingChannel chan TSWConnector_Message
 	Subscribers     *pubsub_utils.PubSubSlice[TSWConnector_Message]
+	connected       atomic.Bool
 }
 
 var _ TSWConnector = (*SocketProxyConnection)(nil)
@@ -93,6 +95,7 @@ func (c *SocketProxyConnection) Start() error {
 			return nil
 		}
 		defer connection.Close()
+		c.connected.Store(true)
 
 		sender_ctx, cancel_sender := context.WithCancel(c.context)
 		go func() {
@@ -115,17 +118,23 @@ func (c *SocketProxyConnection) Start() error {
 			select {
 			case msg := <-c.waitForMessage(connection):
 				if msg.err != nil {
+					c.connected.Store(false)
 					break read_loop
 				}
 				socket_message := TSWConnector_Message_FromString(msg.message)
 				c.Subscribers.EmitTimeout(time.Second, socket_message)
 			case <-c.context.Done():
+				c.connected.Store(false)
 				return nil
 			}
 		}
 	}
 }
 
+func (c *SocketProxyConnection) IsConnected() bool {
+	return c.connected.Load()
+}
+
 func (c *SocketProxyConnection) Stop() error {
 	c.cancel()
 	return nil
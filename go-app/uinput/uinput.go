@@ -0,0 +1,47 @@
+package uinput
+
+import "errors"
+
+/*
+Low level access to the Linux uinput module; used to create virtual input devices.
+Virtual devices work independently of the desktop session (eg: Wayland or headless setups)
+but require write access to /dev/uinput.
+*/
+
+const DEVICE_PATH = "/dev/uinput"
+
+/* event types; see linux/input-event-codes.h */
+const (
+	EV_SYN uint16 = 0x00
+	EV_KEY uint16 = 0x01
+	EV_REL uint16 = 0x02
+	EV_ABS uint16 = 0x03
+)
+
+const SYN_REPORT uint16 = 0
+
+const BUS_VIRTUAL uint16 = 0x06
+
+var ErrUnsupported = errors.New("uinput is only supported on linux")
+var ErrClosed = errors.New("uinput device is closed")
+
+type AbsAxisConfig struct {
+	Code uint16
+	Min  int32
+	Max  int32
+	Fuzz int32
+	Flat int32
+}
+
+type DeviceConfig struct {
+	Name    string
+	Vendor  uint16
+	Product uint16
+	Version uint16
+	/* the key (or button) codes the device can emit */
+	Keys []uint16
+	/* the relative axes the device can emit */
+	RelAxes []uint16
+	/* the absolute axes the device can emit */
+	AbsAxes []AbsAxisConfig
+}
@@ -0,0 +1,163 @@
+//go:build linux
+
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ui_DEV_CREATE  = 0x5501
+	ui_DEV_DESTROY = 0x5502
+	ui_SET_EVBIT   = 0x40045564
+	ui_SET_KEYBIT  = 0x40045565
+	ui_SET_RELBIT  = 0x40045566
+	ui_SET_ABSBIT  = 0x40045567
+)
+
+/* whether virtual devices can be created on this platform */
+const IS_SUPPORTED = true
+
+const uinput_MAX_NAME_SIZE = 80
+const abs_CNT = 64
+
+/* struct uinput_user_dev; used by the legacy (but universally supported) setup interface */
+type uinputUserDev struct {
+	Name         [uinput_MAX_NAME_SIZE]byte
+	BusType      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	AbsMax       [abs_CNT]int32
+	AbsMin       [abs_CNT]int32
+	AbsFuzz      [abs_CNT]int32
+	AbsFlat      [abs_CNT]int32
+}
+
+/* struct input_event; the timeval matches the layout of the platform (32-bit on 32-bit platforms) */
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+type Device struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func Open(config DeviceConfig) (*Device, error) {
+	file, err := os.OpenFile(DEVICE_PATH, os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", DEVICE_PATH, err)
+	}
+
+	if err := setup(file, config); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	/* give the system some time to register the device before sending events */
+	time.Sleep(100 * time.Millisecond)
+	return &Device{file: file}, nil
+}
+
+func setup(file *os.File, config DeviceConfig) error {
+	fd := int(file.Fd())
+	set_bits := func(ev_type uint16, bit_request uint, codes []uint16) error {
+		if len(codes) == 0 {
+			return nil
+		}
+		if err := unix.IoctlSetInt(fd, ui_SET_EVBIT, int(ev_type)); err != nil {
+			return fmt.Errorf("could not enable event type %d: %w", ev_type, err)
+		}
+		for _, code := range codes {
+			if err := unix.IoctlSetInt(fd, bit_request, int(code)); err != nil {
+				return fmt.Errorf("could not enable event code %d: %w", code, err)
+			}
+		}
+		return nil
+	}
+
+	abs_codes := make([]uint16, 0, len(config.AbsAxes))
+	for _, axis := range config.AbsAxes {
+		abs_codes = append(abs_codes, axis.Code)
+	}
+	if err := set_bits(EV_KEY, ui_SET_KEYBIT, config.Keys); err != nil {
+		return err
+	}
+	if err := set_bits(EV_REL, ui_SET_RELBIT, config.RelAxes); err != nil {
+		return err
+	}
+	if err := set_bits(EV_ABS, ui_SET_ABSBIT, abs_codes); err != nil {
+		return err
+	}
+
+	dev := uinputUserDev{
+		BusType: BUS_VIRTUAL,
+		Vendor:  config.Vendor,
+		Product: config.Product,
+		Version: config.Version,
+	}
+	copy(dev.Name[:uinput_MAX_NAME_SIZE-1], config.Name)
+	for _, axis := range config.AbsAxes {
+		dev.AbsMin[axis.Code] = axis.Min
+		dev.AbsMax[axis.Code] = axis.Max
+		dev.AbsFuzz[axis.Code] = axis.Fuzz
+		dev.AbsFlat[axis.Code] = axis.Flat
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.NativeEndian, dev)
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("could not write device description: %w", err)
+	}
+	if err := unix.IoctlSetInt(fd, ui_DEV_CREATE, 0); err != nil {
+		return fmt.Errorf("could not create device: %w", err)
+	}
+	return nil
+}
+
+/* writes a single event; call Sync to make the events visible */
+func (d *Device) Emit(ev_type uint16, code uint16, value int32) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.file == nil {
+		return ErrClosed
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.NativeEndian, inputEvent{
+		Time:  unix.NsecToTimeval(time.Now().UnixNano()),
+		Type:  ev_type,
+		Code:  code,
+		Value: value,
+	})
+	_, err := d.file.Write(buf.Bytes())
+	return err
+}
+
+func (d *Device) Sync() error {
+	return d.Emit(EV_SYN, SYN_REPORT, 0)
+}
+
+func (d *Device) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	unix.IoctlSetInt(int(d.file.Fd()), ui_DEV_DESTROY, 0)
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package uinput
+
+/* whether virtual devices can be created on this platform */
+const IS_SUPPORTED = false
+
+type Device struct{}
+
+func Open(config DeviceConfig) (*Device, error) {
+	return nil, ErrUnsupported
+}
+
+func (d *Device) Emit(ev_type uint16, code uint16, value int32) error {
+	return ErrUnsupported
+}
+
+func (d *Device) Sync() error {
+	return ErrUnsupported
+}
+
+func (d *Device) Close() error {
+	return nil
+}
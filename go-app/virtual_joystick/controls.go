@@ -0,0 +1,87 @@
+package virtual_joystick
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownAxis = errors.New("unknown virtual axis")
+var ErrUnknownButton = errors.New("unknown virtual button")
+
+/* the number of buttons exposed by the virtual joystick */
+const BUTTON_COUNT = 32
+
+type Axis struct {
+	Name string
+	/* the Linux absolute axis code (see linux/input-event-codes.h) */
+	Code uint16
+}
+
+type Button struct {
+	/* the button number; starting at 1 */
+	Number int
+	/* the Linux button code (see linux/input-event-codes.h) */
+	Code uint16
+}
+
+var known_axes = map[string]uint16{
+	"x":        0x00,
+	"y":        0x01,
+	"z":        0x02,
+	"rx":       0x03,
+	"ry":       0x04,
+	"rz":       0x05,
+	"throttle": 0x06,
+	"rudder":   0x07,
+	"wheel":    0x08,
+	"gas":      0x09,
+	"brake":    0x0a,
+}
+
+/* returns all the axes exposed by the virtual joystick */
+func Axes() []Axis {
+	axes := make([]Axis, 0, len(known_axes))
+	for name, code := range known_axes {
+		axes = append(axes, Axis{Name: name, Code: code})
+	}
+	return axes
+}
+
+/* returns all the buttons exposed by the virtual joystick */
+func Buttons() []Button {
+	buttons := make([]Button, 0, BUTTON_COUNT)
+	for number := 1; number <= BUTTON_COUNT; number++ {
+		button, _ := ParseButton(strconv.Itoa(number))
+		buttons = append(buttons, button)
+	}
+	return buttons
+}
+
+/*
+Parses an axis name (case insensitive); eg: "x", "rz" or "throttle"
+*/
+func ParseAxis(name string) (Axis, error) {
+	axis_name := strings.ToLower(strings.TrimSpace(name))
+	code, is_known := known_axes[axis_name]
+	if !is_known {
+		return Axis{}, fmt.Errorf("%w (%s)", ErrUnknownAxis, name)
+	}
+	return Axis{Name: axis_name, Code: code}, nil
+}
+
+/*
+Parses a button number (1 to BUTTON_COUNT)
+*/
+func ParseButton(name string) (Button, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(name))
+	if err != nil || number < 1 || number > BUTTON_COUNT {
+		return Button{}, fmt.Errorf("%w (%s); expected a number between 1 and %d", ErrUnknownButton, name, BUTTON_COUNT)
+	}
+	/* the first 16 buttons use the joystick button range (BTN_TRIGGER...), the rest the "trigger happy" range */
+	if number <= 16 {
+		return Button{Number: number, Code: 0x120 + uint16(number-1)}, nil
+	}
+	return Button{Number: number, Code: 0x2c0 + uint16(number-17)}, nil
+}
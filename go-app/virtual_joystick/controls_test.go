@@ -0,0 +1,45 @@
+package virtual_joystick
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAxis(t *testing.T) {
+	axis, err := ParseAxis(" Throttle ")
+	require.NoError(t, err)
+	assert.Equal(t, Axis{Name: "throttle", Code: 0x06}, axis)
+
+	_, err = ParseAxis("slider")
+	assert.ErrorIs(t, err, ErrUnknownAxis)
+}
+
+func TestParseButton(t *testing.T) {
+	/* the first 16 buttons use the joystick button range, the rest the "trigger happy" range */
+	button, err := ParseButton("1")
+	require.NoError(t, err)
+	assert.Equal(t, Button{Number: 1, Code: 0x120}, button)
+	button, err = ParseButton("16")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x12f), button.Code)
+	button, err = ParseButton("17")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(0x2c0), button.Code)
+
+	for _, name := range []string{"0", "33", "a", ""} {
+		_, err = ParseButton(name)
+		assert.ErrorIs(t, err, ErrUnknownButton, name)
+	}
+}
+
+func TestButtonsHaveUniqueCodes(t *testing.T) {
+	codes := map[uint16]bool{}
+	for _, button := range Buttons() {
+		assert.False(t, codes[button.Code], button.Number)
+		codes[button.Code] = true
+	}
+	assert.Len(t, codes, BUTTON_COUNT)
+	assert.Len(t, Axes(), len(known_axes))
+}
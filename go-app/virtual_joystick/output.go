@@ -0,0 +1,12 @@
+package virtual_joystick
+
+/*
+A virtual joystick output; axis values are in the range of 0 to 1 where 0.5 is the center
+*/
+type Output interface {
+	/* whether the output can (still) be used; false on unsupported platforms or once creating the device failed */
+	IsAvailable() bool
+	SetAxis(axis Axis, value float64) error
+	SetButton(button Button, pressed bool) error
+	Close() error
+}
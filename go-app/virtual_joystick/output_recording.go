@@ -0,0 +1,69 @@
+package virtual_joystick
+
+import "sync"
+
+type RecordingOutput_Event struct {
+	/* the axis name or the button number */
+	Axis   string
+	Button int
+	/* the axis value or 1 for pressed and 0 for released buttons */
+	Value float64
+}
+
+/* records the axis and button changes instead of sending them; used for testing */
+type RecordingOutput struct {
+	mutex     sync.Mutex
+	events    []RecordingOutput_Event
+	available bool
+}
+
+func NewRecordingOutput() *RecordingOutput {
+	return &RecordingOutput{
+		events:    []RecordingOutput_Event{},
+		available: true,
+	}
+}
+
+func (o *RecordingOutput) IsAvailable() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.available
+}
+
+/* simulates an output which can't be used (eg: on an unsupported platform) */
+func (o *RecordingOutput) SetAvailable(available bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.available = available
+}
+
+func (o *RecordingOutput) SetAxis(axis Axis, value float64) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, RecordingOutput_Event{Axis: axis.Name, Value: value})
+	return nil
+}
+
+func (o *RecordingOutput) SetButton(button Button, pressed bool) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	event := RecordingOutput_Event{Button: button.Number}
+	if pressed {
+		event.Value = 1
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+func (o *RecordingOutput) Close() error {
+	return nil
+}
+
+/* Returns a copy of the recorded events */
+func (o *RecordingOutput) Events() []RecordingOutput_Event {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	events := make([]RecordingOutput_Event, len(o.events))
+	copy(events, o.events)
+	return events
+}
@@ -0,0 +1,108 @@
+package virtual_joystick
+
+import (
+	"math"
+	"sync"
+	"tsw_controller_app/math_utils"
+	"tsw_controller_app/uinput"
+)
+
+const UINPUT_JOYSTICK_NAME = "TSW Controller Virtual Joystick"
+const UINPUT_AXIS_MIN = -32767
+const UINPUT_AXIS_MAX = 32767
+
+/*
+A Linux uinput virtual joystick. The device is only created once it is first used
+so no virtual device shows up for profiles which don't use it.
+*/
+type UinputOutput struct {
+	mutex    sync.Mutex
+	device   *uinput.Device
+	open_err error
+}
+
+func NewUinputOutput() *UinputOutput {
+	return &UinputOutput{}
+}
+
+func (o *UinputOutput) getDevice() (*uinput.Device, error) {
+	if o.device != nil || o.open_err != nil {
+		return o.device, o.open_err
+	}
+
+	abs_axes := []uinput.AbsAxisConfig{}
+	for _, axis := range Axes() {
+		abs_axes = append(abs_axes, uinput.AbsAxisConfig{
+			Code: axis.Code,
+			Min:  UINPUT_AXIS_MIN,
+			Max:  UINPUT_AXIS_MAX,
+		})
+	}
+	buttons := []uint16{}
+	for _, button := range Buttons() {
+		buttons = append(buttons, button.Code)
+	}
+
+	o.device, o.open_err = uinput.Open(uinput.DeviceConfig{
+		Name:    UINPUT_JOYSTICK_NAME,
+		Vendor:  0x1209,
+		Product: 0x5458,
+		Version: 1,
+		Keys:    buttons,
+		AbsAxes: abs_axes,
+	})
+	if o.open_err == nil {
+		/* center all the axes initially */
+		for _, axis := range abs_axes {
+			o.device.Emit(uinput.EV_ABS, axis.Code, 0)
+		}
+		o.device.Sync()
+	}
+	return o.device, o.open_err
+}
+
+func (o *UinputOutput) emit(ev_type uint16, code uint16, value int32) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	device, err := o.getDevice()
+	if err != nil {
+		return err
+	}
+	if err := device.Emit(ev_type, code, value); err != nil {
+		return err
+	}
+	return device.Sync()
+}
+
+func (o *UinputOutput) IsAvailable() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return uinput.IS_SUPPORTED && o.open_err == nil
+}
+
+func (o *UinputOutput) SetAxis(axis Axis, value float64) error {
+	raw_value := UINPUT_AXIS_MIN + math.Round(math_utils.Clamp(value, 0, 1)*(UINPUT_AXIS_MAX-UINPUT_AXIS_MIN))
+	return o.emit(uinput.EV_ABS, axis.Code, int32(raw_value))
+}
+
+func (o *UinputOutput) SetButton(button Button, pressed bool) error {
+	var value int32 = 0
+	if pressed {
+		value = 1
+	}
+	return o.emit(uinput.EV_KEY, button.Code, value)
+}
+
+func (o *UinputOutput) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.device == nil {
+		o.open_err = uinput.ErrClosed
+		return nil
+	}
+	err := o.device.Close()
+	o.device = nil
+	/* don't re-create the device once it has been closed */
+	o.open_err = uinput.ErrClosed
+	return err
+}